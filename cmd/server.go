@@ -47,17 +47,25 @@ and provide REST API interfaces for approval workflow management.`,
 		// 3. 初始化服务
 		auditLogSvc := service.NewAuditLogService(repository.NewAuditLogRepository(ctr.DB()))
 		templateSvc := service.NewTemplateService(ctr.TemplateManager(), ctr.DB(), auditLogSvc, ctr.OpenFGAClient())
-		taskSvc := service.NewTaskService(ctr.TaskManager(), ctr.DB(), auditLogSvc, ctr.OpenFGAClient())
+		taskSvc := service.NewTaskService(ctr.TaskManager(), ctr.DB(), auditLogSvc, cfg.Keycloak.AdminRole, ctr.OpenFGAClient())
 		querySvc := service.NewQueryService(ctr.DB(), ctr.TaskManager())
+		eventSvc := service.NewEventService(ctr.DB(), ctr.EventHandler(), auditLogSvc)
+		webhookSvc := service.NewWebhookService(ctr.DB(), ctr.EventHandler(), ctr.WebhookSigner(), auditLogSvc)
+		calendarSvc := service.NewCalendarService(ctr.DB(), auditLogSvc)
+		delegationSvc := service.NewDelegationService(ctr.DB(), ctr.TaskManager(), auditLogSvc, cfg.Keycloak.AdminRole)
 
 		// 4. 初始化控制器
 		templateController := api.NewTemplateController(templateSvc, ctr.DB())
 		taskController := api.NewTaskController(taskSvc)
 		queryController := api.NewQueryController(querySvc)
 		backupController := api.NewBackupController(ctr.BackupService())
+		eventController := api.NewEventController(eventSvc)
+		webhookController := api.NewWebhookController(webhookSvc)
+		calendarController := api.NewCalendarController(calendarSvc)
+		delegationController := api.NewDelegationController(delegationSvc)
 
 		// 5. 设置路由
-		router := setupRoutesWithControllers(ctr, templateController, taskController, queryController, backupController, cfg)
+		router := setupRoutesWithControllers(ctr, templateController, taskController, queryController, backupController, eventController, webhookController, calendarController, delegationController, cfg)
 
 		// 7. 启动服务器
 		addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -100,6 +108,10 @@ func setupRoutesWithControllers(
 	taskController *api.TaskController,
 	queryController *api.QueryController,
 	backupController *api.BackupController,
+	eventController *api.EventController,
+	webhookController *api.WebhookController,
+	calendarController *api.CalendarController,
+	delegationController *api.DelegationController,
 	cfg *config.Config,
 ) *gin.Engine {
 	// 使用配置的 host 和 port 设置 Swagger URL
@@ -123,41 +135,90 @@ func setupRoutesWithControllers(
 			templates.DELETE("/:id", templateController.Delete)
 			templates.GET("/:id/versions", templateController.ListVersions)
 			templates.DELETE("/:id/versions/:version", templateController.DeleteVersion)
+			templates.POST("/:id/webhooks/preview", templateController.PreviewWebhook)
 		}
 
-		// 任务管理路由
+		// 任务管理路由(单个任务的变更操作支持 If-Match 乐观锁,批量操作不读取 If-Match)
 		tasks := v1.Group("/tasks")
+		ifMatch := api.IfMatchMiddleware()
 		{
 			// 批量操作路由（必须在 /:id 之前）
 			tasks.POST("/batch/approve", taskController.BatchApprove)
 			tasks.POST("/batch/transfer", taskController.BatchTransfer)
+			tasks.POST("/migrate", taskController.MigrateTasks)
 
 			// 基础路由
 			tasks.POST("", taskController.Create)
 			tasks.GET("", queryController.ListTasks)
+			tasks.GET("/cc", queryController.ListCCTasks)
 
 			// 通用路由（必须在具体路径路由之前）
 			tasks.GET("/:id", taskController.Get)
-			tasks.DELETE("/:id", taskController.Delete)
+			tasks.DELETE("/:id", ifMatch, taskController.Delete)
 
 			// 具体路径的路由（必须在 /:id 之后，Gin 会优先匹配更长的路径）
-			tasks.POST("/:id/submit", taskController.Submit)
-			tasks.POST("/:id/approve", taskController.Approve)
-			tasks.POST("/:id/reject", taskController.Reject)
-			tasks.POST("/:id/cancel", taskController.Cancel)
-			tasks.POST("/:id/withdraw", taskController.Withdraw)
-			tasks.POST("/:id/transfer", taskController.Transfer)
-			tasks.POST("/:id/pause", taskController.Pause)
-			tasks.POST("/:id/resume", taskController.Resume)
-			tasks.POST("/:id/rollback", taskController.RollbackToNode)
-			tasks.POST("/:id/timeout", taskController.HandleTimeout)
+			tasks.POST("/:id/submit", ifMatch, taskController.Submit)
+			tasks.POST("/:id/approve", ifMatch, taskController.Approve)
+			tasks.POST("/:id/reject", ifMatch, taskController.Reject)
+			tasks.POST("/:id/cancel", ifMatch, taskController.Cancel)
+			tasks.POST("/:id/withdraw", ifMatch, taskController.Withdraw)
+			tasks.POST("/:id/transfer", ifMatch, taskController.Transfer)
+			tasks.POST("/:id/pause", ifMatch, taskController.Pause)
+			tasks.POST("/:id/resume", ifMatch, taskController.Resume)
+			tasks.POST("/:id/rollback", ifMatch, taskController.RollbackToNode)
+			tasks.POST("/:id/timeout", ifMatch, taskController.HandleTimeout)
+			tasks.POST("/:id/reminders/snooze", ifMatch, taskController.SnoozeReminder)
+			tasks.POST("/:id/return", ifMatch, taskController.Return)
+			tasks.POST("/:id/resubmit", ifMatch, taskController.Resubmit)
 			tasks.GET("/:id/records", queryController.GetRecords)
 			tasks.GET("/:id/history", queryController.GetHistory)
+			tasks.GET("/:id/rounds", queryController.GetRounds)
 
 			// 审批人相关路由（必须在 /:id 之后，Gin 会优先匹配更长的路径）
-			tasks.POST("/:id/approvers", taskController.AddApprover)
-			tasks.POST("/:id/approvers/replace", taskController.ReplaceApprover)
-			tasks.DELETE("/:id/approvers", taskController.RemoveApprover)
+			tasks.POST("/:id/approvers", ifMatch, taskController.AddApprover)
+			tasks.POST("/:id/approvers/replace", ifMatch, taskController.ReplaceApprover)
+			tasks.DELETE("/:id/approvers", ifMatch, taskController.RemoveApprover)
+		}
+
+		// 事件管理路由(投递记录、重放)
+		events := v1.Group("/events")
+		{
+			events.GET("", eventController.List)
+			events.POST("/replay", eventController.BatchReplay)
+			events.GET("/:id", eventController.Get)
+			events.GET("/:id/cloudevent", eventController.GetCloudEvent)
+			events.POST("/:id/replay", eventController.Replay)
+		}
+
+		// Webhook 订阅路由
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.POST("", webhookController.Create)
+			webhooks.GET("", webhookController.List)
+			webhooks.GET("/:id", webhookController.Get)
+			webhooks.PUT("/:id", webhookController.Update)
+			webhooks.DELETE("/:id", webhookController.Delete)
+			webhooks.POST("/:id/test", webhookController.Test)
+		}
+
+		// 工作日历路由
+		calendars := v1.Group("/calendars")
+		{
+			calendars.POST("", calendarController.Create)
+			calendars.GET("", calendarController.List)
+			calendars.GET("/:id", calendarController.Get)
+			calendars.PUT("/:id", calendarController.Update)
+			calendars.DELETE("/:id", calendarController.Delete)
+		}
+
+		// 审批委托路由
+		delegations := v1.Group("/delegations")
+		{
+			delegations.POST("", delegationController.Create)
+			delegations.GET("", delegationController.List)
+			delegations.GET("/:id", delegationController.Get)
+			delegations.PUT("/:id", delegationController.Update)
+			delegations.DELETE("/:id", delegationController.Delete)
 		}
 
 		// 备份管理路由
@@ -214,14 +214,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks": {
+        "/calendars": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "分页获取任务列表,支持多条件查询、排序",
+                "description": "获取所有工作日历",
                 "consumes": [
                     "application/json"
                 ],
@@ -229,90 +229,29 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "查询统计"
-                ],
-                "summary": "获取任务列表",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "任务状态",
-                        "name": "state",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "模板 ID",
-                        "name": "template_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "业务 ID",
-                        "name": "business_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "审批人",
-                        "name": "approver",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "创建时间起始",
-                        "name": "created_at_start",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "创建时间结束",
-                        "name": "created_at_end",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "页码",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 20,
-                        "description": "每页数量",
-                        "name": "page_size",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "default": "created_at",
-                        "description": "排序字段",
-                        "name": "sort_by",
-                        "in": "query"
-                    },
-                    {
-                        "enum": [
-                            "asc",
-                            "desc"
-                        ],
-                        "type": "string",
-                        "default": "desc",
-                        "description": "排序方向",
-                        "name": "order",
-                        "in": "query"
-                    }
+                    "工作日历"
                 ],
+                "summary": "获取工作日历列表",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.PaginatedResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.Calendar"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "500": {
@@ -329,7 +268,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "基于模板创建新的审批任务",
+                "description": "创建包含时区、每周工作日、工作时段、节假日和调休上班日的工作日历。模板配置或审批节点配置的 calendar_id 引用日历后,超时、提醒和统计只计算工作时间",
                 "consumes": [
                     "application/json"
                 ],
@@ -337,17 +276,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "工作日历"
                 ],
-                "summary": "创建审批任务",
+                "summary": "创建工作日历",
                 "parameters": [
                     {
-                        "description": "任务信息",
+                        "description": "日历信息",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.CreateTaskRequest"
+                            "$ref": "#/definitions/service.CalendarRequest"
                         }
                     }
                 ],
@@ -355,7 +294,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.Calendar"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -364,12 +315,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -379,14 +324,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/batch/approve": {
-            "post": {
+        "/calendars/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "批量审批多个任务",
+                "description": "根据 ID 获取工作日历",
                 "consumes": [
                     "application/json"
                 ],
@@ -394,18 +339,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "工作日历"
                 ],
-                "summary": "批量审批任务",
+                "summary": "获取工作日历详情",
                 "parameters": [
                     {
-                        "description": "批量审批请求",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/service.BatchApproveRequest"
-                        }
+                        "type": "string",
+                        "description": "日历 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -420,45 +363,28 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/service.BatchOperationResult"
-                                            }
+                                            "$ref": "#/definitions/service.Calendar"
                                         }
                                     }
                                 }
                             ]
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/tasks/batch/transfer": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "批量转交多个任务",
+                "description": "全量更新工作日历,运行中任务的超时和提醒时间在下次到期检查时按新日历重新计算",
                 "consumes": [
                     "application/json"
                 ],
@@ -466,17 +392,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "工作日历"
                 ],
-                "summary": "批量转交任务",
+                "summary": "更新工作日历",
                 "parameters": [
                     {
-                        "description": "批量转交请求",
+                        "type": "string",
+                        "description": "日历 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "日历信息",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.BatchTransferRequest"
+                            "$ref": "#/definitions/service.CalendarRequest"
                         }
                     }
                 ],
@@ -492,10 +425,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/service.BatchOperationResult"
-                                            }
+                                            "$ref": "#/definitions/service.Calendar"
                                         }
                                     }
                                 }
@@ -508,8 +438,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -521,16 +451,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tasks/{id}": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "根据 ID 获取任务详情",
+                "description": "删除工作日历,仍引用该日历的模板按自然时间计算超时和提醒",
                 "consumes": [
                     "application/json"
                 ],
@@ -538,13 +466,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "工作日历"
                 ],
-                "summary": "获取任务详情",
+                "summary": "删除工作日历",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
+                        "description": "日历 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -572,14 +500,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/approve": {
-            "post": {
+        "/delegations": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "审批人同意审批任务",
+                "description": "获取委托规则,非管理员只能看到自己作为委托人或代理人的规则",
                 "consumes": [
                     "application/json"
                 ],
@@ -587,50 +515,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "审批委托"
                 ],
-                "summary": "审批同意",
+                "summary": "获取委托规则列表",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "委托人",
+                        "name": "delegator",
+                        "in": "query"
                     },
                     {
-                        "description": "审批信息",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/service.ApproveRequest"
-                        }
+                        "type": "string",
+                        "description": "代理人",
+                        "name": "delegate",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.Delegation"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "500": {
@@ -640,16 +561,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tasks/{id}/approvers": {
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "在审批人列表中添加新的审批人",
+                "description": "设置委托人在指定时间段内的代理人,可以只委托某个模板的审批。委托期间到达委托人的审批自动转给代理人,审批记录保留\"代理人代委托人审批\";规则当前已生效时,运行中任务里委托人尚未审批的节点立即转给代理人。为其他用户设置委托需要管理员角色",
                 "consumes": [
                     "application/json"
                 ],
@@ -657,24 +576,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "审批委托"
                 ],
-                "summary": "加签",
+                "summary": "创建委托规则",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "加签信息",
+                        "description": "委托规则",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.AddApproverRequest"
+                            "$ref": "#/definitions/service.DelegationRequest"
                         }
                     }
                 ],
@@ -682,7 +594,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.Delegation"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -691,8 +615,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -704,14 +634,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/delegations/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "从审批人列表中移除指定的审批人",
+                "description": "根据 ID 获取委托规则,委托人、代理人和管理员可见",
                 "consumes": [
                     "application/json"
                 ],
@@ -719,38 +651,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "审批委托"
                 ],
-                "summary": "减签",
+                "summary": "获取委托规则详情",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
+                        "description": "委托规则 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "减签信息",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/service.RemoveApproverRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.Delegation"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "404": {
@@ -758,24 +687,16 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
                     }
                 }
-            }
-        },
-        "/tasks/{id}/approvers/replace": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "替换审批人,只能替换尚未审批的审批人",
+                "description": "全量更新委托规则。已转给代理人的任务不会转回,缩短或删除规则只影响之后到达的审批",
                 "consumes": [
                     "application/json"
                 ],
@@ -783,24 +704,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "审批委托"
                 ],
-                "summary": "替换审批人",
+                "summary": "更新委托规则",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
+                        "description": "委托规则 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "替换信息",
+                        "description": "委托规则",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.ReplaceApproverRequest"
+                            "$ref": "#/definitions/service.DelegationRequest"
                         }
                     }
                 ],
@@ -808,7 +729,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.Delegation"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -817,12 +750,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -830,16 +775,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tasks/{id}/pause": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "暂停任务,只有 pending、submitted、approving 状态可以暂停",
+                "description": "删除委托规则,已转给代理人的任务不会转回",
                 "consumes": [
                     "application/json"
                 ],
@@ -847,30 +790,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "审批委托"
                 ],
-                "summary": "暂停任务",
+                "summary": "删除委托规则",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
+                        "description": "委托规则 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "暂停原因",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "reason": {
-                                    "type": "string"
-                                }
-                            }
-                        }
                     }
                 ],
                 "responses": {
@@ -880,8 +809,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -901,14 +830,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/resume": {
-            "post": {
+        "/events": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "恢复任务,只有 paused 状态可以恢复",
+                "description": "分页获取任务事件及其 Webhook 投递记录,按创建时间倒序",
                 "consumes": [
                     "application/json"
                 ],
@@ -916,37 +845,81 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "事件管理"
                 ],
-                "summary": "恢复任务",
+                "summary": "获取事件列表",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "任务 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "name": "task_id",
+                        "in": "query"
                     },
                     {
-                        "description": "恢复原因",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "reason": {
-                                    "type": "string"
-                                }
-                            }
-                        }
+                        "type": "string",
+                        "description": "事件类型",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "success",
+                            "failed",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "投递状态",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间起始(RFC3339)",
+                        "name": "created_at_start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间结束(RFC3339)",
+                        "name": "created_at_end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "page_size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.PaginatedResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.EventDetail"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -955,12 +928,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -970,14 +937,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/rollback": {
+        "/events/replay": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "回退到指定节点,只能回退到已完成的节点",
+                "description": "批量重放失败或死信事件,指定 event_ids 时只重放这些事件,否则按任务、类型、状态和时间条件重放",
                 "consumes": [
                     "application/json"
                 ],
@@ -985,24 +952,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "事件管理"
                 ],
-                "summary": "回退到指定节点",
+                "summary": "批量重放事件",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "任务 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "回退信息",
+                        "description": "重放条件",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.RollbackRequest"
+                            "$ref": "#/definitions/service.ReplayEventsRequest"
                         }
                     }
                 ],
@@ -1010,7 +970,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.ReplayEventsResponse"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1019,12 +991,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1034,14 +1000,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/submit": {
-            "post": {
+        "/events/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "提交任务进入审批流程",
+                "description": "获取事件内容及其在各个 Webhook 端点的投递记录(响应码、耗时、截断的响应体)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1049,13 +1015,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "事件管理"
                 ],
-                "summary": "提交审批任务",
+                "summary": "获取事件详情",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
+                        "description": "事件 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1065,13 +1031,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.EventDetail"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "404": {
@@ -1079,9 +1051,58 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/events/{id}/cloudevent": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "返回事件的 CloudEvents 1.0 结构化格式,与 payload_format 为 cloudevents_structured 的订阅收到的请求体相同;二进制格式的 ce-* 请求头取自同名字段",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "事件管理"
+                ],
+                "summary": "获取事件的 CloudEvents 表示",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "事件 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/integration.CloudEvent"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -1089,14 +1110,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}/transfer": {
+        "/events/{id}/replay": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "将审批任务从原审批人转交给新审批人",
+                "description": "将失败或死信事件重置为待投递,由投递 worker 重新推送",
                 "consumes": [
                     "application/json"
                 ],
@@ -1104,25 +1125,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "任务管理"
+                    "事件管理"
                 ],
-                "summary": "转交审批",
+                "summary": "重放事件",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "任务 ID",
+                        "description": "事件 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "转交信息",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/service.TransferRequest"
-                        }
                     }
                 ],
                 "responses": {
@@ -1132,14 +1144,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/api.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -1153,14 +1159,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/templates": {
+        "/tasks": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "分页获取模板列表,支持搜索和排序",
+                "description": "分页获取任务列表,支持多条件查询、排序",
                 "consumes": [
                     "application/json"
                 ],
@@ -1168,10 +1174,52 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "模板管理"
+                    "查询统计"
                 ],
-                "summary": "获取模板列表",
+                "summary": "获取任务列表",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务状态",
+                        "name": "state",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "template_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "业务 ID",
+                        "name": "business_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "审批人",
+                        "name": "approver",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间起始",
+                        "name": "created_at_start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "创建时间结束",
+                        "name": "created_at_end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "父任务 ID(查询子任务)",
+                        "name": "parent_task_id",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -1186,12 +1234,6 @@ const docTemplate = `{
                         "name": "page_size",
                         "in": "query"
                     },
-                    {
-                        "type": "string",
-                        "description": "搜索关键词",
-                        "name": "search",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
                         "default": "created_at",
@@ -1215,11 +1257,11 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "$ref": "#/definitions/api.PaginatedResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -1238,7 +1280,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "创建新的审批模板",
+                "description": "基于模板创建新的审批任务",
                 "consumes": [
                     "application/json"
                 ],
@@ -1246,17 +1288,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "模板管理"
+                    "任务管理"
                 ],
-                "summary": "创建审批模板",
+                "summary": "创建审批任务",
                 "parameters": [
                     {
-                        "description": "模板信息",
+                        "description": "任务信息",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.CreateTemplateRequest"
+                            "$ref": "#/definitions/service.CreateTaskRequest"
                         }
                     }
                 ],
@@ -1288,14 +1330,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/templates/{id}": {
-            "get": {
+        "/tasks/batch/approve": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "根据 ID 获取模板详情,支持版本查询",
+                "description": "批量审批多个任务",
                 "consumes": [
                     "application/json"
                 ],
@@ -1303,33 +1345,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "模板管理"
+                    "任务管理"
                 ],
-                "summary": "获取模板详情",
+                "summary": "批量审批任务",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "模板 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "版本号,不传则获取最新版本",
-                        "name": "version",
-                        "in": "query"
+                        "description": "批量审批请求",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.BatchApproveRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.BatchOperationResult"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -1341,14 +1400,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/tasks/batch/transfer": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "更新现有审批模板(创建新版本)",
+                "description": "批量转交多个任务",
                 "consumes": [
                     "application/json"
                 ],
@@ -1356,24 +1417,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "模板管理"
+                    "任务管理"
                 ],
-                "summary": "更新审批模板",
+                "summary": "批量转交任务",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "模板 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "模板信息",
+                        "description": "批量转交请求",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/service.UpdateTemplateRequest"
+                            "$ref": "#/definitions/service.BatchTransferRequest"
                         }
                     }
                 ],
@@ -1381,7 +1435,22 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.BatchOperationResult"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1390,8 +1459,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/api.ErrorResponse"
                         }
@@ -1403,14 +1472,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/tasks/cc": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "删除指定的审批模板",
+                "description": "分页获取抄送给当前用户的任务(创建时指定的抄送人和通知节点的通知对象),过滤和排序参数与任务列表相同",
                 "consumes": [
                     "application/json"
                 ],
@@ -1418,23 +1489,147 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "模板管理"
+                    "查询统计"
                 ],
-                "summary": "删除审批模板",
+                "summary": "获取抄送给我的任务",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务状态",
+                        "name": "state",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "模板 ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "name": "template_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "排序字段",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "排序方向",
+                        "name": "order",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api.Response"
+                            "$ref": "#/definitions/api.PaginatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/migrate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "将运行中的任务迁移到模板的指定版本,支持节点映射和 dry-run 报告;需要管理员角色或模板的 editor 权限",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "迁移任务模板版本",
+                "parameters": [
+                    {
+                        "description": "迁移请求",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.MigrateTasksRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.MigrateTasksResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
                     "404": {
@@ -1452,14 +1647,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/templates/{id}/versions": {
+        "/tasks/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "获取指定模板的所有版本号列表",
+                "description": "根据 ID 获取任务详情,active_nodes 为当前活动节点集合(并行分支时包含多个节点)\n响应头 ETag 为任务修订号,变更操作可以携带 If-Match 请求头,修订号不一致时返回 412",
                 "consumes": [
                     "application/json"
                 ],
@@ -1467,13 +1662,78 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "模板管理"
+                    "任务管理"
                 ],
-                "summary": "获取模板版本列表",
+                "summary": "获取任务详情",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "模板 ID",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.TaskDetail"
+                                        }
+                                    }
+                                }
+                            ]
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "任务修订号"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除审批任务,只允许删除待审批或已取消状态的任务,且不能有审批记录",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "删除任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1486,6 +1746,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/api.Response"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1500,261 +1772,2911 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "api.ErrorResponse": {
-            "description": "错误响应格式,包含错误码、错误消息和错误详情",
-            "type": "object",
-            "properties": {
-                "code": {
-                    "description": "错误码",
-                    "type": "integer",
-                    "example": 400
+        },
+        "/tasks/{id}/approve": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "审批人同意审批任务",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "审批同意",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "审批信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.ApproveRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/approvers": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "在审批人列表中添加新的审批人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "加签",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "加签信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.AddApproverRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "从审批人列表中移除指定的审批人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "减签",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "减签信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.RemoveApproverRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/approvers/replace": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "替换审批人,只能替换尚未审批的审批人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "替换审批人",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "替换信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.ReplaceApproverRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "暂停任务,只有 pending、submitted、approving 状态可以暂停",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "暂停任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "暂停原因",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "reason": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/reminders/snooze": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "当前用户推迟自己在节点上的审批提醒(最长 7 天),到期前不再提醒",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "推迟审批提醒",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "推迟信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.SnoozeReminderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.SnoozeReminderResponse"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/resubmit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "发起人修改任务参数后重新提交被退回的任务,开始新一轮审批。params 为空时沿用原参数。只有任务记录的发起人可以重新提交,否则返回 403",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "重新提交被退回的任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "重新提交信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.ResubmitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "恢复任务,只有 paused 状态可以恢复",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "恢复任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "恢复原因",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "reason": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/return": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "活动节点上轮到审批的审批人将任务退回发起人修改。本轮审批数据归档为历史轮次,任务回到 pending 状态等待发起人重新提交;重新提交后从开始节点还是退回节点继续由节点配置的 return_policy 决定",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "退回发起人修改",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "退回信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.ReturnRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/rollback": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "回退到指定节点,只能回退到已完成的节点",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "回退到指定节点",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "回退信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.RollbackRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/rounds": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取任务因退回修改(kind=return)或驳回(kind=reject)而结束的历次审批轮次,按轮次升序,每轮附带结束时的任务数据",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "查询统计"
+                ],
+                "summary": "获取已结束的审批轮次",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.TaskRound"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/submit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "提交任务进入审批流程",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "提交审批任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/timeout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "检查任务的活动审批节点,对已超时的节点执行节点配置的超时动作(标记超时、自动通过、自动驳回、升级或转派)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "处理任务超时",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/transfer": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "将审批任务从原审批人转交给新审批人",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "任务管理"
+                ],
+                "summary": "转交审批",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "转交信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.TransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "分页获取模板列表,支持搜索和排序",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "获取模板列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "页码",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "每页数量",
+                        "name": "page_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "搜索关键词",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "排序字段",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "排序方向",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "创建新的审批模板",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "创建审批模板",
+                "parameters": [
+                    {
+                        "description": "模板信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据 ID 获取模板详情,支持版本查询",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "获取模板详情",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "版本号,不传则获取最新版本",
+                        "name": "version",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "更新现有审批模板(创建新版本)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "更新审批模板",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "模板信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.UpdateTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除指定的审批模板",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "删除审批模板",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/versions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取指定模板的所有版本号列表",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "获取模板版本列表",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/versions/{version}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除指定模板的指定版本",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "删除模板版本",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "版本号",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/templates/{id}/webhooks/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "使用指定事件(须属于该模板的任务)或示例数据渲染 Webhook 请求体模板和请求头模板,返回渲染结果,不发送请求",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "模板管理"
+                ],
+                "summary": "预览 Webhook 请求模板",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "模板 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "请求模板",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.PreviewWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/integration.RenderedWebhook"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "获取所有全局 Webhook 订阅(不包含认证令牌和签名密钥)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook 订阅"
+                ],
+                "summary": "获取 Webhook 订阅列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/service.WebhookSubscription"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "创建全局 Webhook 订阅,匹配事件类型、模板和业务 ID 的事件会在模板 Webhook 之外额外推送到订阅地址",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook 订阅"
+                ],
+                "summary": "创建 Webhook 订阅",
+                "parameters": [
+                    {
+                        "description": "订阅信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.WebhookSubscription"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "根据 ID 获取 Webhook 订阅(不包含认证令牌和签名密钥)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook 订阅"
+                ],
+                "summary": "获取 Webhook 订阅详情",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "订阅 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.WebhookSubscription"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "全量更新 Webhook 订阅,认证类型不变时 token 留空表示沿用原令牌或签名密钥,填写新的 hmac 密钥即轮换",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook 订阅"
+                ],
+                "summary": "更新 Webhook 订阅",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "订阅 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "订阅信息",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/service.UpdateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/service.WebhookSubscription"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "删除 Webhook 订阅及其签名密钥",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook 订阅"
+                ],
+                "summary": "删除 Webhook 订阅",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "订阅 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/test": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "使用订阅的认证和签名配置向订阅地址同步发送一个 webhook_test 事件,返回响应码、耗时和响应体",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook 订阅"
+                ],
+                "summary": "发送测试事件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "订阅 ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/api.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/integration.WebhookTestResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.ErrorResponse": {
+            "description": "错误响应格式,包含错误码、错误消息和错误详情",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "错误码",
+                    "type": "integer",
+                    "example": 400
+                },
+                "detail": {
+                    "description": "错误详情(可选)",
+                    "type": "string",
+                    "example": "validation failed"
+                },
+                "message": {
+                    "description": "错误消息",
+                    "type": "string",
+                    "example": "invalid request"
+                }
+            }
+        },
+        "api.PaginatedResponse": {
+            "description": "分页响应格式,包含数据列表和分页信息",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "data": {
+                    "description": "数据列表"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "success"
+                },
+                "pagination": {
+                    "description": "分页信息",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/api.PaginationInfo"
+                        }
+                    ]
+                }
+            }
+        },
+        "api.PaginationInfo": {
+            "description": "分页信息,包含当前页码、每页数量、总记录数和总页数",
+            "type": "object",
+            "properties": {
+                "page": {
+                    "description": "当前页码",
+                    "type": "integer",
+                    "example": 1
+                },
+                "page_size": {
+                    "description": "每页数量",
+                    "type": "integer",
+                    "example": 20
+                },
+                "total": {
+                    "description": "总记录数",
+                    "type": "integer",
+                    "example": 100
+                },
+                "total_page": {
+                    "description": "总页数",
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "api.Response": {
+            "description": "统一响应格式,包含状态码、消息和数据",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "状态码: 0 表示成功,非 0 表示失败",
+                    "type": "integer",
+                    "example": 0
+                },
+                "data": {
+                    "description": "响应数据"
+                },
+                "message": {
+                    "description": "响应消息",
+                    "type": "string",
+                    "example": "success"
+                }
+            }
+        },
+        "integration.ApproverProvenance": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "description": "来源说明,如角色名、上级层级、参数字段",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "来源类型",
+                    "type": "string"
+                }
+            }
+        },
+        "integration.CloudEvent": {
+            "description": "CloudEvents 1.0 事件信封(结构化格式的请求体),data 为任务事件内容",
+            "type": "object",
+            "properties": {
+                "data": {
+                    "description": "任务事件内容(与默认格式的请求体相同)",
+                    "type": "object"
+                },
+                "datacontenttype": {
+                    "description": "data 的内容类型",
+                    "type": "string",
+                    "example": "application/json"
+                },
+                "id": {
+                    "description": "事件 ID,重试时不变",
+                    "type": "string",
+                    "example": "2f1c3a6e-8d0b-4c1e-9a57-0e4b7f3d9c21"
+                },
+                "source": {
+                    "description": "服务地址",
+                    "type": "string",
+                    "example": "https://approval.example.com"
+                },
+                "specversion": {
+                    "description": "CloudEvents 版本",
+                    "type": "string",
+                    "example": "1.0"
+                },
+                "subject": {
+                    "description": "任务 ID",
+                    "type": "string",
+                    "example": "task-001"
+                },
+                "time": {
+                    "description": "事件产生时间",
+                    "type": "string",
+                    "example": "2024-01-01T08:00:00Z"
+                },
+                "type": {
+                    "description": "事件类型",
+                    "type": "string",
+                    "example": "com.approval.task.approved"
+                }
+            }
+        },
+        "integration.ReminderState": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "已提醒次数",
+                    "type": "integer"
+                },
+                "last_at": {
+                    "description": "最近一次提醒时间",
+                    "type": "string"
+                },
+                "snoozed_until": {
+                    "description": "推迟到该时间之后再提醒",
+                    "type": "string"
+                }
+            }
+        },
+        "integration.RenderedWebhook": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "description": "请求体",
+                    "type": "string"
+                },
+                "headers": {
+                    "description": "模板渲染的请求头",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "integration.ReturnInfo": {
+            "type": "object",
+            "properties": {
+                "active_nodes": {
+                    "description": "退回时的活动节点,从退回节点继续时重新激活",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "comment": {
+                    "description": "退回意见",
+                    "type": "string"
+                },
+                "node_id": {
+                    "description": "退回任务的节点",
+                    "type": "string"
+                },
+                "resubmit_from": {
+                    "description": "重新提交的起点: start/returned_node",
+                    "type": "string"
+                },
+                "returned_at": {
+                    "description": "退回时间",
+                    "type": "string"
+                },
+                "returned_by": {
+                    "description": "退回人",
+                    "type": "string"
+                }
+            }
+        },
+        "integration.ServiceCallState": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "已调用次数",
+                    "type": "integer"
+                },
+                "failed": {
+                    "description": "重试已用尽,任务恢复后重新调用",
+                    "type": "boolean"
+                },
+                "last_error": {
+                    "description": "最近一次调用的错误",
+                    "type": "string"
+                },
+                "last_status": {
+                    "description": "最近一次调用的响应状态码",
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "description": "下次调用时间",
+                    "type": "string"
+                }
+            }
+        },
+        "integration.SubprocessChild": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "创建时间",
+                    "type": "string"
+                },
+                "node_id": {
+                    "description": "父任务中的子流程节点 ID",
+                    "type": "string"
+                },
+                "state": {
+                    "description": "子任务状态",
+                    "type": "string"
+                },
+                "task_id": {
+                    "description": "子任务 ID",
+                    "type": "string"
+                },
+                "template_id": {
+                    "description": "子任务模板 ID",
+                    "type": "string"
+                }
+            }
+        },
+        "integration.TaskMigrationResult": {
+            "type": "object",
+            "properties": {
+                "can_migrate": {
+                    "description": "是否可以安全迁移",
+                    "type": "boolean"
+                },
+                "from_version": {
+                    "description": "迁移前的模板版本",
+                    "type": "integer"
+                },
+                "migrated": {
+                    "description": "是否已迁移(dry-run 时始终为 false)",
+                    "type": "boolean"
+                },
+                "node_mapping": {
+                    "description": "进行中节点的映射结果(原节点 ID -\u003e 新节点 ID)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "reasons": {
+                    "description": "不能迁移的原因",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "state": {
+                    "description": "任务状态",
+                    "type": "string"
+                },
+                "task_id": {
+                    "description": "任务 ID",
+                    "type": "string"
+                },
+                "to_version": {
+                    "description": "目标模板版本",
+                    "type": "integer"
+                }
+            }
+        },
+        "integration.TimerState": {
+            "type": "object",
+            "properties": {
+                "fire_at": {
+                    "description": "触发时间",
+                    "type": "string"
+                }
+            }
+        },
+        "integration.WebhookTestResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "失败原因",
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "请求耗时(毫秒)",
+                    "type": "integer"
+                },
+                "response_body": {
+                    "description": "响应体(截断)",
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "HTTP 响应码",
+                    "type": "integer"
+                },
+                "success": {
+                    "description": "是否成功",
+                    "type": "boolean"
+                }
+            }
+        },
+        "integration.WorkingPeriod": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "string",
+                    "example": "18:00"
+                },
+                "start": {
+                    "type": "string",
+                    "example": "09:00"
+                }
+            }
+        },
+        "service.AddApproverRequest": {
+            "description": "加签的请求参数",
+            "type": "object",
+            "required": [
+                "approver",
+                "node_id"
+            ],
+            "properties": {
+                "approver": {
+                    "description": "新审批人 ID",
+                    "type": "string",
+                    "example": "user-003"
+                },
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string",
+                    "example": "node-001"
+                },
+                "reason": {
+                    "description": "加签原因",
+                    "type": "string",
+                    "example": "加签原因"
+                }
+            }
+        },
+        "service.ApproveRequest": {
+            "description": "审批同意的请求参数",
+            "type": "object",
+            "required": [
+                "node_id"
+            ],
+            "properties": {
+                "attachments": {
+                    "description": "附件列表",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "[\"file1.pdf\"",
+                        "\"file2.pdf\"]"
+                    ]
+                },
+                "comment": {
+                    "description": "审批意见",
+                    "type": "string",
+                    "example": "同意"
+                },
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string",
+                    "example": "node-001"
+                }
+            }
+        },
+        "service.BackupInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "database_type": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "service.BatchApproveRequest": {
+            "description": "批量审批的请求参数",
+            "type": "object",
+            "required": [
+                "node_id",
+                "task_ids"
+            ],
+            "properties": {
+                "comment": {
+                    "description": "审批意见",
+                    "type": "string"
+                },
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string"
+                },
+                "task_ids": {
+                    "description": "任务 ID 列表",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "service.BatchOperationResult": {
+            "description": "批量操作的结果",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "错误信息(如果失败)",
+                    "type": "string"
+                },
+                "success": {
+                    "description": "是否成功",
+                    "type": "boolean"
+                },
+                "task_id": {
+                    "description": "任务 ID",
+                    "type": "string"
+                }
+            }
+        },
+        "service.BatchTransferRequest": {
+            "description": "批量转交的请求参数",
+            "type": "object",
+            "required": [
+                "new_approver",
+                "node_id",
+                "old_approver",
+                "task_ids"
+            ],
+            "properties": {
+                "comment": {
+                    "description": "转交原因",
+                    "type": "string"
+                },
+                "new_approver": {
+                    "description": "新审批人 ID",
+                    "type": "string"
+                },
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string"
+                },
+                "old_approver": {
+                    "description": "原审批人 ID",
+                    "type": "string"
+                },
+                "task_ids": {
+                    "description": "任务 ID 列表",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "service.Calendar": {
+            "description": "工作日历详情",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "extra_workdays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "holidays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 },
-                "detail": {
-                    "description": "错误详情(可选)",
-                    "type": "string",
-                    "example": "validation failed"
+                "workdays": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "message": {
-                    "description": "错误消息",
-                    "type": "string",
-                    "example": "invalid request"
+                "working_hours": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/integration.WorkingPeriod"
+                    }
                 }
             }
         },
-        "api.PaginatedResponse": {
-            "description": "分页响应格式,包含数据列表和分页信息",
+        "service.CalendarRequest": {
             "type": "object",
+            "required": [
+                "name"
+            ],
             "properties": {
-                "code": {
-                    "type": "integer",
-                    "example": 0
+                "description": {
+                    "type": "string"
                 },
-                "data": {
-                    "description": "数据列表"
+                "extra_workdays": {
+                    "description": "调休上班日(YYYY-MM-DD)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2025-09-28"
+                    ]
                 },
-                "message": {
+                "holidays": {
+                    "description": "节假日(YYYY-MM-DD)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "2025-10-01",
+                        "2025-10-02"
+                    ]
+                },
+                "name": {
                     "type": "string",
-                    "example": "success"
+                    "example": "中国大陆工作日"
                 },
-                "pagination": {
-                    "description": "分页信息",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/api.PaginationInfo"
-                        }
+                "timezone": {
+                    "description": "IANA 时区,默认 UTC",
+                    "type": "string",
+                    "example": "Asia/Shanghai"
+                },
+                "workdays": {
+                    "description": "每周工作日,默认周一至周五",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "mon",
+                        "tue",
+                        "wed",
+                        "thu",
+                        "fri"
                     ]
+                },
+                "working_hours": {
+                    "description": "工作时段,默认 09:00-18:00",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/integration.WorkingPeriod"
+                    }
                 }
             }
         },
-        "api.PaginationInfo": {
-            "description": "分页信息,包含当前页码、每页数量、总记录数和总页数",
+        "service.CreateTaskRequest": {
+            "type": "object"
+        },
+        "service.CreateTemplateRequest": {
+            "type": "object"
+        },
+        "service.CreateWebhookRequest": {
             "type": "object",
+            "required": [
+                "name",
+                "url"
+            ],
             "properties": {
-                "page": {
-                    "description": "当前页码",
-                    "type": "integer",
-                    "example": 1
+                "auth": {
+                    "description": "认证配置",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/service.WebhookAuth"
+                        }
+                    ]
                 },
-                "page_size": {
-                    "description": "每页数量",
-                    "type": "integer",
-                    "example": 20
+                "business_id": {
+                    "description": "只订阅该业务 ID 的事件",
+                    "type": "string"
                 },
-                "total": {
-                    "description": "总记录数",
-                    "type": "integer",
-                    "example": 100
+                "enabled": {
+                    "description": "是否启用,默认启用",
+                    "type": "boolean"
                 },
-                "total_page": {
-                    "description": "总页数",
-                    "type": "integer",
-                    "example": 5
+                "event_types": {
+                    "description": "订阅的事件类型,为空时订阅所有事件",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "task_created",
+                        "task_completed"
+                    ]
+                },
+                "headers": {
+                    "description": "自定义请求头",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "description": "请求方法,默认 POST",
+                    "type": "string",
+                    "example": "POST"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "数据平台"
+                },
+                "payload_format": {
+                    "description": "请求体格式,默认 raw(事件 JSON)",
+                    "type": "string",
+                    "enum": [
+                        "raw",
+                        "cloudevents_structured",
+                        "cloudevents_binary"
+                    ],
+                    "example": "cloudevents_structured"
+                },
+                "template_id": {
+                    "description": "只订阅该模板的事件",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/approval-events"
                 }
             }
         },
-        "api.Response": {
-            "description": "统一响应格式,包含状态码、消息和数据",
+        "service.Delegation": {
+            "description": "委托规则详情",
             "type": "object",
             "properties": {
-                "code": {
-                    "description": "状态码: 0 表示成功,非 0 表示失败",
-                    "type": "integer",
-                    "example": 0
+                "active": {
+                    "description": "当前是否处于委托期间",
+                    "type": "boolean"
                 },
-                "data": {
-                    "description": "响应数据"
+                "created_at": {
+                    "type": "string"
                 },
-                "message": {
-                    "description": "响应消息",
-                    "type": "string",
-                    "example": "success"
+                "created_by": {
+                    "type": "string"
+                },
+                "delegate": {
+                    "type": "string"
+                },
+                "delegator": {
+                    "type": "string"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "redirected_tasks": {
+                    "description": "创建或更新后立即转给代理人的运行中任务数",
+                    "type": "integer"
+                },
+                "start_at": {
+                    "type": "string"
+                },
+                "template_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "service.AddApproverRequest": {
-            "description": "加签的请求参数",
+        "service.DelegationRequest": {
             "type": "object",
             "required": [
-                "approver",
-                "node_id"
+                "delegate",
+                "end_at",
+                "start_at"
             ],
             "properties": {
-                "approver": {
-                    "description": "新审批人 ID",
+                "delegate": {
+                    "description": "代理人",
                     "type": "string",
-                    "example": "user-002"
+                    "example": "bob"
                 },
-                "node_id": {
-                    "description": "节点 ID",
+                "delegator": {
+                    "description": "委托人,默认当前用户;为其他用户设置需要管理员角色",
                     "type": "string",
-                    "example": "node-001"
+                    "example": "alice"
+                },
+                "end_at": {
+                    "description": "委托结束时间(不含)",
+                    "type": "string",
+                    "example": "2025-10-08T00:00:00+08:00"
                 },
                 "reason": {
-                    "description": "加签原因",
                     "type": "string",
-                    "example": "加签原因"
+                    "example": "国庆休假"
+                },
+                "start_at": {
+                    "description": "委托开始时间",
+                    "type": "string",
+                    "example": "2025-10-01T00:00:00+08:00"
+                },
+                "template_id": {
+                    "description": "只委托该模板的审批,为空时不限",
+                    "type": "string"
                 }
             }
         },
-        "service.ApproveRequest": {
-            "description": "审批同意的请求参数",
+        "service.EventDelivery": {
+            "description": "向一个 Webhook 端点投递一次事件的结果",
             "type": "object",
-            "required": [
-                "node_id"
-            ],
             "properties": {
-                "attachments": {
-                    "description": "附件列表",
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    },
-                    "example": [
-                        "[\"file1.pdf\"",
-                        "\"file2.pdf\"]"
-                    ]
+                "attempt": {
+                    "description": "第几次投递",
+                    "type": "integer"
                 },
-                "comment": {
-                    "description": "审批意见",
-                    "type": "string",
-                    "example": "同意"
+                "created_at": {
+                    "type": "string"
                 },
-                "node_id": {
-                    "description": "节点 ID",
-                    "type": "string",
-                    "example": "node-001"
+                "endpoint": {
+                    "description": "Webhook URL",
+                    "type": "string"
+                },
+                "error": {
+                    "description": "失败原因",
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "请求耗时(毫秒)",
+                    "type": "integer"
+                },
+                "response_body": {
+                    "description": "响应体(截断)",
+                    "type": "string"
+                },
+                "status_code": {
+                    "description": "HTTP 响应码",
+                    "type": "integer"
+                },
+                "success": {
+                    "description": "是否成功",
+                    "type": "boolean"
+                },
+                "target": {
+                    "description": "投递目标标识(消息总线、模板 Webhook 或全局订阅)",
+                    "type": "string"
                 }
             }
         },
-        "service.BackupInfo": {
+        "service.EventDetail": {
+            "description": "事件及其在各个 Webhook 端点的投递记录",
             "type": "object",
             "properties": {
+                "attempts": {
+                    "description": "已投递次数",
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "database_type": {
+                "data": {
+                    "description": "事件内容",
+                    "type": "object"
+                },
+                "deliveries": {
+                    "description": "投递记录",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.EventDelivery"
+                    }
+                },
+                "id": {
+                    "description": "事件 ID",
                     "type": "string"
                 },
-                "filename": {
+                "last_error": {
+                    "description": "最近一次投递失败的原因",
                     "type": "string"
                 },
-                "path": {
+                "next_attempt_at": {
+                    "description": "下次投递时间",
                     "type": "string"
                 },
-                "size": {
-                    "type": "integer"
+                "status": {
+                    "description": "投递状态: pending/success/failed/dead",
+                    "type": "string"
+                },
+                "task_id": {
+                    "description": "任务 ID",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "事件类型",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "service.BatchApproveRequest": {
-            "description": "批量审批的请求参数",
+        "service.MigrateTasksRequest": {
+            "description": "将运行中的任务迁移到模板指定版本的请求参数",
             "type": "object",
             "required": [
-                "node_id",
-                "task_ids"
+                "target_version",
+                "template_id"
             ],
             "properties": {
-                "comment": {
-                    "description": "审批意见",
-                    "type": "string"
+                "dry_run": {
+                    "description": "只生成报告,不修改任务",
+                    "type": "boolean"
                 },
-                "node_id": {
-                    "description": "节点 ID",
-                    "type": "string"
+                "from_version": {
+                    "description": "只迁移该版本上的任务(可选)",
+                    "type": "integer",
+                    "example": 2
+                },
+                "node_mapping": {
+                    "description": "节点映射(原节点 ID -\u003e 新节点 ID),未映射的节点按同名节点处理",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "target_version": {
+                    "description": "目标版本",
+                    "type": "integer",
+                    "example": 3
                 },
                 "task_ids": {
-                    "description": "任务 ID 列表",
+                    "description": "只迁移指定任务(可选)",
                     "type": "array",
-                    "minItems": 1,
                     "items": {
                         "type": "string"
                     }
+                },
+                "template_id": {
+                    "description": "模板 ID",
+                    "type": "string",
+                    "example": "tpl-001"
                 }
             }
         },
-        "service.BatchOperationResult": {
-            "description": "批量操作的结果项",
+        "service.MigrateTasksResponse": {
+            "description": "任务版本迁移的报告",
             "type": "object",
             "properties": {
-                "error": {
-                    "description": "错误信息(如果失败)",
-                    "type": "string"
+                "dry_run": {
+                    "description": "是否为 dry-run",
+                    "type": "boolean"
+                },
+                "migratable": {
+                    "description": "可以安全迁移的任务数",
+                    "type": "integer"
+                },
+                "migrated": {
+                    "description": "已迁移的任务数",
+                    "type": "integer"
+                },
+                "target_version": {
+                    "description": "目标版本",
+                    "type": "integer"
                 },
-                "success": {
-                    "description": "是否成功",
-                    "type": "boolean"
+                "tasks": {
+                    "description": "每个任务的迁移结果",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/integration.TaskMigrationResult"
+                    }
                 },
-                "task_id": {
-                    "description": "任务 ID",
-                    "type": "string"
+                "total": {
+                    "description": "检查的任务数",
+                    "type": "integer"
                 }
             }
         },
-        "service.BatchTransferRequest": {
-            "description": "批量转交的请求参数",
+        "service.PreviewWebhookRequest": {
+            "description": "使用指定事件或示例数据渲染 Webhook 请求模板",
             "type": "object",
-            "required": [
-                "new_approver",
-                "node_id",
-                "old_approver",
-                "task_ids"
-            ],
             "properties": {
-                "comment": {
-                    "description": "转交说明",
-                    "type": "string"
-                },
-                "new_approver": {
-                    "description": "新审批人 ID",
-                    "type": "string"
-                },
-                "node_id": {
-                    "description": "节点 ID",
+                "body_template": {
+                    "description": "请求体模板,为空时发送事件 JSON",
                     "type": "string"
                 },
-                "old_approver": {
-                    "description": "原审批人 ID",
+                "event_id": {
+                    "description": "用于渲染的事件 ID(须属于该模板的任务),为空时使用示例数据",
                     "type": "string"
                 },
-                "task_ids": {
-                    "description": "任务 ID 列表",
-                    "type": "array",
-                    "minItems": 1,
-                    "items": {
+                "header_templates": {
+                    "description": "请求头模板,在固定请求头之后设置",
+                    "type": "object",
+                    "additionalProperties": {
                         "type": "string"
                     }
                 }
             }
         },
-        "service.CreateTaskRequest": {
-            "type": "object"
-        },
-        "service.CreateTemplateRequest": {
-            "type": "object"
-        },
         "service.RemoveApproverRequest": {
             "description": "减签的请求参数",
             "type": "object",
@@ -1766,7 +4688,7 @@ const docTemplate = `{
                 "approver": {
                     "description": "要移除的审批人 ID",
                     "type": "string",
-                    "example": "user-001"
+                    "example": "user-003"
                 },
                 "node_id": {
                     "description": "节点 ID",
@@ -1811,6 +4733,76 @@ const docTemplate = `{
                 }
             }
         },
+        "service.ReplayEventsRequest": {
+            "description": "批量重放失败或死信事件,指定 event_ids 时只重放这些事件,否则按条件重放",
+            "type": "object",
+            "properties": {
+                "created_at_end": {
+                    "description": "创建时间结束",
+                    "type": "string"
+                },
+                "created_at_start": {
+                    "description": "创建时间起始",
+                    "type": "string"
+                },
+                "event_ids": {
+                    "description": "事件 ID 列表",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "description": "投递状态,默认 failed 和 dead",
+                    "type": "string",
+                    "enum": [
+                        "failed",
+                        "dead"
+                    ],
+                    "example": "dead"
+                },
+                "task_id": {
+                    "description": "任务 ID",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "事件类型",
+                    "type": "string"
+                }
+            }
+        },
+        "service.ReplayEventsResponse": {
+            "description": "批量重放事件的结果",
+            "type": "object",
+            "properties": {
+                "replayed": {
+                    "description": "重放的事件数",
+                    "type": "integer"
+                }
+            }
+        },
+        "service.ResubmitRequest": {
+            "type": "object"
+        },
+        "service.ReturnRequest": {
+            "description": "将任务退回发起人修改的请求参数",
+            "type": "object",
+            "required": [
+                "node_id"
+            ],
+            "properties": {
+                "comment": {
+                    "description": "退回意见",
+                    "type": "string",
+                    "example": "请补充预算明细"
+                },
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string",
+                    "example": "node-001"
+                }
+            }
+        },
         "service.RollbackRequest": {
             "description": "回退到指定节点的请求参数",
             "type": "object",
@@ -1830,20 +4822,171 @@ const docTemplate = `{
                 }
             }
         },
+        "service.SnoozeReminderRequest": {
+            "description": "推迟当前用户在节点上的审批提醒,duration 和 until 二选一",
+            "type": "object",
+            "required": [
+                "node_id"
+            ],
+            "properties": {
+                "duration": {
+                    "description": "推迟时长(Go duration 格式)",
+                    "type": "string",
+                    "example": "4h"
+                },
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string",
+                    "example": "node-001"
+                },
+                "until": {
+                    "description": "推迟到指定时间",
+                    "type": "string",
+                    "example": "2025-01-02T09:00:00Z"
+                }
+            }
+        },
+        "service.SnoozeReminderResponse": {
+            "description": "推迟提醒的结果",
+            "type": "object",
+            "properties": {
+                "node_id": {
+                    "description": "节点 ID",
+                    "type": "string"
+                },
+                "snoozed_until": {
+                    "description": "该时间之前不再提醒",
+                    "type": "string"
+                }
+            }
+        },
+        "service.TaskDetail": {
+            "description": "任务详情,在任务数据的基础上附加流程引擎的活动节点集合",
+            "type": "object",
+            "properties": {
+                "active_nodes": {
+                    "description": "当前活动节点集合(并行分支时包含多个节点)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "approver_sources": {
+                    "description": "ApproverSources 审批人的解析来源(节点 ID -\u003e 审批人 ID -\u003e 来源)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "object",
+                        "additionalProperties": {
+                            "$ref": "#/definitions/integration.ApproverProvenance"
+                        }
+                    }
+                },
+                "cc": {
+                    "description": "CC 任务的抄送人(创建时指定的抄送人和通知节点的通知对象)",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "children": {
+                    "description": "Children 子流程节点创建的子任务",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/integration.SubprocessChild"
+                    }
+                },
+                "parent_node_id": {
+                    "description": "ParentNodeID 父任务中创建该任务的子流程节点 ID",
+                    "type": "string"
+                },
+                "parent_task_id": {
+                    "description": "ParentTaskID 父任务 ID,只有子流程节点创建的子任务存在",
+                    "type": "string"
+                },
+                "reminders": {
+                    "description": "Reminders 审批人的提醒状态(节点 ID -\u003e 审批人 ID -\u003e 提醒状态)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "object",
+                        "additionalProperties": {
+                            "$ref": "#/definitions/integration.ReminderState"
+                        }
+                    }
+                },
+                "returned": {
+                    "description": "Returned 任务被退回修改的信息,只在等待发起人重新提交时存在",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/integration.ReturnInfo"
+                        }
+                    ]
+                },
+                "revision": {
+                    "description": "修订号(乐观锁),与响应头 ETag 一致",
+                    "type": "integer"
+                },
+                "round": {
+                    "description": "Round 当前审批轮次,每次退回修改后重新提交加 1",
+                    "type": "integer"
+                },
+                "service_calls": {
+                    "description": "ServiceCalls 活动服务节点的调用状态(节点 ID -\u003e 调用状态)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/integration.ServiceCallState"
+                    }
+                },
+                "timers": {
+                    "description": "Timers 活动定时节点的触发时间(节点 ID -\u003e 计时状态)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/integration.TimerState"
+                    }
+                }
+            }
+        },
+        "service.TaskRound": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "本轮结束方式",
+                    "type": "string"
+                },
+                "nodeID": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "round": {
+                    "type": "integer"
+                },
+                "task": {
+                    "description": "本轮结束时的任务数据",
+                    "type": "object"
+                },
+                "taskID": {
+                    "type": "string"
+                }
+            }
+        },
         "service.TransferRequest": {
             "description": "转交审批的请求参数",
             "type": "object",
             "required": [
-                "from_approver",
                 "node_id",
                 "to_approver"
             ],
             "properties": {
-                "from_approver": {
-                    "description": "原审批人 ID",
-                    "type": "string",
-                    "example": "user-001"
-                },
                 "node_id": {
                     "description": "节点 ID",
                     "type": "string",
@@ -1863,6 +5006,143 @@ const docTemplate = `{
         },
         "service.UpdateTemplateRequest": {
             "type": "object"
+        },
+        "service.UpdateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "url"
+            ],
+            "properties": {
+                "auth": {
+                    "$ref": "#/definitions/service.WebhookAuth"
+                },
+                "business_id": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "task_created",
+                        "task_completed"
+                    ]
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "method": {
+                    "type": "string",
+                    "example": "POST"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "数据平台"
+                },
+                "payload_format": {
+                    "type": "string",
+                    "enum": [
+                        "raw",
+                        "cloudevents_structured",
+                        "cloudevents_binary"
+                    ],
+                    "example": "cloudevents_structured"
+                },
+                "template_id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/approval-events"
+                }
+            }
+        },
+        "service.WebhookAuth": {
+            "description": "Webhook 认证配置,hmac 类型对请求签名",
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "basic 用户名或 header 请求头名称",
+                    "type": "string"
+                },
+                "token": {
+                    "description": "令牌、密码或 HMAC 签名密钥(响应中不返回)",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "认证类型",
+                    "type": "string",
+                    "enum": [
+                        "bearer",
+                        "basic",
+                        "header",
+                        "hmac"
+                    ],
+                    "example": "hmac"
+                }
+            }
+        },
+        "service.WebhookSubscription": {
+            "description": "Webhook 订阅详情(不包含认证令牌和签名密钥)",
+            "type": "object",
+            "properties": {
+                "auth": {
+                    "$ref": "#/definitions/service.WebhookAuth"
+                },
+                "business_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "headers": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "payload_format": {
+                    "type": "string"
+                },
+                "template_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/service"
+)
+
+// CalendarController 工作日历控制器
+type CalendarController struct {
+	calendarService service.CalendarService
+}
+
+// NewCalendarController 创建工作日历控制器
+func NewCalendarController(calendarService service.CalendarService) *CalendarController {
+	return &CalendarController{
+		calendarService: calendarService,
+	}
+}
+
+// Create 创建工作日历
+// @Summary      创建工作日历
+// @Description  创建包含时区、每周工作日、工作时段、节假日和调休上班日的工作日历。模板配置或审批节点配置的 calendar_id 引用日历后,超时、提醒和统计只计算工作时间
+// @Tags         工作日历
+// @Accept       json
+// @Produce      json
+// @Param        request body service.CalendarRequest true "日历信息"
+// @Success      200  {object}  Response{data=service.Calendar}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /calendars [post]
+// @Security     BearerAuth
+func (c *CalendarController) Create(ctx *gin.Context) {
+	var req service.CalendarRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	calendar, err := c.calendarService.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		c.handleError(ctx, "failed to create calendar", err)
+		return
+	}
+
+	Success(ctx, calendar)
+}
+
+// List 列出工作日历
+// @Summary      获取工作日历列表
+// @Description  获取所有工作日历
+// @Tags         工作日历
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  Response{data=[]service.Calendar}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /calendars [get]
+// @Security     BearerAuth
+func (c *CalendarController) List(ctx *gin.Context) {
+	calendars, err := c.calendarService.List()
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to list calendars", err.Error())
+		return
+	}
+
+	Success(ctx, calendars)
+}
+
+// Get 获取工作日历
+// @Summary      获取工作日历详情
+// @Description  根据 ID 获取工作日历
+// @Tags         工作日历
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "日历 ID"
+// @Success      200  {object}  Response{data=service.Calendar}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /calendars/{id} [get]
+// @Security     BearerAuth
+func (c *CalendarController) Get(ctx *gin.Context) {
+	calendar, err := c.calendarService.Get(ctx.Param("id"))
+	if err != nil {
+		c.handleError(ctx, "failed to get calendar", err)
+		return
+	}
+
+	Success(ctx, calendar)
+}
+
+// Update 更新工作日历
+// @Summary      更新工作日历
+// @Description  全量更新工作日历,运行中任务的超时和提醒时间在下次到期检查时按新日历重新计算
+// @Tags         工作日历
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "日历 ID"
+// @Param        request body service.CalendarRequest true "日历信息"
+// @Success      200  {object}  Response{data=service.Calendar}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /calendars/{id} [put]
+// @Security     BearerAuth
+func (c *CalendarController) Update(ctx *gin.Context) {
+	var req service.CalendarRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	calendar, err := c.calendarService.Update(ctx.Request.Context(), ctx.Param("id"), &req)
+	if err != nil {
+		c.handleError(ctx, "failed to update calendar", err)
+		return
+	}
+
+	Success(ctx, calendar)
+}
+
+// Delete 删除工作日历
+// @Summary      删除工作日历
+// @Description  删除工作日历,仍引用该日历的模板按自然时间计算超时和提醒
+// @Tags         工作日历
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "日历 ID"
+// @Success      200  {object}  Response
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /calendars/{id} [delete]
+// @Security     BearerAuth
+func (c *CalendarController) Delete(ctx *gin.Context) {
+	if err := c.calendarService.Delete(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		c.handleError(ctx, "failed to delete calendar", err)
+		return
+	}
+
+	Success(ctx, nil)
+}
+
+// handleError 将日历服务错误映射为 HTTP 响应
+func (c *CalendarController) handleError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrCalendarNotFound):
+		Error(ctx, http.StatusNotFound, "calendar not found", err.Error())
+	case errors.Is(err, service.ErrInvalidCalendar):
+		Error(ctx, http.StatusBadRequest, "invalid calendar", err.Error())
+	default:
+		Error(ctx, http.StatusInternalServerError, message, err.Error())
+	}
+}
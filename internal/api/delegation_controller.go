@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/service"
+)
+
+// DelegationController 审批委托控制器
+type DelegationController struct {
+	delegationService service.DelegationService
+}
+
+// NewDelegationController 创建审批委托控制器
+func NewDelegationController(delegationService service.DelegationService) *DelegationController {
+	return &DelegationController{
+		delegationService: delegationService,
+	}
+}
+
+// Create 创建委托规则
+// @Summary      创建委托规则
+// @Description  设置委托人在指定时间段内的代理人,可以只委托某个模板的审批。委托期间到达委托人的审批自动转给代理人,审批记录保留"代理人代委托人审批";规则当前已生效时,运行中任务里委托人尚未审批的节点立即转给代理人。为其他用户设置委托需要管理员角色
+// @Tags         审批委托
+// @Accept       json
+// @Produce      json
+// @Param        request body service.DelegationRequest true "委托规则"
+// @Success      200  {object}  Response{data=service.Delegation}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /delegations [post]
+// @Security     BearerAuth
+func (c *DelegationController) Create(ctx *gin.Context) {
+	var req service.DelegationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	delegation, err := c.delegationService.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		c.handleError(ctx, "failed to create delegation", err)
+		return
+	}
+
+	Success(ctx, delegation)
+}
+
+// List 列出委托规则
+// @Summary      获取委托规则列表
+// @Description  获取委托规则,非管理员只能看到自己作为委托人或代理人的规则
+// @Tags         审批委托
+// @Accept       json
+// @Produce      json
+// @Param        delegator query string false "委托人"
+// @Param        delegate  query string false "代理人"
+// @Success      200  {object}  Response{data=[]service.Delegation}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /delegations [get]
+// @Security     BearerAuth
+func (c *DelegationController) List(ctx *gin.Context) {
+	var filter service.DelegationListFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid query", err.Error())
+		return
+	}
+
+	delegations, err := c.delegationService.List(ctx.Request.Context(), &filter)
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to list delegations", err.Error())
+		return
+	}
+
+	Success(ctx, delegations)
+}
+
+// Get 获取委托规则
+// @Summary      获取委托规则详情
+// @Description  根据 ID 获取委托规则,委托人、代理人和管理员可见
+// @Tags         审批委托
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "委托规则 ID"
+// @Success      200  {object}  Response{data=service.Delegation}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /delegations/{id} [get]
+// @Security     BearerAuth
+func (c *DelegationController) Get(ctx *gin.Context) {
+	delegation, err := c.delegationService.Get(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		c.handleError(ctx, "failed to get delegation", err)
+		return
+	}
+
+	Success(ctx, delegation)
+}
+
+// Update 更新委托规则
+// @Summary      更新委托规则
+// @Description  全量更新委托规则。已转给代理人的任务不会转回,缩短或删除规则只影响之后到达的审批
+// @Tags         审批委托
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "委托规则 ID"
+// @Param        request body service.DelegationRequest true "委托规则"
+// @Success      200  {object}  Response{data=service.Delegation}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /delegations/{id} [put]
+// @Security     BearerAuth
+func (c *DelegationController) Update(ctx *gin.Context) {
+	var req service.DelegationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	delegation, err := c.delegationService.Update(ctx.Request.Context(), ctx.Param("id"), &req)
+	if err != nil {
+		c.handleError(ctx, "failed to update delegation", err)
+		return
+	}
+
+	Success(ctx, delegation)
+}
+
+// Delete 删除委托规则
+// @Summary      删除委托规则
+// @Description  删除委托规则,已转给代理人的任务不会转回
+// @Tags         审批委托
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "委托规则 ID"
+// @Success      200  {object}  Response
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /delegations/{id} [delete]
+// @Security     BearerAuth
+func (c *DelegationController) Delete(ctx *gin.Context) {
+	if err := c.delegationService.Delete(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		c.handleError(ctx, "failed to delete delegation", err)
+		return
+	}
+
+	Success(ctx, nil)
+}
+
+// handleError 将委托服务错误映射为 HTTP 响应
+func (c *DelegationController) handleError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrDelegationNotFound):
+		Error(ctx, http.StatusNotFound, "delegation not found", err.Error())
+	case errors.Is(err, service.ErrInvalidDelegation):
+		Error(ctx, http.StatusBadRequest, "invalid delegation", err.Error())
+	case errors.Is(err, service.ErrDelegationForbidden):
+		Error(ctx, http.StatusForbidden, "forbidden", err.Error())
+	case errors.Is(err, service.ErrDelegationConflict):
+		Error(ctx, http.StatusConflict, "delegation conflict", err.Error())
+	default:
+		Error(ctx, http.StatusInternalServerError, message, err.Error())
+	}
+}
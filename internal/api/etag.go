@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/service"
+)
+
+// IfMatchMiddleware If-Match 请求头中间件
+// 将 If-Match 中的任务修订号(即 GET 响应的 ETag)放入请求 context,变更操作只在修订号一致时执行
+// If-Match 为 * 或未设置时不校验;修订号只对应一个任务,不能用于批量操作的路由
+func IfMatchMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+		if ifMatch == "" || ifMatch == "*" || c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		revision, err := parseETag(ifMatch)
+		if err != nil {
+			Error(c, http.StatusBadRequest, "invalid If-Match header", err.Error())
+			c.Abort()
+			return
+		}
+
+		c.Request = c.Request.WithContext(service.WithExpectedRevision(c.Request.Context(), revision))
+		c.Next()
+	}
+}
+
+// formatETag 将任务修订号格式化为 ETag
+func formatETag(revision int64) string {
+	return strconv.Quote(strconv.FormatInt(revision, 10))
+}
+
+// parseETag 从 ETag 解析任务修订号(兼容弱校验前缀 W/ 和未加引号的值)
+func parseETag(etag string) (int64, error) {
+	etag = strings.TrimPrefix(etag, "W/")
+	etag = strings.Trim(etag, `"`)
+	return strconv.ParseInt(etag, 10, 64)
+}
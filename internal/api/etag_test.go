@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := map[string]int64{
+		formatETag(42): 42,
+		`"7"`:          7,
+		`W/"7"`:        7,
+		`7`:            7,
+	}
+	for etag, want := range tests {
+		got, err := parseETag(etag)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", etag, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %d, want %d", etag, got, want)
+		}
+	}
+
+	if _, err := parseETag(`"abc"`); err == nil {
+		t.Fatal("expected invalid ETag to fail")
+	}
+}
+
+func TestIfMatchMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(IfMatchMiddleware())
+	handler := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/tasks/:id", handler)
+	router.POST("/tasks/:id/approve", handler)
+
+	tests := []struct {
+		method  string
+		ifMatch string
+		want    int
+	}{
+		{http.MethodPost, "", http.StatusNoContent},
+		{http.MethodPost, "*", http.StatusNoContent},
+		{http.MethodPost, `"3"`, http.StatusNoContent},
+		{http.MethodPost, `"three"`, http.StatusBadRequest},
+		{http.MethodGet, `"three"`, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		path := "/tasks/t1"
+		if tt.method == http.MethodPost {
+			path += "/approve"
+		}
+		req := httptest.NewRequest(tt.method, path, nil)
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s If-Match %q: status = %d, want %d", tt.method, tt.ifMatch, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/service"
+)
+
+// EventController 事件控制器
+type EventController struct {
+	eventService service.EventService
+}
+
+// NewEventController 创建事件控制器
+func NewEventController(eventService service.EventService) *EventController {
+	return &EventController{
+		eventService: eventService,
+	}
+}
+
+// List 列出事件
+// @Summary      获取事件列表
+// @Description  分页获取任务事件及其 Webhook 投递记录,按创建时间倒序
+// @Tags         事件管理
+// @Accept       json
+// @Produce      json
+// @Param        task_id query string false "任务 ID"
+// @Param        type query string false "事件类型"
+// @Param        status query string false "投递状态" Enums(pending, success, failed, dead)
+// @Param        created_at_start query string false "创建时间起始(RFC3339)"
+// @Param        created_at_end query string false "创建时间结束(RFC3339)"
+// @Param        page query int false "页码" default(1)
+// @Param        page_size query int false "每页数量" default(20)
+// @Success      200  {object}  PaginatedResponse{data=[]service.EventDetail}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /events [get]
+// @Security     BearerAuth
+func (c *EventController) List(ctx *gin.Context) {
+	var filter service.ListEventsFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid query parameters", err.Error())
+		return
+	}
+
+	// 设置默认值
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize <= 0 {
+		filter.PageSize = 20
+	}
+
+	events, total, err := c.eventService.List(&filter)
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to list events", err.Error())
+		return
+	}
+
+	// 计算总页数
+	totalPage := int((total + int64(filter.PageSize) - 1) / int64(filter.PageSize))
+
+	Paginated(ctx, events, PaginationInfo{
+		Page:      filter.Page,
+		PageSize:  filter.PageSize,
+		Total:     total,
+		TotalPage: totalPage,
+	})
+}
+
+// Get 获取事件详情
+// @Summary      获取事件详情
+// @Description  获取事件内容及其在各个 Webhook 端点的投递记录(响应码、耗时、截断的响应体)
+// @Tags         事件管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "事件 ID"
+// @Success      200  {object}  Response{data=service.EventDetail}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /events/{id} [get]
+// @Security     BearerAuth
+func (c *EventController) Get(ctx *gin.Context) {
+	detail, err := c.eventService.Get(ctx.Param("id"))
+	if err != nil {
+		Error(ctx, http.StatusNotFound, "event not found", err.Error())
+		return
+	}
+
+	Success(ctx, detail)
+}
+
+// GetCloudEvent 获取事件的 CloudEvents 表示
+// @Summary      获取事件的 CloudEvents 表示
+// @Description  返回事件的 CloudEvents 1.0 结构化格式,与 payload_format 为 cloudevents_structured 的订阅收到的请求体相同;二进制格式的 ce-* 请求头取自同名字段
+// @Tags         事件管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "事件 ID"
+// @Success      200  {object}  Response{data=integration.CloudEvent}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /events/{id}/cloudevent [get]
+// @Security     BearerAuth
+func (c *EventController) GetCloudEvent(ctx *gin.Context) {
+	ce, err := c.eventService.GetCloudEvent(ctx.Param("id"))
+	if err != nil {
+		Error(ctx, http.StatusNotFound, "event not found", err.Error())
+		return
+	}
+
+	Success(ctx, ce)
+}
+
+// Replay 重放事件
+// @Summary      重放事件
+// @Description  将失败或死信事件重置为待投递,由投递 worker 重新推送
+// @Tags         事件管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "事件 ID"
+// @Success      200  {object}  Response
+// @Failure      409  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /events/{id}/replay [post]
+// @Security     BearerAuth
+func (c *EventController) Replay(ctx *gin.Context) {
+	if err := c.eventService.Replay(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		if errors.Is(err, service.ErrEventNotReplayable) {
+			Error(ctx, http.StatusConflict, "event cannot be replayed", err.Error())
+			return
+		}
+		Error(ctx, http.StatusInternalServerError, "failed to replay event", err.Error())
+		return
+	}
+
+	Success(ctx, nil)
+}
+
+// BatchReplay 批量重放事件
+// @Summary      批量重放事件
+// @Description  批量重放失败或死信事件,指定 event_ids 时只重放这些事件,否则按任务、类型、状态和时间条件重放
+// @Tags         事件管理
+// @Accept       json
+// @Produce      json
+// @Param        request body service.ReplayEventsRequest true "重放条件"
+// @Success      200  {object}  Response{data=service.ReplayEventsResponse}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /events/replay [post]
+// @Security     BearerAuth
+func (c *EventController) BatchReplay(ctx *gin.Context) {
+	var req service.ReplayEventsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	resp, err := c.eventService.BatchReplay(ctx.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrEventNotReplayable) {
+			Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+			return
+		}
+		Error(ctx, http.StatusInternalServerError, "failed to replay events", err.Error())
+		return
+	}
+
+	Success(ctx, resp)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/service"
+	"gorm.io/gorm"
+)
+
+// newEventRouter 创建注册事件路由的 router,events 为预置的事件 ID -> 任务 ID/投递状态
+func newEventRouter(t *testing.T, events map[string][2]string) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	now := time.Now()
+	for id, event := range events {
+		eventModel := &model.EventModel{
+			ID:             id,
+			TaskID:         event[0],
+			Type:           "task_created",
+			Data:           []byte(`{}`),
+			Status:         event[1],
+			RetryCount:     5,
+			LeaseOwner:     "worker-1",
+			LeaseExpiresAt: &now,
+			LastError:      "connection refused",
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := db.Create(eventModel).Error; err != nil {
+			t.Fatalf("failed to create event: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	controller := NewEventController(service.NewEventService(db, nil, nil))
+	router.GET("/events", controller.List)
+	router.POST("/events/replay", controller.BatchReplay)
+	router.POST("/events/:id/replay", controller.Replay)
+	return router, db
+}
+
+// eventStatus 读取事件的投递状态
+func eventStatus(t *testing.T, db *gorm.DB, id string) *model.EventModel {
+	t.Helper()
+	var eventModel model.EventModel
+	if err := db.Where("id = ?", id).First(&eventModel).Error; err != nil {
+		t.Fatalf("failed to get event: %v", err)
+	}
+	return &eventModel
+}
+
+func TestReplayEvent(t *testing.T) {
+	router, db := newEventRouter(t, map[string][2]string{
+		"evt-success": {"task-1", model.EventStatusSuccess},
+		"evt-pending": {"task-1", model.EventStatusPending},
+		"evt-failed":  {"task-1", model.EventStatusFailed},
+		"evt-dead":    {"task-2", model.EventStatusDead},
+	})
+
+	// 只有失败和死信事件可以重放
+	for _, id := range []string{"evt-success", "evt-pending"} {
+		if w := serve(router, http.MethodPost, "/events/"+id+"/replay", ""); w.Code != http.StatusConflict {
+			t.Errorf("%s: status = %d, want %d", id, w.Code, http.StatusConflict)
+		}
+	}
+
+	for _, id := range []string{"evt-failed", "evt-dead"} {
+		if w := serve(router, http.MethodPost, "/events/"+id+"/replay", ""); w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", id, w.Code, w.Body.String())
+		}
+		replayed := eventStatus(t, db, id)
+		if replayed.Status != model.EventStatusPending || replayed.RetryCount != 0 || replayed.LeaseOwner != "" || replayed.LeaseExpiresAt != nil {
+			t.Fatalf("%s after replay: %+v", id, replayed)
+		}
+		// 已重放的事件不能再次重放
+		if w := serve(router, http.MethodPost, "/events/"+id+"/replay", ""); w.Code != http.StatusConflict {
+			t.Errorf("%s replayed twice: status = %d, want %d", id, w.Code, http.StatusConflict)
+		}
+	}
+}
+
+func TestBatchReplayDeadLetters(t *testing.T) {
+	router, db := newEventRouter(t, map[string][2]string{
+		"evt-1": {"task-1", model.EventStatusDead},
+		"evt-2": {"task-1", model.EventStatusFailed},
+		"evt-3": {"task-2", model.EventStatusDead},
+		"evt-4": {"task-2", model.EventStatusSuccess},
+	})
+
+	// 死信队列即 status=dead 的事件
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		Pagination PaginationInfo `json:"pagination"`
+	}
+	w := serve(router, http.MethodGet, "/events?status=dead", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Pagination.Total != 2 {
+		t.Fatalf("dead letters = %d, want 2", list.Pagination.Total)
+	}
+
+	if w := serve(router, http.MethodPost, "/events/replay", `{"status":"success"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("replay succeeded events: status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	tests := []struct {
+		body string
+		want int64
+	}{
+		{`{"task_id":"task-1","status":"dead"}`, 1},
+		{`{"event_ids":["evt-4"]}`, 0},
+		{`{}`, 2},
+		{`{}`, 0},
+	}
+	for _, tt := range tests {
+		w := serve(router, http.MethodPost, "/events/replay", tt.body)
+		var resp struct {
+			Data service.ReplayEventsResponse `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", tt.body, w.Code, w.Body.String())
+		}
+		if resp.Data.Replayed != tt.want {
+			t.Errorf("%s: replayed = %d, want %d", tt.body, resp.Data.Replayed, tt.want)
+		}
+	}
+	if got := eventStatus(t, db, "evt-4").Status; got != model.EventStatusSuccess {
+		t.Fatalf("succeeded event status = %s after replay", got)
+	}
+}
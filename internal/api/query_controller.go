@@ -1,11 +1,13 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mautops/approval-gin/internal/service"
+	"github.com/mautops/approval-kit/pkg/task"
 	"github.com/mautops/approval-kit/pkg/types"
 )
 
@@ -33,6 +35,7 @@ func NewQueryController(queryService service.QueryService) *QueryController {
 // @Param        approver query string false "审批人"
 // @Param        created_at_start query string false "创建时间起始"
 // @Param        created_at_end query string false "创建时间结束"
+// @Param        parent_task_id query string false "父任务 ID(查询子任务)"
 // @Param        page query int false "页码" default(1)
 // @Param        page_size query int false "每页数量" default(20)
 // @Param        sort_by query string false "排序字段" default(created_at)
@@ -43,10 +46,63 @@ func NewQueryController(queryService service.QueryService) *QueryController {
 // @Router       /tasks [get]
 // @Security     BearerAuth
 func (c *QueryController) ListTasks(ctx *gin.Context) {
+	filter, ok := bindListTasksFilter(ctx)
+	if !ok {
+		return
+	}
+
+	tasks, total, err := c.queryService.ListTasks(filter)
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to list tasks", err.Error())
+		return
+	}
+
+	paginatedTasks(ctx, filter, tasks, total)
+}
+
+// ListCCTasks 列出抄送给我的任务
+// @Summary      获取抄送给我的任务
+// @Description  分页获取抄送给当前用户的任务(创建时指定的抄送人和通知节点的通知对象),过滤和排序参数与任务列表相同
+// @Tags         查询统计
+// @Accept       json
+// @Produce      json
+// @Param        state query string false "任务状态"
+// @Param        template_id query string false "模板 ID"
+// @Param        page query int false "页码" default(1)
+// @Param        page_size query int false "每页数量" default(20)
+// @Param        sort_by query string false "排序字段" default(created_at)
+// @Param        order query string false "排序方向" Enums(asc, desc) default(desc)
+// @Success      200  {object}  PaginatedResponse
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tasks/cc [get]
+// @Security     BearerAuth
+func (c *QueryController) ListCCTasks(ctx *gin.Context) {
+	filter, ok := bindListTasksFilter(ctx)
+	if !ok {
+		return
+	}
+
+	tasks, total, err := c.queryService.ListCCTasks(ctx.Request.Context(), filter)
+	if errors.Is(err, service.ErrUnauthenticated) {
+		Error(ctx, http.StatusUnauthorized, "user is not authenticated", err.Error())
+		return
+	}
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to list cc tasks", err.Error())
+		return
+	}
+
+	paginatedTasks(ctx, filter, tasks, total)
+}
+
+// bindListTasksFilter 解析任务列表查询参数,解析失败时已写入错误响应
+func bindListTasksFilter(ctx *gin.Context) (*service.ListTasksFilter, bool) {
 	var filter service.ListTasksFilter
 	if err := ctx.ShouldBindQuery(&filter); err != nil {
 		Error(ctx, http.StatusBadRequest, "invalid query parameters", err.Error())
-		return
+		return nil, false
 	}
 
 	// 手动解析 state 参数（因为 Gin 无法直接将字符串绑定到 types.TaskState）
@@ -81,6 +137,11 @@ func (c *QueryController) ListTasks(ctx *gin.Context) {
 		filter.EndTime = &endTimeStr
 	}
 
+	// 手动解析 parent_task_id 参数(查询子流程节点创建的子任务)
+	if parentTaskID := ctx.Query("parent_task_id"); parentTaskID != "" {
+		filter.ParentID = &parentTaskID
+	}
+
 	// 设置默认值
 	if filter.Page <= 0 {
 		filter.Page = 1
@@ -89,12 +150,11 @@ func (c *QueryController) ListTasks(ctx *gin.Context) {
 		filter.PageSize = 20
 	}
 
-	tasks, total, err := c.queryService.ListTasks(&filter)
-	if err != nil {
-		Error(ctx, http.StatusInternalServerError, "failed to list tasks", err.Error())
-		return
-	}
+	return &filter, true
+}
 
+// paginatedTasks 返回分页的任务列表
+func paginatedTasks(ctx *gin.Context, filter *service.ListTasksFilter, tasks []*task.Task, total int64) {
 	// 计算总页数
 	totalPage := int((total + int64(filter.PageSize) - 1) / int64(filter.PageSize))
 
@@ -132,3 +192,25 @@ func (c *QueryController) GetHistory(ctx *gin.Context) {
 	Success(ctx, history)
 }
 
+// GetRounds 获取已结束的审批轮次
+// @Summary      获取已结束的审批轮次
+// @Description  获取任务因退回修改(kind=return)或驳回(kind=reject)而结束的历次审批轮次,按轮次升序,每轮附带结束时的任务数据
+// @Tags         查询统计
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "任务 ID"
+// @Success      200  {object}  Response{data=[]service.TaskRound}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tasks/{id}/rounds [get]
+// @Security     BearerAuth
+func (c *QueryController) GetRounds(ctx *gin.Context) {
+	taskID := ctx.Param("id")
+
+	rounds, err := c.queryService.GetRounds(taskID)
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to get rounds", err.Error())
+		return
+	}
+
+	Success(ctx, rounds)
+}
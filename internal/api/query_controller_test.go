@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-gin/internal/service"
+	"github.com/mautops/approval-kit/pkg/template"
+	"gorm.io/gorm"
+)
+
+// newTestTaskManager 创建使用内存数据库的任务管理器,并创建单节点审批模板 leave
+func newTestTaskManager(t *testing.T) (*gorm.DB, *integration.DBTaskManager) {
+	t.Helper()
+	db := newTestDB(t)
+	templates := integration.NewTemplateManager(db).(*integration.DBTemplateManager)
+	nodes := json.RawMessage(`{"start":{"id":"start","type":"start"},
+		"review":{"id":"review","type":"approval","config":{"approver_sources":[{"type":"users","users":["ann"]}]}},
+		"end":{"id":"end","type":"end"}}`)
+	edges := json.RawMessage(`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+	if err := templates.CreateWithRawGraph(&template.Template{ID: "leave", Name: "leave", Version: 1}, nodes, edges, nil); err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	return db, integration.NewTaskManager(db, templates, nil, nil).(*integration.DBTaskManager)
+}
+
+func TestListCCTasks(t *testing.T) {
+	db, tasks := newTestTaskManager(t)
+	created := make(map[string][]string)
+	for _, cc := range [][]string{{"cat"}, {"cat", "dan"}, nil} {
+		tsk, err := tasks.CreateWithCC("leave", "biz", json.RawMessage(`{}`), "ini", cc)
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		for _, userID := range cc {
+			created[userID] = append(created[userID], tsk.ID)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(withUser)
+	router.GET("/tasks/cc", NewQueryController(service.NewQueryService(db, tasks)).ListCCTasks)
+
+	tests := []struct {
+		user  string
+		query string
+		want  int
+		total int64
+		items int
+	}{
+		{"cat", "", http.StatusOK, 2, 2},
+		{"dan", "", http.StatusOK, 1, 1},
+		{"ann", "", http.StatusOK, 0, 0},
+		{"cat", "?page_size=1", http.StatusOK, 2, 1},
+		{"", "", http.StatusUnauthorized, 0, 0},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/tasks/cc"+tt.query, nil)
+		req.Header.Set("X-User", tt.user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s%s: status = %d, want %d", tt.user, tt.query, w.Code, tt.want)
+			continue
+		}
+		if tt.want != http.StatusOK {
+			continue
+		}
+		var resp struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+			Pagination PaginationInfo `json:"pagination"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Pagination.Total != tt.total || len(resp.Data) != tt.items {
+			t.Errorf("%s%s: got %d of %d tasks, want %d of %d", tt.user, tt.query, len(resp.Data), resp.Pagination.Total, tt.items, tt.total)
+		}
+		for _, item := range resp.Data {
+			if !containsID(created[tt.user], item.ID) {
+				t.Errorf("%s: listed task %q that was not copied to the user", tt.user, item.ID)
+			}
+		}
+	}
+}
+
+// containsID 判断任务 ID 列表是否包含 id
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
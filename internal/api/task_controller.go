@@ -1,11 +1,13 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/integration"
 	"github.com/mautops/approval-gin/internal/service"
 	"github.com/mautops/approval-gin/internal/utils"
 )
@@ -32,9 +34,37 @@ func (c *TaskController) validateTaskID(ctx *gin.Context, id string) bool {
 }
 
 // handleServiceError 统一处理服务层错误
+// 并发修改返回 409,If-Match 修订号不一致返回 412,前置钩子拒绝返回 422(message 为钩子返回的原因),
+// 前置钩子不可用(fail_closed)返回 503
 func (c *TaskController) handleServiceError(ctx *gin.Context, err error, operation string) bool {
 	if err != nil {
-		Error(ctx, http.StatusInternalServerError, "failed to "+operation, err.Error())
+		var denied *integration.PreActionDeniedError
+		switch {
+		case errors.As(err, &denied):
+			message := denied.Reason
+			if message == "" {
+				message = integration.ErrPreActionDenied.Error()
+			}
+			Error(ctx, http.StatusUnprocessableEntity, message, err.Error())
+		case errors.Is(err, integration.ErrPreActionHookFailed):
+			Error(ctx, http.StatusServiceUnavailable, "pre-action hook unavailable", err.Error())
+		case errors.Is(err, integration.ErrInvalidSnooze):
+			Error(ctx, http.StatusBadRequest, "invalid reminder snooze", err.Error())
+		case errors.Is(err, integration.ErrInvalidRejectTarget):
+			Error(ctx, http.StatusBadRequest, "invalid reject target", err.Error())
+		case errors.Is(err, integration.ErrNotReturned):
+			Error(ctx, http.StatusConflict, "task is not returned for revision", err.Error())
+		case errors.Is(err, service.ErrNotTaskInitiator):
+			Error(ctx, http.StatusForbidden, "not the task initiator", err.Error())
+		case errors.Is(err, integration.ErrNotPendingApprover):
+			Error(ctx, http.StatusForbidden, "not a pending approver of the node", err.Error())
+		case errors.Is(err, integration.ErrTaskConflict):
+			Error(ctx, http.StatusConflict, "task was modified concurrently", err.Error())
+		case errors.Is(err, integration.ErrRevisionMismatch):
+			Error(ctx, http.StatusPreconditionFailed, "task revision does not match If-Match", err.Error())
+		default:
+			Error(ctx, http.StatusInternalServerError, "failed to "+operation, err.Error())
+		}
 		return false
 	}
 	return true
@@ -71,12 +101,14 @@ func (c *TaskController) Create(ctx *gin.Context) {
 
 // Get 获取任务
 // @Summary      获取任务详情
-// @Description  根据 ID 获取任务详情
+// @Description  根据 ID 获取任务详情,active_nodes 为当前活动节点集合(并行分支时包含多个节点)
+// @Description  响应头 ETag 为任务修订号,变更操作可以携带 If-Match 请求头,修订号不一致时返回 412
 // @Tags         任务管理
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "任务 ID"
-// @Success      200  {object}  Response
+// @Success      200  {object}  Response{data=service.TaskDetail}
+// @Header       200  {string}  ETag  "任务修订号"
 // @Failure      404  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id} [get]
@@ -93,6 +125,7 @@ func (c *TaskController) Get(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", formatETag(task.Revision))
 	Success(ctx, task)
 }
 
@@ -106,7 +139,11 @@ func (c *TaskController) Get(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      422  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /tasks/{id}/submit [post]
 // @Security     BearerAuth
 func (c *TaskController) Submit(ctx *gin.Context) {
@@ -134,7 +171,11 @@ func (c *TaskController) Submit(ctx *gin.Context) {
 // @Failure      400  {object}  ErrorResponse
 // @Failure      401  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      422  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /tasks/{id}/approve [post]
 // @Security     BearerAuth
 func (c *TaskController) Approve(ctx *gin.Context) {
@@ -231,6 +272,8 @@ func (c *TaskController) Withdraw(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/transfer [post]
 // @Security     BearerAuth
@@ -264,6 +307,8 @@ func (c *TaskController) Transfer(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/approvers [post]
 // @Security     BearerAuth
@@ -297,6 +342,8 @@ func (c *TaskController) AddApprover(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/approvers [delete]
 // @Security     BearerAuth
@@ -330,6 +377,8 @@ func (c *TaskController) RemoveApprover(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/pause [post]
 // @Security     BearerAuth
@@ -365,6 +414,8 @@ func (c *TaskController) Pause(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/resume [post]
 // @Security     BearerAuth
@@ -400,6 +451,8 @@ func (c *TaskController) Resume(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/rollback [post]
 // @Security     BearerAuth
@@ -433,6 +486,8 @@ func (c *TaskController) RollbackToNode(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/approvers/replace [post]
 // @Security     BearerAuth
@@ -513,9 +568,160 @@ func (c *TaskController) BatchTransfer(ctx *gin.Context) {
 	Success(ctx, results)
 }
 
+// MigrateTasks 迁移任务到模板指定版本
+// @Summary      迁移任务模板版本
+// @Description  将运行中的任务迁移到模板的指定版本,支持节点映射和 dry-run 报告;需要管理员角色或模板的 editor 权限
+// @Tags         任务管理
+// @Accept       json
+// @Produce      json
+// @Param        request body service.MigrateTasksRequest true "迁移请求"
+// @Success      200  {object}  Response{data=service.MigrateTasksResponse}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      401  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tasks/migrate [post]
+// @Security     BearerAuth
+func (c *TaskController) MigrateTasks(ctx *gin.Context) {
+	var req service.MigrateTasksRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	resp, err := c.taskService.MigrateTasks(ctx.Request.Context(), &req)
+	switch {
+	case errors.Is(err, service.ErrMigrationForbidden):
+		Error(ctx, http.StatusForbidden, "not allowed to migrate tasks", err.Error())
+		return
+	case errors.Is(err, integration.ErrTemplateVersionNotFound):
+		Error(ctx, http.StatusNotFound, "template version not found", err.Error())
+		return
+	case errors.Is(err, integration.ErrInvalidNodeMapping):
+		Error(ctx, http.StatusBadRequest, "invalid node mapping", err.Error())
+		return
+	case err != nil:
+		Error(ctx, http.StatusInternalServerError, "failed to migrate tasks", err.Error())
+		return
+	}
+
+	Success(ctx, resp)
+}
+
+// SnoozeReminder 推迟审批提醒
+// @Summary      推迟审批提醒
+// @Description  当前用户推迟自己在节点上的审批提醒(最长 7 天),到期前不再提醒
+// @Tags         任务管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "任务 ID"
+// @Param        request body service.SnoozeReminderRequest true "推迟信息"
+// @Success      200  {object}  Response{data=service.SnoozeReminderResponse}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tasks/{id}/reminders/snooze [post]
+// @Security     BearerAuth
+func (c *TaskController) SnoozeReminder(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !c.validateTaskID(ctx, id) {
+		return
+	}
+
+	var req service.SnoozeReminderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	resp, err := c.taskService.SnoozeReminder(ctx.Request.Context(), id, &req)
+	if !c.handleServiceError(ctx, err, "snooze reminder") {
+		return
+	}
+
+	Success(ctx, resp)
+}
+
+// Return 退回修改
+// @Summary      退回发起人修改
+// @Description  活动节点上轮到审批的审批人将任务退回发起人修改。本轮审批数据归档为历史轮次,任务回到 pending 状态等待发起人重新提交;重新提交后从开始节点还是退回节点继续由节点配置的 return_policy 决定
+// @Tags         任务管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "任务 ID"
+// @Param        request body service.ReturnRequest true "退回信息"
+// @Success      200  {object}  Response
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tasks/{id}/return [post]
+// @Security     BearerAuth
+func (c *TaskController) Return(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !c.validateTaskID(ctx, id) {
+		return
+	}
+
+	var req service.ReturnRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	if !c.handleServiceError(ctx, c.taskService.ReturnForRevision(ctx.Request.Context(), id, &req), "return task") {
+		return
+	}
+
+	Success(ctx, nil)
+}
+
+// Resubmit 重新提交
+// @Summary      重新提交被退回的任务
+// @Description  发起人修改任务参数后重新提交被退回的任务,开始新一轮审批。params 为空时沿用原参数。只有任务记录的发起人可以重新提交,否则返回 403
+// @Tags         任务管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "任务 ID"
+// @Param        request body service.ResubmitRequest true "重新提交信息"
+// @Success      200  {object}  Response
+// @Failure      400  {object}  ErrorResponse
+// @Failure      403  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
+// @Failure      422  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /tasks/{id}/resubmit [post]
+// @Security     BearerAuth
+func (c *TaskController) Resubmit(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if !c.validateTaskID(ctx, id) {
+		return
+	}
+
+	var req service.ResubmitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	if !c.handleServiceError(ctx, c.taskService.Resubmit(ctx.Request.Context(), id, &req), "resubmit task") {
+		return
+	}
+
+	Success(ctx, nil)
+}
+
 // HandleTimeout 处理任务超时
 // @Summary      处理任务超时
-// @Description  处理任务超时,如果任务已超时,将任务状态转换为 timeout
+// @Description  检查任务的活动审批节点,对已超时的节点执行节点配置的超时动作(标记超时、自动通过、自动驳回、升级或转派)
 // @Tags         任务管理
 // @Accept       json
 // @Produce      json
@@ -523,6 +729,8 @@ func (c *TaskController) BatchTransfer(ctx *gin.Context) {
 // @Success      200  {object}  Response
 // @Failure      400  {object}  ErrorResponse
 // @Failure      404  {object}  ErrorResponse
+// @Failure      409  {object}  ErrorResponse
+// @Failure      412  {object}  ErrorResponse
 // @Failure      500  {object}  ErrorResponse
 // @Router       /tasks/{id}/timeout [post]
 // @Security     BearerAuth
@@ -2,11 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/integration"
 	"github.com/mautops/approval-gin/internal/model"
 	"github.com/mautops/approval-gin/internal/service"
 	"github.com/mautops/approval-gin/internal/utils"
@@ -58,6 +60,10 @@ func (c *TemplateController) Create(ctx *gin.Context) {
 
 	template, err := c.templateService.Create(ctx.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, integration.ErrInvalidWebhookTemplate) || errors.Is(err, service.ErrInvalidTemplateConfig) {
+			Error(ctx, http.StatusBadRequest, "invalid template config", err.Error())
+			return
+		}
 		Error(ctx, http.StatusInternalServerError, "failed to create template", err.Error())
 		return
 	}
@@ -170,6 +176,10 @@ func (c *TemplateController) Update(ctx *gin.Context) {
 			Error(ctx, http.StatusNotFound, "template not found", err.Error())
 			return
 		}
+		if errors.Is(err, integration.ErrInvalidWebhookTemplate) || errors.Is(err, service.ErrInvalidTemplateConfig) {
+			Error(ctx, http.StatusBadRequest, "invalid template config", err.Error())
+			return
+		}
 		Error(ctx, http.StatusInternalServerError, "failed to update template", err.Error())
 		return
 	}
@@ -328,6 +338,51 @@ func (c *TemplateController) DeleteVersion(ctx *gin.Context) {
 	Success(ctx, nil)
 }
 
+// PreviewWebhook 预览 Webhook 请求模板
+// @Summary      预览 Webhook 请求模板
+// @Description  使用指定事件(须属于该模板的任务)或示例数据渲染 Webhook 请求体模板和请求头模板,返回渲染结果,不发送请求
+// @Tags         模板管理
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "模板 ID"
+// @Param        request body service.PreviewWebhookRequest true "请求模板"
+// @Success      200  {object}  Response{data=integration.RenderedWebhook}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /templates/{id}/webhooks/preview [post]
+// @Security     BearerAuth
+func (c *TemplateController) PreviewWebhook(ctx *gin.Context) {
+	id := ctx.Param("id")
+
+	// 验证模板 ID 格式
+	if err := utils.ValidateTemplateID(id); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid template id", err.Error())
+		return
+	}
+
+	var req service.PreviewWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	rendered, err := c.templateService.PreviewWebhook(id, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, integration.ErrInvalidWebhookTemplate), errors.Is(err, service.ErrPreviewEventMismatch):
+			Error(ctx, http.StatusBadRequest, "invalid webhook template", err.Error())
+		case strings.Contains(err.Error(), "not found"):
+			Error(ctx, http.StatusNotFound, "not found", err.Error())
+		default:
+			Error(ctx, http.StatusInternalServerError, "failed to preview webhook", err.Error())
+		}
+		return
+	}
+
+	Success(ctx, rendered)
+}
+
 // getTemplateWithPositions 直接从数据库读取模板数据，保留 position 字段
 func (c *TemplateController) getTemplateWithPositions(id string, version int) map[string]interface{} {
 	var tm model.TemplateModel
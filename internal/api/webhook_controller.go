@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/service"
+)
+
+// WebhookController Webhook 订阅控制器
+type WebhookController struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookController 创建 Webhook 订阅控制器
+func NewWebhookController(webhookService service.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// Create 创建 Webhook 订阅
+// @Summary      创建 Webhook 订阅
+// @Description  创建全局 Webhook 订阅,匹配事件类型、模板和业务 ID 的事件会在模板 Webhook 之外额外推送到订阅地址
+// @Tags         Webhook 订阅
+// @Accept       json
+// @Produce      json
+// @Param        request body service.CreateWebhookRequest true "订阅信息"
+// @Success      200  {object}  Response{data=service.WebhookSubscription}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks [post]
+// @Security     BearerAuth
+func (c *WebhookController) Create(ctx *gin.Context) {
+	var req service.CreateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	subscription, err := c.webhookService.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		c.handleError(ctx, "failed to create webhook subscription", err)
+		return
+	}
+
+	Success(ctx, subscription)
+}
+
+// List 列出 Webhook 订阅
+// @Summary      获取 Webhook 订阅列表
+// @Description  获取所有全局 Webhook 订阅(不包含认证令牌和签名密钥)
+// @Tags         Webhook 订阅
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  Response{data=[]service.WebhookSubscription}
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks [get]
+// @Security     BearerAuth
+func (c *WebhookController) List(ctx *gin.Context) {
+	subscriptions, err := c.webhookService.List()
+	if err != nil {
+		Error(ctx, http.StatusInternalServerError, "failed to list webhook subscriptions", err.Error())
+		return
+	}
+
+	Success(ctx, subscriptions)
+}
+
+// Get 获取 Webhook 订阅
+// @Summary      获取 Webhook 订阅详情
+// @Description  根据 ID 获取 Webhook 订阅(不包含认证令牌和签名密钥)
+// @Tags         Webhook 订阅
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "订阅 ID"
+// @Success      200  {object}  Response{data=service.WebhookSubscription}
+// @Failure      404  {object}  ErrorResponse
+// @Router       /webhooks/{id} [get]
+// @Security     BearerAuth
+func (c *WebhookController) Get(ctx *gin.Context) {
+	subscription, err := c.webhookService.Get(ctx.Param("id"))
+	if err != nil {
+		c.handleError(ctx, "failed to get webhook subscription", err)
+		return
+	}
+
+	Success(ctx, subscription)
+}
+
+// Update 更新 Webhook 订阅
+// @Summary      更新 Webhook 订阅
+// @Description  全量更新 Webhook 订阅,认证类型不变时 token 留空表示沿用原令牌或签名密钥,填写新的 hmac 密钥即轮换
+// @Tags         Webhook 订阅
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "订阅 ID"
+// @Param        request body service.UpdateWebhookRequest true "订阅信息"
+// @Success      200  {object}  Response{data=service.WebhookSubscription}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id} [put]
+// @Security     BearerAuth
+func (c *WebhookController) Update(ctx *gin.Context) {
+	var req service.UpdateWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		Error(ctx, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+
+	subscription, err := c.webhookService.Update(ctx.Request.Context(), ctx.Param("id"), &req)
+	if err != nil {
+		c.handleError(ctx, "failed to update webhook subscription", err)
+		return
+	}
+
+	Success(ctx, subscription)
+}
+
+// Delete 删除 Webhook 订阅
+// @Summary      删除 Webhook 订阅
+// @Description  删除 Webhook 订阅及其签名密钥
+// @Tags         Webhook 订阅
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "订阅 ID"
+// @Success      200  {object}  Response
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id} [delete]
+// @Security     BearerAuth
+func (c *WebhookController) Delete(ctx *gin.Context) {
+	if err := c.webhookService.Delete(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		c.handleError(ctx, "failed to delete webhook subscription", err)
+		return
+	}
+
+	Success(ctx, nil)
+}
+
+// Test 发送测试事件
+// @Summary      发送测试事件
+// @Description  使用订阅的认证和签名配置向订阅地址同步发送一个 webhook_test 事件,返回响应码、耗时和响应体
+// @Tags         Webhook 订阅
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "订阅 ID"
+// @Success      200  {object}  Response{data=integration.WebhookTestResult}
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Router       /webhooks/{id}/test [post]
+// @Security     BearerAuth
+func (c *WebhookController) Test(ctx *gin.Context) {
+	result, err := c.webhookService.Test(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		c.handleError(ctx, "failed to send test event", err)
+		return
+	}
+
+	Success(ctx, result)
+}
+
+// handleError 将订阅服务错误映射为 HTTP 响应
+func (c *WebhookController) handleError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound):
+		Error(ctx, http.StatusNotFound, "webhook subscription not found", err.Error())
+	case errors.Is(err, service.ErrInvalidWebhook):
+		Error(ctx, http.StatusBadRequest, "invalid webhook subscription", err.Error())
+	default:
+		Error(ctx, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/service"
+	"gorm.io/gorm"
+)
+
+// receivedRequest 接收方收到的 Webhook 请求
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver 记录收到的请求,路径 /fail 返回 503
+type webhookReceiver struct {
+	mu       sync.Mutex
+	requests []receivedRequest
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+	r.mu.Unlock()
+	if req.URL.Path == "/fail" {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// last 返回最近收到的请求
+func (r *webhookReceiver) last(t *testing.T) receivedRequest {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		t.Fatal("no webhook request received")
+	}
+	return r.requests[len(r.requests)-1]
+}
+
+// newWebhookRouter 创建注册 Webhook 订阅路由的 router
+func newWebhookRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t)
+	signer := integration.NewWebhookSigner("0123456789abcdef0123456789abcdef", time.Hour)
+	handler := integration.NewEventHandler(db, integration.EventDeliveryOptions{Signer: signer})
+	t.Cleanup(handler.(interface{ Stop() }).Stop)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	controller := NewWebhookController(service.NewWebhookService(db, handler, signer, nil))
+	router.POST("/webhooks", controller.Create)
+	router.GET("/webhooks", controller.List)
+	router.GET("/webhooks/:id", controller.Get)
+	router.PUT("/webhooks/:id", controller.Update)
+	router.DELETE("/webhooks/:id", controller.Delete)
+	router.POST("/webhooks/:id/test", controller.Test)
+	return router, db
+}
+
+// decodeData 解析成功响应的 data 字段
+func decodeData(t *testing.T, w *httptest.ResponseRecorder, data interface{}) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	resp := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+}
+
+func TestWebhookSubscriptionCRUD(t *testing.T) {
+	router, db := newWebhookRouter(t)
+
+	for body, why := range map[string]string{
+		`{"url":"https://example.com/hook"}`:                                                   "missing name",
+		`{"name":"ftp","url":"ftp://example.com/hook"}`:                                        "not http",
+		`{"name":"get","url":"https://example.com/hook","method":"GET"}`:                       "unsupported method",
+		`{"name":"type","url":"https://example.com/hook","event_types":["x"]}`:                 "unknown event type",
+		`{"name":"auth","url":"https://example.com/hook","auth":{"type":"basic","token":"t"}}`: "basic auth without key",
+	} {
+		if w := serve(router, http.MethodPost, "/webhooks", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", why, w.Code, http.StatusBadRequest)
+		}
+	}
+
+	var created service.WebhookSubscription
+	decodeData(t, serve(router, http.MethodPost, "/webhooks",
+		`{"name":"data","url":"https://example.com/hook","event_types":["task_created"],"auth":{"type":"hmac","token":"s3cret"}}`), &created)
+	if created.ID == "" || created.Method != "POST" || created.PayloadFormat != integration.PayloadFormatRaw || !created.Enabled {
+		t.Fatalf("unexpected defaults: %+v", created)
+	}
+	// 响应不返回签名密钥
+	if created.Auth == nil || created.Auth.Type != "hmac" || created.Auth.Token != "" {
+		t.Fatalf("auth = %+v, want hmac without token", created.Auth)
+	}
+	var secrets int64
+	mustCount := func(want int64) {
+		t.Helper()
+		if err := db.Model(&model.WebhookSecretModel{}).Where("template_id = ?", created.ID).Count(&secrets).Error; err != nil || secrets != want {
+			t.Fatalf("secrets = %d, %v, want %d", secrets, err, want)
+		}
+	}
+	mustCount(1)
+
+	// 全量更新,改为 bearer 认证后删除签名密钥
+	var updated service.WebhookSubscription
+	decodeData(t, serve(router, http.MethodPut, "/webhooks/"+created.ID,
+		`{"name":"data v2","url":"https://example.com/hook","auth":{"type":"bearer","token":"tok"},"enabled":false}`), &updated)
+	if updated.Name != "data v2" || updated.Enabled || len(updated.EventTypes) != 0 || updated.Auth.Type != "bearer" {
+		t.Fatalf("unexpected update: %+v", updated)
+	}
+	mustCount(0)
+
+	var listed []service.WebhookSubscription
+	decodeData(t, serve(router, http.MethodGet, "/webhooks", ""), &listed)
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Fatalf("listed = %+v", listed)
+	}
+
+	decodeData(t, serve(router, http.MethodDelete, "/webhooks/"+created.ID, ""), nil)
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := serve(router, method, "/webhooks/"+created.ID, ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s deleted: status = %d, want %d", method, w.Code, http.StatusNotFound)
+		}
+	}
+	if w := serve(router, http.MethodPut, "/webhooks/missing", `{"name":"n","url":"https://example.com"}`); w.Code != http.StatusNotFound {
+		t.Errorf("update missing: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestWebhookTestEvent(t *testing.T) {
+	router, _ := newWebhookRouter(t)
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// 停用的订阅也可以发送测试事件,使用订阅的签名配置
+	var created service.WebhookSubscription
+	decodeData(t, serve(router, http.MethodPost, "/webhooks",
+		`{"name":"signed","url":"`+server.URL+`/hook","headers":{"X-Tenant":"acme"},"auth":{"type":"hmac","token":"s3cret"},"enabled":false}`), &created)
+
+	var result integration.WebhookTestResult
+	decodeData(t, serve(router, http.MethodPost, "/webhooks/"+created.ID+"/test", ""), &result)
+	if !result.Success || result.StatusCode != http.StatusOK || result.ResponseBody != "ok" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	req := receiver.last(t)
+	var evt struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(req.body, &evt); err != nil || evt.Type != string(integration.EventWebhookTest) {
+		t.Fatalf("test event = %s, %v", req.body, err)
+	}
+	if req.header.Get("X-Tenant") != "acme" || !strings.HasPrefix(req.header.Get(integration.HeaderDeliveryID), "test-") {
+		t.Fatalf("unexpected headers: %v", req.header)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(req.header.Get(integration.HeaderSignatureTimestamp) + "."))
+	mac.Write(req.body)
+	if got, want := req.header.Get(integration.HeaderSignature), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+
+	// 接收方返回失败时返回状态码和原因,不报错
+	decodeData(t, serve(router, http.MethodPut, "/webhooks/"+created.ID,
+		`{"name":"signed","url":"`+server.URL+`/fail","auth":{"type":"bearer","token":"tok"}}`), nil)
+	decodeData(t, serve(router, http.MethodPost, "/webhooks/"+created.ID+"/test", ""), &result)
+	if result.Success || result.StatusCode != http.StatusServiceUnavailable || result.Error == "" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if got := receiver.last(t).header.Get("Authorization"); got != "Bearer tok" {
+		t.Fatalf("authorization = %q, want bearer token", got)
+	}
+
+	if w := serve(router, http.MethodPost, "/webhooks/missing/test", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("test missing: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ApproverDirectory 审批人目录
+// 组合 Keycloak(角色、用户组、上级关系)和 OpenFGA(权限关系),供流程引擎解析审批人来源
+type ApproverDirectory struct {
+	keycloak  *KeycloakAdminClient
+	fgaClient *OpenFGAClient
+}
+
+// NewApproverDirectory 创建审批人目录,未配置的客户端可以传 nil
+func NewApproverDirectory(keycloak *KeycloakAdminClient, fgaClient *OpenFGAClient) *ApproverDirectory {
+	return &ApproverDirectory{
+		keycloak:  keycloak,
+		fgaClient: fgaClient,
+	}
+}
+
+// errKeycloakAdminNotConfigured Keycloak Admin 客户端未配置
+var errKeycloakAdminNotConfigured = errors.New("keycloak admin client is not configured")
+
+// UsersWithRole 查询拥有指定角色的用户
+func (d *ApproverDirectory) UsersWithRole(ctx context.Context, role string) ([]string, error) {
+	if d.keycloak == nil {
+		return nil, errKeycloakAdminNotConfigured
+	}
+	return d.keycloak.UsersWithRole(ctx, role)
+}
+
+// UsersInGroup 查询用户组成员
+func (d *ApproverDirectory) UsersInGroup(ctx context.Context, group string) ([]string, error) {
+	if d.keycloak == nil {
+		return nil, errKeycloakAdminNotConfigured
+	}
+	return d.keycloak.UsersInGroup(ctx, group)
+}
+
+// ManagerOf 查询用户的直属上级
+func (d *ApproverDirectory) ManagerOf(ctx context.Context, userID string) (string, error) {
+	if d.keycloak == nil {
+		return "", errKeycloakAdminNotConfigured
+	}
+	return d.keycloak.ManagerOf(ctx, userID)
+}
+
+// UsersWithRelation 查询与对象具有指定 OpenFGA 关系的用户
+func (d *ApproverDirectory) UsersWithRelation(ctx context.Context, relation string, objectType string, objectID string) ([]string, error) {
+	if d.fgaClient == nil {
+		return nil, errors.New("openfga client is not configured")
+	}
+	return d.fgaClient.ListUsers(ctx, relation, objectType, objectID)
+}
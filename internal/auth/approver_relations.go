@@ -0,0 +1,29 @@
+package auth
+
+import "context"
+
+// ApproverRelations 审批人权限关系
+// 维护 OpenFGA 中 task 对象的 approver 和 viewer 关系,审批人被委托替换或任务抄送时由流程引擎调用
+type ApproverRelations struct {
+	fgaClient *OpenFGAClient
+}
+
+// NewApproverRelations 创建审批人权限关系
+func NewApproverRelations(fgaClient *OpenFGAClient) *ApproverRelations {
+	return &ApproverRelations{fgaClient: fgaClient}
+}
+
+// GrantApprover 授予用户任务的 approver 关系
+func (r *ApproverRelations) GrantApprover(ctx context.Context, taskID string, userID string) error {
+	return r.fgaClient.SetRelation(ctx, userID, "approver", "task", taskID)
+}
+
+// RevokeApprover 撤销用户任务的 approver 关系
+func (r *ApproverRelations) RevokeApprover(ctx context.Context, taskID string, userID string) error {
+	return r.fgaClient.DeleteRelation(ctx, userID, "approver", "task", taskID)
+}
+
+// GrantViewer 授予用户任务的 viewer 关系(抄送人)
+func (r *ApproverRelations) GrantViewer(ctx context.Context, taskID string, userID string) error {
+	return r.fgaClient.SetRelation(ctx, userID, "viewer", "task", taskID)
+}
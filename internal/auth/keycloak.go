@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
@@ -212,6 +213,11 @@ func KeycloakAuthMiddleware(validator *KeycloakTokenValidator) gin.HandlerFunc {
 		c.Set("name", claims.Name)
 		c.Set("roles", claims.RealmAccess.Roles)
 
+		// 同时写入请求 context,供服务层获取当前用户(审计日志、任务发起人等)和角色(管理员操作)
+		reqCtx := context.WithValue(c.Request.Context(), "user_id", claims.Sub)
+		reqCtx = context.WithValue(reqCtx, "roles", claims.RealmAccess.Roles)
+		c.Request = c.Request.WithContext(reqCtx)
+
 		c.Next()
 	}
 }
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeycloakAdminClient Keycloak Admin REST API 客户端
+// 使用 client credentials 授权,用于按角色、用户组和上级关系查询用户
+type KeycloakAdminClient struct {
+	adminURL         string
+	tokenURL         string
+	clientID         string
+	clientSecret     string
+	managerAttribute string
+	httpClient       *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewKeycloakAdminClient 创建 Keycloak Admin 客户端
+// issuer 形如 https://keycloak.example.com/realms/your-realm,managerAttribute 为记录直属上级用户 ID 的用户属性
+func NewKeycloakAdminClient(issuer string, clientID string, clientSecret string, managerAttribute string) (*KeycloakAdminClient, error) {
+	idx := strings.Index(issuer, "/realms/")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid keycloak issuer %q", issuer)
+	}
+	if managerAttribute == "" {
+		managerAttribute = "manager"
+	}
+	return &KeycloakAdminClient{
+		adminURL:         issuer[:idx] + "/admin" + issuer[idx:],
+		tokenURL:         fmt.Sprintf("%s/protocol/openid-connect/token", issuer),
+		clientID:         clientID,
+		clientSecret:     clientSecret,
+		managerAttribute: managerAttribute,
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// keycloakUser Keycloak 用户表示
+type keycloakUser struct {
+	ID         string              `json:"id"`
+	Username   string              `json:"username"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// UsersWithRole 查询拥有指定 realm 角色的用户 ID
+func (c *KeycloakAdminClient) UsersWithRole(ctx context.Context, role string) ([]string, error) {
+	var users []keycloakUser
+	if err := c.get(ctx, fmt.Sprintf("/roles/%s/users", url.PathEscape(role)), &users); err != nil {
+		return nil, fmt.Errorf("failed to list users with role %q: %w", role, err)
+	}
+	return userIDs(users), nil
+}
+
+// UsersInGroup 查询用户组成员的用户 ID
+// group 为用户组路径,如 /finance/managers
+func (c *KeycloakAdminClient) UsersInGroup(ctx context.Context, group string) ([]string, error) {
+	var g struct {
+		ID string `json:"id"`
+	}
+	if err := c.get(ctx, "/group-by-path/"+strings.TrimPrefix(group, "/"), &g); err != nil {
+		return nil, fmt.Errorf("failed to get group %q: %w", group, err)
+	}
+
+	var users []keycloakUser
+	if err := c.get(ctx, fmt.Sprintf("/groups/%s/members", url.PathEscape(g.ID)), &users); err != nil {
+		return nil, fmt.Errorf("failed to list members of group %q: %w", group, err)
+	}
+	return userIDs(users), nil
+}
+
+// ManagerOf 查询用户的直属上级用户 ID,没有上级时返回空字符串
+func (c *KeycloakAdminClient) ManagerOf(ctx context.Context, userID string) (string, error) {
+	var user keycloakUser
+	if err := c.get(ctx, "/users/"+url.PathEscape(userID), &user); err != nil {
+		return "", fmt.Errorf("failed to get user %q: %w", userID, err)
+	}
+	if values := user.Attributes[c.managerAttribute]; len(values) > 0 {
+		return values[0], nil
+	}
+	return "", nil
+}
+
+// get 调用 Admin REST API 并解码 JSON 响应
+func (c *KeycloakAdminClient) get(ctx context.Context, path string, out interface{}) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.adminURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("keycloak admin API returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// token 获取访问令牌(过期前复用)
+func (c *KeycloakAdminClient) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.expiresAt) {
+		return c.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch admin token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("empty access token")
+	}
+
+	// 提前 30 秒过期,避免使用即将失效的令牌
+	c.accessToken = body.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn)*time.Second - 30*time.Second)
+	return c.accessToken, nil
+}
+
+// userIDs 提取用户 ID 列表
+func userIDs(users []keycloakUser) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
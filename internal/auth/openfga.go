@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	fgaSdk "github.com/openfga/go-sdk"
 	"github.com/openfga/go-sdk/client"
 	"github.com/openfga/go-sdk/credentials"
 )
@@ -114,6 +115,37 @@ func (c *OpenFGAClient) DeleteRelation(
 	return nil
 }
 
+// ListUsers 查询与对象具有指定关系的用户 ID
+func (c *OpenFGAClient) ListUsers(
+	ctx context.Context,
+	relation string,
+	objectType string,
+	objectID string,
+) ([]string, error) {
+	body := client.ClientListUsersRequest{
+		Object: fgaSdk.FgaObject{
+			Type: objectType,
+			Id:   objectID,
+		},
+		Relation:    relation,
+		UserFilters: []fgaSdk.UserTypeFilter{{Type: "user"}},
+	}
+
+	response, err := c.client.ListUsers(ctx).Body(body).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	users := response.GetUsers()
+	userIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		if object, ok := user.GetObjectOk(); ok && object != nil {
+			userIDs = append(userIDs, object.GetId())
+		}
+	}
+	return userIDs, nil
+}
+
 // PermissionMiddleware 权限检查中间件
 func PermissionMiddleware(
 	fgaClient *OpenFGAClient,
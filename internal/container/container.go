@@ -23,8 +23,13 @@ type Container struct {
 	taskMgr           task.TaskManager
 	fgaClient         *auth.OpenFGAClient
 	eventHandler      event.EventHandler
+	webhookSigner     *integration.WebhookSigner
 	keycloakValidator *auth.KeycloakTokenValidator
 	backupService     *service.BackupService
+	timeoutScheduler  *integration.TimeoutScheduler
+	reminderScheduler *integration.ReminderScheduler
+	serviceScheduler  *integration.ServiceScheduler
+	timerScheduler    *integration.TimerScheduler
 }
 
 // NewContainer 创建依赖注入容器
@@ -42,13 +47,38 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	// 2. 初始化 TemplateManager
+	// 2. 初始化 TemplateManager(Webhook 的 HMAC 签名密钥使用主密钥加密存储)
+	webhookSigner := integration.NewWebhookSigner(cfg.Events.SigningKey, time.Duration(cfg.Events.SecretGracePeriod)*time.Second)
 	templateMgr := integration.NewTemplateManager(db)
+	if dbTemplateMgr, ok := templateMgr.(*integration.DBTemplateManager); ok {
+		dbTemplateMgr.SetWebhookSigner(webhookSigner)
+	}
 
-	// 3. 初始化 EventHandler
-	// 默认使用 5 个 worker
-	eventWorkers := 5
-	eventHandler := integration.NewEventHandler(db, eventWorkers)
+	// 3. 初始化 EventHandler(事件写入 outbox 后由 worker 领取,发布到消息总线并推送到 Webhook)
+	publisher, err := integration.NewEventPublisher(integration.PublisherOptions{
+		Backend: cfg.Events.Publisher.Backend,
+		URL:     cfg.Events.Publisher.URL,
+		Subject: cfg.Events.Publisher.Subject,
+		Timeout: time.Duration(cfg.Events.Publisher.Timeout) * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize event publisher: %w", err)
+	}
+	eventSource := cfg.Events.Source
+	if eventSource == "" {
+		eventSource = fmt.Sprintf("http://%s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+	eventHandler := integration.NewEventHandler(db, integration.EventDeliveryOptions{
+		Workers:      cfg.Events.Workers,
+		PollInterval: time.Duration(cfg.Events.PollInterval) * time.Second,
+		Lease:        time.Duration(cfg.Events.LeaseSeconds) * time.Second,
+		MaxAttempts:  cfg.Events.MaxAttempts,
+		BackoffBase:  time.Duration(cfg.Events.BackoffBase) * time.Second,
+		BackoffMax:   time.Duration(cfg.Events.BackoffMax) * time.Second,
+		Signer:       webhookSigner,
+		Publisher:    publisher,
+		Source:       eventSource,
+	})
 
 	// 4. 初始化 TaskManager
 	// 注意: 状态机已集成(任务 3.5),传递 nil 时会自动创建默认状态机实例
@@ -65,6 +95,44 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 	// 6. 初始化 Keycloak Token 验证器
 	keycloakValidator := auth.NewKeycloakTokenValidator(cfg.Keycloak.Issuer)
 
+	// 初始化审批人目录(配置了 Keycloak Admin 客户端时支持角色、用户组、上级审批人来源)
+	var keycloakAdmin *auth.KeycloakAdminClient
+	if cfg.Keycloak.AdminClientID != "" {
+		keycloakAdmin, err = auth.NewKeycloakAdminClient(cfg.Keycloak.Issuer, cfg.Keycloak.AdminClientID, cfg.Keycloak.AdminClientSecret, cfg.Keycloak.ManagerAttribute)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Keycloak admin client: %w", err)
+		}
+	}
+	if dbTaskMgr, ok := taskMgr.(*integration.DBTaskManager); ok {
+		dbTaskMgr.SetApproverDirectory(auth.NewApproverDirectory(keycloakAdmin, fgaClient))
+		// 委托替换审批人时同步 OpenFGA 中的 approver 关系,抄送任务时授予 viewer 关系
+		dbTaskMgr.SetApproverRelations(auth.NewApproverRelations(fgaClient))
+	}
+
+	// 启动审批节点超时扫描器、提醒扫描器、服务节点扫描器和定时节点扫描器(多副本通过 leader 租约保证只有一个实例扫描)
+	var timeoutScheduler *integration.TimeoutScheduler
+	var reminderScheduler *integration.ReminderScheduler
+	var serviceScheduler *integration.ServiceScheduler
+	var timerScheduler *integration.TimerScheduler
+	if dbTaskMgr, ok := taskMgr.(*integration.DBTaskManager); ok {
+		if cfg.Scheduler.Timeout.Enabled {
+			timeoutScheduler = integration.NewTimeoutScheduler(db, dbTaskMgr, schedulerOptions(cfg.Scheduler.Timeout))
+			timeoutScheduler.Start()
+		}
+		if cfg.Scheduler.Reminder.Enabled {
+			reminderScheduler = integration.NewReminderScheduler(db, dbTaskMgr, schedulerOptions(cfg.Scheduler.Reminder))
+			reminderScheduler.Start()
+		}
+		if cfg.Scheduler.Service.Enabled {
+			serviceScheduler = integration.NewServiceScheduler(db, dbTaskMgr, schedulerOptions(cfg.Scheduler.Service))
+			serviceScheduler.Start()
+		}
+		if cfg.Scheduler.Timer.Enabled {
+			timerScheduler = integration.NewTimerScheduler(db, dbTaskMgr, schedulerOptions(cfg.Scheduler.Timer))
+			timerScheduler.Start()
+		}
+	}
+
 	// 7. 初始化备份服务
 	// 默认备份目录为 ./backups，可以通过环境变量配置
 	backupDir := "./backups"
@@ -76,11 +144,25 @@ func NewContainer(cfg *config.Config) (*Container, error) {
 		taskMgr:           taskMgr,
 		fgaClient:         fgaClient,
 		eventHandler:      eventHandler,
+		webhookSigner:     webhookSigner,
 		keycloakValidator: keycloakValidator,
 		backupService:     backupService,
+		timeoutScheduler:  timeoutScheduler,
+		reminderScheduler: reminderScheduler,
+		serviceScheduler:  serviceScheduler,
+		timerScheduler:    timerScheduler,
 	}, nil
 }
 
+// schedulerOptions 将扫描配置转换为扫描器选项
+func schedulerOptions(cfg config.ScannerConfig) integration.SchedulerOptions {
+	return integration.SchedulerOptions{
+		Interval:  time.Duration(cfg.Interval) * time.Second,
+		Lease:     time.Duration(cfg.LeaseSeconds) * time.Second,
+		BatchSize: cfg.BatchSize,
+	}
+}
+
 // DB 获取数据库连接
 func (c *Container) DB() *gorm.DB {
 	return c.db
@@ -106,6 +188,11 @@ func (c *Container) EventHandler() event.EventHandler {
 	return c.eventHandler
 }
 
+// WebhookSigner 获取 Webhook 签名器
+func (c *Container) WebhookSigner() *integration.WebhookSigner {
+	return c.webhookSigner
+}
+
 // KeycloakValidator 获取 Keycloak Token 验证器
 func (c *Container) KeycloakValidator() *auth.KeycloakTokenValidator {
 	return c.keycloakValidator
@@ -118,6 +205,20 @@ func (c *Container) BackupService() *service.BackupService {
 
 // Close 关闭容器,清理资源
 func (c *Container) Close() error {
+	// 停止后台扫描器(需要在关闭数据库之前释放 leader 租约)
+	if c.timeoutScheduler != nil {
+		c.timeoutScheduler.Stop()
+	}
+	if c.reminderScheduler != nil {
+		c.reminderScheduler.Stop()
+	}
+	if c.serviceScheduler != nil {
+		c.serviceScheduler.Stop()
+	}
+	if c.timerScheduler != nil {
+		c.timerScheduler.Stop()
+	}
+
 	if c.db != nil {
 		sqlDB, err := c.db.DB()
 		if err == nil {
@@ -125,9 +226,10 @@ func (c *Container) Close() error {
 		}
 	}
 
-	// 关闭事件处理器
-	// 注意: EventHandler 接口可能没有 Close 方法
-	// 如果需要清理资源,可以在 EventHandler 接口中添加 Close 方法
+	// 关闭事件处理器(停止投递 worker,未投递的事件保留在 outbox 中,重启后继续投递)
+	if stopper, ok := c.eventHandler.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
 
 	return nil
 }
@@ -167,7 +167,15 @@ func Migrate(db *gorm.DB) error {
 			&model.ApprovalRecordModel{},
 			&model.StateHistoryModel{},
 			&model.EventModel{},
+			&model.EventDeliveryModel{},
+			&model.WebhookSecretModel{},
+			&model.WebhookSubscriptionModel{},
 			&model.AuditLogModel{},
+			&model.SchedulerLeaseModel{},
+			&model.CalendarModel{},
+			&model.DelegationModel{},
+			&model.TaskRoundModel{},
+			&model.TaskCCModel{},
 		); err != nil {
 			return fmt.Errorf("failed to auto migrate: %w", err)
 		}
@@ -181,6 +189,17 @@ func Migrate(db *gorm.DB) error {
 	return nil
 }
 
+// addSQLiteColumn 为已存在的 SQLite 表补充列(列已存在时忽略)
+func addSQLiteColumn(db *gorm.DB, table string, column string, definition string) error {
+	if db.Migrator().HasColumn(table, column) {
+		return nil
+	}
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)).Error; err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
 // createSQLiteTables 为 SQLite 手动创建表（使用 TEXT 替代 jsonb）
 func createSQLiteTables(db *gorm.DB) error {
 	// 创建 templates 表 (使用组合主键 id, version)
@@ -211,15 +230,49 @@ func createSQLiteTables(db *gorm.DB) error {
 			state VARCHAR(32) NOT NULL,
 			current_node VARCHAR(64),
 			data TEXT NOT NULL,
+			runtime TEXT,
+			revision INTEGER NOT NULL DEFAULT 0,
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL,
 			submitted_at DATETIME,
-			created_by VARCHAR(64)
+			timeout_at DATETIME,
+			remind_at DATETIME,
+			service_at DATETIME,
+			timer_at DATETIME,
+			created_by VARCHAR(64),
+			parent_task_id VARCHAR(64),
+			parent_node_id VARCHAR(64)
 		)
 	`).Error; err != nil {
 		return fmt.Errorf("failed to create tasks table: %w", err)
 	}
 
+	// 已存在的 tasks 表补充新增列
+	if err := addSQLiteColumn(db, "tasks", "runtime", "TEXT"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "revision", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "timeout_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "remind_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "service_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "timer_at", "DATETIME"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "parent_task_id", "VARCHAR(64)"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "tasks", "parent_node_id", "VARCHAR(64)"); err != nil {
+		return err
+	}
+
 	// 创建 approval_records 表
 	if err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS approval_records (
@@ -235,6 +288,12 @@ func createSQLiteTables(db *gorm.DB) error {
 	`).Error; err != nil {
 		return fmt.Errorf("failed to create approval_records table: %w", err)
 	}
+	if err := addSQLiteColumn(db, "approval_records", "on_behalf_of", "VARCHAR(64)"); err != nil {
+		return err
+	}
+	if err := addSQLiteColumn(db, "approval_records", "round", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
 
 	// 创建 state_history 表
 	if err := db.Exec(`
@@ -260,6 +319,11 @@ func createSQLiteTables(db *gorm.DB) error {
 			data TEXT NOT NULL,
 			status VARCHAR(32) NOT NULL DEFAULT 'pending',
 			retry_count INTEGER DEFAULT 0,
+			next_attempt_at DATETIME,
+			lease_owner VARCHAR(64),
+			lease_expires_at DATETIME,
+			last_error TEXT,
+			released BOOLEAN NOT NULL DEFAULT 0,
 			created_at DATETIME NOT NULL,
 			updated_at DATETIME NOT NULL
 		)
@@ -267,6 +331,84 @@ func createSQLiteTables(db *gorm.DB) error {
 		return fmt.Errorf("failed to create events table: %w", err)
 	}
 
+	// 已存在的 events 表补充 outbox 投递相关的列
+	for _, column := range []struct{ name, definition string }{
+		{"next_attempt_at", "DATETIME"},
+		{"lease_owner", "VARCHAR(64)"},
+		{"lease_expires_at", "DATETIME"},
+		{"last_error", "TEXT"},
+		{"released", "BOOLEAN NOT NULL DEFAULT 0"},
+	} {
+		if err := addSQLiteColumn(db, "events", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// 创建 event_deliveries 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS event_deliveries (
+			id VARCHAR(64) PRIMARY KEY,
+			event_id VARCHAR(64) NOT NULL,
+			endpoint VARCHAR(512) NOT NULL,
+			target VARCHAR(128),
+			attempt INTEGER NOT NULL,
+			status_code INTEGER,
+			success BOOLEAN NOT NULL,
+			latency_ms INTEGER,
+			response_body TEXT,
+			error TEXT,
+			created_at DATETIME NOT NULL
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create event_deliveries table: %w", err)
+	}
+	if err := addSQLiteColumn(db, "event_deliveries", "target", "VARCHAR(128)"); err != nil {
+		return err
+	}
+
+	// 创建 webhook_secrets 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_secrets (
+			template_id VARCHAR(64) NOT NULL,
+			endpoint VARCHAR(512) NOT NULL,
+			secret TEXT NOT NULL,
+			previous_secret TEXT,
+			previous_expires_at DATETIME,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (template_id, endpoint)
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create webhook_secrets table: %w", err)
+	}
+
+	// 创建 webhook_subscriptions 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+			id VARCHAR(64) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			url VARCHAR(512) NOT NULL,
+			method VARCHAR(16),
+			headers TEXT,
+			event_types TEXT,
+			template_id VARCHAR(64),
+			business_id VARCHAR(255),
+			auth_type VARCHAR(32),
+			auth_key VARCHAR(255),
+			auth_token TEXT,
+			payload_format VARCHAR(32),
+			enabled BOOLEAN NOT NULL DEFAULT 1,
+			created_by VARCHAR(64),
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create webhook_subscriptions table: %w", err)
+	}
+	if err := addSQLiteColumn(db, "webhook_subscriptions", "payload_format", "VARCHAR(32)"); err != nil {
+		return err
+	}
+
 	// 创建 audit_logs 表
 	if err := db.Exec(`
 		CREATE TABLE IF NOT EXISTS audit_logs (
@@ -285,6 +427,86 @@ func createSQLiteTables(db *gorm.DB) error {
 		return fmt.Errorf("failed to create audit_logs table: %w", err)
 	}
 
+	// 创建 scheduler_leases 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS scheduler_leases (
+			name VARCHAR(64) PRIMARY KEY,
+			holder VARCHAR(64) NOT NULL,
+			expires_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create scheduler_leases table: %w", err)
+	}
+
+	// 创建 calendars 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS calendars (
+			id VARCHAR(64) PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			description TEXT,
+			timezone VARCHAR(64) NOT NULL,
+			workdays TEXT,
+			working_hours TEXT,
+			holidays TEXT,
+			extra_workdays TEXT,
+			created_by VARCHAR(64),
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create calendars table: %w", err)
+	}
+
+	// 创建 delegations 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS delegations (
+			id VARCHAR(64) PRIMARY KEY,
+			delegator VARCHAR(64) NOT NULL,
+			delegate VARCHAR(64) NOT NULL,
+			template_id VARCHAR(64),
+			start_at DATETIME NOT NULL,
+			end_at DATETIME NOT NULL,
+			reason TEXT,
+			created_by VARCHAR(64),
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create delegations table: %w", err)
+	}
+
+	// 创建 task_rounds 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_rounds (
+			id VARCHAR(64) PRIMARY KEY,
+			task_id VARCHAR(64) NOT NULL,
+			round INTEGER NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			node_id VARCHAR(64),
+			operator VARCHAR(64),
+			comment TEXT,
+			data TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create task_rounds table: %w", err)
+	}
+
+	// 创建 task_ccs 表
+	if err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_ccs (
+			task_id VARCHAR(64) NOT NULL,
+			user_id VARCHAR(64) NOT NULL,
+			node_id VARCHAR(64),
+			added_by VARCHAR(64),
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (task_id, user_id)
+		)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to create task_ccs table: %w", err)
+	}
+
 	return nil
 }
 
@@ -317,6 +539,21 @@ func CreateIndexes(db *gorm.DB) error {
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at)").Error; err != nil {
 		return fmt.Errorf("failed to create idx_tasks_updated_at: %w", err)
 	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_timeout_at ON tasks(timeout_at)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_tasks_timeout_at: %w", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_remind_at ON tasks(remind_at)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_tasks_remind_at: %w", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_service_at ON tasks(service_at)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_tasks_service_at: %w", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_timer_at ON tasks(timer_at)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_tasks_timer_at: %w", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_parent_task_id ON tasks(parent_task_id)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_tasks_parent_task_id: %w", err)
+	}
 	
 	// approval_records 表索引
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_records_task_id ON approval_records(task_id)").Error; err != nil {
@@ -347,6 +584,37 @@ func CreateIndexes(db *gorm.DB) error {
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_events_created_at ON events(created_at)").Error; err != nil {
 		return fmt.Errorf("failed to create idx_events_created_at: %w", err)
 	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_events_next_attempt_at ON events(next_attempt_at)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_events_next_attempt_at: %w", err)
+	}
+
+	// event_deliveries 表索引
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_event_deliveries_event_id ON event_deliveries(event_id)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_event_deliveries_event_id: %w", err)
+	}
+	
+	// webhook_subscriptions 表索引
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_enabled ON webhook_subscriptions(enabled)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_webhook_subscriptions_enabled: %w", err)
+	}
+	
+	// task_rounds 表索引
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_task_rounds_task_id ON task_rounds(task_id)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_task_rounds_task_id: %w", err)
+	}
+	
+	// task_ccs 表索引
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_task_ccs_user_id ON task_ccs(user_id)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_task_ccs_user_id: %w", err)
+	}
+	
+	// delegations 表索引
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_delegations_delegator ON delegations(delegator)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_delegations_delegator: %w", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_delegations_delegate ON delegations(delegate)").Error; err != nil {
+		return fmt.Errorf("failed to create idx_delegations_delegate: %w", err)
+	}
 	
 	// audit_logs 表索引
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_audit_resource ON audit_logs(resource_type, resource_id)").Error; err != nil {
@@ -0,0 +1,235 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/mautops/approval-kit/pkg/task"
+)
+
+// 多人审批模式
+const (
+	// ApprovalModeAny 或签: 任意一人同意即通过
+	ApprovalModeAny = "any"
+	// ApprovalModeAll 会签: 所有人同意才通过
+	ApprovalModeAll = "all"
+	// ApprovalModeSequential 依次审批: 按审批人列表顺序逐个审批,全部同意才通过
+	ApprovalModeSequential = "sequential"
+	// ApprovalModeQuorum 比例/人数投票: 同意人数达到 N 人或百分比即通过
+	ApprovalModeQuorum = "quorum"
+	// ApprovalModeWeighted 加权投票: 同意权重达到阈值即通过
+	ApprovalModeWeighted = "weighted"
+)
+
+// 拒绝策略
+const (
+	// RejectPolicyImmediate 任意一人拒绝即驳回节点
+	RejectPolicyImmediate = "immediate"
+	// RejectPolicyUnreachable 剩余未审批人全部同意也无法达到通过条件时才驳回节点
+	RejectPolicyUnreachable = "unreachable"
+)
+
+// 节点审批结论
+const (
+	nodeOutcomePending  = "pending"
+	nodeOutcomeApproved = "approved"
+	nodeOutcomeRejected = "rejected"
+)
+
+// approvalPolicy 审批节点的多人审批策略
+// 从节点原始 config 中解析,未配置时为会签模式
+type approvalPolicy struct {
+	Mode          string             `json:"approval_mode,omitempty"`  // 审批模式: any/all/sequential/quorum/weighted
+	Quorum        int                `json:"quorum,omitempty"`         // quorum 模式: 需要同意的人数
+	QuorumPercent float64            `json:"quorum_percent,omitempty"` // quorum 模式: 需要同意的百分比(0-100),未配置人数时生效
+	Weights       map[string]float64 `json:"weights,omitempty"`        // weighted 模式: 审批人权重,未配置的审批人权重为 1
+	PassWeight    float64            `json:"pass_weight,omitempty"`    // weighted 模式: 通过所需的同意权重,为 0 时需超过总权重的一半
+	RejectPolicy  string             `json:"reject_policy,omitempty"`  // 拒绝策略: immediate/unreachable,为空时按模式取默认值
+}
+
+// approvalPolicyFor 获取节点的多人审批策略
+func (f *flowDefinition) approvalPolicyFor(nodeID string) (*approvalPolicy, error) {
+	policy := &approvalPolicy{}
+	if node, exists := f.Nodes[nodeID]; exists && node != nil && len(node.Config) > 0 && string(node.Config) != "null" {
+		if err := json.Unmarshal(node.Config, policy); err != nil {
+			return nil, fmt.Errorf("invalid approval config for node %q: %w", nodeID, err)
+		}
+	}
+	if err := policy.normalize(); err != nil {
+		return nil, fmt.Errorf("node %q: %w", nodeID, err)
+	}
+	return policy, nil
+}
+
+// normalize 补全默认值并校验配置
+func (p *approvalPolicy) normalize() error {
+	if p.Mode == "" {
+		p.Mode = ApprovalModeAll
+	}
+	switch p.Mode {
+	case ApprovalModeAny, ApprovalModeAll, ApprovalModeSequential:
+	case ApprovalModeQuorum:
+		if p.Quorum < 0 {
+			return fmt.Errorf("quorum must not be negative")
+		}
+		if p.QuorumPercent < 0 || p.QuorumPercent > 100 {
+			return fmt.Errorf("quorum_percent must be between 0 and 100")
+		}
+	case ApprovalModeWeighted:
+		if p.PassWeight < 0 {
+			return fmt.Errorf("pass_weight must not be negative")
+		}
+		for approver, weight := range p.Weights {
+			if weight < 0 {
+				return fmt.Errorf("weight of approver %q must not be negative", approver)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown approval mode %q", p.Mode)
+	}
+
+	if p.RejectPolicy == "" {
+		// 或签、会签、依次审批中一人拒绝即驳回;投票模式只有无法达到通过条件时才驳回
+		switch p.Mode {
+		case ApprovalModeQuorum, ApprovalModeWeighted:
+			p.RejectPolicy = RejectPolicyUnreachable
+		default:
+			p.RejectPolicy = RejectPolicyImmediate
+		}
+	}
+	if p.RejectPolicy != RejectPolicyImmediate && p.RejectPolicy != RejectPolicyUnreachable {
+		return fmt.Errorf("unknown reject policy %q", p.RejectPolicy)
+	}
+	return nil
+}
+
+// checkTurn 校验审批人是否可以在节点上操作
+// 审批人必须在审批人列表中(列表为空时不限制)、不能重复操作,依次审批模式下必须轮到该审批人
+func (p *approvalPolicy) checkTurn(nodeID string, approvers []string, approvals map[string]*task.Approval, approver string) error {
+	if len(approvers) == 0 {
+		return nil
+	}
+	if !containsString(approvers, approver) {
+		return fmt.Errorf("user %q is not an approver for node %q", approver, nodeID)
+	}
+	if approval, exists := approvals[approver]; exists && approval != nil {
+		return fmt.Errorf("approver %q has already acted on node %q", approver, nodeID)
+	}
+	if p.Mode == ApprovalModeSequential {
+		for _, approverID := range approvers {
+			if approval, exists := approvals[approverID]; !exists || approval == nil {
+				if approverID != approver {
+					return fmt.Errorf("it is not approver %q's turn on node %q, waiting for %q", approver, nodeID, approverID)
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// evaluate 根据已有审批结果计算节点结论
+// 审批人列表为空时视为单人审批,由本次操作的结果直接决定
+func (p *approvalPolicy) evaluate(approvers []string, approvals map[string]*task.Approval, lastResult string) string {
+	if len(approvers) == 0 {
+		if lastResult == "reject" {
+			return nodeOutcomeRejected
+		}
+		return nodeOutcomeApproved
+	}
+
+	var approved, rejected, pending []string
+	for _, approverID := range approvers {
+		approval, exists := approvals[approverID]
+		switch {
+		case !exists || approval == nil:
+			pending = append(pending, approverID)
+		case approval.Result == "approve":
+			approved = append(approved, approverID)
+		case approval.Result == "reject":
+			rejected = append(rejected, approverID)
+		}
+	}
+
+	if len(rejected) > 0 && p.RejectPolicy == RejectPolicyImmediate {
+		return nodeOutcomeRejected
+	}
+
+	switch p.Mode {
+	case ApprovalModeAny:
+		if len(approved) > 0 {
+			return nodeOutcomeApproved
+		}
+		if len(pending) == 0 {
+			return nodeOutcomeRejected
+		}
+	case ApprovalModeAll, ApprovalModeSequential:
+		if len(approved) == len(approvers) {
+			return nodeOutcomeApproved
+		}
+		if len(rejected) > 0 {
+			return nodeOutcomeRejected
+		}
+	case ApprovalModeQuorum:
+		required := p.requiredVotes(len(approvers))
+		if len(approved) >= required {
+			return nodeOutcomeApproved
+		}
+		if len(approved)+len(pending) < required {
+			return nodeOutcomeRejected
+		}
+	case ApprovalModeWeighted:
+		approvedWeight := p.weightOf(approved)
+		reachableWeight := approvedWeight + p.weightOf(pending)
+		if p.PassWeight > 0 {
+			if approvedWeight >= p.PassWeight {
+				return nodeOutcomeApproved
+			}
+			if reachableWeight < p.PassWeight {
+				return nodeOutcomeRejected
+			}
+		} else {
+			// 未配置阈值时需超过总权重的一半
+			half := p.weightOf(approvers) / 2
+			if approvedWeight > half {
+				return nodeOutcomeApproved
+			}
+			if reachableWeight <= half {
+				return nodeOutcomeRejected
+			}
+		}
+	}
+	return nodeOutcomePending
+}
+
+// requiredVotes 计算 quorum 模式需要的同意人数
+// 优先使用人数配置,其次使用百分比(向上取整),都未配置时为过半数
+func (p *approvalPolicy) requiredVotes(total int) int {
+	required := total/2 + 1
+	if p.Quorum > 0 {
+		required = p.Quorum
+	} else if p.QuorumPercent > 0 {
+		required = int(math.Ceil(float64(total) * p.QuorumPercent / 100))
+	}
+	if required > total {
+		required = total
+	}
+	if required < 1 {
+		required = 1
+	}
+	return required
+}
+
+// weightOf 计算审批人权重合计
+func (p *approvalPolicy) weightOf(approvers []string) float64 {
+	total := 0.0
+	for _, approverID := range approvers {
+		if weight, exists := p.Weights[approverID]; exists {
+			total += weight
+		} else {
+			total++
+		}
+	}
+	return total
+}
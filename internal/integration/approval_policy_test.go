@@ -0,0 +1,165 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// votes 将 "ann:approve,bob:reject" 形式的投票转换为审批结果
+func votes(spec string) map[string]*task.Approval {
+	approvals := make(map[string]*task.Approval)
+	for _, vote := range strings.Split(spec, ",") {
+		if vote == "" {
+			continue
+		}
+		parts := strings.SplitN(vote, ":", 2)
+		approvals[parts[0]] = &task.Approval{Result: parts[1]}
+	}
+	return approvals
+}
+
+func TestApprovalPolicyEvaluate(t *testing.T) {
+	approvers := []string{"ann", "bob", "cat", "dan"}
+	tests := []struct {
+		name   string
+		policy approvalPolicy
+		votes  string
+		want   string
+	}{
+		{"any approves on first approval", approvalPolicy{Mode: ApprovalModeAny}, "bob:approve", nodeOutcomeApproved},
+		{"any rejects immediately", approvalPolicy{Mode: ApprovalModeAny}, "bob:reject", nodeOutcomeRejected},
+		{"any unreachable waits for everyone", approvalPolicy{Mode: ApprovalModeAny, RejectPolicy: RejectPolicyUnreachable}, "ann:reject,bob:reject", nodeOutcomePending},
+		{"any unreachable rejects when all reject", approvalPolicy{Mode: ApprovalModeAny, RejectPolicy: RejectPolicyUnreachable}, "ann:reject,bob:reject,cat:reject,dan:reject", nodeOutcomeRejected},
+		{"all waits for everyone", approvalPolicy{}, "ann:approve,bob:approve,cat:approve", nodeOutcomePending},
+		{"all approves when everyone approves", approvalPolicy{}, "ann:approve,bob:approve,cat:approve,dan:approve", nodeOutcomeApproved},
+		{"all rejects on one rejection", approvalPolicy{}, "ann:approve,bob:reject", nodeOutcomeRejected},
+		{"sequential rejects on one rejection", approvalPolicy{Mode: ApprovalModeSequential}, "ann:reject", nodeOutcomeRejected},
+		{"quorum defaults to majority", approvalPolicy{Mode: ApprovalModeQuorum}, "ann:approve,bob:approve", nodeOutcomePending},
+		{"quorum majority reached", approvalPolicy{Mode: ApprovalModeQuorum}, "ann:approve,bob:approve,cat:approve", nodeOutcomeApproved},
+		{"quorum tolerates reachable rejections", approvalPolicy{Mode: ApprovalModeQuorum, Quorum: 2}, "ann:reject,bob:reject,cat:approve", nodeOutcomePending},
+		{"quorum count reached", approvalPolicy{Mode: ApprovalModeQuorum, Quorum: 2}, "ann:reject,bob:approve,cat:approve", nodeOutcomeApproved},
+		{"quorum unreachable", approvalPolicy{Mode: ApprovalModeQuorum, Quorum: 2}, "ann:reject,bob:reject,cat:reject", nodeOutcomeRejected},
+		{"quorum percent rounds up", approvalPolicy{Mode: ApprovalModeQuorum, QuorumPercent: 60}, "ann:approve,bob:approve", nodeOutcomePending},
+		{"quorum percent reached", approvalPolicy{Mode: ApprovalModeQuorum, QuorumPercent: 60}, "ann:approve,bob:approve,cat:approve", nodeOutcomeApproved},
+		{"quorum immediate reject policy", approvalPolicy{Mode: ApprovalModeQuorum, Quorum: 1, RejectPolicy: RejectPolicyImmediate}, "ann:reject", nodeOutcomeRejected},
+		{"weighted pass weight reached", approvalPolicy{Mode: ApprovalModeWeighted, Weights: map[string]float64{"ann": 3}, PassWeight: 3}, "ann:approve", nodeOutcomeApproved},
+		{"weighted pass weight pending", approvalPolicy{Mode: ApprovalModeWeighted, Weights: map[string]float64{"ann": 3}, PassWeight: 4}, "ann:approve", nodeOutcomePending},
+		{"weighted pass weight unreachable", approvalPolicy{Mode: ApprovalModeWeighted, Weights: map[string]float64{"ann": 3}, PassWeight: 4}, "ann:reject", nodeOutcomeRejected},
+		{"weighted default needs more than half", approvalPolicy{Mode: ApprovalModeWeighted, Weights: map[string]float64{"ann": 2}}, "ann:approve,bob:approve", nodeOutcomeApproved},
+		{"weighted unreachable below half", approvalPolicy{Mode: ApprovalModeWeighted, Weights: map[string]float64{"ann": 2}}, "ann:approve,bob:reject,cat:reject,dan:reject", nodeOutcomeRejected},
+		{"weighted exactly half is not enough", approvalPolicy{Mode: ApprovalModeWeighted}, "ann:approve,bob:approve,cat:reject,dan:reject", nodeOutcomeRejected},
+		{"weighted exactly half still reachable", approvalPolicy{Mode: ApprovalModeWeighted}, "ann:approve,bob:approve", nodeOutcomePending},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			mustNoError(t, policy.normalize())
+			if got := policy.evaluate(approvers, votes(tt.votes), ""); got != tt.want {
+				t.Fatalf("evaluate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicyEvaluateWithoutApprovers(t *testing.T) {
+	policy := approvalPolicy{}
+	mustNoError(t, policy.normalize())
+	if got := policy.evaluate(nil, nil, "approve"); got != nodeOutcomeApproved {
+		t.Fatalf("evaluate = %s, want approved", got)
+	}
+	if got := policy.evaluate(nil, nil, "reject"); got != nodeOutcomeRejected {
+		t.Fatalf("evaluate = %s, want rejected", got)
+	}
+}
+
+func TestApprovalPolicyCheckTurn(t *testing.T) {
+	approvers := []string{"ann", "bob", "cat"}
+	sequential := approvalPolicy{Mode: ApprovalModeSequential}
+	mustNoError(t, sequential.normalize())
+
+	mustNoError(t, sequential.checkTurn("n", approvers, votes(""), "ann"))
+	if err := sequential.checkTurn("n", approvers, votes(""), "bob"); err == nil {
+		t.Fatal("expected out-of-turn approval to fail")
+	}
+	mustNoError(t, sequential.checkTurn("n", approvers, votes("ann:approve"), "bob"))
+	if err := sequential.checkTurn("n", approvers, votes("ann:approve"), "ann"); err == nil {
+		t.Fatal("expected repeated approval to fail")
+	}
+	if err := sequential.checkTurn("n", approvers, votes(""), "eve"); err == nil {
+		t.Fatal("expected unknown approver to fail")
+	}
+
+	all := approvalPolicy{}
+	mustNoError(t, all.normalize())
+	mustNoError(t, all.checkTurn("n", approvers, votes(""), "cat"))
+	mustNoError(t, all.checkTurn("n", nil, votes(""), "anyone"))
+}
+
+func TestApprovalPolicyNormalize(t *testing.T) {
+	for _, policy := range []approvalPolicy{
+		{Mode: "majority"},
+		{Mode: ApprovalModeQuorum, Quorum: -1},
+		{Mode: ApprovalModeQuorum, QuorumPercent: 120},
+		{Mode: ApprovalModeWeighted, PassWeight: -1},
+		{Mode: ApprovalModeWeighted, Weights: map[string]float64{"ann": -2}},
+		{Mode: ApprovalModeAll, RejectPolicy: "never"},
+	} {
+		if err := policy.normalize(); err == nil {
+			t.Errorf("%+v: expected validation error", policy)
+		}
+	}
+
+	policy := approvalPolicy{Mode: ApprovalModeWeighted}
+	mustNoError(t, policy.normalize())
+	if policy.RejectPolicy != RejectPolicyUnreachable {
+		t.Fatalf("weighted reject policy = %s, want unreachable", policy.RejectPolicy)
+	}
+}
+
+func TestSequentialApprovalNode(t *testing.T) {
+	env := newTestEnv(t)
+	env.createTemplate(t, "seq",
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("review", []string{"ann", "bob"}, `"approval_mode":"sequential"`)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+
+	tsk := env.startTask(t, "seq", `{}`)
+	if err := env.tasks.Approve(tsk.ID, "review", "bob", ""); err == nil {
+		t.Fatal("expected out-of-turn approval to fail")
+	}
+	mustNoError(t, env.tasks.Approve(tsk.ID, "review", "ann", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"review"})
+	mustNoError(t, env.tasks.Approve(tsk.ID, "review", "bob", ""))
+	if got := env.getTask(t, tsk.ID); got.State != types.TaskStateApproved {
+		t.Fatalf("state = %s, want approved", got.State)
+	}
+}
+
+func TestQuorumApprovalNode(t *testing.T) {
+	env := newTestEnv(t)
+	env.createTemplate(t, "vote",
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("board", []string{"ann", "bob", "cat"}, `"approval_mode":"quorum","quorum":2`)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"board"},{"from":"board","to":"end"}]`)
+
+	passed := env.startTask(t, "vote", `{}`)
+	mustNoError(t, env.tasks.Reject(passed.ID, "board", "ann", "no"))
+	assertEqualStrings(t, env.activeNodes(t, passed.ID), []string{"board"})
+	mustNoError(t, env.tasks.Approve(passed.ID, "board", "bob", ""))
+	mustNoError(t, env.tasks.Approve(passed.ID, "board", "cat", ""))
+	if got := env.getTask(t, passed.ID); got.State != types.TaskStateApproved {
+		t.Fatalf("state = %s, want approved", got.State)
+	}
+
+	failed := env.startTask(t, "vote", `{}`)
+	mustNoError(t, env.tasks.Reject(failed.ID, "board", "ann", "no"))
+	mustNoError(t, env.tasks.Reject(failed.ID, "board", "bob", "no"))
+	if got := env.getTask(t, failed.ID); got.State != types.TaskStateRejected {
+		t.Fatalf("state = %s, want rejected", got.State)
+	}
+}
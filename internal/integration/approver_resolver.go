@@ -0,0 +1,352 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+)
+
+// 审批人来源类型
+const (
+	// ApproverSourceUsers 固定用户
+	ApproverSourceUsers = "users"
+	// ApproverSourceRole Keycloak 角色
+	ApproverSourceRole = "role"
+	// ApproverSourceGroup Keycloak 用户组
+	ApproverSourceGroup = "group"
+	// ApproverSourceManager 发起人的上级链
+	ApproverSourceManager = "manager"
+	// ApproverSourceParam 任务参数中的字段
+	ApproverSourceParam = "param"
+	// ApproverSourceRelation OpenFGA 关系
+	ApproverSourceRelation = "relation"
+)
+
+// ErrApproverDirectoryNotConfigured 审批人来源需要查询目录,但未配置审批人目录
+var ErrApproverDirectoryNotConfigured = errors.New("approver directory is not configured")
+
+// maxManagerLevels manager 来源最多向上查询的层级数(上级关系存在环时也能结束)
+const maxManagerLevels = 20
+
+// approverResolveTimeout 一轮审批人目录查询的超时时间(查询在事务外执行,见 directoryLookups)
+const approverResolveTimeout = 10 * time.Second
+
+// ApproverDirectory 审批人目录
+// 将角色、用户组、上级关系和权限关系解析为具体用户 ID,由 auth 包基于 Keycloak 和 OpenFGA 实现
+type ApproverDirectory interface {
+	UsersWithRole(ctx context.Context, role string) ([]string, error)
+	UsersInGroup(ctx context.Context, group string) ([]string, error)
+	ManagerOf(ctx context.Context, userID string) (string, error)
+	UsersWithRelation(ctx context.Context, relation string, objectType string, objectID string) ([]string, error)
+}
+
+// managerChainDirectory 可以一次查询整条上级链的审批人目录
+// 事务内的 directoryLookups 实现该接口,使上级链在一轮目录查询中完成
+type managerChainDirectory interface {
+	ManagerChain(ctx context.Context, userID string, levels int) ([]string, error)
+}
+
+// managerChain 查询用户向上 levels 级的上级链(第 1 级为直属上级),上级链提前结束时返回较短的结果
+func managerChain(ctx context.Context, directory ApproverDirectory, userID string, levels int) ([]string, error) {
+	if chainDirectory, ok := directory.(managerChainDirectory); ok {
+		return chainDirectory.ManagerChain(ctx, userID, levels)
+	}
+	var chain []string
+	current := userID
+	for len(chain) < levels {
+		manager, err := directory.ManagerOf(ctx, current)
+		if err != nil {
+			return nil, err
+		}
+		if manager == "" {
+			break
+		}
+		chain = append(chain, manager)
+		current = manager
+	}
+	return chain, nil
+}
+
+// approverSource 审批节点的审批人来源配置
+type approverSource struct {
+	Type       string   `json:"type"`                  // 来源类型: users/role/group/manager/param/relation
+	Users      []string `json:"users,omitempty"`       // users: 固定用户 ID 列表
+	Role       string   `json:"role,omitempty"`        // role: Keycloak realm 角色
+	Group      string   `json:"group,omitempty"`       // group: Keycloak 用户组路径
+	Levels     int      `json:"levels,omitempty"`      // manager: 向上的层级数,默认 1(直属上级)
+	Chain      bool     `json:"chain,omitempty"`       // manager: 为 true 时包含 1..levels 每一级上级,否则只取第 levels 级
+	Field      string   `json:"field,omitempty"`       // param: 任务参数字段路径,如 reviewer 或 project.owners
+	Relation   string   `json:"relation,omitempty"`    // relation: OpenFGA 关系
+	ObjectType string   `json:"object_type,omitempty"` // relation: 对象类型,默认 task
+	ObjectID   string   `json:"object_id,omitempty"`   // relation: 对象 ID,默认当前任务 ID
+}
+
+// approverSourcesConfig 审批节点中审批人来源相关的配置
+type approverSourcesConfig struct {
+	ApproverSources []*approverSource `json:"approver_sources,omitempty"`
+}
+
+// ApproverProvenance 审批人的解析来源
+type ApproverProvenance struct {
+	Source string `json:"source"`           // 来源类型
+	Detail string `json:"detail,omitempty"` // 来源说明,如角色名、上级层级、参数字段
+}
+
+// approverSourcesFor 获取节点配置的审批人来源
+func (f *flowDefinition) approverSourcesFor(nodeID string) ([]*approverSource, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, nil
+	}
+	var cfg approverSourcesConfig
+	if err := json.Unmarshal(node.Config, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid approver sources for node %q: %w", nodeID, err)
+	}
+	for _, source := range cfg.ApproverSources {
+		if err := source.validate(); err != nil {
+			return nil, fmt.Errorf("node %q: %w", nodeID, err)
+		}
+	}
+	return cfg.ApproverSources, nil
+}
+
+// validate 校验审批人来源配置
+func (s *approverSource) validate() error {
+	switch s.Type {
+	case ApproverSourceUsers:
+		if len(s.Users) == 0 {
+			return fmt.Errorf("approver source %q requires users", s.Type)
+		}
+	case ApproverSourceRole:
+		if s.Role == "" {
+			return fmt.Errorf("approver source %q requires role", s.Type)
+		}
+	case ApproverSourceGroup:
+		if s.Group == "" {
+			return fmt.Errorf("approver source %q requires group", s.Type)
+		}
+	case ApproverSourceManager:
+		if s.Levels < 0 || s.Levels > maxManagerLevels {
+			return fmt.Errorf("approver source %q levels must be between 0 and %d", s.Type, maxManagerLevels)
+		}
+	case ApproverSourceParam:
+		if s.Field == "" {
+			return fmt.Errorf("approver source %q requires field", s.Type)
+		}
+	case ApproverSourceRelation:
+		if s.Relation == "" {
+			return fmt.Errorf("approver source %q requires relation", s.Type)
+		}
+	default:
+		return fmt.Errorf("unknown approver source type %q", s.Type)
+	}
+	return nil
+}
+
+// SetApproverDirectory 设置审批人目录
+// 未设置时,只能使用不依赖目录的审批人来源(users、param)
+func (m *DBTaskManager) SetApproverDirectory(directory ApproverDirectory) {
+	m.approverDirectory = directory
+}
+
+// resolveApprovers 节点激活时按配置的来源解析审批人
+// 未配置来源时保留已有审批人列表(手动加签的审批人);配置了来源但解析结果为空时返回错误
+func (m *dbTaskManager) resolveApprovers(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string) error {
+	sources, err := flow.approverSourcesFor(nodeID)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), approverResolveTimeout)
+	defer cancel()
+
+	var approvers []string
+	var lookupErr error
+	provenance := make(map[string]*ApproverProvenance)
+	for _, source := range sources {
+		resolved, err := m.resolveSource(ctx, tsk, source)
+		if errors.Is(err, errDirectoryLookupRequired) {
+			// 继续解析其他来源,使节点需要的目录查询在同一轮中完成
+			lookupErr = err
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve approvers for node %q: %w", nodeID, err)
+		}
+		for _, user := range resolved {
+			if _, exists := provenance[user.userID]; exists {
+				continue
+			}
+			approvers = append(approvers, user.userID)
+			provenance[user.userID] = &ApproverProvenance{Source: source.Type, Detail: user.detail}
+		}
+	}
+	if lookupErr != nil {
+		return fmt.Errorf("failed to resolve approvers for node %q: %w", nodeID, lookupErr)
+	}
+	if len(approvers) == 0 {
+		return fmt.Errorf("no approvers resolved for node %q", nodeID)
+	}
+
+	if tsk.Approvers == nil {
+		tsk.Approvers = make(map[string][]string)
+	}
+	tsk.Approvers[nodeID] = approvers
+	// 重新激活节点(如回退后)时清除上一轮的审批结果
+	if tsk.Approvals != nil {
+		delete(tsk.Approvals, nodeID)
+	}
+	if rt.ApproverProvenance == nil {
+		rt.ApproverProvenance = make(map[string]map[string]*ApproverProvenance)
+	}
+	rt.ApproverProvenance[nodeID] = provenance
+	return nil
+}
+
+// resolveSource 解析单个审批人来源,按解析顺序返回用户及来源说明
+func (m *dbTaskManager) resolveSource(ctx context.Context, tsk *task.Task, source *approverSource) (resolvedUsers, error) {
+	users := resolvedUsers{}
+	switch source.Type {
+	case ApproverSourceUsers:
+		for _, userID := range source.Users {
+			users.add(userID, "fixed")
+		}
+	case ApproverSourceParam:
+		ids, err := approverIDsFromParams(tsk.Params, source.Field)
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range ids {
+			users.add(userID, "params."+source.Field)
+		}
+	case ApproverSourceRole, ApproverSourceGroup, ApproverSourceRelation:
+		if m.approverDirectory == nil {
+			return nil, ErrApproverDirectoryNotConfigured
+		}
+		var ids []string
+		var detail string
+		var err error
+		switch source.Type {
+		case ApproverSourceRole:
+			ids, err = m.approverDirectory.UsersWithRole(ctx, source.Role)
+			detail = source.Role
+		case ApproverSourceGroup:
+			ids, err = m.approverDirectory.UsersInGroup(ctx, source.Group)
+			detail = source.Group
+		default:
+			objectType, objectID := source.ObjectType, source.ObjectID
+			if objectType == "" {
+				objectType = "task"
+			}
+			if objectID == "" {
+				objectID = tsk.ID
+			}
+			ids, err = m.approverDirectory.UsersWithRelation(ctx, source.Relation, objectType, objectID)
+			detail = fmt.Sprintf("%s#%s:%s", source.Relation, objectType, objectID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, userID := range ids {
+			users.add(userID, detail)
+		}
+	case ApproverSourceManager:
+		if m.approverDirectory == nil {
+			return nil, ErrApproverDirectoryNotConfigured
+		}
+		initiator, err := m.taskInitiator(tsk.ID)
+		if err != nil {
+			return nil, err
+		}
+		levels := source.Levels
+		if levels == 0 {
+			levels = 1
+		}
+		chain, err := managerChain(ctx, m.approverDirectory, initiator, levels)
+		if err != nil {
+			return nil, err
+		}
+		// 上级链提前结束时,只取第 levels 级的配置没有结果
+		for i, manager := range chain {
+			level := i + 1
+			if source.Chain || level == levels {
+				users.add(manager, fmt.Sprintf("level %d manager of %s", level, initiator))
+			}
+		}
+	}
+	return users, nil
+}
+
+// taskInitiator 获取任务发起人
+func (m *dbTaskManager) taskInitiator(taskID string) (string, error) {
+	var tm model.TaskModel
+	if err := m.db.Select("id", "created_by").Where("id = ?", taskID).First(&tm).Error; err != nil {
+		return "", fmt.Errorf("task not found: %w", err)
+	}
+	if tm.CreatedBy == "" {
+		return "", fmt.Errorf("task %q has no initiator", taskID)
+	}
+	return tm.CreatedBy, nil
+}
+
+// approverIDsFromParams 从任务参数中读取审批人,字段值可以是字符串或字符串数组
+func approverIDsFromParams(params json.RawMessage, field string) ([]string, error) {
+	var value interface{} = map[string]interface{}{}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &value); err != nil {
+			return nil, fmt.Errorf("failed to parse task params: %w", err)
+		}
+	}
+	for _, key := range strings.Split(field, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("params field %q not found", field)
+		}
+		if value, ok = obj[key]; !ok {
+			return nil, fmt.Errorf("params field %q not found", field)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		ids := make([]string, 0, len(v))
+		for _, item := range v {
+			id, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("params field %q must contain user IDs", field)
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	default:
+		return nil, fmt.Errorf("params field %q must be a user ID or a list of user IDs", field)
+	}
+}
+
+// resolvedUser 解析得到的审批人
+type resolvedUser struct {
+	userID string
+	detail string
+}
+
+// resolvedUsers 按解析顺序保存的审批人
+type resolvedUsers []resolvedUser
+
+// add 添加审批人(忽略空用户 ID)
+func (u *resolvedUsers) add(userID string, detail string) {
+	if userID == "" {
+		return
+	}
+	*u = append(*u, resolvedUser{userID: userID, detail: detail})
+}
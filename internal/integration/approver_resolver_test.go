@@ -0,0 +1,230 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// fakeDirectory 内存中的审批人目录,记录每次查询以及查询时是否有数据库连接被事务占用
+type fakeDirectory struct {
+	mu        sync.Mutex
+	db        *gorm.DB
+	roles     map[string][]string
+	groups    map[string][]string
+	managers  map[string]string
+	relations map[string][]string
+	err       error
+	calls     []string
+	inTx      int
+}
+
+// newFakeDirectory 创建审批人目录,db 用于检查查询是否发生在事务外
+func newFakeDirectory(db *gorm.DB) *fakeDirectory {
+	return &fakeDirectory{
+		db:        db,
+		roles:     make(map[string][]string),
+		groups:    make(map[string][]string),
+		managers:  make(map[string]string),
+		relations: make(map[string][]string),
+	}
+}
+
+// record 记录一次查询;测试中没有其他数据库操作并发执行,查询时有连接在使用说明处于事务中
+func (d *fakeDirectory) record(call string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = append(d.calls, call)
+	if sqlDB, err := d.db.DB(); err == nil && sqlDB.Stats().InUse > 0 {
+		d.inTx++
+	}
+	return d.err
+}
+
+func (d *fakeDirectory) UsersWithRole(_ context.Context, role string) ([]string, error) {
+	if err := d.record("role:" + role); err != nil {
+		return nil, err
+	}
+	return d.roles[role], nil
+}
+
+func (d *fakeDirectory) UsersInGroup(_ context.Context, group string) ([]string, error) {
+	if err := d.record("group:" + group); err != nil {
+		return nil, err
+	}
+	return d.groups[group], nil
+}
+
+func (d *fakeDirectory) ManagerOf(_ context.Context, userID string) (string, error) {
+	if err := d.record("manager:" + userID); err != nil {
+		return "", err
+	}
+	return d.managers[userID], nil
+}
+
+func (d *fakeDirectory) UsersWithRelation(_ context.Context, relation string, objectType string, objectID string) ([]string, error) {
+	if err := d.record("relation:" + relation + "#" + objectType); err != nil {
+		return nil, err
+	}
+	return d.relations[relation], nil
+}
+
+// sourceNodes 单个审批节点 review 的节点 JSON,sources 为节点的 approver_sources JSON
+func sourceNodes(sources string) string {
+	return `{"start":{"id":"start","type":"start"},
+		"review":{"id":"review","type":"approval","config":{"approver_sources":` + sources + `}},
+		"end":{"id":"end","type":"end"}}`
+}
+
+const sourceEdges = `[{"from":"start","to":"review"},{"from":"review","to":"end"}]`
+
+// startTaskAs 以 initiator 为发起人创建并提交任务
+func (e *testEnv) startTaskAs(t *testing.T, templateID string, initiator string, params string) (string, error) {
+	t.Helper()
+	tsk, err := e.tasks.CreateWithCC(templateID, "biz-"+templateID, []byte(params), initiator, nil)
+	mustNoError(t, err)
+	return tsk.ID, e.tasks.Submit(tsk.ID)
+}
+
+func TestResolveApproversFromAllSources(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	directory.roles["finance"] = []string{"fin", "ann"}
+	directory.groups["/ops"] = []string{"ops"}
+	directory.managers["ini"] = "lead"
+	directory.managers["lead"] = "head"
+	directory.relations["auditor"] = []string{"aud"}
+	env.tasks.SetApproverDirectory(directory)
+	env.createTemplate(t, "all", sourceNodes(`[
+		{"type":"users","users":["ann"]},
+		{"type":"role","role":"finance"},
+		{"type":"group","group":"/ops"},
+		{"type":"manager","levels":2,"chain":true},
+		{"type":"param","field":"project.reviewer"},
+		{"type":"relation","relation":"auditor"}]`), sourceEdges)
+
+	taskID, err := env.startTaskAs(t, "all", "ini", `{"project":{"reviewer":"pat"}}`)
+	mustNoError(t, err)
+
+	assertEqualStrings(t, env.getTask(t, taskID).Approvers["review"], []string{"ann", "fin", "ops", "lead", "head", "pat", "aud"})
+	provenance, err := env.tasks.ApproverProvenance(taskID)
+	mustNoError(t, err)
+	want := map[string]ApproverProvenance{
+		"ann":  {Source: ApproverSourceUsers, Detail: "fixed"},
+		"fin":  {Source: ApproverSourceRole, Detail: "finance"},
+		"ops":  {Source: ApproverSourceGroup, Detail: "/ops"},
+		"head": {Source: ApproverSourceManager, Detail: "level 2 manager of ini"},
+		"pat":  {Source: ApproverSourceParam, Detail: "params.project.reviewer"},
+		"aud":  {Source: ApproverSourceRelation, Detail: "auditor#task:" + taskID},
+	}
+	for user, expected := range want {
+		if got := provenance["review"][user]; got == nil || *got != expected {
+			t.Errorf("provenance of %s = %+v, want %+v", user, got, expected)
+		}
+	}
+}
+
+func TestManagerSourceSelectsSingleLevel(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	directory.managers["ini"] = "lead"
+	directory.managers["lead"] = "head"
+	env.tasks.SetApproverDirectory(directory)
+	env.createTemplate(t, "second", sourceNodes(`[{"type":"manager","levels":2}]`), sourceEdges)
+	env.createTemplate(t, "third", sourceNodes(`[{"type":"manager","levels":3}]`), sourceEdges)
+
+	taskID, err := env.startTaskAs(t, "second", "ini", `{}`)
+	mustNoError(t, err)
+	assertEqualStrings(t, env.getTask(t, taskID).Approvers["review"], []string{"head"})
+
+	// 上级链不足 3 级时没有审批人
+	if _, err := env.startTaskAs(t, "third", "ini", `{}`); err == nil {
+		t.Fatal("expected submission to fail when the manager chain is too short")
+	}
+}
+
+func TestDirectoryLookupsRunOutsideTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	directory.roles["finance"] = []string{"fin"}
+	directory.roles["legal"] = []string{"law"}
+	directory.managers["ini"] = "lead"
+	directory.managers["lead"] = "head"
+	env.tasks.SetApproverDirectory(directory)
+	// 并行的两个节点在不同轮次中查询,每轮查询后重新执行事务
+	env.createTemplate(t, "parallel",
+		`{"start":{"id":"start","type":"start"},
+		"fork":{"id":"fork","type":"parallel_split"},
+		"a":{"id":"a","type":"approval","config":{"approver_sources":[{"type":"role","role":"finance"},{"type":"manager","levels":2,"chain":true}]}},
+		"b":{"id":"b","type":"approval","config":{"approver_sources":[{"type":"role","role":"legal"}]}},
+		"join":{"id":"join","type":"parallel_join"},
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"fork"},{"from":"fork","to":"a"},{"from":"fork","to":"b"},
+		{"from":"a","to":"join"},{"from":"b","to":"join"},{"from":"join","to":"end"}]`)
+
+	taskID, err := env.startTaskAs(t, "parallel", "ini", `{}`)
+	mustNoError(t, err)
+
+	tsk := env.getTask(t, taskID)
+	assertEqualStrings(t, tsk.Approvers["a"], []string{"fin", "lead", "head"})
+	assertEqualStrings(t, tsk.Approvers["b"], []string{"law"})
+	// 重新执行事务时复用已查询的结果,每个查询只执行一次
+	assertEqualStrings(t, directory.calls, []string{"role:finance", "manager:ini", "manager:lead", "role:legal"})
+	if directory.inTx != 0 {
+		t.Fatalf("%d directory lookups ran inside a transaction", directory.inTx)
+	}
+}
+
+func TestDirectoryLookupErrorFailsMutation(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	directory.err = errors.New("keycloak unavailable")
+	env.tasks.SetApproverDirectory(directory)
+	env.createTemplate(t, "role", sourceNodes(`[{"type":"role","role":"finance"}]`), sourceEdges)
+
+	taskID, err := env.startTaskAs(t, "role", "ini", `{}`)
+	if !errors.Is(err, directory.err) {
+		t.Fatalf("submit error = %v, want %v", err, directory.err)
+	}
+	// 查询失败的结果在重新执行事务时复用,不会重复查询
+	assertEqualStrings(t, directory.calls, []string{"role:finance"})
+	if got := env.getTask(t, taskID); got.SubmittedAt != nil {
+		t.Fatalf("task was submitted after a failed lookup: state=%s", got.State)
+	}
+
+	// 未配置审批人目录时需要目录的来源直接失败
+	env.tasks.SetApproverDirectory(nil)
+	if _, err := env.startTaskAs(t, "role", "ini", `{}`); !errors.Is(err, ErrApproverDirectoryNotConfigured) {
+		t.Fatalf("submit error = %v, want %v", err, ErrApproverDirectoryNotConfigured)
+	}
+}
+
+func TestManagerChainDepthIsCapped(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	// 上级关系存在环时,查询在配置的层级数处结束
+	directory.managers["ini"] = "lead"
+	directory.managers["lead"] = "ini"
+	env.tasks.SetApproverDirectory(directory)
+	env.createTemplate(t, "cycle", sourceNodes(`[{"type":"manager","levels":20,"chain":true}]`), sourceEdges)
+
+	taskID, err := env.startTaskAs(t, "cycle", "ini", `{}`)
+	mustNoError(t, err)
+	assertEqualStrings(t, env.getTask(t, taskID).Approvers["review"], []string{"lead", "ini"})
+	if len(directory.calls) != maxManagerLevels {
+		t.Fatalf("manager lookups = %d, want %d", len(directory.calls), maxManagerLevels)
+	}
+
+	for name, sources := range map[string]string{
+		"too deep": `[{"type":"manager","levels":21}]`,
+		"negative": `[{"type":"manager","levels":-1}]`,
+		"unknown":  `[{"type":"team"}]`,
+	} {
+		if err := ValidateFlow([]byte(sourceNodes(sources)), []byte(sourceEdges)); err == nil {
+			t.Errorf("%s: expected flow validation to fail", name)
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// maxCalendarSearchDays 计算截止时间时最多向后查找的天数
+// 日历在该范围内没有足够的工作时间(如全年都是节假日)时按自然时间计算
+const maxCalendarSearchDays = 3660
+
+// calendarDateLayout 节假日和调休上班日的日期格式
+const calendarDateLayout = "2006-01-02"
+
+// calendarWeekdays 每周工作日的取值
+var calendarWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// WorkingPeriod 每天的工作时段,格式 HH:MM,结束时间可以为 24:00
+type WorkingPeriod struct {
+	Start string `json:"start" example:"09:00"`
+	End   string `json:"end" example:"18:00"`
+}
+
+// BusinessCalendar 工作日历
+// 按日历所在时区的工作日和工作时段计算工作时间,节假日不计时,调休上班日按工作日计时。
+// nil 日历表示按自然时间计算
+type BusinessCalendar struct {
+	Timezone      string          `json:"timezone"`                 // IANA 时区,为空时为 UTC
+	Workdays      []string        `json:"workdays,omitempty"`       // 每周工作日: mon/tue/wed/thu/fri/sat/sun,为空时为周一至周五
+	WorkingHours  []WorkingPeriod `json:"working_hours,omitempty"`  // 工作时段,为空时为 09:00-18:00
+	Holidays      []string        `json:"holidays,omitempty"`       // 节假日(YYYY-MM-DD)
+	ExtraWorkdays []string        `json:"extra_workdays,omitempty"` // 调休上班日(YYYY-MM-DD)
+
+	location      *time.Location
+	workdays      [7]bool
+	periods       [][2]int // 工作时段(当天的分钟数),按开始时间排序
+	holidays      map[string]bool
+	extraWorkdays map[string]bool
+}
+
+// NewBusinessCalendar 从日历模型创建工作日历
+func NewBusinessCalendar(cm *model.CalendarModel) (*BusinessCalendar, error) {
+	cal := &BusinessCalendar{Timezone: cm.Timezone}
+	fields := []struct {
+		name string
+		data []byte
+		v    interface{}
+	}{
+		{"workdays", cm.Workdays, &cal.Workdays},
+		{"working_hours", cm.WorkingHours, &cal.WorkingHours},
+		{"holidays", cm.Holidays, &cal.Holidays},
+		{"extra_workdays", cm.ExtraWorkdays, &cal.ExtraWorkdays},
+	}
+	for _, field := range fields {
+		if len(field.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.data, field.v); err != nil {
+			return nil, fmt.Errorf("invalid calendar %s: %w", field.name, err)
+		}
+	}
+	if err := cal.Validate(); err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// Validate 补全默认值并校验日历配置
+func (c *BusinessCalendar) Validate() error {
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", c.Timezone, err)
+	}
+	c.location = location
+
+	if len(c.Workdays) == 0 {
+		c.Workdays = []string{"mon", "tue", "wed", "thu", "fri"}
+	}
+	c.workdays = [7]bool{}
+	for i, day := range c.Workdays {
+		day = strings.ToLower(strings.TrimSpace(day))
+		weekday, ok := calendarWeekdays[day]
+		if !ok {
+			return fmt.Errorf("invalid workday %q", c.Workdays[i])
+		}
+		c.Workdays[i] = day
+		c.workdays[weekday] = true
+	}
+
+	if len(c.WorkingHours) == 0 {
+		c.WorkingHours = []WorkingPeriod{{Start: "09:00", End: "18:00"}}
+	}
+	c.periods = make([][2]int, 0, len(c.WorkingHours))
+	for _, period := range c.WorkingHours {
+		start, err := parseClock(period.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(period.End)
+		if err != nil {
+			return err
+		}
+		if start >= end {
+			return fmt.Errorf("working period %s-%s must end after it starts", period.Start, period.End)
+		}
+		c.periods = append(c.periods, [2]int{start, end})
+	}
+	sort.Slice(c.periods, func(i, j int) bool {
+		return c.periods[i][0] < c.periods[j][0]
+	})
+	for i := 1; i < len(c.periods); i++ {
+		if c.periods[i][0] < c.periods[i-1][1] {
+			return errors.New("working periods must not overlap")
+		}
+	}
+
+	if c.holidays, err = parseCalendarDates("holiday", c.Holidays); err != nil {
+		return err
+	}
+	if c.extraWorkdays, err = parseCalendarDates("extra workday", c.ExtraWorkdays); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseClock 解析 HH:MM 格式的时间,返回当天的分钟数
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil || len(value) != 5 {
+		return 0, fmt.Errorf("invalid working hour %q, expected HH:MM", value)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid working hour %q, expected HH:MM", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// parseCalendarDates 解析 YYYY-MM-DD 格式的日期列表
+func parseCalendarDates(kind string, values []string) (map[string]bool, error) {
+	dates := make(map[string]bool, len(values))
+	for _, value := range values {
+		if _, err := time.Parse(calendarDateLayout, value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q, expected YYYY-MM-DD", kind, value)
+		}
+		dates[value] = true
+	}
+	return dates, nil
+}
+
+// isWorkday 判断日期(日历时区当天零点)是否为工作日,调休上班日优先于节假日
+func (c *BusinessCalendar) isWorkday(day time.Time) bool {
+	date := day.Format(calendarDateLayout)
+	if c.extraWorkdays[date] {
+		return true
+	}
+	if c.holidays[date] {
+		return false
+	}
+	return c.workdays[day.Weekday()]
+}
+
+// workingPeriods 获取某天的工作时段(非工作日返回 nil)
+// 使用 time.Date 构造时段边界,夏令时切换当天按实际时长计算
+func (c *BusinessCalendar) workingPeriods(day time.Time) [][2]time.Time {
+	if !c.isWorkday(day) {
+		return nil
+	}
+	year, month, date := day.Date()
+	periods := make([][2]time.Time, 0, len(c.periods))
+	for _, period := range c.periods {
+		periods = append(periods, [2]time.Time{
+			time.Date(year, month, date, period[0]/60, period[0]%60, 0, 0, c.location),
+			time.Date(year, month, date, period[1]/60, period[1]%60, 0, 0, c.location),
+		})
+	}
+	return periods
+}
+
+// startOfDay 获取时间在日历时区中当天的零点
+func (c *BusinessCalendar) startOfDay(t time.Time) time.Time {
+	year, month, date := t.In(c.location).Date()
+	return time.Date(year, month, date, 0, 0, 0, 0, c.location)
+}
+
+// Add 计算从 start 开始经过 d 工作时间后的时刻
+// start 不在工作时间内时从下一个工作时段开始计时;nil 日历按自然时间计算
+func (c *BusinessCalendar) Add(start time.Time, d time.Duration) time.Time {
+	if c == nil {
+		return start.Add(d)
+	}
+	if d <= 0 {
+		return start
+	}
+
+	remaining := d
+	day := c.startOfDay(start)
+	for i := 0; i < maxCalendarSearchDays; i++ {
+		for _, period := range c.workingPeriods(day) {
+			if !period[1].After(start) {
+				continue
+			}
+			from := period[0]
+			if start.After(from) {
+				from = start
+			}
+			available := period[1].Sub(from)
+			if remaining <= available {
+				return from.Add(remaining)
+			}
+			remaining -= available
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.location)
+	}
+
+	log.Printf("calendar %s has not enough working time within %d days, using wall-clock time", c.Timezone, maxCalendarSearchDays)
+	return start.Add(d)
+}
+
+// Sub 计算到 end 为止恰好经过 d 工作时间的起始时刻,是 Add 的逆运算;nil 日历按自然时间计算
+func (c *BusinessCalendar) Sub(end time.Time, d time.Duration) time.Time {
+	if c == nil {
+		return end.Add(-d)
+	}
+	if d <= 0 {
+		return end
+	}
+
+	remaining := d
+	day := c.startOfDay(end)
+	for i := 0; i < maxCalendarSearchDays; i++ {
+		periods := c.workingPeriods(day)
+		for j := len(periods) - 1; j >= 0; j-- {
+			period := periods[j]
+			if !period[0].Before(end) {
+				continue
+			}
+			to := period[1]
+			if end.Before(to) {
+				to = end
+			}
+			available := to.Sub(period[0])
+			if remaining <= available {
+				return to.Add(-remaining)
+			}
+			remaining -= available
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()-1, 0, 0, 0, 0, c.location)
+	}
+
+	log.Printf("calendar %s has not enough working time within %d days, using wall-clock time", c.Timezone, maxCalendarSearchDays)
+	return end.Add(-d)
+}
+
+// Between 计算 start 到 end 之间的工作时间,end 不晚于 start 时返回 0;nil 日历按自然时间计算
+func (c *BusinessCalendar) Between(start time.Time, end time.Time) time.Duration {
+	if !end.After(start) {
+		return 0
+	}
+	if c == nil {
+		return end.Sub(start)
+	}
+
+	var total time.Duration
+	for day := c.startOfDay(start); day.Before(end); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.location) {
+		for _, period := range c.workingPeriods(day) {
+			from, to := period[0], period[1]
+			if start.After(from) {
+				from = start
+			}
+			if end.Before(to) {
+				to = end
+			}
+			if to.After(from) {
+				total += to.Sub(from)
+			}
+		}
+	}
+	return total
+}
+
+// calendarConfig 模板配置或节点配置中引用的日历
+type calendarConfig struct {
+	CalendarID string `json:"calendar_id,omitempty"`
+}
+
+// calendarIDFor 获取节点使用的日历 ID: 节点配置的 calendar_id 优先,其次为模板配置的 calendar_id,都未配置时返回空字符串
+func (f *flowDefinition) calendarIDFor(nodeID string) (string, error) {
+	if node, exists := f.Nodes[nodeID]; exists && node != nil && len(node.Config) > 0 && string(node.Config) != "null" {
+		var cfg calendarConfig
+		if err := json.Unmarshal(node.Config, &cfg); err != nil {
+			return "", fmt.Errorf("invalid calendar for node %q: %w", nodeID, err)
+		}
+		if cfg.CalendarID != "" {
+			return cfg.CalendarID, nil
+		}
+	}
+	if f.Config != nil {
+		return f.Config.CalendarID, nil
+	}
+	return "", nil
+}
+
+// loadCalendar 加载工作日历
+// 日历不存在(已被删除)或配置无效时记录日志并按自然时间计算,避免截止时间无法计算导致扫描器反复失败
+func loadCalendar(db *gorm.DB, calendarID string) (*BusinessCalendar, error) {
+	if calendarID == "" {
+		return nil, nil
+	}
+	var cm model.CalendarModel
+	err := db.Where("id = ?", calendarID).First(&cm).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("calendar %q not found, using wall-clock time", calendarID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar %q: %w", calendarID, err)
+	}
+	cal, err := NewBusinessCalendar(&cm)
+	if err != nil {
+		log.Printf("calendar %q is invalid, using wall-clock time: %v", calendarID, err)
+		return nil, nil
+	}
+	return cal, nil
+}
+
+// nodeCalendar 加载节点使用的工作日历,未配置时返回 nil(按自然时间计算)
+func nodeCalendar(db *gorm.DB, flow *flowDefinition, nodeID string) (*BusinessCalendar, error) {
+	calendarID, err := flow.calendarIDFor(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return loadCalendar(db, calendarID)
+}
+
+// LoadNodeCalendar 加载模板版本中节点使用的工作日历(统计时调用),未配置时返回 nil
+func LoadNodeCalendar(db *gorm.DB, templateID string, version int, nodeID string) (*BusinessCalendar, error) {
+	flow, err := loadFlow(db, templateID, version)
+	if err != nil {
+		return nil, err
+	}
+	return nodeCalendar(db, flow, nodeID)
+}
+
+// ValidateCalendarReferences 校验模板配置和节点配置引用的日历是否存在(模板保存时调用)
+func ValidateCalendarReferences(db *gorm.DB, rawNodes json.RawMessage, rawConfig json.RawMessage) error {
+	var ids []string
+	if len(rawConfig) > 0 {
+		var cfg calendarConfig
+		if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+			return fmt.Errorf("failed to parse config: %w", err)
+		}
+		if cfg.CalendarID != "" {
+			ids = append(ids, cfg.CalendarID)
+		}
+	}
+	if len(rawNodes) > 0 {
+		var nodes map[string]*flowNode
+		if err := json.Unmarshal(rawNodes, &nodes); err != nil {
+			return fmt.Errorf("failed to parse nodes: %w", err)
+		}
+		flow := &flowDefinition{Nodes: nodes}
+		for id := range nodes {
+			calendarID, err := flow.calendarIDFor(id)
+			if err != nil {
+				return err
+			}
+			if calendarID != "" && !containsString(ids, calendarID) {
+				ids = append(ids, calendarID)
+			}
+		}
+	}
+
+	for _, id := range ids {
+		var count int64
+		if err := db.Model(&model.CalendarModel{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check calendar %q: %w", id, err)
+		}
+		if count == 0 {
+			return fmt.Errorf("calendar %q not found", id)
+		}
+	}
+	return nil
+}
+
+// mergeCalendarID 将原始配置中的日历 ID 合并到待保存的模板数据中
+func mergeCalendarID(templateMap map[string]interface{}, rawConfig json.RawMessage) error {
+	if len(rawConfig) == 0 {
+		return nil
+	}
+	var cfg calendarConfig
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.CalendarID == "" {
+		return nil
+	}
+
+	configMap, _ := templateMap["config"].(map[string]interface{})
+	if configMap == nil {
+		configMap = make(map[string]interface{})
+		templateMap["config"] = configMap
+	}
+	configMap["calendar_id"] = cfg.CalendarID
+	return nil
+}
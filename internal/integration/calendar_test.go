@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"testing"
+	"time"
+)
+
+// testCalendar 上海时区,工作时段 09:00-12:00、13:00-18:00,国庆 10-01、10-02 放假,10-10(周六)调休上班
+func testCalendar(t *testing.T) *BusinessCalendar {
+	t.Helper()
+	cal := &BusinessCalendar{
+		Timezone:      "Asia/Shanghai",
+		WorkingHours:  []WorkingPeriod{{Start: "13:00", End: "18:00"}, {Start: "09:00", End: "12:00"}},
+		Holidays:      []string{"2026-10-01", "2026-10-02"},
+		ExtraWorkdays: []string{"2026-10-10"},
+	}
+	mustNoError(t, cal.Validate())
+	return cal
+}
+
+func shanghai(t *testing.T, value string) time.Time {
+	t.Helper()
+	location, err := time.LoadLocation("Asia/Shanghai")
+	mustNoError(t, err)
+	parsed, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+	mustNoError(t, err)
+	return parsed
+}
+
+func TestBusinessCalendarAdd(t *testing.T) {
+	cal := testCalendar(t)
+	tests := []struct {
+		name  string
+		start string
+		d     time.Duration
+		want  string
+	}{
+		{"within a period", "2026-10-05 09:30", time.Hour, "2026-10-05 10:30"},
+		{"skips lunch break", "2026-10-05 11:30", time.Hour, "2026-10-05 13:30"},
+		{"starts at next period", "2026-10-05 07:00", time.Hour, "2026-10-05 10:00"},
+		{"ends exactly at period end", "2026-10-05 17:00", time.Hour, "2026-10-05 18:00"},
+		{"continues next workday", "2026-10-05 17:00", 2 * time.Hour, "2026-10-06 10:00"},
+		{"skips holidays and weekend", "2026-09-30 17:00", 2 * time.Hour, "2026-10-05 10:00"},
+		{"counts extra workday", "2026-10-09 17:00", 2 * time.Hour, "2026-10-10 10:00"},
+		{"skips regular weekend", "2026-10-16 17:00", 2 * time.Hour, "2026-10-19 10:00"},
+		{"zero duration", "2026-10-05 12:30", 0, "2026-10-05 12:30"},
+		{"multiple days", "2026-10-05 09:00", 24 * time.Hour, "2026-10-07 18:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cal.Add(shanghai(t, tt.start), tt.d)
+			if want := shanghai(t, tt.want); !got.Equal(want) {
+				t.Fatalf("Add = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestBusinessCalendarBetween(t *testing.T) {
+	cal := testCalendar(t)
+	tests := []struct {
+		start, end string
+		want       time.Duration
+	}{
+		{"2026-10-05 09:00", "2026-10-05 18:00", 8 * time.Hour},
+		{"2026-10-05 08:00", "2026-10-06 10:00", 9 * time.Hour},
+		{"2026-10-05 12:10", "2026-10-05 12:50", 0},
+		{"2026-09-30 17:00", "2026-10-05 10:00", 2 * time.Hour},
+		{"2026-10-09 18:00", "2026-10-11 23:00", 8 * time.Hour},
+		{"2026-10-06 10:00", "2026-10-05 10:00", 0},
+	}
+	for _, tt := range tests {
+		if got := cal.Between(shanghai(t, tt.start), shanghai(t, tt.end)); got != tt.want {
+			t.Errorf("Between(%s, %s) = %s, want %s", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestBusinessCalendarSubIsInverseOfBetween(t *testing.T) {
+	cal := testCalendar(t)
+	base := shanghai(t, "2026-10-05 10:30")
+	for _, d := range []time.Duration{time.Minute, 90 * time.Minute, 3 * time.Hour, 8 * time.Hour, 40 * time.Hour} {
+		for _, offset := range []time.Duration{0, 5 * time.Hour, 3 * 24 * time.Hour, 14*time.Hour + 7*time.Minute} {
+			end := base.Add(offset)
+			start := cal.Sub(end, d)
+			if got := cal.Between(start, end); got != d {
+				t.Errorf("Between(Sub(%s, %s), end) = %s", end, d, got)
+			}
+		}
+	}
+
+	// 跨越节假日向前计算
+	if got, want := cal.Sub(shanghai(t, "2026-10-05 10:00"), 2*time.Hour), shanghai(t, "2026-09-30 17:00"); !got.Equal(want) {
+		t.Fatalf("Sub = %s, want %s", got, want)
+	}
+}
+
+func TestNilBusinessCalendarUsesWallClock(t *testing.T) {
+	var cal *BusinessCalendar
+	start := shanghai(t, "2026-10-01 23:00")
+	if got := cal.Add(start, 3*time.Hour); !got.Equal(start.Add(3 * time.Hour)) {
+		t.Fatalf("Add = %s", got)
+	}
+	if got := cal.Between(start, start.Add(90*time.Minute)); got != 90*time.Minute {
+		t.Fatalf("Between = %s", got)
+	}
+	if got := cal.Sub(start, time.Hour); !got.Equal(start.Add(-time.Hour)) {
+		t.Fatalf("Sub = %s", got)
+	}
+}
+
+func TestBusinessCalendarDaylightSaving(t *testing.T) {
+	cal := &BusinessCalendar{
+		Timezone:     "America/New_York",
+		Workdays:     []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+		WorkingHours: []WorkingPeriod{{Start: "00:00", End: "24:00"}},
+	}
+	mustNoError(t, cal.Validate())
+
+	location, err := time.LoadLocation("America/New_York")
+	mustNoError(t, err)
+	// 2026-03-08 夏令时开始,当天只有 23 小时
+	start := time.Date(2026, 3, 8, 0, 0, 0, 0, location)
+	end := time.Date(2026, 3, 9, 0, 0, 0, 0, location)
+	if got := cal.Between(start, end); got != 23*time.Hour {
+		t.Fatalf("Between = %s, want 23h", got)
+	}
+	if got := cal.Add(start, 23*time.Hour); !got.Equal(end) {
+		t.Fatalf("Add = %s, want %s", got, end)
+	}
+}
+
+func TestBusinessCalendarValidate(t *testing.T) {
+	invalid := map[string]*BusinessCalendar{
+		"timezone":        {Timezone: "Mars/Olympus"},
+		"workday":         {Workdays: []string{"funday"}},
+		"clock format":    {WorkingHours: []WorkingPeriod{{Start: "9:00", End: "18:00"}}},
+		"clock range":     {WorkingHours: []WorkingPeriod{{Start: "09:00", End: "24:30"}}},
+		"empty period":    {WorkingHours: []WorkingPeriod{{Start: "18:00", End: "09:00"}}},
+		"overlap":         {WorkingHours: []WorkingPeriod{{Start: "09:00", End: "13:00"}, {Start: "12:00", End: "18:00"}}},
+		"holiday date":    {Holidays: []string{"2026/10/01"}},
+		"extra work date": {ExtraWorkdays: []string{"2026-13-01"}},
+	}
+	for name, cal := range invalid {
+		if err := cal.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	cal := &BusinessCalendar{Workdays: []string{" MON "}}
+	mustNoError(t, cal.Validate())
+	if cal.Timezone != "UTC" || cal.Workdays[0] != "mon" || len(cal.WorkingHours) != 1 {
+		t.Fatalf("defaults not applied: %+v", cal)
+	}
+}
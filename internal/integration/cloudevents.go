@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/event"
+)
+
+// Webhook 请求体格式
+const (
+	// PayloadFormatRaw 事件 JSON(默认)
+	PayloadFormatRaw = "raw"
+	// PayloadFormatCloudEventsStructured CloudEvents 1.0 结构化格式,事件信封和内容都在请求体中
+	PayloadFormatCloudEventsStructured = "cloudevents_structured"
+	// PayloadFormatCloudEventsBinary CloudEvents 1.0 二进制格式,信封属性在 ce-* 请求头中,请求体为事件 JSON
+	PayloadFormatCloudEventsBinary = "cloudevents_binary"
+)
+
+// cloudEventTypePrefix CloudEvents type 前缀
+const cloudEventTypePrefix = "com.approval."
+
+// CloudEvent CloudEvents 1.0 事件
+// @Description CloudEvents 1.0 事件信封(结构化格式的请求体),data 为任务事件内容
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion" example:"1.0"`                         // CloudEvents 版本
+	ID              string          `json:"id" example:"2f1c3a6e-8d0b-4c1e-9a57-0e4b7f3d9c21"` // 事件 ID,重试时不变
+	Source          string          `json:"source" example:"https://approval.example.com"`     // 服务地址
+	Type            string          `json:"type" example:"com.approval.task.approved"`         // 事件类型
+	Subject         string          `json:"subject" example:"task-001"`                        // 任务 ID
+	Time            time.Time       `json:"time" example:"2024-01-01T08:00:00Z"`               // 事件产生时间
+	DataContentType string          `json:"datacontenttype" example:"application/json"`        // data 的内容类型
+	Data            json.RawMessage `json:"data" swaggertype:"object"`                         // 任务事件内容(与默认格式的请求体相同)
+}
+
+// IsPayloadFormat 判断是否为支持的 Webhook 请求体格式
+func IsPayloadFormat(format string) bool {
+	switch format {
+	case PayloadFormatRaw, PayloadFormatCloudEventsStructured, PayloadFormatCloudEventsBinary:
+		return true
+	}
+	return false
+}
+
+// CloudEventType 将任务事件类型转换为 CloudEvents type
+// 如 task_approved 转换为 com.approval.task.approved,node_activated 转换为 com.approval.node.activated
+func CloudEventType(eventType string) string {
+	if i := strings.Index(eventType, "_"); i > 0 {
+		return cloudEventTypePrefix + eventType[:i] + "." + eventType[i+1:]
+	}
+	return cloudEventTypePrefix + eventType
+}
+
+// newCloudEvent 创建 CloudEvents 事件
+// id 为事件 ID,data 为序列化后的任务事件
+func newCloudEvent(id string, source string, evt *event.Event, data []byte) *CloudEvent {
+	ce := &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          source,
+		Type:            CloudEventType(string(evt.Type)),
+		Time:            evt.Time.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	if evt.Task != nil {
+		ce.Subject = evt.Task.ID
+	}
+	return ce
+}
+
+// setBinaryHeaders 设置 CloudEvents 二进制格式的 ce-* 请求头
+func (ce *CloudEvent) setBinaryHeaders(header http.Header) {
+	header.Set("ce-specversion", ce.SpecVersion)
+	header.Set("ce-id", ce.ID)
+	header.Set("ce-source", ce.Source)
+	header.Set("ce-type", ce.Type)
+	if ce.Subject != "" {
+		header.Set("ce-subject", ce.Subject)
+	}
+	header.Set("ce-time", ce.Time.Format(time.RFC3339Nano))
+	header.Set("Content-Type", ce.DataContentType)
+}
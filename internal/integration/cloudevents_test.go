@@ -0,0 +1,137 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/template"
+)
+
+func TestCloudEventType(t *testing.T) {
+	tests := map[string]string{
+		"task_approved":  "com.approval.task.approved",
+		"node_activated": "com.approval.node.activated",
+		"task_cc_added":  "com.approval.task.cc_added",
+		"heartbeat":      "com.approval.heartbeat",
+	}
+	for eventType, want := range tests {
+		if got := CloudEventType(eventType); got != want {
+			t.Errorf("CloudEventType(%q) = %q, want %q", eventType, got, want)
+		}
+	}
+}
+
+func TestIsPayloadFormat(t *testing.T) {
+	for _, format := range []string{PayloadFormatRaw, PayloadFormatCloudEventsStructured, PayloadFormatCloudEventsBinary} {
+		if !IsPayloadFormat(format) {
+			t.Errorf("%q should be a payload format", format)
+		}
+	}
+	for _, format := range []string{"", "cloudevents", "xml"} {
+		if IsPayloadFormat(format) {
+			t.Errorf("%q should not be a payload format", format)
+		}
+	}
+}
+
+// capturedRequest Webhook 接收端收到的请求
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// sendTestWebhook 以指定请求体格式投递事件,返回接收端收到的请求
+func sendTestWebhook(t *testing.T, format string, evt *event.Event) *capturedRequest {
+	t.Helper()
+	received := make(chan *capturedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- &capturedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	h := newTestEventHandler(newTestDB(t), EventDeliveryOptions{Source: "https://approval.example.com"})
+	target := &webhookTarget{key: "test", webhook: &template.WebhookConfig{URL: server.URL}, format: format}
+	_, err := h.sendWebhookRequest(target, evt, "evt-1")
+	mustNoError(t, err)
+	return <-received
+}
+
+func testWebhookEvent() *event.Event {
+	return &event.Event{
+		Type: EventTaskApproved,
+		Time: time.Date(2024, 1, 1, 16, 0, 0, 0, time.FixedZone("CST", 8*3600)),
+		Task: &event.TaskInfo{ID: "task-001"},
+	}
+}
+
+func TestWebhookRawPayload(t *testing.T) {
+	evt := testWebhookEvent()
+	req := sendTestWebhook(t, PayloadFormatRaw, evt)
+
+	want, _ := json.Marshal(evt)
+	if string(req.body) != string(want) {
+		t.Fatalf("body = %s, want %s", req.body, want)
+	}
+	if got := req.header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got := req.header.Get(HeaderDeliveryID); got != "evt-1" {
+		t.Fatalf("%s = %q", HeaderDeliveryID, got)
+	}
+	if req.header.Get("ce-id") != "" {
+		t.Fatal("raw payload has CloudEvents headers")
+	}
+}
+
+func TestWebhookCloudEventsStructuredPayload(t *testing.T) {
+	evt := testWebhookEvent()
+	req := sendTestWebhook(t, PayloadFormatCloudEventsStructured, evt)
+
+	if got := req.header.Get("Content-Type"); got != "application/cloudevents+json" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	var ce CloudEvent
+	mustNoError(t, json.Unmarshal(req.body, &ce))
+	if ce.SpecVersion != "1.0" || ce.ID != "evt-1" || ce.Source != "https://approval.example.com" ||
+		ce.Type != "com.approval.task.approved" || ce.Subject != "task-001" || ce.DataContentType != "application/json" {
+		t.Fatalf("unexpected envelope: %+v", ce)
+	}
+	if !ce.Time.Equal(evt.Time) || ce.Time.Location() != time.UTC {
+		t.Fatalf("time = %v, want %v in UTC", ce.Time, evt.Time)
+	}
+	want, _ := json.Marshal(evt)
+	if string(ce.Data) != string(want) {
+		t.Fatalf("data = %s, want %s", ce.Data, want)
+	}
+}
+
+func TestWebhookCloudEventsBinaryPayload(t *testing.T) {
+	evt := testWebhookEvent()
+	req := sendTestWebhook(t, PayloadFormatCloudEventsBinary, evt)
+
+	want, _ := json.Marshal(evt)
+	if string(req.body) != string(want) {
+		t.Fatalf("body = %s, want %s", req.body, want)
+	}
+	headers := map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          "evt-1",
+		"ce-source":      "https://approval.example.com",
+		"ce-type":        "com.approval.task.approved",
+		"ce-subject":     "task-001",
+		"ce-time":        "2024-01-01T08:00:00Z",
+		"Content-Type":   "application/json",
+	}
+	for name, value := range headers {
+		if got := req.header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/template"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// ApproverSourceDelegation 委托人休假期间转给代理人,来源说明为委托人
+const ApproverSourceDelegation = "delegation"
+
+// maxDelegationDepth 委托链的最大长度(A 委托 B、B 又委托 C 时转给 C)
+const maxDelegationDepth = 5
+
+// relationSyncTimeout 同步审批人权限关系的超时时间
+const relationSyncTimeout = 10 * time.Second
+
+// ApproverRelations 审批人权限关系
+// 审批人被委托替换时授予代理人任务的 approver 关系、撤销委托人的关系,
+// 抄送任务时授予抄送人任务的 viewer 关系,由 auth 包基于 OpenFGA 实现
+type ApproverRelations interface {
+	GrantApprover(ctx context.Context, taskID string, userID string) error
+	RevokeApprover(ctx context.Context, taskID string, userID string) error
+	GrantViewer(ctx context.Context, taskID string, userID string) error
+}
+
+// relationChange 待同步的权限关系变更
+type relationChange struct {
+	taskID string
+	userID string
+	grant  bool
+	viewer bool // 为 true 时授予 viewer 关系,否则为 approver 关系
+}
+
+// SetApproverRelations 设置审批人权限关系
+// 未设置时委托只替换任务中的审批人,不同步权限关系
+func (m *DBTaskManager) SetApproverRelations(relations ApproverRelations) {
+	m.approverRelations = relations
+}
+
+// delegateFor 沿委托链查找用户在指定时间的代理人,没有生效的委托或委托链成环时返回空
+func (m *dbTaskManager) delegateFor(userID string, templateID string, at time.Time) (string, error) {
+	repo := repository.NewDelegationRepository(m.db)
+	visited := map[string]bool{userID: true}
+	current := userID
+	for i := 0; i < maxDelegationDepth; i++ {
+		rule, err := repo.FindActive(current, templateID, at)
+		if err != nil {
+			return "", fmt.Errorf("failed to find delegation of %q: %w", current, err)
+		}
+		if rule == nil {
+			break
+		}
+		if visited[rule.Delegate] {
+			log.Printf("delegation chain of %q forms a cycle at %q, keeping original approver", userID, rule.Delegate)
+			return "", nil
+		}
+		visited[rule.Delegate] = true
+		current = rule.Delegate
+	}
+	if current == userID {
+		return "", nil
+	}
+	return current, nil
+}
+
+// applyDelegations 将活动审批节点上尚未审批、且处于委托期间的审批人原位替换为代理人(保持依次审批的顺序)
+// 代理人的来源记录为委托人,审批时据此记录"代理人代委托人审批"。返回是否替换了审批人
+func (m *dbTaskManager) applyDelegations(tsk *task.Task, rt *taskRuntime, nodeID string) (bool, error) {
+	pending := pendingApprovers(tsk, nodeID)
+	if len(pending) == 0 {
+		return false, nil
+	}
+
+	now := time.Now()
+	delegates := make(map[string]string)
+	for _, approver := range pending {
+		if approver == systemOperator {
+			continue
+		}
+		delegate, err := m.delegateFor(approver, tsk.TemplateID, now)
+		if err != nil {
+			return false, err
+		}
+		if delegate != "" {
+			delegates[approver] = delegate
+		}
+	}
+	if len(delegates) == 0 {
+		return false, nil
+	}
+
+	approvers := make([]string, 0, len(tsk.Approvers[nodeID]))
+	var descriptions []string
+	for _, approver := range tsk.Approvers[nodeID] {
+		next := approver
+		if delegate, exists := delegates[approver]; exists {
+			next = delegate
+			descriptions = append(descriptions, fmt.Sprintf("%s -> %s", approver, delegate))
+			m.setProvenance(rt, nodeID, approver, delegate, &ApproverProvenance{Source: ApproverSourceDelegation, Detail: approver})
+			tsk.Records = append(tsk.Records, &task.Record{
+				ID:          generateRecordID(),
+				TaskID:      tsk.ID,
+				NodeID:      nodeID,
+				Approver:    approver,
+				Result:      "delegate",
+				Comment:     fmt.Sprintf("委托 %s 代理审批", delegate),
+				CreatedAt:   now,
+				Attachments: []string{},
+			})
+			m.queueRelation(tsk.ID, delegate, true)
+		}
+		if !containsString(approvers, next) {
+			approvers = append(approvers, next)
+		}
+	}
+	tsk.Approvers[nodeID] = approvers
+
+	// 委托人在其他节点仍是审批人时保留其权限关系
+	for approver := range delegates {
+		if !isTaskApprover(tsk, approver) {
+			m.queueRelation(tsk.ID, approver, false)
+		}
+	}
+
+	m.emit(EventTaskDelegated, tsk, nodeID, systemOperator, "delegate", strings.Join(descriptions, ", "))
+	return true, nil
+}
+
+// delegatedFrom 获取代理人在节点上所代理的委托人,不是代理审批时返回空
+func delegatedFrom(rt *taskRuntime, nodeID string, approver string) string {
+	if rt == nil {
+		return ""
+	}
+	provenance := rt.ApproverProvenance[nodeID][approver]
+	if provenance == nil || provenance.Source != ApproverSourceDelegation {
+		return ""
+	}
+	return provenance.Detail
+}
+
+// isTaskApprover 判断用户是否为任务任一节点的审批人
+func isTaskApprover(tsk *task.Task, userID string) bool {
+	for _, approvers := range tsk.Approvers {
+		if containsString(approvers, userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// queueRelation 记录审批人权限关系变更,事务内在提交后同步
+func (m *dbTaskManager) queueRelation(taskID string, userID string, grant bool) {
+	if m.approverRelations == nil {
+		return
+	}
+	m.queueChange(relationChange{taskID: taskID, userID: userID, grant: grant})
+}
+
+// queueViewer 记录抄送人的 viewer 权限关系,事务内在提交后同步
+func (m *dbTaskManager) queueViewer(taskID string, userID string) {
+	if m.approverRelations == nil {
+		return
+	}
+	m.queueChange(relationChange{taskID: taskID, userID: userID, grant: true, viewer: true})
+}
+
+// queueChange 记录权限关系变更,不在事务内时立即同步
+func (m *dbTaskManager) queueChange(change relationChange) {
+	if m.pendingRelations != nil {
+		*m.pendingRelations = append(*m.pendingRelations, change)
+		return
+	}
+	m.syncRelations([]relationChange{change})
+}
+
+// syncRelations 同步权限关系,失败时只记录日志(任务中的审批人列表和抄送列表为准)
+func (m *dbTaskManager) syncRelations(changes []relationChange) {
+	if m.approverRelations == nil || len(changes) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), relationSyncTimeout)
+	defer cancel()
+
+	for _, change := range changes {
+		var err error
+		if change.viewer {
+			err = m.approverRelations.GrantViewer(ctx, change.taskID, change.userID)
+		} else if change.grant {
+			err = m.approverRelations.GrantApprover(ctx, change.taskID, change.userID)
+		} else {
+			err = m.approverRelations.RevokeApprover(ctx, change.taskID, change.userID)
+		}
+		if err != nil {
+			log.Printf("failed to sync relation of %q on task %q (grant=%v, viewer=%v): %v", change.userID, change.taskID, change.grant, change.viewer, err)
+		}
+	}
+}
+
+// ApplyDelegation 委托规则生效后,将运行中任务里委托人尚未审批的活动节点转给代理人
+// templateID 不为空时只处理该模板的任务。返回转交的任务数量;单个任务失败时记录日志并继续
+func (m *DBTaskManager) ApplyDelegation(delegator string, templateID string) (int, error) {
+	query := m.db.Model(&model.TaskModel{}).
+		Select("id", "data").
+		Where("state IN ?", []string{string(types.TaskStateSubmitted), string(types.TaskStateApproving)})
+	if templateID != "" {
+		query = query.Where("template_id = ?", templateID)
+	}
+	var tasks []model.TaskModel
+	if err := query.Find(&tasks).Error; err != nil {
+		return 0, fmt.Errorf("failed to list running tasks: %w", err)
+	}
+
+	count := 0
+	for _, tm := range tasks {
+		var candidate struct {
+			Approvers map[string][]string `json:"approvers"`
+		}
+		if err := json.Unmarshal(tm.Data, &candidate); err != nil {
+			continue
+		}
+		found := false
+		for _, approvers := range candidate.Approvers {
+			if containsString(approvers, delegator) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		changed := false
+		err := m.inTx(func(txm *dbTaskManager) error {
+			var err error
+			changed, err = txm.delegateTask(tm.ID, delegator)
+			return err
+		})
+		if err != nil {
+			log.Printf("failed to apply delegation of %q to task %q: %v", delegator, tm.ID, err)
+			continue
+		}
+		if changed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// delegateTask 替换任务活动审批节点上处于委托期间的审批人,委托人不在待审批列表时不做修改
+func (m *dbTaskManager) delegateTask(id string, delegator string) (bool, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return false, err
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return false, nil
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return false, err
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return false, fmt.Errorf("failed to load template flow: %w", err)
+	}
+
+	changed := false
+	for _, nodeID := range rt.ActiveNodes {
+		if flow.nodeType(nodeID) != string(template.NodeTypeApproval) || !containsString(pendingApprovers(tsk, nodeID), delegator) {
+			continue
+		}
+		delegated, err := m.applyDelegations(tsk, rt, nodeID)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || delegated
+	}
+	if !changed {
+		return false, nil
+	}
+	tsk.UpdatedAt = time.Now()
+
+	taskData, err := json.Marshal(tsk)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal task: %w", err)
+	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return false, err
+	}
+	taskModel := &model.TaskModel{
+		ID:              tsk.ID,
+		TemplateID:      tsk.TemplateID,
+		TemplateVersion: tsk.TemplateVersion,
+		BusinessID:      tsk.BusinessID,
+		State:           string(tsk.State),
+		CurrentNode:     tsk.CurrentNode,
+		Data:            taskData,
+		Runtime:         runtimeData,
+		CreatedAt:       tsk.CreatedAt,
+		UpdatedAt:       tsk.UpdatedAt,
+		SubmittedAt:     tsk.SubmittedAt,
+	}
+	if err := m.saveTask(taskModel); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,138 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+)
+
+// addDelegation 保存从现在起生效一天的委托规则
+func (e *testEnv) addDelegation(t *testing.T, delegator string, delegate string, templateID string) {
+	t.Helper()
+	now := time.Now()
+	rule := &model.DelegationModel{
+		ID:         fmt.Sprintf("dlg-%s-%s-%s", delegator, delegate, templateID),
+		Delegator:  delegator,
+		Delegate:   delegate,
+		TemplateID: templateID,
+		StartAt:    now.Add(-time.Hour),
+		EndAt:      now.Add(24 * time.Hour),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	mustNoError(t, repository.NewDelegationRepository(e.db).Save(rule))
+}
+
+func TestDelegateForFollowsChain(t *testing.T) {
+	env := newTestEnv(t)
+	env.addDelegation(t, "ann", "bob", "")
+	env.addDelegation(t, "bob", "cid", "")
+
+	delegate, err := env.tasks.delegateFor("ann", "any", time.Now())
+	mustNoError(t, err)
+	if delegate != "cid" {
+		t.Fatalf("delegate = %q, want cid", delegate)
+	}
+
+	delegate, err = env.tasks.delegateFor("dan", "any", time.Now())
+	mustNoError(t, err)
+	if delegate != "" {
+		t.Fatalf("delegate = %q, want none", delegate)
+	}
+}
+
+func TestDelegateForIgnoresInactiveRules(t *testing.T) {
+	env := newTestEnv(t)
+	env.addDelegation(t, "ann", "bob", "")
+
+	delegate, err := env.tasks.delegateFor("ann", "any", time.Now().Add(48*time.Hour))
+	mustNoError(t, err)
+	if delegate != "" {
+		t.Fatalf("delegate = %q, want none after the rule expires", delegate)
+	}
+}
+
+func TestDelegateForPrefersTemplateRule(t *testing.T) {
+	env := newTestEnv(t)
+	env.addDelegation(t, "ann", "bob", "")
+	env.addDelegation(t, "ann", "cid", "leave")
+
+	delegate, err := env.tasks.delegateFor("ann", "leave", time.Now())
+	mustNoError(t, err)
+	if delegate != "cid" {
+		t.Fatalf("delegate = %q, want template-scoped cid", delegate)
+	}
+	delegate, err = env.tasks.delegateFor("ann", "expense", time.Now())
+	mustNoError(t, err)
+	if delegate != "bob" {
+		t.Fatalf("delegate = %q, want bob", delegate)
+	}
+}
+
+func TestDelegateForBreaksCycles(t *testing.T) {
+	tests := map[string][][2]string{
+		"self":        {{"ann", "ann"}},
+		"two users":   {{"ann", "bob"}, {"bob", "ann"}},
+		"three users": {{"ann", "bob"}, {"bob", "cid"}, {"cid", "ann"}},
+		"inner cycle": {{"ann", "bob"}, {"bob", "cid"}, {"cid", "bob"}},
+	}
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			env := newTestEnv(t)
+			for _, rule := range rules {
+				env.addDelegation(t, rule[0], rule[1], "")
+			}
+			delegate, err := env.tasks.delegateFor("ann", "any", time.Now())
+			mustNoError(t, err)
+			if delegate != "" {
+				t.Fatalf("delegate = %q, want original approver kept", delegate)
+			}
+		})
+	}
+}
+
+func TestDelegateForStopsAtMaxDepth(t *testing.T) {
+	env := newTestEnv(t)
+	users := []string{"u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7"}
+	for i := 0; i+1 < len(users); i++ {
+		env.addDelegation(t, users[i], users[i+1], "")
+	}
+
+	delegate, err := env.tasks.delegateFor("u0", "any", time.Now())
+	mustNoError(t, err)
+	if want := users[maxDelegationDepth]; delegate != want {
+		t.Fatalf("delegate = %q, want %q", delegate, want)
+	}
+}
+
+func TestSubmitReplacesDelegatedApprover(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann", "dan"})
+	env.addDelegation(t, "ann", "bob", "")
+
+	tsk := env.startTask(t, "single", `{}`)
+	assertEqualStrings(t, tsk.Approvers["review"], []string{"bob", "dan"})
+
+	rt, err := env.tasks.loadRuntime(tsk)
+	mustNoError(t, err)
+	if from := delegatedFrom(rt, "review", "bob"); from != "ann" {
+		t.Fatalf("delegatedFrom = %q, want ann", from)
+	}
+	if !containsString(env.events.types(), string(EventTaskDelegated)) {
+		t.Fatalf("expected delegated event, got %v", env.events.types())
+	}
+}
+
+func TestSubmitKeepsApproverOnDelegationCycle(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	env.addDelegation(t, "ann", "bob", "")
+	env.addDelegation(t, "bob", "ann", "")
+
+	tsk := env.startTask(t, "single", `{}`)
+	assertEqualStrings(t, tsk.Approvers["review"], []string{"ann"})
+	mustNoError(t, env.tasks.Approve(tsk.ID, "review", "ann", ""))
+}
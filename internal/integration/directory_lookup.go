@@ -0,0 +1,156 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errDirectoryLookupRequired 事务内需要的审批人目录查询尚未执行
+var errDirectoryLookupRequired = errors.New("approver directory lookup required")
+
+// maxDirectoryLookupRounds 一次变更最多执行的目录查询轮数
+// 每轮查询后重新执行事务;同一节点的多个来源在一轮中查询,上级链整条在一轮中查询
+const maxDirectoryLookupRounds = 8
+
+// 目录查询类型
+const (
+	directoryQueryRole     = "role"
+	directoryQueryGroup    = "group"
+	directoryQueryManager  = "manager"
+	directoryQueryChain    = "manager_chain"
+	directoryQueryRelation = "relation"
+)
+
+// directoryQuery 一次审批人目录查询
+type directoryQuery struct {
+	kind       string
+	name       string // 角色、用户组、关系名或用户 ID
+	objectType string
+	objectID   string
+	levels     int // 上级链的层级数
+}
+
+// directoryAnswer 目录查询结果(查询失败的错误也会记录,重试事务时返回相同的错误)
+type directoryAnswer struct {
+	users   []string
+	manager string
+	err     error
+}
+
+// directoryLookups 事务内使用的审批人目录
+// 事务内只读取已经查询过的结果,未查询过的请求被记录下来,inTx 回滚事务后在事务外执行查询并重新执行事务,
+// 因此 Keycloak 和 OpenFGA 的 HTTP 调用不会在持有任务行锁时进行
+type directoryLookups struct {
+	directory ApproverDirectory
+	answers   map[directoryQuery]*directoryAnswer
+	missing   []directoryQuery
+}
+
+// newDirectoryLookups 创建事务内使用的审批人目录,未配置目录时返回 nil
+func newDirectoryLookups(directory ApproverDirectory) *directoryLookups {
+	if directory == nil {
+		return nil
+	}
+	return &directoryLookups{
+		directory: directory,
+		answers:   make(map[directoryQuery]*directoryAnswer),
+	}
+}
+
+// pending 是否有尚未执行的查询
+func (l *directoryLookups) pending() bool {
+	return l != nil && len(l.missing) > 0
+}
+
+// lookup 读取查询结果,未查询过时记录请求并返回 errDirectoryLookupRequired
+func (l *directoryLookups) lookup(query directoryQuery) (*directoryAnswer, error) {
+	if answer, exists := l.answers[query]; exists {
+		return answer, answer.err
+	}
+	if !containsQuery(l.missing, query) {
+		l.missing = append(l.missing, query)
+	}
+	return nil, errDirectoryLookupRequired
+}
+
+// fetch 在事务外执行记录下来的查询
+func (l *directoryLookups) fetch() {
+	ctx, cancel := context.WithTimeout(context.Background(), approverResolveTimeout)
+	defer cancel()
+
+	for _, query := range l.missing {
+		answer := &directoryAnswer{}
+		switch query.kind {
+		case directoryQueryRole:
+			answer.users, answer.err = l.directory.UsersWithRole(ctx, query.name)
+		case directoryQueryGroup:
+			answer.users, answer.err = l.directory.UsersInGroup(ctx, query.name)
+		case directoryQueryManager:
+			answer.manager, answer.err = l.directory.ManagerOf(ctx, query.name)
+		case directoryQueryChain:
+			answer.users, answer.err = managerChain(ctx, l.directory, query.name, query.levels)
+		case directoryQueryRelation:
+			answer.users, answer.err = l.directory.UsersWithRelation(ctx, query.name, query.objectType, query.objectID)
+		default:
+			answer.err = fmt.Errorf("unknown directory query %q", query.kind)
+		}
+		l.answers[query] = answer
+	}
+	l.missing = nil
+}
+
+// UsersWithRole 实现 ApproverDirectory
+func (l *directoryLookups) UsersWithRole(_ context.Context, role string) ([]string, error) {
+	answer, err := l.lookup(directoryQuery{kind: directoryQueryRole, name: role})
+	if err != nil {
+		return nil, err
+	}
+	return answer.users, nil
+}
+
+// UsersInGroup 实现 ApproverDirectory
+func (l *directoryLookups) UsersInGroup(_ context.Context, group string) ([]string, error) {
+	answer, err := l.lookup(directoryQuery{kind: directoryQueryGroup, name: group})
+	if err != nil {
+		return nil, err
+	}
+	return answer.users, nil
+}
+
+// ManagerOf 实现 ApproverDirectory
+func (l *directoryLookups) ManagerOf(_ context.Context, userID string) (string, error) {
+	answer, err := l.lookup(directoryQuery{kind: directoryQueryManager, name: userID})
+	if err != nil {
+		return "", err
+	}
+	return answer.manager, nil
+}
+
+// ManagerChain 实现 managerChainDirectory,整条上级链作为一次查询
+func (l *directoryLookups) ManagerChain(_ context.Context, userID string, levels int) ([]string, error) {
+	answer, err := l.lookup(directoryQuery{kind: directoryQueryChain, name: userID, levels: levels})
+	if err != nil {
+		return nil, err
+	}
+	return answer.users, nil
+}
+
+// UsersWithRelation 实现 ApproverDirectory
+func (l *directoryLookups) UsersWithRelation(_ context.Context, relation string, objectType string, objectID string) ([]string, error) {
+	answer, err := l.lookup(directoryQuery{kind: directoryQueryRelation, name: relation, objectType: objectType, objectID: objectID})
+	if err != nil {
+		return nil, err
+	}
+	return answer.users, nil
+}
+
+// containsQuery 判断查询是否已在列表中
+func containsQuery(queries []directoryQuery, query directoryQuery) bool {
+	for _, q := range queries {
+		if q == query {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,16 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,36 +22,97 @@ import (
 	"gorm.io/gorm"
 )
 
+// EventDeliveryOptions 事件投递配置
+type EventDeliveryOptions struct {
+	Workers      int            // 并发投递的 worker 数
+	PollInterval time.Duration  // outbox 轮询间隔
+	Lease        time.Duration  // 领取事件的租约时长,需大于单个事件的最长投递时间
+	MaxAttempts  int            // 最大投递次数,超过后事件进入死信状态
+	BackoffBase  time.Duration  // 第一次重试前的等待时间,之后每次翻倍
+	BackoffMax   time.Duration  // 重试等待时间上限
+	Signer       *WebhookSigner // Webhook 签名器,用于 HMAC 签名认证的 Webhook
+	Publisher    EventPublisher // 消息总线发布器,为 nil 时只推送 Webhook
+	Source       string         // CloudEvents source(服务地址)
+}
+
+// withDefaults 补全未设置的投递配置
+func (o EventDeliveryOptions) withDefaults() EventDeliveryOptions {
+	if o.Workers <= 0 {
+		o.Workers = 1
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 5 * time.Second
+	}
+	if o.Lease <= 0 {
+		o.Lease = time.Minute
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 10
+	}
+	if o.BackoffBase <= 0 {
+		o.BackoffBase = 10 * time.Second
+	}
+	if o.BackoffMax <= 0 {
+		o.BackoffMax = time.Hour
+	}
+	return o
+}
+
+// backoff 计算第 attempts 次投递失败后的重试等待时间
+func (o EventDeliveryOptions) backoff(attempts int) time.Duration {
+	delay := o.BackoffBase
+	for i := 1; i < attempts && delay < o.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > o.BackoffMax {
+		delay = o.BackoffMax
+	}
+	return delay
+}
+
+// outboxEventHandler 支持事务性 outbox 的事件处理器
+// 事件在任务变更的事务内写入 events 表,事务提交后通知投递器
+type outboxEventHandler interface {
+	event.EventHandler
+	HandleTx(tx *gorm.DB, evt *event.Event) error
+	Notify()
+}
+
 // dbEventHandler 基于数据库的事件处理器
-// 实现 approval-kit 的 EventHandler 接口
+// 实现 approval-kit 的 EventHandler 接口,事件先写入 events 表(outbox),
+// 再由 worker 按 ID 领取(租约锁,多副本不会重复投递)并推送到 Webhook
 type dbEventHandler struct {
-	db          *gorm.DB
-	eventRepo   repository.EventRepository
-	templateMgr template.TemplateManager
-	httpClient  *http.Client
-	queue       chan *event.Event
-	workers     int
-	stop        chan struct{}
+	db               *gorm.DB
+	eventRepo        repository.EventRepository
+	templateMgr      template.TemplateManager
+	subscriptionRepo repository.WebhookSubscriptionRepository
+	httpClient       *http.Client
+	opts             EventDeliveryOptions
+	owner            string        // 当前实例的租约持有者标识
+	wake             chan struct{} // 有新事件时唤醒 worker
+	stop             chan struct{}
+	clockMu          sync.Mutex
+	lastEventTime    time.Time // 最近产生的事件创建时间
 }
 
-// NewEventHandler 创建事件处理器
-func NewEventHandler(db *gorm.DB, workers int) event.EventHandler {
-	if workers <= 0 {
-		workers = 1
-	}
+// NewEventHandler 创建事件处理器并启动投递 worker
+func NewEventHandler(db *gorm.DB, opts EventDeliveryOptions) event.EventHandler {
+	opts = opts.withDefaults()
 
 	handler := &dbEventHandler{
-		db:          db,
-		eventRepo:   repository.NewEventRepository(db),
-		templateMgr: NewTemplateManager(db),
-		httpClient:  &http.Client{Timeout: 10 * time.Second},
-		queue:       make(chan *event.Event, 1000),
-		workers:     workers,
-		stop:        make(chan struct{}),
+		db:               db,
+		eventRepo:        repository.NewEventRepository(db),
+		templateMgr:      NewTemplateManager(db),
+		subscriptionRepo: repository.NewWebhookSubscriptionRepository(db),
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+		opts:             opts,
+		owner:            uuid.New().String(),
+		wake:             make(chan struct{}, 1),
+		stop:             make(chan struct{}),
 	}
 
 	// 启动 worker goroutines
-	for i := 0; i < workers; i++ {
+	for i := 0; i < opts.Workers; i++ {
 		go handler.worker()
 	}
 
@@ -52,144 +120,355 @@ func NewEventHandler(db *gorm.DB, workers int) event.EventHandler {
 }
 
 // Handle 处理事件
+// 持久化到 events 表后通知 worker 投递
 func (h *dbEventHandler) Handle(evt *event.Event) error {
-	// 1. 持久化事件到数据库
+	if err := h.save(h.eventRepo, evt); err != nil {
+		return err
+	}
+	h.Notify()
+	return nil
+}
+
+// HandleTx 在任务变更的事务内持久化事件
+// 事务提交后由调用方调用 Notify 通知 worker 投递
+func (h *dbEventHandler) HandleTx(tx *gorm.DB, evt *event.Event) error {
+	return h.save(repository.NewEventRepository(tx), evt)
+}
+
+// Notify 通知 worker 有新事件待投递(不阻塞)
+func (h *dbEventHandler) Notify() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// save 持久化事件
+func (h *dbEventHandler) save(eventRepo repository.EventRepository, evt *event.Event) error {
 	eventData, err := json.Marshal(evt)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	now := h.eventTime()
 	eventModel := &model.EventModel{
-		ID:        uuid.New().String(),
-		TaskID:    evt.Task.ID,
-		Type:      string(evt.Type),
-		Data:      eventData,
-		Status:    "pending",
-		RetryCount: 0,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:            uuid.New().String(),
+		TaskID:        evt.Task.ID,
+		Type:          string(evt.Type),
+		Data:          eventData,
+		Status:        model.EventStatusPending,
+		RetryCount:    0,
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
 	}
 
-	if err := h.eventRepo.Save(eventModel); err != nil {
+	if err := eventRepo.Save(eventModel); err != nil {
 		return fmt.Errorf("failed to save event: %w", err)
 	}
+	return nil
+}
 
-	// 2. 异步推送到 Webhook
-	select {
-	case h.queue <- evt:
-		// 事件成功入队
-	default:
-		// 队列满时记录日志,不阻塞
-		// TODO: 使用日志库记录
-		fmt.Printf("event queue full, dropping event: type=%q, task=%q\n", evt.Type, evt.Task.ID)
-	}
+// eventTime 获取事件创建时间
+// 同一任务的事件按创建时间顺序投递,因此保证本实例产生的事件创建时间严格递增(精度为数据库支持的微秒)
+func (h *dbEventHandler) eventTime() time.Time {
+	h.clockMu.Lock()
+	defer h.clockMu.Unlock()
 
-	return nil
+	now := time.Now().Truncate(time.Microsecond)
+	if !now.After(h.lastEventTime) {
+		now = h.lastEventTime.Add(time.Microsecond)
+	}
+	h.lastEventTime = now
+	return now
 }
 
-// worker 事件处理 worker
+// worker 事件投递 worker
+// 定时或收到通知时领取可投递的事件,逐个投递直到没有可投递的事件
 func (h *dbEventHandler) worker() {
+	ticker := time.NewTicker(h.opts.PollInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case evt := <-h.queue:
-			h.pushToWebhook(evt)
+		case <-ticker.C:
+		case <-h.wake:
 		case <-h.stop:
 			return
 		}
+
+		for {
+			select {
+			case <-h.stop:
+				return
+			default:
+			}
+
+			now := time.Now()
+			eventModel, err := h.eventRepo.ClaimNext(h.owner, now, now.Add(h.opts.Lease))
+			if err != nil {
+				log.Printf("failed to claim event: %v", err)
+				break
+			}
+			if eventModel == nil {
+				break
+			}
+			h.deliver(eventModel)
+		}
 	}
 }
 
-// pushToWebhook 推送到 Webhook
-func (h *dbEventHandler) pushToWebhook(evt *event.Event) {
-	// 1. 查找事件模型
-	var eventModel model.EventModel
-	err := h.db.Where("task_id = ? AND type = ?", evt.Task.ID, string(evt.Type)).
-		Order("created_at DESC").
-		First(&eventModel).Error
+// deliver 投递已领取的事件,并按结果更新投递状态
+// 失败时按指数退避设置下次投递时间(重试时只投递失败的目标),超过最大投递次数时进入死信状态
+func (h *dbEventHandler) deliver(eventModel *model.EventModel) {
+	attempts := eventModel.RetryCount + 1
+	updates := map[string]interface{}{
+		"retry_count": attempts,
+	}
+
+	released, err := h.push(eventModel)
+	if released {
+		updates["released"] = true
+	}
 	if err != nil {
-		// 如果找不到事件模型，记录错误但不重试
-		fmt.Printf("failed to find event model: %v\n", err)
-		return
+		updates["last_error"] = err.Error()
+		if attempts >= h.opts.MaxAttempts {
+			updates["status"] = model.EventStatusDead
+			updates["next_attempt_at"] = nil
+		} else {
+			updates["status"] = model.EventStatusFailed
+			updates["next_attempt_at"] = time.Now().Add(h.opts.backoff(attempts))
+		}
+	} else {
+		updates["status"] = model.EventStatusSuccess
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
 	}
 
-	// 2. 获取模板配置（包含 Webhook 配置）
-	// 如果 Task 中没有 TemplateID，尝试从事件数据中获取
-	templateID := evt.Task.TemplateID
-	if templateID == "" {
-		// 如果 TemplateID 为空，记录错误但不重试
-		fmt.Printf("template ID is empty in event task: %v\n", evt.Task)
-		// 标记为成功（无需推送，因为没有模板配置）
-		eventModel.Status = "success"
-		eventModel.UpdatedAt = time.Now()
-		h.eventRepo.Save(&eventModel)
+	finished, err := h.eventRepo.Finish(eventModel.ID, h.owner, updates)
+	if err != nil {
+		log.Printf("failed to update event %q: %v", eventModel.ID, err)
 		return
 	}
+	if !finished {
+		log.Printf("lease on event %q expired before delivery finished", eventModel.ID)
+	}
+}
+
+// push 发布事件到消息总线,并推送到任务所用模板版本配置的 Webhook 和匹配的全局订阅
+// 之前已投递成功的目标不再重复投递;没有投递目标时视为投递成功。
+// released 表示事件已发布到消息总线(未配置时恒为 true),同一任务的后续事件不必再等待本事件
+func (h *dbEventHandler) push(eventModel *model.EventModel) (released bool, err error) {
+	// 1. 反序列化事件
+	var evt event.Event
+	if err := json.Unmarshal(eventModel.Data, &evt); err != nil {
+		return false, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	if evt.Task == nil {
+		return true, nil
+	}
 
-	template, err := h.templateMgr.Get(templateID, 0)
+	// 2. 获取投递目标和已投递成功的目标
+	targets, err := h.targets(eventModel, &evt)
 	if err != nil {
-		// 如果找不到模板，记录错误但不重试
-		fmt.Printf("failed to get template: %v\n", err)
-		// 标记为成功（无需推送，因为找不到模板）
-		eventModel.Status = "success"
-		eventModel.UpdatedAt = time.Now()
-		h.eventRepo.Save(&eventModel)
-		return
+		return false, err
+	}
+	succeeded, err := h.eventRepo.SucceededTargets(eventModel.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get event deliveries: %w", err)
 	}
 
-	// 3. 如果没有 Webhook 配置，直接返回
-	if template.Config == nil || len(template.Config.Webhooks) == 0 {
-		// 没有 Webhook 配置，标记为成功（无需推送）
-		eventModel.Status = "success"
-		eventModel.UpdatedAt = time.Now()
-		h.eventRepo.Save(&eventModel)
-		return
+	// 3. 发布到消息总线并推送到尚未成功的 Webhook,记录每个目标的投递结果,失败的目标稍后重试
+	released = true
+	var errs []error
+	if h.opts.Publisher != nil && !succeeded[h.busTarget()] {
+		if err := h.publish(eventModel); err != nil {
+			released = false
+			errs = append(errs, fmt.Errorf("%s: %w", h.opts.Publisher.Name(), err))
+		}
 	}
+	for _, target := range targets {
+		if succeeded[target.key] {
+			continue
+		}
+		if err := h.deliverTo(eventModel, target, &evt); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", target.webhook.URL, err))
+		}
+	}
+	return released, errors.Join(errs...)
+}
+
+// busTarget 消息总线的投递目标标识
+func (h *dbEventHandler) busTarget() string {
+	return "bus:" + h.opts.Publisher.Name()
+}
+
+// webhookTarget 事件投递目标
+type webhookTarget struct {
+	key         string // 目标标识,记录在投递记录中,重试时据此跳过已成功的目标
+	webhook     *template.WebhookConfig
+	secretOwner string                  // HMAC 签名密钥所属的模板 ID 或订阅 ID
+	format      string                  // 请求体格式,为空时为事件 JSON
+	payload     *WebhookPayloadTemplate // 请求模板(仅模板 Webhook),为空时发送事件 JSON
+	tpl         *template.Template      // 事件所属任务的模板版本,用于渲染请求模板
+}
 
-	// 4. 尝试推送到所有 Webhook（使用重试机制）
-	maxRetries := 3
-	backoff := time.Second
+// targets 获取事件的投递目标: 任务固定的模板版本配置的 Webhook,以及匹配的全局订阅
+func (h *dbEventHandler) targets(eventModel *model.EventModel, evt *event.Event) ([]*webhookTarget, error) {
+	var targets []*webhookTarget
 
-	for i := 0; i < maxRetries; i++ {
-		success := true
-		for _, webhook := range template.Config.Webhooks {
-			if err := h.sendWebhookRequest(webhook, evt); err != nil {
-				success = false
-				fmt.Printf("failed to send webhook request: %v\n", err)
+	// 1. 模板 Webhook(使用任务固定的模板版本的配置)
+	if evt.Task.TemplateID != "" {
+		version := 0
+		var taskModel model.TaskModel
+		if err := h.db.Select("id", "template_version").Where("id = ?", evt.Task.ID).First(&taskModel).Error; err == nil {
+			version = taskModel.TemplateVersion
+		}
+		tpl, err := h.templateMgr.Get(evt.Task.TemplateID, version)
+		if err != nil {
+			// 找不到模板时无需推送模板 Webhook
+			log.Printf("failed to get template %q for event %q: %v", evt.Task.TemplateID, eventModel.ID, err)
+		} else if tpl.Config != nil {
+			// 请求模板保存在模板原始配置中,与 webhooks 按顺序对应
+			payloads, err := loadWebhookTemplates(h.db, tpl.ID, tpl.Version)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load webhook templates: %w", err)
+			}
+			for i, webhook := range tpl.Config.Webhooks {
+				if webhook == nil {
+					continue
+				}
+				target := &webhookTarget{
+					key:         fmt.Sprintf("template:%s:%d", tpl.ID, i),
+					webhook:     webhook,
+					secretOwner: tpl.ID,
+					tpl:         tpl,
+				}
+				if i < len(payloads) && !payloads[i].isEmpty() {
+					target.payload = payloads[i]
+				}
+				targets = append(targets, target)
 			}
 		}
+	}
 
-		if success {
-			// 推送成功，更新事件状态
-			eventModel.Status = "success"
-			eventModel.UpdatedAt = time.Now()
-			h.eventRepo.Save(&eventModel)
-			return
+	// 2. 全局订阅
+	subscriptions, err := h.subscriptionRepo.FindEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		if subscriptionMatches(subscription, evt) {
+			targets = append(targets, subscriptionTarget(subscription))
 		}
+	}
+	return targets, nil
+}
+
+// publish 发布事件到消息总线并记录投递结果
+// 排序键为任务 ID;outbox 保证同一任务的事件按产生顺序逐个投递,因此消息总线上同一任务的事件有序
+func (h *dbEventHandler) publish(eventModel *model.EventModel) error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.opts.Lease)
+	defer cancel()
+
+	start := time.Now()
+	err := h.opts.Publisher.Publish(ctx, &BusMessage{
+		ID:   eventModel.ID,
+		Key:  eventModel.TaskID,
+		Type: eventModel.Type,
+		Data: eventModel.Data,
+		Time: eventModel.CreatedAt,
+	})
+
+	delivery := &model.EventDeliveryModel{
+		ID:        uuid.New().String(),
+		EventID:   eventModel.ID,
+		Endpoint:  h.opts.Publisher.Name(),
+		Target:    h.busTarget(),
+		Attempt:   eventModel.RetryCount + 1,
+		Success:   err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CreatedAt: start,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if saveErr := h.eventRepo.SaveDelivery(delivery); saveErr != nil {
+		log.Printf("failed to save delivery of event %q: %v", eventModel.ID, saveErr)
+	}
+	return err
+}
 
-		// 推送失败，增加重试计数
-		eventModel.RetryCount++
-		eventModel.UpdatedAt = time.Now()
-		h.eventRepo.Save(&eventModel)
+// deliverTo 向单个 Webhook 端点投递事件并记录投递结果
+func (h *dbEventHandler) deliverTo(eventModel *model.EventModel, target *webhookTarget, evt *event.Event) error {
+	start := time.Now()
+	resp, err := h.sendWebhookRequest(target, evt, eventModel.ID)
 
-		// 如果还有重试机会，等待后重试
-		if i < maxRetries-1 {
-			time.Sleep(backoff)
-			backoff *= 2 // 指数退避
-		}
+	delivery := &model.EventDeliveryModel{
+		ID:        uuid.New().String(),
+		EventID:   eventModel.ID,
+		Endpoint:  target.webhook.URL,
+		Target:    target.key,
+		Attempt:   eventModel.RetryCount + 1,
+		Success:   err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CreatedAt: start,
+	}
+	if resp != nil {
+		delivery.StatusCode = resp.statusCode
+		delivery.ResponseBody = resp.body
 	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if saveErr := h.eventRepo.SaveDelivery(delivery); saveErr != nil {
+		log.Printf("failed to save delivery of event %q: %v", eventModel.ID, saveErr)
+	}
+	return err
+}
 
-	// 所有重试都失败，更新事件状态为失败
-	eventModel.Status = "failed"
-	eventModel.UpdatedAt = time.Now()
-	h.eventRepo.Save(&eventModel)
+// maxResponseBodySize 投递记录中保存的响应体最大长度
+const maxResponseBodySize = 2048
+
+// webhookResponse Webhook 响应摘要
+type webhookResponse struct {
+	statusCode int
+	body       string // 响应体(截断到 maxResponseBodySize)
 }
 
 // sendWebhookRequest 发送 Webhook 请求
-func (h *dbEventHandler) sendWebhookRequest(webhook *template.WebhookConfig, evt *event.Event) error {
-	// 1. 序列化事件数据
+// deliveryID 为投递 ID(事件 ID),同一事件的重试使用相同的投递 ID;
+// 收到响应时返回响应摘要(即使状态码表示失败)
+func (h *dbEventHandler) sendWebhookRequest(target *webhookTarget, evt *event.Event, deliveryID string) (*webhookResponse, error) {
+	webhook := target.webhook
+
+	// 1. 序列化事件数据,按目标的请求体格式编码,配置了请求模板时使用渲染结果
 	eventData, err := json.Marshal(evt)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+	body := eventData
+	contentType := "application/json"
+	var ce *CloudEvent
+	switch target.format {
+	case PayloadFormatCloudEventsStructured:
+		ce = newCloudEvent(deliveryID, h.opts.Source, evt, eventData)
+		if body, err = json.Marshal(ce); err != nil {
+			return nil, fmt.Errorf("failed to marshal cloud event: %w", err)
+		}
+		contentType = "application/cloudevents+json"
+	case PayloadFormatCloudEventsBinary:
+		ce = newCloudEvent(deliveryID, h.opts.Source, evt, eventData)
+	}
+	var rendered *RenderedWebhook
+	if target.payload != nil {
+		data := newWebhookTemplateData(h.db, evt, deliveryID, target.tpl.Name, target.tpl.Version)
+		if rendered, err = target.payload.render(data); err != nil {
+			return nil, err
+		}
+		if target.payload.BodyTemplate != "" {
+			body = []byte(rendered.Body)
+		}
 	}
 
 	// 2. 创建 HTTP 请求
@@ -198,16 +477,25 @@ func (h *dbEventHandler) sendWebhookRequest(webhook *template.WebhookConfig, evt
 		method = "POST"
 	}
 
-	req, err := http.NewRequest(method, webhook.URL, bytes.NewBuffer(eventData))
+	req, err := http.NewRequest(method, webhook.URL, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// 3. 设置请求头
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	for key, value := range webhook.Headers {
 		req.Header.Set(key, value)
 	}
+	if rendered != nil {
+		for key, value := range rendered.Headers {
+			req.Header.Set(key, value)
+		}
+	}
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	if target.format == PayloadFormatCloudEventsBinary {
+		ce.setBinaryHeaders(req.Header)
+	}
 
 	// 4. 设置认证信息
 	if webhook.Auth != nil {
@@ -218,26 +506,61 @@ func (h *dbEventHandler) sendWebhookRequest(webhook *template.WebhookConfig, evt
 			req.SetBasicAuth(webhook.Auth.Key, webhook.Auth.Token)
 		case "header":
 			req.Header.Set(webhook.Auth.Key, webhook.Auth.Token)
+		case webhookAuthHMAC:
+			if err := h.sign(req, target.secretOwner, webhook.URL, body); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	// 5. 发送请求
 	resp, err := h.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+	// 截断可能切开多字节字符,去掉不完整的 UTF-8 序列
+	result := &webhookResponse{statusCode: resp.StatusCode, body: strings.ToValidUTF8(string(respBody), "")}
+
 	// 6. 检查响应状态码
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook returned status code: %d", resp.StatusCode)
+		return result, fmt.Errorf("webhook returned status code: %d", resp.StatusCode)
+	}
+
+	return result, nil
+}
+
+// sign 为 Webhook 请求添加签名
+// 签名内容为 "时间戳.请求体",接收方应校验签名并拒绝时间戳过旧的请求以防重放
+func (h *dbEventHandler) sign(req *http.Request, secretOwner string, endpoint string, body []byte) error {
+	secrets, err := h.opts.Signer.validSecrets(h.db, secretOwner, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
 	}
 
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(HeaderSignatureTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, signPayload(secrets, timestamp, body))
 	return nil
 }
 
+// CloudEvent 获取已保存事件的 CloudEvents 表示(与结构化格式的 Webhook 请求体相同)
+func (h *dbEventHandler) CloudEvent(eventModel *model.EventModel) (*CloudEvent, error) {
+	var evt event.Event
+	if err := json.Unmarshal(eventModel.Data, &evt); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	return newCloudEvent(eventModel.ID, h.opts.Source, &evt, eventModel.Data), nil
+}
+
 // Stop 停止事件处理器
 func (h *dbEventHandler) Stop() {
 	close(h.stop)
+	if h.opts.Publisher != nil {
+		if err := h.opts.Publisher.Close(); err != nil {
+			log.Printf("failed to close event publisher: %v", err)
+		}
+	}
 }
-
@@ -0,0 +1,232 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/event"
+	"gorm.io/gorm"
+)
+
+// fakePublisher 按 fail 决定发布是否失败的消息总线发布器
+type fakePublisher struct {
+	mu        sync.Mutex
+	fail      bool
+	published []string
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, msg *BusMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail {
+		return errors.New("bus unavailable")
+	}
+	p.published = append(p.published, msg.ID)
+	return nil
+}
+
+func (p *fakePublisher) Name() string { return "fake" }
+
+func (p *fakePublisher) Close() error { return nil }
+
+// newTestEventHandler 创建不启动投递 worker 的事件处理器,测试中手动领取和投递
+func newTestEventHandler(db *gorm.DB, opts EventDeliveryOptions) *dbEventHandler {
+	return &dbEventHandler{
+		db:               db,
+		eventRepo:        repository.NewEventRepository(db),
+		templateMgr:      NewTemplateManager(db),
+		subscriptionRepo: repository.NewWebhookSubscriptionRepository(db),
+		httpClient:       &http.Client{Timeout: 5 * time.Second},
+		opts:             opts.withDefaults(),
+		owner:            uuid.New().String(),
+		wake:             make(chan struct{}, 1),
+		stop:             make(chan struct{}),
+	}
+}
+
+// saveTestEvent 保存任务的事件并返回事件 ID
+func saveTestEvent(t *testing.T, h *dbEventHandler, taskID string) string {
+	t.Helper()
+	mustNoError(t, h.Handle(&event.Event{
+		Type: EventTaskCreated,
+		Time: time.Now(),
+		Task: &event.TaskInfo{ID: taskID},
+	}))
+	var eventModel model.EventModel
+	mustNoError(t, h.db.Where("task_id = ?", taskID).Order("created_at DESC").First(&eventModel).Error)
+	return eventModel.ID
+}
+
+// claim 以处理器的身份领取 at 时刻可投递的事件,没有时返回空字符串
+func claim(t *testing.T, h *dbEventHandler, at time.Time) *model.EventModel {
+	t.Helper()
+	eventModel, err := h.eventRepo.ClaimNext(h.owner, at, at.Add(h.opts.Lease))
+	mustNoError(t, err)
+	return eventModel
+}
+
+func claimedID(eventModel *model.EventModel) string {
+	if eventModel == nil {
+		return ""
+	}
+	return eventModel.ID
+}
+
+func TestEventDeliveryBackoff(t *testing.T) {
+	opts := EventDeliveryOptions{BackoffBase: 10 * time.Second, BackoffMax: time.Minute}.withDefaults()
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, delay := range want {
+		if got := opts.backoff(i + 1); got != delay {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+	if got := opts.backoff(100); got != time.Minute {
+		t.Errorf("backoff(100) = %s, want %s", got, time.Minute)
+	}
+}
+
+func TestOutboxClaimsEventsInTaskOrder(t *testing.T) {
+	db := newTestDB(t)
+	h := newTestEventHandler(db, EventDeliveryOptions{Publisher: &fakePublisher{}})
+
+	first := saveTestEvent(t, h, "task-a")
+	second := saveTestEvent(t, h, "task-a")
+	other := saveTestEvent(t, h, "task-b")
+
+	now := time.Now().Add(time.Second)
+	if got := claimedID(claim(t, h, now)); got != first {
+		t.Fatalf("claimed %q, want the first event of task-a", got)
+	}
+	// task-a 的第一个事件尚未投递,第二个事件需要等待
+	if got := claimedID(claim(t, h, now)); got != other {
+		t.Fatalf("claimed %q, want the event of task-b", got)
+	}
+	if got := claim(t, h, now); got != nil {
+		t.Fatalf("claimed %q while task-a's first event is in flight", got.ID)
+	}
+
+	firstModel, err := h.eventRepo.FindByID(first)
+	mustNoError(t, err)
+	h.deliver(firstModel)
+	if got := claimedID(claim(t, h, now)); got != second {
+		t.Fatalf("claimed %q, want the second event of task-a", got)
+	}
+}
+
+func TestOutboxLeasePreventsDoubleClaim(t *testing.T) {
+	db := newTestDB(t)
+	h := newTestEventHandler(db, EventDeliveryOptions{Lease: time.Minute})
+	other := newTestEventHandler(db, EventDeliveryOptions{Lease: time.Minute})
+
+	id := saveTestEvent(t, h, "task-a")
+	now := time.Now().Add(time.Second)
+	if got := claimedID(claim(t, h, now)); got != id {
+		t.Fatalf("claimed %q, want %q", got, id)
+	}
+	if got := claim(t, other, now); got != nil {
+		t.Fatalf("another instance claimed leased event %q", got.ID)
+	}
+	// 租约过期后其他实例可以重新领取,原持有者不能再写入投递结果
+	expired := now.Add(2 * time.Minute)
+	if got := claimedID(claim(t, other, expired)); got != id {
+		t.Fatalf("claimed %q after lease expiry, want %q", got, id)
+	}
+	finished, err := h.eventRepo.Finish(id, h.owner, map[string]interface{}{"status": model.EventStatusSuccess})
+	mustNoError(t, err)
+	if finished {
+		t.Fatal("expired lease holder finished the event")
+	}
+}
+
+func TestOutboxRetriesWithBackoffAndDeadLetters(t *testing.T) {
+	db := newTestDB(t)
+	publisher := &fakePublisher{fail: true}
+	h := newTestEventHandler(db, EventDeliveryOptions{
+		Publisher:   publisher,
+		MaxAttempts: 2,
+		BackoffBase: time.Hour,
+		BackoffMax:  time.Hour,
+	})
+
+	first := saveTestEvent(t, h, "task-a")
+	second := saveTestEvent(t, h, "task-a")
+
+	eventModel := claim(t, h, time.Now().Add(time.Second))
+	if claimedID(eventModel) != first {
+		t.Fatalf("claimed %q, want %q", claimedID(eventModel), first)
+	}
+	h.deliver(eventModel)
+
+	failed, err := h.eventRepo.FindByID(first)
+	mustNoError(t, err)
+	if failed.Status != model.EventStatusFailed || failed.RetryCount != 1 || failed.Released {
+		t.Fatalf("after first failure: status=%s retry=%d released=%v", failed.Status, failed.RetryCount, failed.Released)
+	}
+	if failed.NextAttemptAt == nil || failed.NextAttemptAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("next attempt at %v, want about an hour from now", failed.NextAttemptAt)
+	}
+
+	// 退避期间不重试,同一任务的后续事件也不投递
+	if got := claim(t, h, time.Now().Add(time.Minute)); got != nil {
+		t.Fatalf("claimed %q during backoff", got.ID)
+	}
+
+	later := time.Now().Add(2 * time.Hour)
+	retry := claim(t, h, later)
+	if claimedID(retry) != first {
+		t.Fatalf("claimed %q after backoff, want %q", claimedID(retry), first)
+	}
+	h.deliver(retry)
+
+	dead, err := h.eventRepo.FindByID(first)
+	mustNoError(t, err)
+	if dead.Status != model.EventStatusDead || dead.NextAttemptAt != nil {
+		t.Fatalf("after last attempt: status=%s next=%v", dead.Status, dead.NextAttemptAt)
+	}
+
+	// 死信事件不再阻塞同一任务的后续事件
+	publisher.fail = false
+	next := claim(t, h, later)
+	if claimedID(next) != second {
+		t.Fatalf("claimed %q, want %q", claimedID(next), second)
+	}
+	h.deliver(next)
+	delivered, err := h.eventRepo.FindByID(second)
+	mustNoError(t, err)
+	if delivered.Status != model.EventStatusSuccess || !delivered.Released {
+		t.Fatalf("second event: status=%s released=%v", delivered.Status, delivered.Released)
+	}
+}
+
+func TestTaskEventsAreWrittenToOutboxInTransaction(t *testing.T) {
+	db := newTestDB(t)
+	handler := newTestEventHandler(db, EventDeliveryOptions{})
+	templates := NewTemplateManager(db).(*DBTemplateManager)
+	env := &testEnv{db: db, templates: templates, tasks: NewTaskManager(db, templates, nil, handler).(*DBTaskManager)}
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+
+	tsk := env.startTask(t, "single", `{}`)
+	var eventTypes []string
+	mustNoError(t, db.Model(&model.EventModel{}).Where("task_id = ?", tsk.ID).Order("created_at").Pluck("type", &eventTypes).Error)
+	if len(eventTypes) == 0 || eventTypes[0] != string(EventTaskCreated) {
+		t.Fatalf("outbox events = %v, want task_created first", eventTypes)
+	}
+
+	// 失败的变更不写入事件
+	count := len(eventTypes)
+	if err := env.tasks.Approve(tsk.ID, "review", "eve", ""); err == nil {
+		t.Fatal("expected approval by a non-approver to fail")
+	}
+	var after int64
+	mustNoError(t, db.Model(&model.EventModel{}).Where("task_id = ?", tsk.ID).Count(&after).Error)
+	if int(after) != count {
+		t.Fatalf("outbox has %d events after a failed mutation, want %d", after, count)
+	}
+}
@@ -0,0 +1,211 @@
+package integration
+
+import (
+	"log"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// 任务事件类型
+const (
+	// EventTaskCreated 任务创建
+	EventTaskCreated event.EventType = "task_created"
+	// EventTaskSubmitted 任务提交
+	EventTaskSubmitted event.EventType = "task_submitted"
+	// EventNodeActivated 节点激活(进入待审批/待处理状态)
+	EventNodeActivated event.EventType = "node_activated"
+	// EventTaskApproved 审批人同意
+	EventTaskApproved event.EventType = "task_approved"
+	// EventTaskRejected 审批人拒绝
+	EventTaskRejected event.EventType = "task_rejected"
+	// EventTaskTransferred 审批转交
+	EventTaskTransferred event.EventType = "task_transferred"
+	// EventApproverAdded 加签
+	EventApproverAdded event.EventType = "approver_added"
+	// EventApproverRemoved 减签
+	EventApproverRemoved event.EventType = "approver_removed"
+	// EventApproverReplaced 替换审批人
+	EventApproverReplaced event.EventType = "approver_replaced"
+	// EventTaskDelegated 审批人处于委托期间,待审批节点转给代理人
+	EventTaskDelegated event.EventType = "task_delegated"
+	// EventTaskReturned 审批人将任务退回发起人修改
+	EventTaskReturned event.EventType = "task_returned"
+	// EventTaskResubmitted 发起人修改后重新提交任务,开始新一轮审批
+	EventTaskResubmitted event.EventType = "task_resubmitted"
+	// EventTaskSentBack 节点被驳回到之前的审批节点重新审批,节点为驳回目标
+	EventTaskSentBack event.EventType = "task_sent_back"
+	// EventServiceCompleted 服务节点调用成功,响应已写入节点输出
+	EventServiceCompleted event.EventType = "service_completed"
+	// EventServiceFailed 服务节点重试用尽后调用失败,评论为最后一次调用的错误
+	EventServiceFailed event.EventType = "service_failed"
+	// EventTaskNotified 任务抄送给用户(创建时指定的抄送人或通知节点的通知对象),操作人为被通知的用户,操作为 cc/notify
+	EventTaskNotified event.EventType = "task_notified"
+	// EventTimerFired 定时节点到期,节点完成后继续流转
+	EventTimerFired event.EventType = "timer_fired"
+	// EventSubprocessStarted 子流程节点创建并提交了子任务,评论为子任务 ID
+	EventSubprocessStarted event.EventType = "subprocess_started"
+	// EventSubprocessCompleted 子流程节点的子任务结束,操作名称为子任务的最终状态,评论为子任务 ID
+	EventSubprocessCompleted event.EventType = "subprocess_completed"
+	// EventTaskPaused 任务暂停
+	EventTaskPaused event.EventType = "task_paused"
+	// EventTaskResumed 任务恢复
+	EventTaskResumed event.EventType = "task_resumed"
+	// EventTaskRolledBack 任务回退到指定节点
+	EventTaskRolledBack event.EventType = "task_rolled_back"
+	// EventTaskTimeout 任务超时
+	EventTaskTimeout event.EventType = "task_timeout"
+	// EventTaskReminder 提醒审批人处理待审批节点,操作人为被提醒的审批人
+	EventTaskReminder event.EventType = "task_reminder"
+	// EventNodeTimeout 审批节点超时,操作名称为执行的超时动作(mark/auto_approve/auto_reject/escalate/reassign)
+	EventNodeTimeout event.EventType = "node_timeout"
+	// EventTaskCancelled 任务取消
+	EventTaskCancelled event.EventType = "task_cancelled"
+	// EventTaskWithdrawn 任务撤回
+	EventTaskWithdrawn event.EventType = "task_withdrawn"
+	// EventTaskCompleted 任务结束(审批通过或被驳回),结果见任务状态
+	EventTaskCompleted event.EventType = "task_completed"
+)
+
+// taskEventTypes 所有任务事件类型
+var taskEventTypes = map[event.EventType]bool{
+	EventTaskCreated:         true,
+	EventTaskSubmitted:       true,
+	EventNodeActivated:       true,
+	EventTaskApproved:        true,
+	EventTaskRejected:        true,
+	EventTaskTransferred:     true,
+	EventApproverAdded:       true,
+	EventApproverRemoved:     true,
+	EventApproverReplaced:    true,
+	EventTaskDelegated:       true,
+	EventTaskReturned:        true,
+	EventTaskResubmitted:     true,
+	EventTaskSentBack:        true,
+	EventServiceCompleted:    true,
+	EventServiceFailed:       true,
+	EventTimerFired:          true,
+	EventTaskNotified:        true,
+	EventSubprocessStarted:   true,
+	EventSubprocessCompleted: true,
+	EventTaskPaused:          true,
+	EventTaskResumed:         true,
+	EventTaskRolledBack:      true,
+	EventTaskTimeout:         true,
+	EventNodeTimeout:         true,
+	EventTaskReminder:        true,
+	EventTaskCancelled:       true,
+	EventTaskWithdrawn:       true,
+	EventTaskCompleted:       true,
+}
+
+// IsTaskEventType 判断是否为已知的任务事件类型
+func IsTaskEventType(eventType string) bool {
+	return taskEventTypes[event.EventType(eventType)]
+}
+
+// systemOperator 没有操作人时(如定时任务触发)使用的操作人
+const systemOperator = "system"
+
+// WithOperator 返回记录操作人的任务管理器副本
+// 副本产生的事件以 operator 作为操作人,未设置时为 system
+func (m *DBTaskManager) WithOperator(operator string) *DBTaskManager {
+	mgr := *m
+	mgr.operator = operator
+	return &mgr
+}
+
+// actor 获取当前操作人
+func (m *dbTaskManager) actor() string {
+	if m.operator == "" {
+		return systemOperator
+	}
+	return m.operator
+}
+
+// emit 产生任务事件
+// 事务内产生的事件随事务写入 outbox(或在提交后分发),事务回滚时丢弃
+// operator 为操作人,action 为操作名称,comment 为审批意见或操作原因
+func (m *dbTaskManager) emit(eventType event.EventType, tsk *task.Task, nodeID string, operator string, action string, comment string) {
+	if m.eventHandler == nil {
+		return
+	}
+
+	evt := &event.Event{
+		Type: eventType,
+		Time: time.Now(),
+		Task: &event.TaskInfo{
+			ID:         tsk.ID,
+			TemplateID: tsk.TemplateID,
+			BusinessID: tsk.BusinessID,
+			State:      string(tsk.State),
+		},
+	}
+	if nodeID != "" {
+		evt.Node = m.nodeInfo(tsk, nodeID)
+	}
+	if operator != "" {
+		evt.Approval = &event.ApprovalInfo{
+			NodeID:   nodeID,
+			Approver: operator,
+			Result:   action,
+			Comment:  comment,
+		}
+	}
+
+	if m.pendingEvents != nil {
+		*m.pendingEvents = append(*m.pendingEvents, evt)
+		return
+	}
+	m.dispatch([]*event.Event{evt})
+}
+
+// emitCompleted 任务进入审批通过或驳回的终态时产生任务结束事件
+func (m *dbTaskManager) emitCompleted(tsk *task.Task, nodeID string, operator string) {
+	if tsk.State == types.TaskStateApproved || tsk.State == types.TaskStateRejected {
+		m.emit(EventTaskCompleted, tsk, nodeID, operator, string(tsk.State), "")
+	}
+}
+
+// nodeInfo 获取事件中的节点信息(节点名称和类型取自任务固定的模板版本)
+func (m *dbTaskManager) nodeInfo(tsk *task.Task, nodeID string) *event.NodeInfo {
+	info := &event.NodeInfo{ID: nodeID}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return info
+	}
+	if node, exists := flow.Nodes[nodeID]; exists && node != nil {
+		info.Name = node.Name
+		info.Type = node.Type
+	}
+	return info
+}
+
+// persistEvents 在事务内将事件写入 outbox(事件处理器支持 outbox 时)
+func (m *dbTaskManager) persistEvents(events []*event.Event) error {
+	outbox, ok := m.eventHandler.(outboxEventHandler)
+	if !ok {
+		return nil
+	}
+	for _, evt := range events {
+		if err := outbox.HandleTx(m.db, evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatch 将事件交给事件处理器
+// 事件处理失败不影响已完成的任务变更,只记录日志
+func (m *dbTaskManager) dispatch(events []*event.Event) {
+	if m.eventHandler == nil {
+		return
+	}
+	for _, evt := range events {
+		if err := m.eventHandler.Handle(evt); err != nil {
+			log.Printf("failed to handle event: type=%q, task=%q, error=%v", evt.Type, evt.Task.ID, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// eventsDuring 返回 fn 执行期间产生的事件
+func (e *testEnv) eventsDuring(t *testing.T, fn func() error) []*event.Event {
+	t.Helper()
+	e.events.mu.Lock()
+	before := len(e.events.events)
+	e.events.mu.Unlock()
+	mustNoError(t, fn())
+	e.events.mu.Lock()
+	defer e.events.mu.Unlock()
+	return append([]*event.Event{}, e.events.events[before:]...)
+}
+
+// typesOf 返回事件的类型
+func typesOf(events []*event.Event) []string {
+	names := make([]string, 0, len(events))
+	for _, evt := range events {
+		names = append(names, string(evt.Type))
+	}
+	return names
+}
+
+func TestTransitionsEmitTypedEvents(t *testing.T) {
+	env := newTestEnv(t)
+	env.createTemplate(t, "steps",
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("first", []string{"ann"}, `"permissions":{"allow_transfer":true,"allow_add_approver":true,"allow_remove_approver":true}`)+`,
+		`+approvalNode("second", []string{"bob"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"first"},{"from":"first","to":"second"},{"from":"second","to":"end"}]`)
+	tasks := env.tasks.WithOperator("ini")
+	rejected, cancelled, withdrawn := env.startTask(t, "steps", `{}`).ID, env.startTask(t, "steps", `{}`).ID, env.startTask(t, "steps", `{}`).ID
+	rolledBack := env.startTask(t, "steps", `{}`).ID
+	mustNoError(t, env.tasks.Approve(rolledBack, "first", "ann", ""))
+
+	var taskID string
+	steps := []struct {
+		name string
+		run  func() error
+		want []event.EventType
+	}{
+		{"create", func() error {
+			tsk, err := tasks.Create("steps", "biz", nil)
+			if err == nil {
+				taskID = tsk.ID
+			}
+			return err
+		}, []event.EventType{EventTaskCreated}},
+		{"submit", func() error { return tasks.Submit(taskID) }, []event.EventType{EventTaskSubmitted, EventNodeActivated}},
+		{"transfer", func() error { return tasks.Transfer(taskID, "first", "ann", "amy", "on leave") }, []event.EventType{EventTaskTransferred}},
+		{"add approver", func() error { return tasks.AddApprover(taskID, "first", "cat", "") }, []event.EventType{EventApproverAdded}},
+		{"remove approver", func() error { return tasks.RemoveApprover(taskID, "first", "cat", "") }, []event.EventType{EventApproverRemoved}},
+		{"replace approver", func() error { return tasks.ReplaceApprover(taskID, "first", "amy", "ann", "") }, []event.EventType{EventApproverReplaced}},
+		{"pause", func() error { return tasks.Pause(taskID, "wait") }, []event.EventType{EventTaskPaused}},
+		{"resume", func() error { return tasks.Resume(taskID, "go") }, []event.EventType{EventTaskResumed}},
+		// 后续节点在节点完成时激活,先于审批事件产生
+		{"approve", func() error { return tasks.Approve(taskID, "first", "ann", "") }, []event.EventType{EventNodeActivated, EventTaskApproved}},
+		{"approve last", func() error { return tasks.Approve(taskID, "second", "bob", "") }, []event.EventType{EventTaskApproved, EventTaskCompleted}},
+		{"rollback", func() error { return tasks.RollbackToNode(rolledBack, "first", "redo") }, []event.EventType{EventTaskRolledBack, EventNodeActivated}},
+		{"reject", func() error { return tasks.Reject(rejected, "first", "ann", "no") }, []event.EventType{EventTaskRejected, EventTaskCompleted}},
+		{"cancel", func() error { return tasks.Cancel(cancelled, "stop") }, []event.EventType{EventTaskCancelled}},
+		{"withdraw", func() error { return tasks.Withdraw(withdrawn, "oops") }, []event.EventType{EventTaskWithdrawn}},
+	}
+	for _, step := range steps {
+		events := env.eventsDuring(t, step.run)
+		if len(events) != len(step.want) {
+			t.Errorf("%s: events = %v, want %v", step.name, typesOf(events), step.want)
+			continue
+		}
+		for i, evt := range events {
+			if evt.Type != step.want[i] || !IsTaskEventType(string(evt.Type)) {
+				t.Errorf("%s: events = %v, want %v", step.name, typesOf(events), step.want)
+				break
+			}
+		}
+	}
+
+	// 事件携带操作人和转换后的任务状态
+	for _, evt := range env.events.events {
+		if evt.Type == EventTaskCancelled && (evt.Approval == nil || evt.Approval.Approver != "ini" || evt.Task.State != string(types.TaskStateCancelled)) {
+			t.Fatalf("cancel event: task=%+v approval=%+v", evt.Task, evt.Approval)
+		}
+		if evt.Type == EventTaskCompleted && evt.Task.ID == taskID && evt.Task.State != string(types.TaskStateApproved) {
+			t.Fatalf("completed event state = %s, want %s", evt.Task.State, types.TaskStateApproved)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidExpression 条件表达式语法错误
+var ErrInvalidExpression = errors.New("invalid condition expression")
+
+// 条件表达式求值器
+// 支持的语法:
+//   - 字面量: 数字、"字符串"/'字符串'、true、false、null、[列表]
+//   - 变量路径: params.amount、params.applicant.dept、outputs.node-2.result
+//   - 比较运算: == != > >= < <=,以及 in (成员判断,如 params.dept in ["finance","legal"])
+//   - 逻辑运算: && || !,以及括号分组
+
+// exprNode 表达式语法树节点
+type exprNode interface {
+	eval(ctx *exprContext) (interface{}, error)
+}
+
+// exprContext 表达式求值上下文
+type exprContext struct {
+	params  interface{}
+	outputs map[string]interface{}
+}
+
+// newExprContext 基于任务参数和节点输出构建求值上下文
+func newExprContext(params json.RawMessage, nodeOutputs map[string]json.RawMessage) (*exprContext, error) {
+	ctx := &exprContext{outputs: make(map[string]interface{})}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &ctx.params); err != nil {
+			return nil, fmt.Errorf("failed to decode task params: %w", err)
+		}
+	}
+	for nodeID, raw := range nodeOutputs {
+		if len(raw) == 0 {
+			continue
+		}
+		var output interface{}
+		if err := json.Unmarshal(raw, &output); err != nil {
+			return nil, fmt.Errorf("failed to decode output of node %q: %w", nodeID, err)
+		}
+		ctx.outputs[nodeID] = output
+	}
+	return ctx, nil
+}
+
+// ValidateExpression 校验条件表达式语法(模板保存时调用)
+func ValidateExpression(expr string) error {
+	_, err := parseExpression(expr)
+	return err
+}
+
+// evaluateCondition 对条件表达式求值,结果必须为布尔值
+func evaluateCondition(expr string, ctx *exprContext) (bool, error) {
+	ast, err := parseExpression(expr)
+	if err != nil {
+		return false, err
+	}
+	value, err := ast.eval(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate %q: %w", expr, err)
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q does not evaluate to a boolean", expr)
+	}
+	return result, nil
+}
+
+// parseExpression 解析条件表达式
+func parseExpression(expr string) (exprNode, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q in %q", ErrInvalidExpression, p.tokens[p.pos].text, expr)
+	}
+	return node, nil
+}
+
+// ---- 词法分析 ----
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeExpression(expr string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			quote := r
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != quote; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				sb.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string in %q", ErrInvalidExpression, expr)
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: sb.String()})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) && expectsOperand(tokens)):
+			j := i + 1
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: string(runes[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			// 路径段允许包含 - 和数字,以支持 outputs.node-2.result 这类节点 ID
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_' || runes[j] == '-' || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: string(runes[i:j])})
+			i = j
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch string(runes[i : i+2]) {
+				case "==", "!=", ">=", "<=", "&&", "||":
+					op = string(runes[i : i+2])
+				}
+			}
+			if op == "" {
+				switch r {
+				case '>', '<', '!', '(', ')', '[', ']', ',':
+					op = string(r)
+				default:
+					return nil, fmt.Errorf("%w: unexpected character %q in %q", ErrInvalidExpression, r, expr)
+				}
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// expectsOperand 判断下一个 token 是否应为操作数(用于区分负数)
+func expectsOperand(tokens []exprToken) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind == tokenOperator && last.text != ")" && last.text != "]"
+}
+
+// ---- 语法分析 ----
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *exprParser) acceptOperator(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok == nil || tok.kind != tokenOperator && !(tok.kind == tokenIdent && tok.text == "in") {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOperator("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.acceptOperator("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptOperator("==", "!=", ">=", "<=", ">", "<", "in"); ok {
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrInvalidExpression)
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q", ErrInvalidExpression, tok.text)
+		}
+		return &literalNode{value: value}, nil
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null", "nil":
+			return &literalNode{value: nil}, nil
+		}
+		segments := strings.Split(tok.text, ".")
+		if segments[0] != "params" && segments[0] != "outputs" {
+			return nil, fmt.Errorf("%w: unknown variable %q, expected params.* or outputs.*", ErrInvalidExpression, tok.text)
+		}
+		for _, segment := range segments {
+			if segment == "" {
+				return nil, fmt.Errorf("%w: invalid path %q", ErrInvalidExpression, tok.text)
+			}
+		}
+		return &pathNode{segments: segments}, nil
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.acceptOperator(")"); !ok {
+				return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidExpression)
+			}
+			return inner, nil
+		case "[":
+			list := &listNode{}
+			if _, ok := p.acceptOperator("]"); ok {
+				return list, nil
+			}
+			for {
+				item, err := p.parsePrimary()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if _, ok := p.acceptOperator(","); ok {
+					continue
+				}
+				if _, ok := p.acceptOperator("]"); ok {
+					return list, nil
+				}
+				return nil, fmt.Errorf("%w: missing closing bracket", ErrInvalidExpression)
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidExpression, tok.text)
+}
+
+// ---- 语法树节点 ----
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(*exprContext) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (n *listNode) eval(ctx *exprContext) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type pathNode struct {
+	segments []string
+}
+
+// eval 按路径取值,路径不存在时返回 nil
+func (n *pathNode) eval(ctx *exprContext) (interface{}, error) {
+	var current interface{}
+	rest := n.segments[1:]
+	if n.segments[0] == "params" {
+		current = ctx.params
+	} else {
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("outputs requires a node ID")
+		}
+		current = ctx.outputs[rest[0]]
+		rest = rest[1:]
+	}
+
+	for _, segment := range rest {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil, nil
+			}
+			current = v[index]
+		default:
+			return nil, nil
+		}
+	}
+	return current, nil
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (n *notNode) eval(ctx *exprContext) (interface{}, error) {
+	value, err := n.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator ! requires a boolean operand")
+	}
+	return !b, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *logicalNode) eval(ctx *exprContext) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lb, ok := left.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator %s requires boolean operands", n.op)
+	}
+	// 短路求值
+	if n.op == "&&" && !lb {
+		return false, nil
+	}
+	if n.op == "||" && lb {
+		return true, nil
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rb, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator %s requires boolean operands", n.op)
+	}
+	return rb, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(ctx *exprContext) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "in":
+		list, ok := right.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operator in requires a list on the right-hand side")
+		}
+		for _, item := range list {
+			if valuesEqual(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	// 有序比较: 任一侧为 null(如参数缺失)时结果为 false
+	if left == nil || right == nil {
+		return false, nil
+	}
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %T", right)
+		}
+		cmp = compareFloat(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %T", right)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("operator %s does not support %T", n.op, left)
+	}
+
+	switch n.op {
+	case ">":
+		return cmp > 0, nil
+	case ">=":
+		return cmp >= 0, nil
+	case "<":
+		return cmp < 0, nil
+	default:
+		return cmp <= 0, nil
+	}
+}
+
+func compareFloat(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// valuesEqual 比较两个 JSON 值是否相等
+func valuesEqual(left, right interface{}) bool {
+	switch left.(type) {
+	case nil:
+		return right == nil
+	case float64, string, bool:
+		return left == right
+	default:
+		lb, err1 := json.Marshal(left)
+		rb, err2 := json.Marshal(right)
+		return err1 == nil && err2 == nil && string(lb) == string(rb)
+	}
+}
@@ -0,0 +1,157 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	ctx, err := newExprContext(
+		json.RawMessage(`{"amount":12000,"type":"travel","applicant":{"dept":"finance","level":3},"tags":["urgent"],"note":null}`),
+		map[string]json.RawMessage{"node-2": json.RawMessage(`{"result":"approve","score":88}`)},
+	)
+	mustNoError(t, err)
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`params.amount > 10000`, true},
+		{`params.amount >= 12000 && params.amount <= 12000`, true},
+		{`params.amount < -1`, false},
+		{`params.type == "travel"`, true},
+		{`params.type != 'travel'`, false},
+		{`params.applicant.dept in ["finance", "legal"]`, true},
+		{`params.applicant.level in [1, 2]`, false},
+		{`params.tags.0 == "urgent"`, true},
+		{`params.tags.5 == null`, true},
+		{`params.missing == null`, true},
+		{`params.missing > 10`, false},
+		{`params.note == nil`, true},
+		{`outputs.node-2.result == "approve" && outputs.node-2.score > 80`, true},
+		{`outputs.node-3.result == "approve"`, false},
+		{`!(params.amount > 10000) || params.type == "travel"`, true},
+		{`params.amount > 100000 || (params.type == "travel" && !false)`, true},
+		{`params.type > "a"`, true},
+		{`true && false`, false},
+	}
+	for _, tt := range tests {
+		got, err := evaluateCondition(tt.expr, ctx)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateConditionShortCircuits(t *testing.T) {
+	ctx, err := newExprContext(json.RawMessage(`{"amount":1}`), nil)
+	mustNoError(t, err)
+
+	// 右侧类型错误的表达式不会被求值
+	got, err := evaluateCondition(`params.amount > 10 && params.amount > "x"`, ctx)
+	mustNoError(t, err)
+	if got {
+		t.Fatal("expected false")
+	}
+	got, err = evaluateCondition(`params.amount == 1 || params.amount > "x"`, ctx)
+	mustNoError(t, err)
+	if !got {
+		t.Fatal("expected true")
+	}
+}
+
+func TestEvaluateConditionErrors(t *testing.T) {
+	ctx, err := newExprContext(json.RawMessage(`{"amount":1,"type":"a"}`), nil)
+	mustNoError(t, err)
+
+	for _, expr := range []string{
+		`params.amount`,
+		`params.amount > "x"`,
+		`params.type in "abc"`,
+		`!params.amount`,
+		`params.amount && true`,
+	} {
+		if _, err := evaluateCondition(expr, ctx); err == nil {
+			t.Errorf("%s: expected evaluation error", expr)
+		}
+	}
+}
+
+func TestValidateExpression(t *testing.T) {
+	for _, expr := range []string{
+		`params.amount > 1 && (params.type == "a" || params.type in ["b", "c"])`,
+		`outputs.node-1.result != null`,
+		`params.amount > -5`,
+	} {
+		if err := ValidateExpression(expr); err != nil {
+			t.Errorf("%s: unexpected error: %v", expr, err)
+		}
+	}
+
+	for _, expr := range []string{
+		``,
+		`amount > 1`,
+		`params..amount > 1`,
+		`params.amount > `,
+		`(params.amount > 1`,
+		`params.type in ["a", "b"`,
+		`params.type == "a`,
+		`params.amount = 1`,
+		`params.amount > 1 params.type`,
+	} {
+		if err := ValidateExpression(expr); !errors.Is(err, ErrInvalidExpression) {
+			t.Errorf("%s: expected ErrInvalidExpression, got %v", expr, err)
+		}
+	}
+}
+
+func TestConditionalRouting(t *testing.T) {
+	env := newTestEnv(t)
+	env.createTemplate(t, "expense",
+		`{"start":{"id":"start","type":"start"},
+		"route":{"id":"route","type":"condition"},
+		`+approvalNode("director", []string{"dora"}, "")+`,
+		`+approvalNode("manager", []string{"mike"}, "")+`,
+		`+approvalNode("clerk", []string{"carl"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"route"},
+		{"from":"route","to":"manager","condition":"params.amount > 1000","priority":2},
+		{"from":"route","to":"director","condition":"params.amount > 10000","priority":1},
+		{"from":"route","to":"clerk","default":true},
+		{"from":"director","to":"end"},{"from":"manager","to":"end"},{"from":"clerk","to":"end"}]`)
+
+	tests := []struct {
+		params string
+		want   string
+	}{
+		{`{"amount":20000}`, "director"},
+		{`{"amount":5000}`, "manager"},
+		{`{"amount":10}`, "clerk"},
+		{`{}`, "clerk"},
+	}
+	for _, tt := range tests {
+		tsk := env.startTask(t, "expense", tt.params)
+		assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{tt.want})
+	}
+}
+
+func TestConditionalRoutingWithoutMatchingBranch(t *testing.T) {
+	env := newTestEnv(t)
+	env.createTemplate(t, "strict",
+		`{"start":{"id":"start","type":"start"},
+		"route":{"id":"route","type":"condition"},
+		`+approvalNode("a", []string{"ann"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"route"},{"from":"route","to":"a","condition":"params.amount > 1"},{"from":"a","to":"end"}]`)
+
+	tsk, err := env.tasks.Create("strict", "biz", json.RawMessage(`{"amount":0}`))
+	mustNoError(t, err)
+	if err := env.tasks.Submit(tsk.ID); !errors.Is(err, ErrNoMatchingBranch) {
+		t.Fatalf("expected ErrNoMatchingBranch, got %v", err)
+	}
+}
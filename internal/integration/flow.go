@@ -0,0 +1,393 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/template"
+	"gorm.io/gorm"
+)
+
+// 扩展节点类型
+// approval-kit 只内置 start/approval/end 三种节点,以下节点类型由本服务的流程引擎解释执行
+const (
+	// NodeTypeCondition 条件网关: 按出边上的条件表达式选择唯一分支,自动流转
+	NodeTypeCondition = "condition"
+	// NodeTypeParallelSplit 并行分支网关: 同时激活所有满足条件的出边分支
+	NodeTypeParallelSplit = "parallel_split"
+	// NodeTypeParallelJoin 并行汇聚网关: 等待全部(或配置的部分)入边分支到达后继续流转
+	NodeTypeParallelJoin = "parallel_join"
+	// NodeTypeService 服务节点: 调用配置的 HTTP 接口,响应写入节点输出后自动流转
+	NodeTypeService = "service"
+	// NodeTypeTimer 定时节点: 等待配置的时长或等待到参数中的日期后自动流转
+	NodeTypeTimer = "timer"
+	// NodeTypeNotify 通知节点: 抄送任务并通知配置的对象,不等待处理直接流转
+	NodeTypeNotify = "notify"
+	// NodeTypeSubprocess 子流程节点: 按配置的模板创建并提交子任务,子任务结束后按结果流转
+	NodeTypeSubprocess = "subprocess"
+)
+
+// ErrNoMatchingBranch 没有任何出边条件满足且未配置默认分支
+var ErrNoMatchingBranch = errors.New("no outgoing edge matched")
+
+// flowNode 流程节点定义
+// 直接从模板原始 JSON 解析,保留 approval-kit 模型之外的扩展类型和配置
+type flowNode struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// flowEdge 流程连线定义
+type flowEdge struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Condition string `json:"condition,omitempty"` // 条件表达式,如 params.amount > 10000
+	Default   bool   `json:"default,omitempty"`   // 默认分支,其余出边条件都不满足时选择
+	Priority  int    `json:"priority,omitempty"`  // 条件求值顺序,数值越小越先求值
+	Failure   bool   `json:"failure,omitempty"`   // 失败分支: 服务节点重试用尽或子任务未通过时选择,正常流转时忽略
+}
+
+// parallelJoinConfig 并行汇聚节点配置
+type parallelJoinConfig struct {
+	Branches []string `json:"branches,omitempty"` // 需要等待的分支(与汇聚节点直接相连的节点 ID),为空时等待全部入边
+	Required int      `json:"required,omitempty"` // 需要到达的分支数量,为 0 时等待全部分支
+}
+
+// flowDefinition 流程定义(节点 + 连线)
+type flowDefinition struct {
+	Nodes  map[string]*flowNode `json:"nodes"`
+	Edges  []*flowEdge          `json:"edges"`
+	Config *calendarConfig      `json:"config,omitempty"` // 模板配置中流程引擎使用的扩展字段
+}
+
+// loadFlow 从模板原始数据加载流程定义
+// version 为 0 时加载最新版本
+func loadFlow(db *gorm.DB, templateID string, version int) (*flowDefinition, error) {
+	var tm model.TemplateModel
+	query := db.Where("id = ?", templateID)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Order("version DESC").Limit(1)
+	}
+	if err := query.First(&tm).Error; err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+
+	var flow flowDefinition
+	if err := json.Unmarshal(tm.Data, &flow); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template flow: %w", err)
+	}
+	if flow.Nodes == nil {
+		flow.Nodes = make(map[string]*flowNode)
+	}
+	return &flow, nil
+}
+
+// nodeType 获取节点类型,节点不存在时返回空字符串
+func (f *flowDefinition) nodeType(nodeID string) string {
+	if node, exists := f.Nodes[nodeID]; exists && node != nil {
+		return node.Type
+	}
+	return ""
+}
+
+// outgoing 获取节点的出边,按优先级排序(优先级相同时保持定义顺序)
+func (f *flowDefinition) outgoing(nodeID string) []*flowEdge {
+	var edges []*flowEdge
+	for _, edge := range f.Edges {
+		if edge != nil && edge.From == nodeID {
+			edges = append(edges, edge)
+		}
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].Priority < edges[j].Priority
+	})
+	return edges
+}
+
+// incoming 获取节点的入边来源节点 ID
+func (f *flowDefinition) incoming(nodeID string) []string {
+	var sources []string
+	for _, edge := range f.Edges {
+		if edge != nil && edge.To == nodeID {
+			sources = append(sources, edge.From)
+		}
+	}
+	return sources
+}
+
+// joinConfig 解析并行汇聚节点配置
+func (f *flowDefinition) joinConfig(nodeID string) (*parallelJoinConfig, error) {
+	cfg := &parallelJoinConfig{}
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(node.Config, cfg); err != nil {
+		return nil, fmt.Errorf("invalid parallel join config for node %q: %w", nodeID, err)
+	}
+	return cfg, nil
+}
+
+// reaches 判断从 from 节点出发能否到达 target 节点
+func (f *flowDefinition) reaches(from string, target string) bool {
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		for _, edge := range f.outgoing(nodeID) {
+			if edge.To == target {
+				return true
+			}
+			if !visited[edge.To] {
+				visited[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	return false
+}
+
+// selectParallelBranches 选择并行分支网关需要激活的分支
+// 无条件出边和条件满足的出边全部激活;都不满足时走默认分支
+func (f *flowDefinition) selectParallelBranches(nodeID string, tsk *task.Task) ([]string, error) {
+	edges := f.outgoing(nodeID)
+	if len(edges) == 0 {
+		return nil, nil
+	}
+
+	ctx, err := newExprContext(tsk.Params, tsk.NodeOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	var defaultEdge *flowEdge
+	for _, edge := range edges {
+		if edge.Default {
+			if defaultEdge == nil {
+				defaultEdge = edge
+			}
+			continue
+		}
+		if edge.Condition != "" {
+			matched, err := evaluateCondition(edge.Condition, ctx)
+			if err != nil {
+				return nil, fmt.Errorf("edge %q -> %q: %w", edge.From, edge.To, err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		branches = append(branches, edge.To)
+	}
+
+	if len(branches) == 0 {
+		if defaultEdge == nil {
+			return nil, fmt.Errorf("%w: node %q has no satisfied condition and no default edge", ErrNoMatchingBranch, nodeID)
+		}
+		branches = append(branches, defaultEdge.To)
+	}
+	return branches, nil
+}
+
+// selectNextNode 选择指定节点的下一个节点
+// 条件出边按优先级依次求值,第一个满足的分支胜出;均不满足时走默认分支
+// 没有出边时返回空字符串,表示流程结束
+func (f *flowDefinition) selectNextNode(nodeID string, tsk *task.Task) (string, error) {
+	var edges []*flowEdge
+	for _, edge := range f.outgoing(nodeID) {
+		if !edge.Failure {
+			edges = append(edges, edge)
+		}
+	}
+	if len(edges) == 0 {
+		return "", nil
+	}
+	if len(edges) == 1 && edges[0].Condition == "" {
+		return edges[0].To, nil
+	}
+
+	ctx, err := newExprContext(tsk.Params, tsk.NodeOutputs)
+	if err != nil {
+		return "", err
+	}
+
+	var defaultEdge *flowEdge
+	for _, edge := range edges {
+		if edge.Default {
+			if defaultEdge == nil {
+				defaultEdge = edge
+			}
+			continue
+		}
+		// 无条件的出边视为恒成立
+		if edge.Condition == "" {
+			return edge.To, nil
+		}
+		matched, err := evaluateCondition(edge.Condition, ctx)
+		if err != nil {
+			return "", fmt.Errorf("edge %q -> %q: %w", edge.From, edge.To, err)
+		}
+		if matched {
+			return edge.To, nil
+		}
+	}
+
+	if defaultEdge != nil {
+		return defaultEdge.To, nil
+	}
+	return "", fmt.Errorf("%w: node %q has no satisfied condition and no default edge", ErrNoMatchingBranch, nodeID)
+}
+
+// ValidateFlow 校验流程连线配置(模板保存时调用)
+// 检查条件表达式语法、默认分支唯一性、网关节点的出边以及汇聚节点配置
+func ValidateFlow(rawNodes json.RawMessage, rawEdges json.RawMessage) error {
+	var nodes map[string]*flowNode
+	if len(rawNodes) > 0 {
+		if err := json.Unmarshal(rawNodes, &nodes); err != nil {
+			return fmt.Errorf("failed to parse nodes: %w", err)
+		}
+	}
+	var edges []*flowEdge
+	if len(rawEdges) > 0 {
+		if err := json.Unmarshal(rawEdges, &edges); err != nil {
+			return fmt.Errorf("failed to parse edges: %w", err)
+		}
+	}
+
+	flow := &flowDefinition{Nodes: nodes, Edges: edges}
+	defaults := make(map[string]int)
+	failures := make(map[string]int)
+	for _, edge := range edges {
+		if edge == nil {
+			continue
+		}
+		if edge.Condition != "" {
+			if err := ValidateExpression(edge.Condition); err != nil {
+				return fmt.Errorf("edge %q -> %q: %w", edge.From, edge.To, err)
+			}
+		}
+		if edge.Default {
+			defaults[edge.From]++
+			if defaults[edge.From] > 1 {
+				return fmt.Errorf("node %q has more than one default edge", edge.From)
+			}
+		}
+		if edge.Failure {
+			if fromType := flow.nodeType(edge.From); fromType != NodeTypeService && fromType != NodeTypeSubprocess {
+				return fmt.Errorf("edge %q -> %q: failure edges are only allowed on service and subprocess nodes", edge.From, edge.To)
+			}
+			if edge.Condition != "" || edge.Default {
+				return fmt.Errorf("edge %q -> %q: failure edge cannot have a condition or be the default edge", edge.From, edge.To)
+			}
+			failures[edge.From]++
+			if failures[edge.From] > 1 {
+				return fmt.Errorf("node %q has more than one failure edge", edge.From)
+			}
+		}
+	}
+
+	for id, node := range nodes {
+		if node == nil {
+			continue
+		}
+		switch node.Type {
+		case NodeTypeCondition, NodeTypeParallelSplit, NodeTypeParallelJoin:
+			if len(flow.outgoing(id)) == 0 {
+				return fmt.Errorf("%s node %q has no outgoing edges", node.Type, id)
+			}
+		}
+		if node.Type == string(template.NodeTypeApproval) {
+			if _, err := flow.approvalPolicyFor(id); err != nil {
+				return err
+			}
+			if _, err := flow.approverSourcesFor(id); err != nil {
+				return err
+			}
+			if _, err := flow.timeoutPolicyFor(id); err != nil {
+				return err
+			}
+			if _, err := flow.reminderPolicyFor(id); err != nil {
+				return err
+			}
+			if _, err := flow.calendarIDFor(id); err != nil {
+				return err
+			}
+			if _, err := flow.returnPolicyFor(id); err != nil {
+				return err
+			}
+			if _, err := flow.rejectActionFor(id); err != nil {
+				return err
+			}
+		}
+		if node.Type == NodeTypeService {
+			if _, err := flow.serviceConfigFor(id); err != nil {
+				return err
+			}
+			if len(flow.outgoing(id)) == failures[id] {
+				return fmt.Errorf("service node %q has no outgoing edges", id)
+			}
+		}
+		if node.Type == NodeTypeNotify {
+			if _, err := flow.notifyConfigFor(id); err != nil {
+				return err
+			}
+			if len(flow.outgoing(id)) == 0 {
+				return fmt.Errorf("notify node %q has no outgoing edges", id)
+			}
+		}
+		if node.Type == NodeTypeTimer {
+			if _, err := flow.timerConfigFor(id); err != nil {
+				return err
+			}
+		}
+		if node.Type == NodeTypeSubprocess {
+			if _, err := flow.subprocessConfigFor(id); err != nil {
+				return err
+			}
+			if len(flow.outgoing(id)) == failures[id] {
+				return fmt.Errorf("subprocess node %q has no outgoing edges", id)
+			}
+		}
+		if node.Type == NodeTypeParallelJoin {
+			cfg, err := flow.joinConfig(id)
+			if err != nil {
+				return err
+			}
+			sources := flow.incoming(id)
+			for _, branch := range cfg.Branches {
+				if !containsString(sources, branch) {
+					return fmt.Errorf("parallel join node %q: branch %q is not connected to the join", id, branch)
+				}
+			}
+			candidates := len(sources)
+			if len(cfg.Branches) > 0 {
+				candidates = len(cfg.Branches)
+			}
+			if cfg.Required < 0 || cfg.Required > candidates {
+				return fmt.Errorf("parallel join node %q: required must be between 0 and %d", id, candidates)
+			}
+		}
+	}
+
+	return nil
+}
+
+// containsString 判断字符串切片是否包含指定值
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
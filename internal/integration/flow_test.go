@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// parallelTemplate 创建 fork -> (a, b, c?) -> join -> final 的并行模板
+// 分支 c 只有 params.extra 为 true 时激活
+func parallelTemplate(t *testing.T, env *testEnv, id string, joinConfig string) {
+	t.Helper()
+	join := `"join":{"id":"join","type":"parallel_join"}`
+	if joinConfig != "" {
+		join = `"join":{"id":"join","type":"parallel_join","config":` + joinConfig + `}`
+	}
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		"fork":{"id":"fork","type":"parallel_split"},
+		`+approvalNode("a", []string{"ann"}, "")+`,
+		`+approvalNode("b", []string{"bob"}, "")+`,
+		`+approvalNode("c", []string{"cat"}, "")+`,
+		`+join+`,
+		`+approvalNode("final", []string{"fay"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"fork"},
+		{"from":"fork","to":"a"},{"from":"fork","to":"b"},{"from":"fork","to":"c","condition":"params.extra == true"},
+		{"from":"a","to":"join"},{"from":"b","to":"join"},{"from":"c","to":"join"},
+		{"from":"join","to":"final"},{"from":"final","to":"end"}]`)
+}
+
+func TestParallelJoinWaitsForAllActivatedBranches(t *testing.T) {
+	env := newTestEnv(t)
+	parallelTemplate(t, env, "review", "")
+
+	tsk := env.startTask(t, "review", `{"extra":true}`)
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"a", "b", "c"})
+
+	mustNoError(t, env.tasks.Approve(tsk.ID, "a", "ann", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"b", "c"})
+	mustNoError(t, env.tasks.Approve(tsk.ID, "c", "cat", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"b"})
+	mustNoError(t, env.tasks.Approve(tsk.ID, "b", "bob", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"final"})
+
+	var output struct {
+		Arrived   []string `json:"arrived"`
+		Cancelled []string `json:"cancelled"`
+	}
+	mustNoError(t, json.Unmarshal(env.getTask(t, tsk.ID).NodeOutputs["join"], &output))
+	assertEqualStrings(t, output.Arrived, []string{"a", "c", "b"})
+	assertEqualStrings(t, output.Cancelled, nil)
+
+	mustNoError(t, env.tasks.Approve(tsk.ID, "final", "fay", ""))
+	if got := env.getTask(t, tsk.ID); got.State != types.TaskStateApproved {
+		t.Fatalf("state = %s, want approved", got.State)
+	}
+}
+
+func TestParallelJoinIgnoresInactiveConditionalBranch(t *testing.T) {
+	env := newTestEnv(t)
+	parallelTemplate(t, env, "review", "")
+
+	tsk := env.startTask(t, "review", `{"extra":false}`)
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"a", "b"})
+
+	mustNoError(t, env.tasks.Approve(tsk.ID, "b", "bob", ""))
+	mustNoError(t, env.tasks.Approve(tsk.ID, "a", "ann", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"final"})
+}
+
+func TestParallelJoinWithRequiredCancelsRemainingBranches(t *testing.T) {
+	env := newTestEnv(t)
+	parallelTemplate(t, env, "review", `{"required":1}`)
+
+	tsk := env.startTask(t, "review", `{"extra":true}`)
+	mustNoError(t, env.tasks.Approve(tsk.ID, "b", "bob", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"final"})
+
+	var output struct {
+		Arrived   []string `json:"arrived"`
+		Cancelled []string `json:"cancelled"`
+	}
+	mustNoError(t, json.Unmarshal(env.getTask(t, tsk.ID).NodeOutputs["join"], &output))
+	assertEqualStrings(t, output.Arrived, []string{"b"})
+	assertEqualStrings(t, output.Cancelled, []string{"a", "c"})
+
+	// 已取消分支的审批人不能再审批
+	if err := env.tasks.Approve(tsk.ID, "a", "ann", ""); err == nil {
+		t.Fatal("expected approval on a cancelled branch to fail")
+	}
+}
+
+func TestParallelJoinWaitsForConfiguredBranches(t *testing.T) {
+	env := newTestEnv(t)
+	parallelTemplate(t, env, "review", `{"branches":["a","b"]}`)
+
+	tsk := env.startTask(t, "review", `{"extra":true}`)
+	mustNoError(t, env.tasks.Approve(tsk.ID, "a", "ann", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"b", "c"})
+	mustNoError(t, env.tasks.Approve(tsk.ID, "b", "bob", ""))
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"final"})
+}
@@ -0,0 +1,164 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mautops/approval-gin/internal/database"
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/template"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testEnv 测试环境:内存 SQLite 数据库、模板管理器和任务管理器
+type testEnv struct {
+	db        *gorm.DB
+	templates *DBTemplateManager
+	tasks     *DBTaskManager
+	events    *recordingHandler
+}
+
+// testDBSeq 测试数据库序号,保证同一测试中创建的多个数据库互相独立
+var testDBSeq atomic.Int64
+
+// newTestDB 创建测试用的内存数据库,每次调用使用独立的数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	name := fmt.Sprintf("%s_%d", strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()), testDBSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// newTestEnv 创建测试环境,任务事件记录在 env.events 中
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db := newTestDB(t)
+	templates := NewTemplateManager(db).(*DBTemplateManager)
+	events := &recordingHandler{}
+	return &testEnv{
+		db:        db,
+		templates: templates,
+		tasks:     NewTaskManager(db, templates, nil, events).(*DBTaskManager),
+		events:    events,
+	}
+}
+
+// createTemplate 使用原始节点和连线 JSON 创建模板
+func (e *testEnv) createTemplate(t *testing.T, id string, nodes string, edges string) {
+	t.Helper()
+	e.createTemplateWithConfig(t, id, nodes, edges, "")
+}
+
+// createTemplateWithConfig 使用原始节点、连线和模板配置 JSON 创建模板
+func (e *testEnv) createTemplateWithConfig(t *testing.T, id string, nodes string, edges string, config string) {
+	t.Helper()
+	var rawConfig json.RawMessage
+	if config != "" {
+		rawConfig = json.RawMessage(config)
+	}
+	tpl := &template.Template{ID: id, Name: id, Version: 1}
+	if err := e.templates.CreateWithRawGraph(tpl, json.RawMessage(nodes), json.RawMessage(edges), rawConfig); err != nil {
+		t.Fatalf("failed to create template %q: %v", id, err)
+	}
+}
+
+// startTask 创建并提交任务
+func (e *testEnv) startTask(t *testing.T, templateID string, params string) *task.Task {
+	t.Helper()
+	tsk, err := e.tasks.Create(templateID, "biz-"+templateID, json.RawMessage(params))
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := e.tasks.Submit(tsk.ID); err != nil {
+		t.Fatalf("failed to submit task: %v", err)
+	}
+	return e.getTask(t, tsk.ID)
+}
+
+// getTask 获取任务
+func (e *testEnv) getTask(t *testing.T, id string) *task.Task {
+	t.Helper()
+	tsk, err := e.tasks.Get(id)
+	if err != nil {
+		t.Fatalf("failed to get task: %v", err)
+	}
+	return tsk
+}
+
+// activeNodes 获取任务的活动节点
+func (e *testEnv) activeNodes(t *testing.T, id string) []string {
+	t.Helper()
+	nodes, err := e.tasks.ActiveNodes(id)
+	if err != nil {
+		t.Fatalf("failed to get active nodes: %v", err)
+	}
+	return nodes
+}
+
+// approvalNode 生成指定审批人的审批节点 JSON,extra 为附加的节点配置字段
+func approvalNode(id string, users []string, extra string) string {
+	usersJSON, _ := json.Marshal(users)
+	config := fmt.Sprintf(`{"approver_sources":[{"type":"users","users":%s}]`, usersJSON)
+	if extra != "" {
+		config += "," + extra
+	}
+	return fmt.Sprintf(`%q:{"id":%q,"type":"approval","config":%s}}`, id, id, config)
+}
+
+// recordingHandler 记录收到的事件
+type recordingHandler struct {
+	mu     sync.Mutex
+	events []*event.Event
+}
+
+func (h *recordingHandler) Handle(evt *event.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, evt)
+	return nil
+}
+
+// types 返回已记录事件的类型
+func (h *recordingHandler) types() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	types := make([]string, 0, len(h.events))
+	for _, evt := range h.events {
+		types = append(types, string(evt.Type))
+	}
+	return types
+}
+
+func mustNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func assertEqualStrings(t *testing.T, got []string, want []string) {
+	t.Helper()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
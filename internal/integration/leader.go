@@ -0,0 +1,39 @@
+package integration
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mautops/approval-gin/internal/repository"
+	"gorm.io/gorm"
+)
+
+// LeaderElector 基于数据库租约的 leader 选举
+// 多副本部署时同一名称的租约只有一个实例持有,持有者每次调度前续约;实例退出或失联后租约过期,由其他实例接管
+type LeaderElector struct {
+	leaseRepo repository.SchedulerLeaseRepository
+	name      string
+	holder    string
+	lease     time.Duration
+}
+
+// NewLeaderElector 创建 leader 选举器,holder 为当前实例的随机标识
+func NewLeaderElector(db *gorm.DB, name string, lease time.Duration) *LeaderElector {
+	return &LeaderElector{
+		leaseRepo: repository.NewSchedulerLeaseRepository(db),
+		name:      name,
+		holder:    uuid.New().String(),
+		lease:     lease,
+	}
+}
+
+// Acquire 获取或续约 leader 租约,返回当前实例是否为 leader
+func (e *LeaderElector) Acquire() (bool, error) {
+	now := time.Now()
+	return e.leaseRepo.Acquire(e.name, e.holder, now, now.Add(e.lease))
+}
+
+// Release 释放 leader 租约(实例退出时调用,其他实例无需等待租约过期)
+func (e *LeaderElector) Release() error {
+	return e.leaseRepo.Release(e.name, e.holder)
+}
@@ -0,0 +1,209 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/types"
+	"gorm.io/gorm"
+)
+
+// ErrTemplateVersionNotFound 迁移的目标模板版本不存在
+var ErrTemplateVersionNotFound = errors.New("template version not found")
+
+// ErrInvalidNodeMapping 节点映射的目标节点不在目标版本中
+var ErrInvalidNodeMapping = errors.New("invalid node mapping")
+
+// RunningTaskStates 运行中的任务状态(固定在创建时的模板版本上执行,可以迁移到其他版本)
+var RunningTaskStates = []string{
+	string(types.TaskStatePending),
+	string(types.TaskStateSubmitted),
+	string(types.TaskStateApproving),
+	string(types.TaskStatePaused),
+}
+
+// TaskMigrationResult 单个任务的版本迁移结果
+type TaskMigrationResult struct {
+	TaskID      string            `json:"task_id"`                // 任务 ID
+	State       string            `json:"state"`                  // 任务状态
+	FromVersion int               `json:"from_version"`           // 迁移前的模板版本
+	ToVersion   int               `json:"to_version"`             // 目标模板版本
+	NodeMapping map[string]string `json:"node_mapping,omitempty"` // 进行中节点的映射结果(原节点 ID -> 新节点 ID)
+	CanMigrate  bool              `json:"can_migrate"`            // 是否可以安全迁移
+	Migrated    bool              `json:"migrated"`               // 是否已迁移(dry-run 时始终为 false)
+	Reasons     []string          `json:"reasons,omitempty"`      // 不能迁移的原因
+}
+
+// CheckMigrationTarget 校验迁移的目标版本存在,且节点映射的目标节点都在目标版本中
+func (m *DBTaskManager) CheckMigrationTarget(templateID string, targetVersion int, nodeMapping map[string]string) error {
+	toFlow, err := loadFlow(m.db, templateID, targetVersion)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s version %d", ErrTemplateVersionNotFound, templateID, targetVersion)
+	}
+	if err != nil {
+		return err
+	}
+	for source, target := range nodeMapping {
+		if target != "" && toFlow.nodeType(target) == "" {
+			return fmt.Errorf("%w: node %q is mapped to %q which does not exist in version %d", ErrInvalidNodeMapping, source, target, targetVersion)
+		}
+	}
+	return nil
+}
+
+// MigrateToVersion 将运行中的任务迁移到模板的指定版本
+// nodeMapping 为原版本节点 ID 到目标版本节点 ID 的映射,未映射的节点按同名节点处理
+// 进行中的节点(活动节点、汇聚记录)必须在目标版本中存在且类型一致才能迁移;dryRun 为 true 时只检查不修改
+func (m *DBTaskManager) MigrateToVersion(id string, targetVersion int, nodeMapping map[string]string, dryRun bool) (*TaskMigrationResult, error) {
+	var result *TaskMigrationResult
+	err := m.inTx(func(txm *dbTaskManager) error {
+		var err error
+		result, err = txm.migrateToVersion(id, targetVersion, nodeMapping, dryRun)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// migrateToVersion MigrateToVersion 的事务内实现
+func (m *dbTaskManager) migrateToVersion(id string, targetVersion int, nodeMapping map[string]string, dryRun bool) (*TaskMigrationResult, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TaskMigrationResult{
+		TaskID:      tsk.ID,
+		State:       string(tsk.State),
+		FromVersion: tsk.TemplateVersion,
+		ToVersion:   targetVersion,
+		NodeMapping: make(map[string]string),
+	}
+
+	if !containsString(RunningTaskStates, string(tsk.State)) {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("task in state %q is not running", tsk.State))
+	}
+	if tsk.TemplateVersion == targetVersion {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("task is already on version %d", targetVersion))
+	}
+
+	fromFlow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template version %d: %w", tsk.TemplateVersion, err)
+	}
+	toFlow, err := loadFlow(m.db, tsk.TemplateID, targetVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template version %d: %w", targetVersion, err)
+	}
+
+	mapNode := func(nodeID string) string {
+		if mapped, exists := nodeMapping[nodeID]; exists && mapped != "" {
+			return mapped
+		}
+		return nodeID
+	}
+
+	// 进行中的节点必须能映射到目标版本的同类型节点
+	inFlight := append([]string{}, rt.ActiveNodes...)
+	if len(rt.ActiveNodes) == 0 && tsk.CurrentNode != "" && containsString(RunningTaskStates, string(tsk.State)) {
+		inFlight = append(inFlight, tsk.CurrentNode)
+	}
+	for joinID, sources := range rt.JoinArrivals {
+		inFlight = append(inFlight, joinID)
+		inFlight = append(inFlight, sources...)
+	}
+	if rt.Returned != nil {
+		// 退回修改中的任务重新提交时可能重新激活退回时的活动节点
+		inFlight = append(inFlight, rt.Returned.ActiveNodes...)
+	}
+	sort.Strings(inFlight)
+	for _, nodeID := range inFlight {
+		if _, checked := result.NodeMapping[nodeID]; checked {
+			continue
+		}
+		target := mapNode(nodeID)
+		result.NodeMapping[nodeID] = target
+		targetType := toFlow.nodeType(target)
+		if targetType == "" {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("node %q has no counterpart %q in version %d", nodeID, target, targetVersion))
+			continue
+		}
+		if sourceType := fromFlow.nodeType(nodeID); sourceType != "" && sourceType != targetType {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("node %q changes type from %q to %q", nodeID, sourceType, targetType))
+		}
+	}
+
+	result.CanMigrate = len(result.Reasons) == 0
+	if !result.CanMigrate || dryRun {
+		return result, nil
+	}
+
+	// 按映射改写任务中以节点 ID 为键的数据
+	tsk.TemplateVersion = targetVersion
+	tsk.CurrentNode = mapNode(tsk.CurrentNode)
+	tsk.Approvers = remapKeys(tsk.Approvers, mapNode)
+	tsk.Approvals = remapKeys(tsk.Approvals, mapNode)
+	tsk.NodeOutputs = remapKeys(tsk.NodeOutputs, mapNode)
+	for i, nodeID := range tsk.CompletedNodes {
+		tsk.CompletedNodes[i] = mapNode(nodeID)
+	}
+	for i, nodeID := range rt.ActiveNodes {
+		rt.ActiveNodes[i] = mapNode(nodeID)
+	}
+	joinArrivals := make(map[string][]string, len(rt.JoinArrivals))
+	for joinID, sources := range rt.JoinArrivals {
+		mapped := make([]string, 0, len(sources))
+		for _, source := range sources {
+			mapped = append(mapped, mapNode(source))
+		}
+		joinArrivals[mapNode(joinID)] = mapped
+	}
+	rt.JoinArrivals = joinArrivals
+	rt.ApproverProvenance = remapKeys(rt.ApproverProvenance, mapNode)
+	rt.ActivatedAt = remapKeys(rt.ActivatedAt, mapNode)
+	rt.Escalations = remapKeys(rt.Escalations, mapNode)
+	rt.Reminders = remapKeys(rt.Reminders, mapNode)
+	rt.ServiceCalls = remapKeys(rt.ServiceCalls, mapNode)
+	rt.Timers = remapKeys(rt.Timers, mapNode)
+	rt.Subprocesses = remapKeys(rt.Subprocesses, mapNode)
+	if rt.Returned != nil {
+		rt.Returned.NodeID = mapNode(rt.Returned.NodeID)
+		for i, nodeID := range rt.Returned.ActiveNodes {
+			rt.Returned.ActiveNodes[i] = mapNode(nodeID)
+		}
+	}
+	tsk.UpdatedAt = time.Now()
+
+	if err := m.saveTaskData(tsk, rt); err != nil {
+		return nil, err
+	}
+	// 子任务记录的父节点随之改写
+	for nodeID, childID := range rt.Subprocesses {
+		if err := m.db.Model(&model.TaskModel{}).Where("id = ?", childID).Update("parent_node_id", nodeID).Error; err != nil {
+			return nil, fmt.Errorf("failed to update subprocess parent node: %w", err)
+		}
+	}
+	result.Migrated = true
+	return result, nil
+}
+
+// remapKeys 按节点映射改写 map 的键
+func remapKeys[V any](values map[string]V, mapNode func(string) string) map[string]V {
+	if values == nil {
+		return nil
+	}
+	remapped := make(map[string]V, len(values))
+	for nodeID, value := range values {
+		remapped[mapNode(nodeID)] = value
+	}
+	return remapped
+}
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mautops/approval-kit/pkg/template"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// addTemplateVersion 为模板创建新版本
+func (e *testEnv) addTemplateVersion(t *testing.T, id string, nodes string, edges string) {
+	t.Helper()
+	tpl := &template.Template{ID: id, Name: id}
+	if err := e.templates.UpdateWithRawGraph(id, tpl, []byte(nodes), []byte(edges), nil); err != nil {
+		t.Fatalf("failed to add version of template %q: %v", id, err)
+	}
+}
+
+// startMigrationTask 在 v1(审批节点 review)上启动任务,再创建 v2(审批节点改名为 check)
+func startMigrationTask(t *testing.T, env *testEnv) string {
+	t.Helper()
+	singleApprovalTemplate(t, env, "leave", []string{"ann"})
+	taskID := env.startTask(t, "leave", `{}`).ID
+	env.addTemplateVersion(t, "leave",
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("check", []string{"ann"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"check"},{"from":"check","to":"end"}]`)
+	return taskID
+}
+
+func TestMigrateToVersionDryRunReportsWithoutChanges(t *testing.T) {
+	env := newTestEnv(t)
+	taskID := startMigrationTask(t, env)
+
+	result, err := env.tasks.MigrateToVersion(taskID, 2, map[string]string{"review": "check"}, true)
+	mustNoError(t, err)
+	if !result.CanMigrate || result.Migrated || result.FromVersion != 1 || result.ToVersion != 2 {
+		t.Fatalf("unexpected dry-run result: %+v", result)
+	}
+	if result.NodeMapping["review"] != "check" {
+		t.Fatalf("node mapping = %v", result.NodeMapping)
+	}
+	if got := env.getTask(t, taskID); got.TemplateVersion != 1 {
+		t.Fatalf("dry-run changed template version to %d", got.TemplateVersion)
+	}
+}
+
+func TestMigrateToVersionReportsMissingCounterpart(t *testing.T) {
+	env := newTestEnv(t)
+	taskID := startMigrationTask(t, env)
+
+	result, err := env.tasks.MigrateToVersion(taskID, 2, nil, false)
+	mustNoError(t, err)
+	if result.CanMigrate || result.Migrated || len(result.Reasons) != 1 {
+		t.Fatalf("expected migration to be refused: %+v", result)
+	}
+	if got := env.getTask(t, taskID); got.TemplateVersion != 1 {
+		t.Fatalf("refused migration changed template version to %d", got.TemplateVersion)
+	}
+}
+
+func TestMigrateToVersionReportsNodeTypeChange(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "leave", []string{"ann"})
+	taskID := env.startTask(t, "leave", `{}`).ID
+	env.addTemplateVersion(t, "leave",
+		`{"start":{"id":"start","type":"start"},
+		"review":{"id":"review","type":"end"}}`,
+		`[{"from":"start","to":"review"}]`)
+
+	result, err := env.tasks.MigrateToVersion(taskID, 2, nil, true)
+	mustNoError(t, err)
+	if result.CanMigrate {
+		t.Fatalf("expected type change to be refused: %+v", result)
+	}
+}
+
+func TestMigrateToVersionRemapsNodeState(t *testing.T) {
+	env := newTestEnv(t)
+	taskID := startMigrationTask(t, env)
+
+	result, err := env.tasks.MigrateToVersion(taskID, 2, map[string]string{"review": "check"}, false)
+	mustNoError(t, err)
+	if !result.Migrated {
+		t.Fatalf("expected task to be migrated: %+v", result)
+	}
+
+	migrated := env.getTask(t, taskID)
+	if migrated.TemplateVersion != 2 || migrated.CurrentNode != "check" {
+		t.Fatalf("version = %d, current node = %q", migrated.TemplateVersion, migrated.CurrentNode)
+	}
+	assertEqualStrings(t, migrated.Approvers["check"], []string{"ann"})
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"check"})
+	rt, err := env.tasks.loadRuntime(migrated)
+	mustNoError(t, err)
+	if _, exists := rt.ActivatedAt["check"]; !exists {
+		t.Fatalf("activation time was not remapped: %v", rt.ActivatedAt)
+	}
+
+	// 迁移后按新版本继续审批
+	mustNoError(t, env.tasks.Approve(taskID, "check", "ann", ""))
+	if got := env.getTask(t, taskID); got.State != types.TaskStateApproved {
+		t.Fatalf("state = %s, want approved", got.State)
+	}
+}
+
+func TestMigrateToVersionSkipsFinishedTasks(t *testing.T) {
+	env := newTestEnv(t)
+	taskID := startMigrationTask(t, env)
+	mustNoError(t, env.tasks.Approve(taskID, "review", "ann", ""))
+
+	result, err := env.tasks.MigrateToVersion(taskID, 2, map[string]string{"review": "check"}, false)
+	mustNoError(t, err)
+	if result.CanMigrate || result.Migrated {
+		t.Fatalf("finished task must not migrate: %+v", result)
+	}
+}
+
+func TestCheckMigrationTarget(t *testing.T) {
+	env := newTestEnv(t)
+	startMigrationTask(t, env)
+
+	mustNoError(t, env.tasks.CheckMigrationTarget("leave", 2, map[string]string{"review": "check"}))
+	if err := env.tasks.CheckMigrationTarget("leave", 9, nil); !errors.Is(err, ErrTemplateVersionNotFound) {
+		t.Fatalf("expected ErrTemplateVersionNotFound, got %v", err)
+	}
+	if err := env.tasks.CheckMigrationTarget("missing", 1, nil); !errors.Is(err, ErrTemplateVersionNotFound) {
+		t.Fatalf("expected ErrTemplateVersionNotFound, got %v", err)
+	}
+	if err := env.tasks.CheckMigrationTarget("leave", 2, map[string]string{"review": "nope"}); !errors.Is(err, ErrInvalidNodeMapping) {
+		t.Fatalf("expected ErrInvalidNodeMapping, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/task"
+)
+
+// 抄送通知事件的操作名称
+const (
+	// NotifyActionCC 创建任务时指定的抄送
+	NotifyActionCC = "cc"
+	// NotifyActionNotify 通知节点发出的通知
+	NotifyActionNotify = "notify"
+)
+
+// notifyNodeConfig 通知节点配置
+// 从节点原始 config 的 notify 中解析,通知对象的来源与审批人来源相同
+type notifyNodeConfig struct {
+	Recipients []*approverSource `json:"recipients"`        // 通知对象来源
+	Message    string            `json:"message,omitempty"` // 通知内容,作为事件的审批意见
+}
+
+// notifyNodeConfigHolder 通知节点中通知相关的配置
+type notifyNodeConfigHolder struct {
+	Notify *notifyNodeConfig `json:"notify,omitempty"`
+}
+
+// notifyConfigFor 解析通知节点配置
+func (f *flowDefinition) notifyConfigFor(nodeID string) (*notifyNodeConfig, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, fmt.Errorf("notify node %q: notify config is required", nodeID)
+	}
+	var holder notifyNodeConfigHolder
+	if err := json.Unmarshal(node.Config, &holder); err != nil {
+		return nil, fmt.Errorf("invalid notify config for node %q: %w", nodeID, err)
+	}
+	cfg := holder.Notify
+	if cfg == nil || len(cfg.Recipients) == 0 {
+		return nil, fmt.Errorf("notify node %q: recipients are required", nodeID)
+	}
+	for _, source := range cfg.Recipients {
+		if source == nil {
+			return nil, fmt.Errorf("notify node %q: recipient source must not be null", nodeID)
+		}
+		if err := source.validate(); err != nil {
+			return nil, fmt.Errorf("notify node %q: %w", nodeID, err)
+		}
+	}
+	return cfg, nil
+}
+
+// enterNotify 进入通知节点
+// 解析通知对象并抄送任务,每个通知对象产生一个 task_notified 事件,然后自动流转到后续节点
+func (m *dbTaskManager) enterNotify(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, depth int) error {
+	cfg, err := flow.notifyConfigFor(nodeID)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), approverResolveTimeout)
+	defer cancel()
+
+	var recipients []string
+	for _, source := range cfg.Recipients {
+		resolved, err := m.resolveSource(ctx, tsk, source)
+		if err != nil {
+			return fmt.Errorf("failed to resolve recipients for node %q: %w", nodeID, err)
+		}
+		for _, user := range resolved {
+			if !containsString(recipients, user.userID) {
+				recipients = append(recipients, user.userID)
+			}
+		}
+	}
+
+	if err := m.addCC(tsk, recipients, nodeID, NotifyActionNotify, cfg.Message); err != nil {
+		return err
+	}
+	markNodeCompleted(tsk, nodeID)
+	output, _ := json.Marshal(map[string][]string{"recipients": recipients})
+	setNodeOutput(tsk, nodeID, output)
+	return m.leaveNode(tsk, rt, flow, nodeID, depth)
+}
+
+// addCC 抄送任务给用户并授予 viewer 关系
+// 每个通知对象都会产生 task_notified 事件(操作人为通知对象);已被抄送的用户不重复记录抄送
+func (m *dbTaskManager) addCC(tsk *task.Task, users []string, nodeID string, action string, message string) error {
+	repo := repository.NewTaskCCRepository(m.db)
+	now := time.Now()
+	for _, userID := range users {
+		added, err := repo.Save(&model.TaskCCModel{
+			TaskID:    tsk.ID,
+			UserID:    userID,
+			NodeID:    nodeID,
+			AddedBy:   m.actor(),
+			CreatedAt: now,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save task cc: %w", err)
+		}
+		if added {
+			m.queueViewer(tsk.ID, userID)
+		}
+		m.emit(EventTaskNotified, tsk, nodeID, userID, action, message)
+	}
+	return nil
+}
+
+// AddCC 抄送任务给用户(发起人创建任务时指定的抄送列表)
+// 抄送人获得任务的 viewer 关系,并收到 task_notified 事件
+func (m *DBTaskManager) AddCC(id string, users []string, message string) error {
+	ccUsers := normalizeCCUsers(users)
+	if len(ccUsers) == 0 {
+		return nil
+	}
+
+	return m.inTx(func(txm *dbTaskManager) error {
+		tsk, err := txm.Get(id)
+		if err != nil {
+			return fmt.Errorf("failed to get task: %w", err)
+		}
+		return txm.addCC(tsk, ccUsers, "", NotifyActionCC, message)
+	})
+}
+
+// CreateWithCC 创建任务,记录发起人并抄送给发起人指定的用户
+// 发起人、抄送列表和任务在同一事务中保存,task_created 事件之后产生抄送的 task_notified 事件
+func (m *DBTaskManager) CreateWithCC(templateID string, businessID string, params json.RawMessage, initiator string, users []string) (*task.Task, error) {
+	ccUsers := normalizeCCUsers(users)
+	var tsk *task.Task
+	err := m.inTx(func(txm *dbTaskManager) error {
+		var err error
+		if tsk, err = txm.create(templateID, businessID, params, initiator); err != nil {
+			return err
+		}
+		return txm.addCC(tsk, ccUsers, "", NotifyActionCC, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tsk, nil
+}
+
+// normalizeCCUsers 去除抄送列表中的空白和重复用户
+func normalizeCCUsers(users []string) []string {
+	var ccUsers []string
+	for _, userID := range users {
+		userID = strings.TrimSpace(userID)
+		if userID != "" && !containsString(ccUsers, userID) {
+			ccUsers = append(ccUsers, userID)
+		}
+	}
+	return ccUsers
+}
+
+// CCUsers 获取任务的抄送人(按抄送时间排序)
+func (m *DBTaskManager) CCUsers(id string) ([]string, error) {
+	ccs, err := repository.NewTaskCCRepository(m.db).FindByTaskID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task cc: %w", err)
+	}
+	users := make([]string, 0, len(ccs))
+	for _, cc := range ccs {
+		users = append(users, cc.UserID)
+	}
+	return users, nil
+}
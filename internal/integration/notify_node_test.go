@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRelations 记录同步的权限关系,格式为 "<关系>:<任务 ID>:<用户>"
+type fakeRelations struct {
+	mu     sync.Mutex
+	tuples []string
+}
+
+func (r *fakeRelations) record(relation string, taskID string, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tuples = append(r.tuples, relation+":"+taskID+":"+userID)
+	return nil
+}
+
+func (r *fakeRelations) GrantApprover(_ context.Context, taskID string, userID string) error {
+	return r.record("approver", taskID, userID)
+}
+
+func (r *fakeRelations) RevokeApprover(_ context.Context, taskID string, userID string) error {
+	return r.record("-approver", taskID, userID)
+}
+
+func (r *fakeRelations) GrantViewer(_ context.Context, taskID string, userID string) error {
+	return r.record("viewer", taskID, userID)
+}
+
+// viewers 返回被授予任务 viewer 关系的用户
+func (r *fakeRelations) viewers(taskID string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var users []string
+	prefix := "viewer:" + taskID + ":"
+	for _, tuple := range r.tuples {
+		if user, ok := strings.CutPrefix(tuple, prefix); ok {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// notifications 返回任务的 task_notified 事件,格式为 "<操作>:<通知对象>"
+func (e *testEnv) notifications(taskID string) []string {
+	var got []string
+	for _, evt := range e.events.events {
+		if evt.Type == EventTaskNotified && evt.Task.ID == taskID {
+			got = append(got, evt.Approval.Result+":"+evt.Approval.Approver)
+		}
+	}
+	return got
+}
+
+func TestNotifyNodeCopiesRecipients(t *testing.T) {
+	env := newTestEnv(t)
+	relations := &fakeRelations{}
+	env.tasks.SetApproverRelations(relations)
+	env.createTemplate(t, "notify",
+		`{"start":{"id":"start","type":"start"},
+		"fyi":{"id":"fyi","type":"notify","config":{"notify":{"message":"FYI","recipients":[
+			{"type":"users","users":["cat","eve"]},{"type":"param","field":"watcher"}]}}},
+		`+approvalNode("review", []string{"ann"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"fyi"},{"from":"fyi","to":"review"},{"from":"review","to":"end"}]`)
+
+	// 创建时的抄送列表去除空白和重复
+	tsk, err := env.tasks.CreateWithCC("notify", "biz", json.RawMessage(`{"watcher":"pat"}`), "ini", []string{"eve", " eve ", ""})
+	mustNoError(t, err)
+	mustNoError(t, env.tasks.Submit(tsk.ID))
+
+	// 通知节点自动流转,输出通知对象
+	assertEqualStrings(t, env.activeNodes(t, tsk.ID), []string{"review"})
+	var output map[string][]string
+	mustNoError(t, json.Unmarshal(env.getTask(t, tsk.ID).NodeOutputs["fyi"], &output))
+	assertEqualStrings(t, output["recipients"], []string{"cat", "eve", "pat"})
+
+	// 已被抄送的用户不重复记录,但仍收到通知事件
+	ccUsers, err := env.tasks.CCUsers(tsk.ID)
+	mustNoError(t, err)
+	assertEqualStrings(t, ccUsers, []string{"eve", "cat", "pat"})
+	assertEqualStrings(t, env.notifications(tsk.ID), []string{"cc:eve", "notify:cat", "notify:eve", "notify:pat"})
+	assertEqualStrings(t, relations.viewers(tsk.ID), []string{"eve", "cat", "pat"})
+}
+
+func TestAddCCGrantsViewer(t *testing.T) {
+	env := newTestEnv(t)
+	relations := &fakeRelations{}
+	env.tasks.SetApproverRelations(relations)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	taskID := env.startTask(t, "single", `{}`).ID
+
+	mustNoError(t, env.tasks.AddCC(taskID, []string{"cat", "dan", "cat"}, "please review"))
+	mustNoError(t, env.tasks.AddCC(taskID, []string{"dan"}, ""))
+	mustNoError(t, env.tasks.AddCC(taskID, []string{" "}, ""))
+
+	ccUsers, err := env.tasks.CCUsers(taskID)
+	mustNoError(t, err)
+	assertEqualStrings(t, ccUsers, []string{"cat", "dan"})
+	assertEqualStrings(t, relations.viewers(taskID), []string{"cat", "dan"})
+	assertEqualStrings(t, env.notifications(taskID), []string{"cc:cat", "cc:dan", "cc:dan"})
+}
@@ -0,0 +1,398 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"gorm.io/gorm"
+)
+
+// 前置钩子拦截的操作
+const (
+	HookActionSubmit  = "submit"
+	HookActionApprove = "approve"
+	HookActionReject  = "reject"
+)
+
+// 前置钩子调用失败(超时、网络错误、响应无效)时的处理策略
+const (
+	// HookFailClosed 拒绝操作(默认)
+	HookFailClosed = "fail_closed"
+	// HookFailOpen 放行操作
+	HookFailOpen = "fail_open"
+)
+
+// 前置钩子的决定
+const (
+	HookDecisionAllow = "allow" // 放行
+	HookDecisionDeny  = "deny"  // 拒绝,reason 返回给调用方
+	HookDecisionPatch = "patch" // 放行并按 patch 修改任务参数
+)
+
+// 前置钩子超时时间
+const (
+	defaultPreActionHookTimeout = 5 * time.Second
+	maxPreActionHookTimeout     = 30 * time.Second
+)
+
+// ErrPreActionDenied 操作被前置钩子拒绝
+var ErrPreActionDenied = errors.New("action denied by pre-action hook")
+
+// ErrPreActionHookFailed 前置钩子调用失败且策略为 fail_closed
+var ErrPreActionHookFailed = errors.New("pre-action hook failed")
+
+// PreActionDeniedError 前置钩子拒绝操作的错误,Reason 为钩子返回的原因
+type PreActionDeniedError struct {
+	Action string
+	Reason string
+}
+
+// Error 实现 error 接口
+func (e *PreActionDeniedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%s denied by pre-action hook", e.Action)
+	}
+	return fmt.Sprintf("%s denied by pre-action hook: %s", e.Action, e.Reason)
+}
+
+// Is 支持 errors.Is(err, ErrPreActionDenied)
+func (e *PreActionDeniedError) Is(target error) bool {
+	return target == ErrPreActionDenied
+}
+
+// PreActionHookConfig 前置钩子配置
+// 保存在模板配置的 pre_action_hook 中,提交、同意、拒绝操作开始事务前同步调用
+type PreActionHookConfig struct {
+	URL           string            `json:"url"`                      // 钩子地址
+	Actions       []string          `json:"actions,omitempty"`        // 拦截的操作: submit/approve/reject,为空时拦截全部
+	Headers       map[string]string `json:"headers,omitempty"`        // 请求头(如认证信息)
+	TimeoutMs     int               `json:"timeout_ms,omitempty"`     // 超时时间(毫秒),默认 5000,最大 30000
+	FailurePolicy string            `json:"failure_policy,omitempty"` // 调用失败时的策略: fail_closed(默认)/fail_open
+}
+
+// PreActionHookRequest 发送给前置钩子的待执行操作
+type PreActionHookRequest struct {
+	Action          string          `json:"action"`            // 操作: submit/approve/reject
+	TaskID          string          `json:"task_id"`           // 任务 ID
+	TemplateID      string          `json:"template_id"`       // 模板 ID
+	TemplateVersion int             `json:"template_version"`  // 模板版本
+	BusinessID      string          `json:"business_id"`       // 业务 ID
+	State           string          `json:"state"`             // 任务当前状态
+	NodeID          string          `json:"node_id,omitempty"` // 审批节点 ID(同意、拒绝)
+	Operator        string          `json:"operator"`          // 操作人
+	Comment         string          `json:"comment,omitempty"` // 审批意见
+	Params          json.RawMessage `json:"params,omitempty"`  // 任务参数
+}
+
+// PreActionHookResponse 前置钩子的响应
+type PreActionHookResponse struct {
+	Decision string          `json:"decision"`        // allow/deny/patch
+	Reason   string          `json:"reason"`          // 拒绝原因
+	Patch    json.RawMessage `json:"patch,omitempty"` // 任务参数的 JSON Merge Patch(RFC 7386)
+}
+
+// preActionHookClient 调用前置钩子的 HTTP 客户端,超时由每次请求的 context 控制
+var preActionHookClient = &http.Client{}
+
+// timeout 获取超时时间
+func (c *PreActionHookConfig) timeout() time.Duration {
+	if c.TimeoutMs <= 0 {
+		return defaultPreActionHookTimeout
+	}
+	return time.Duration(c.TimeoutMs) * time.Millisecond
+}
+
+// intercepts 判断钩子是否拦截指定操作
+func (c *PreActionHookConfig) intercepts(action string) bool {
+	if len(c.Actions) == 0 {
+		return true
+	}
+	for _, a := range c.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验前置钩子配置
+func (c *PreActionHookConfig) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid pre-action hook url %q", c.URL)
+	}
+	for _, action := range c.Actions {
+		switch action {
+		case HookActionSubmit, HookActionApprove, HookActionReject:
+		default:
+			return fmt.Errorf("unsupported pre-action hook action %q", action)
+		}
+	}
+	if c.TimeoutMs < 0 || time.Duration(c.TimeoutMs)*time.Millisecond > maxPreActionHookTimeout {
+		return fmt.Errorf("pre-action hook timeout_ms must be between 0 and %d", maxPreActionHookTimeout.Milliseconds())
+	}
+	switch c.FailurePolicy {
+	case "", HookFailClosed, HookFailOpen:
+	default:
+		return fmt.Errorf("unsupported pre-action hook failure_policy %q", c.FailurePolicy)
+	}
+	return nil
+}
+
+// preActionHookTemplateConfig 模板配置中的前置钩子
+type preActionHookTemplateConfig struct {
+	PreActionHook *PreActionHookConfig `json:"pre_action_hook"`
+}
+
+// ValidatePreActionHook 校验模板原始配置中的前置钩子(模板保存时调用)
+func ValidatePreActionHook(rawConfig json.RawMessage) error {
+	if len(rawConfig) == 0 {
+		return nil
+	}
+	var cfg preActionHookTemplateConfig
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.PreActionHook == nil {
+		return nil
+	}
+	return cfg.PreActionHook.Validate()
+}
+
+// mergePreActionHook 将原始配置中的前置钩子合并到待保存的模板数据中
+func mergePreActionHook(templateMap map[string]interface{}, rawConfig json.RawMessage) error {
+	if len(rawConfig) == 0 {
+		return nil
+	}
+	var cfg preActionHookTemplateConfig
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if cfg.PreActionHook == nil {
+		return nil
+	}
+
+	configMap, _ := templateMap["config"].(map[string]interface{})
+	if configMap == nil {
+		configMap = make(map[string]interface{})
+		templateMap["config"] = configMap
+	}
+	configMap["pre_action_hook"] = cfg.PreActionHook
+	return nil
+}
+
+// loadPreActionHook 加载模板版本的前置钩子配置,未配置时返回 nil
+func loadPreActionHook(db *gorm.DB, templateID string, version int) (*PreActionHookConfig, error) {
+	var tm model.TemplateModel
+	if err := db.Select("data").Where("id = ? AND version = ?", templateID, version).First(&tm).Error; err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+
+	var raw struct {
+		Config preActionHookTemplateConfig `json:"config"`
+	}
+	if err := json.Unmarshal(tm.Data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template: %w", err)
+	}
+	return raw.Config.PreActionHook, nil
+}
+
+// preActionOutcome 事务外调用前置钩子的结果
+type preActionOutcome struct {
+	taskID   string
+	revision int64           // 调用钩子时任务的修订号
+	params   json.RawMessage // 钩子修改后的任务参数,未修改时为 nil
+}
+
+// inTxWithPreActionHook 调用前置钩子后在事务中执行操作
+// 钩子按任务快照在事务外调用,HTTP 调用期间不持有任务行锁;fn 通过 applyPreActionOutcome 应用钩子的结果。
+// params 不为空时代替任务当前参数发送给钩子(重新提交时的新参数)
+func (m *dbTaskManager) inTxWithPreActionHook(id string, action string, nodeID string, operator string, comment string, params json.RawMessage, fn func(txm *dbTaskManager) error) error {
+	outcome, err := m.runPreActionHook(id, action, nodeID, operator, comment, params)
+	if err != nil {
+		return err
+	}
+	mgr := *m
+	mgr.preAction = outcome
+	return mgr.inTx(fn)
+}
+
+// runPreActionHook 按任务快照调用模板配置的前置钩子,未配置或不拦截该操作时返回 nil
+// 钩子拒绝时返回 PreActionDeniedError;钩子返回 patch 时结果中包含修改后的任务参数;
+// 调用失败时按 failure_policy 放行或返回 ErrPreActionHookFailed
+func (m *dbTaskManager) runPreActionHook(id string, action string, nodeID string, operator string, comment string, params json.RawMessage) (*preActionOutcome, error) {
+	tsk, revision, err := m.GetWithRevision(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	hook, err := loadPreActionHook(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil || !hook.intercepts(action) {
+		return nil, nil
+	}
+	if len(params) == 0 {
+		params = tsk.Params
+	}
+
+	outcome := &preActionOutcome{taskID: tsk.ID, revision: revision}
+	resp, err := callPreActionHook(hook, &PreActionHookRequest{
+		Action:          action,
+		TaskID:          tsk.ID,
+		TemplateID:      tsk.TemplateID,
+		TemplateVersion: tsk.TemplateVersion,
+		BusinessID:      tsk.BusinessID,
+		State:           string(tsk.State),
+		NodeID:          nodeID,
+		Operator:        operator,
+		Comment:         comment,
+		Params:          params,
+	})
+	if err != nil {
+		if hook.FailurePolicy == HookFailOpen {
+			log.Printf("pre-action hook for task %q %s failed, allowing: %v", tsk.ID, action, err)
+			return outcome, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrPreActionHookFailed, err)
+	}
+
+	switch resp.Decision {
+	case HookDecisionDeny:
+		return nil, &PreActionDeniedError{Action: action, Reason: resp.Reason}
+	case HookDecisionAllow, HookDecisionPatch:
+		if len(resp.Patch) == 0 || string(resp.Patch) == "null" {
+			return outcome, nil
+		}
+		patched, err := applyMergePatch(params, resp.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid params patch: %v", ErrPreActionHookFailed, err)
+		}
+		outcome.params = patched
+		return outcome, nil
+	default:
+		// 无法识别的决定按调用失败处理
+		if hook.FailurePolicy == HookFailOpen {
+			log.Printf("pre-action hook for task %q %s returned unknown decision %q, allowing", tsk.ID, action, resp.Decision)
+			return outcome, nil
+		}
+		return nil, fmt.Errorf("%w: unknown decision %q", ErrPreActionHookFailed, resp.Decision)
+	}
+}
+
+// applyPreActionOutcome 在事务内应用事务外前置钩子的结果,修改后的参数随本次操作一起保存
+// 任务在钩子调用后被其他操作修改时返回 ErrTaskConflict(钩子的决定基于旧数据)。
+// 引擎在事务内提交的子流程任务没有钩子结果,不经过钩子
+func (m *dbTaskManager) applyPreActionOutcome(tsk *task.Task) error {
+	outcome := m.preAction
+	if outcome == nil || outcome.taskID != tsk.ID {
+		return nil
+	}
+	if revision, loaded := m.loadedRevisions[tsk.ID]; loaded && revision != outcome.revision {
+		return fmt.Errorf("%w: task %q was modified while the pre-action hook was running", ErrTaskConflict, tsk.ID)
+	}
+	if outcome.params != nil {
+		tsk.Params = outcome.params
+	}
+	return nil
+}
+
+// callPreActionHook 发送钩子请求并解析响应
+// 只有 2xx 响应会被解析为决定,其他状态码视为调用失败
+func callPreActionHook(hook *PreActionHookConfig, hookReq *PreActionHookRequest) (*PreActionHookResponse, error) {
+	body, err := json.Marshal(hookReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hook request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hook.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := preActionHookClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("hook returned status code: %d", resp.StatusCode)
+	}
+
+	var hookResp PreActionHookResponse
+	if err := json.Unmarshal(respBody, &hookResp); err != nil {
+		return nil, fmt.Errorf("invalid hook response: %w", err)
+	}
+	hookResp.Decision = strings.ToLower(strings.TrimSpace(hookResp.Decision))
+	return &hookResp, nil
+}
+
+// applyMergePatch 按 JSON Merge Patch(RFC 7386)修改任务参数,结果必须为 JSON 对象
+func applyMergePatch(params json.RawMessage, patch json.RawMessage) (json.RawMessage, error) {
+	var target interface{}
+	if len(params) > 0 {
+		if err := decodeJSONNumber(params, &target); err != nil {
+			return nil, err
+		}
+	}
+	var patchValue interface{}
+	if err := decodeJSONNumber(patch, &patchValue); err != nil {
+		return nil, err
+	}
+
+	merged, ok := mergePatchValue(target, patchValue).(map[string]interface{})
+	if !ok {
+		return nil, errors.New("patched params must be a JSON object")
+	}
+	return json.Marshal(merged)
+}
+
+// mergePatchValue 合并单个值: patch 为对象时逐字段合并(null 表示删除),否则整体替换
+func mergePatchValue(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatchValue(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// decodeJSONNumber 解码 JSON,数字保留为 json.Number 以免精度丢失
+func decodeJSONNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,137 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		patch  string
+		want   string
+	}{
+		{"replace value", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add value", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"remove value", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":["c"]}`, `{"a":["c"]}`},
+		{"replace array with object", `{"a":["b"]}`, `{"a":{"b":"c"}}`, `{"a":{"b":"c"}}`},
+		{"merge nested object", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"x","d":null}}`, `{"a":{"b":"x"}}`},
+		{"create nested object", `{"e":null}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}},"e":null}`},
+		{"empty params", ``, `{"a":1}`, `{"a":1}`},
+		{"empty patch", `{"a":1}`, `{}`, `{"a":1}`},
+		{"large numbers keep precision", `{"id":9007199254740993}`, `{"amount":12345678901234567890}`, `{"amount":12345678901234567890,"id":9007199254740993}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyMergePatch(json.RawMessage(tt.params), json.RawMessage(tt.patch))
+			mustNoError(t, err)
+			if string(got) != tt.want {
+				t.Fatalf("patched = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatchRequiresObject(t *testing.T) {
+	for _, patch := range []string{`["a"]`, `"a"`, `null`, `{`} {
+		if _, err := applyMergePatch(json.RawMessage(`{"a":1}`), json.RawMessage(patch)); err == nil {
+			t.Errorf("patch %s: expected error", patch)
+		}
+	}
+}
+
+// hookServer 前置钩子测试服务,返回固定的响应并记录收到的请求
+type hookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*PreActionHookRequest
+}
+
+func newHookServer(t *testing.T, status int, response string) *hookServer {
+	t.Helper()
+	s := &hookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req PreActionHookRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		s.mu.Lock()
+		s.requests = append(s.requests, &req)
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// hookTemplate 创建配置了前置钩子的单节点审批模板
+func hookTemplate(t *testing.T, env *testEnv, url string, extra string) {
+	t.Helper()
+	hook := `{"url":"` + url + `","actions":["approve"]`
+	if extra != "" {
+		hook += "," + extra
+	}
+	env.createTemplateWithConfig(t, "hooked",
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("review", []string{"ann"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`,
+		`{"pre_action_hook":`+hook+`}}`)
+}
+
+func TestPreActionHookPatchesParams(t *testing.T) {
+	env := newTestEnv(t)
+	server := newHookServer(t, http.StatusOK, `{"decision":"patch","patch":{"budget_code":"B-1","draft":null}}`)
+	hookTemplate(t, env, server.URL, "")
+
+	tsk := env.startTask(t, "hooked", `{"amount":100,"draft":true}`)
+	mustNoError(t, env.tasks.Approve(tsk.ID, "review", "ann", "ok"))
+
+	got := env.getTask(t, tsk.ID)
+	if string(got.Params) != `{"amount":100,"budget_code":"B-1"}` {
+		t.Fatalf("params = %s", got.Params)
+	}
+	if len(server.requests) != 1 {
+		t.Fatalf("hook called %d times, want 1 (submit is not intercepted)", len(server.requests))
+	}
+	req := server.requests[0]
+	if req.Action != HookActionApprove || req.NodeID != "review" || req.Operator != "ann" || req.Comment != "ok" {
+		t.Fatalf("unexpected hook request: %+v", req)
+	}
+}
+
+func TestPreActionHookDenies(t *testing.T) {
+	env := newTestEnv(t)
+	server := newHookServer(t, http.StatusOK, `{"decision":"deny","reason":"budget exhausted"}`)
+	hookTemplate(t, env, server.URL, "")
+
+	tsk := env.startTask(t, "hooked", `{}`)
+	err := env.tasks.Approve(tsk.ID, "review", "ann", "")
+	var denied *PreActionDeniedError
+	if !errors.As(err, &denied) || denied.Reason != "budget exhausted" || !errors.Is(err, ErrPreActionDenied) {
+		t.Fatalf("expected PreActionDeniedError, got %v", err)
+	}
+	if approvals := env.getTask(t, tsk.ID).Approvals["review"]; len(approvals) != 0 {
+		t.Fatalf("denied approval was saved: %v", approvals)
+	}
+}
+
+func TestPreActionHookFailurePolicy(t *testing.T) {
+	closed := newTestEnv(t)
+	failing := newHookServer(t, http.StatusInternalServerError, `oops`)
+	hookTemplate(t, closed, failing.URL, "")
+	tsk := closed.startTask(t, "hooked", `{}`)
+	if err := closed.tasks.Approve(tsk.ID, "review", "ann", ""); !errors.Is(err, ErrPreActionHookFailed) {
+		t.Fatalf("expected ErrPreActionHookFailed, got %v", err)
+	}
+
+	open := newTestEnv(t)
+	hookTemplate(t, open, failing.URL, `"failure_policy":"fail_open"`)
+	tsk = open.startTask(t, "hooked", `{}`)
+	mustNoError(t, open.tasks.Approve(tsk.ID, "review", "ann", ""))
+}
@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsPublisher NATS JetStream 事件发布器
+// 消息通过 JetStream 发布,收到流的发布确认(PubAck)后才返回,
+// 主题 "<前缀>.>" 需要被某个流捕获,否则发布失败并由 outbox 重试
+type natsPublisher struct {
+	mu      sync.Mutex
+	url     *url.URL
+	subject string
+	timeout time.Duration
+	conn    *nats.Conn
+	js      jetstream.JetStream
+}
+
+// newNATSPublisher 创建 NATS 事件发布器
+// 连接在第一次发布时建立,消息总线不可用时不影响服务启动,事件由 outbox 重试
+func newNATSPublisher(rawURL string, subject string, timeout time.Duration) (*natsPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats url: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid nats url scheme: %q", u.Scheme)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	return &natsPublisher{
+		url:     u,
+		subject: subject,
+		timeout: timeout,
+	}, nil
+}
+
+// jetStream 返回 JetStream 上下文,未连接或连接已关闭时重新连接
+// 连接建立后断线重连由客户端自动完成
+func (p *natsPublisher) jetStream() (jetstream.JetStream, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && !p.conn.IsClosed() {
+		return p.js, nil
+	}
+	conn, err := nats.Connect(p.url.String(),
+		nats.Name("approval-gin"),
+		nats.Timeout(p.timeout),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+	p.conn = conn
+	p.js = js
+	return js, nil
+}
+
+// Publish 发布消息到 "<前缀>.<任务 ID>" 并等待 JetStream 确认
+// 消息头 Nats-Msg-Id 为事件 ID(JetStream 在去重窗口内据此去重),Approval-Ordering-Key 为任务 ID
+func (p *natsPublisher) Publish(ctx context.Context, msg *BusMessage) error {
+	js, err := p.jetStream()
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	natsMsg := nats.NewMsg(p.subject + "." + natsToken(msg.Key))
+	natsMsg.Header.Set("Approval-Ordering-Key", msg.Key)
+	natsMsg.Header.Set("Approval-Event-Type", msg.Type)
+	natsMsg.Header.Set("Content-Type", "application/json")
+	natsMsg.Data = msg.Data
+
+	// 重复发布(上次已写入流但未收到确认)时 PubAck.Duplicate 为 true,同样视为成功
+	if _, err := js.PublishMsg(ctx, natsMsg, jetstream.WithMsgID(msg.ID)); err != nil {
+		return fmt.Errorf("failed to publish event %q to nats: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Name 发布目标描述(不包含认证信息)
+func (p *natsPublisher) Name() string {
+	return fmt.Sprintf("%s://%s/%s", p.url.Scheme, p.url.Host, p.subject)
+}
+
+// Close 关闭连接
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	p.conn = nil
+	p.js = nil
+	return nil
+}
+
+// natsToken 将任务 ID 转换为合法的主题片段(替换分隔符、通配符和空白字符)
+func natsToken(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const testNATSSubject = "approval.events"
+
+// runJetStreamServer 启动开启 JetStream 的内嵌 NATS 服务,测试结束时关闭
+func runJetStreamServer(t *testing.T) string {
+	t.Helper()
+	opts := natsserver.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	server := natsserver.RunServer(&opts)
+	t.Cleanup(server.Shutdown)
+	return server.ClientURL()
+}
+
+// newTestNATSPublisher 创建连接内嵌服务的发布器和用于检查流的 JetStream 上下文
+func newTestNATSPublisher(t *testing.T, url string) (*natsPublisher, jetstream.JetStream) {
+	t.Helper()
+	publisher, err := newNATSPublisher(url, testNATSSubject, 5*time.Second)
+	mustNoError(t, err)
+	t.Cleanup(func() { publisher.Close() })
+	js, err := publisher.jetStream()
+	mustNoError(t, err)
+	return publisher, js
+}
+
+// createTestStream 创建捕获 "<前缀>.>" 的流
+func createTestStream(t *testing.T, js jetstream.JetStream) jetstream.Stream {
+	t.Helper()
+	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{
+		Name:     "APPROVAL_EVENTS",
+		Subjects: []string{testNATSSubject + ".>"},
+	})
+	mustNoError(t, err)
+	return stream
+}
+
+// streamMessages 按流中的顺序返回所有消息
+func streamMessages(t *testing.T, stream jetstream.Stream) []*jetstream.RawStreamMsg {
+	t.Helper()
+	info, err := stream.Info(context.Background())
+	mustNoError(t, err)
+	var msgs []*jetstream.RawStreamMsg
+	for seq := info.State.FirstSeq; seq <= info.State.LastSeq && info.State.Msgs > 0; seq++ {
+		msg, err := stream.GetMsg(context.Background(), seq)
+		mustNoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+// deliverAll 领取并投递所有可投递的事件
+func deliverAll(t *testing.T, h *dbEventHandler, at time.Time) {
+	t.Helper()
+	for eventModel := claim(t, h, at); eventModel != nil; eventModel = claim(t, h, at) {
+		h.deliver(eventModel)
+	}
+}
+
+func TestNATSPublisherKeepsTaskOrder(t *testing.T) {
+	publisher, js := newTestNATSPublisher(t, runJetStreamServer(t))
+	stream := createTestStream(t, js)
+
+	db := newTestDB(t)
+	h := newTestEventHandler(db, EventDeliveryOptions{Publisher: publisher})
+	want := map[string][]string{}
+	for _, taskID := range []string{"task-a", "task-b", "task-a", "task-a", "task-b"} {
+		want[taskID] = append(want[taskID], saveTestEvent(t, h, taskID))
+	}
+	deliverAll(t, h, time.Now().Add(time.Second))
+
+	got := map[string][]string{}
+	for _, msg := range streamMessages(t, stream) {
+		key := msg.Header.Get("Approval-Ordering-Key")
+		if msg.Subject != testNATSSubject+"."+key {
+			t.Fatalf("message for %q published to %q", key, msg.Subject)
+		}
+		if msg.Header.Get("Approval-Event-Type") != string(EventTaskCreated) {
+			t.Fatalf("event type header = %q", msg.Header.Get("Approval-Event-Type"))
+		}
+		got[key] = append(got[key], msg.Header.Get("Nats-Msg-Id"))
+	}
+	for taskID, ids := range want {
+		assertEqualStrings(t, got[taskID], ids)
+	}
+
+	// 未收到确认时重新发布的事件被 JetStream 去重
+	first := want["task-a"][0]
+	eventModel, err := h.eventRepo.FindByID(first)
+	mustNoError(t, err)
+	mustNoError(t, h.publish(eventModel))
+	info, err := stream.Info(context.Background())
+	mustNoError(t, err)
+	if info.State.Msgs != 5 {
+		t.Fatalf("stream has %d messages after republishing, want 5", info.State.Msgs)
+	}
+}
+
+func TestNATSPublishFailureKeepsEventUnreleased(t *testing.T) {
+	// JetStream 没有捕获该主题的流,发布得不到确认
+	publisher, js := newTestNATSPublisher(t, runJetStreamServer(t))
+
+	db := newTestDB(t)
+	h := newTestEventHandler(db, EventDeliveryOptions{
+		Publisher:   publisher,
+		BackoffBase: time.Hour,
+		BackoffMax:  time.Hour,
+	})
+	first := saveTestEvent(t, h, "task-a")
+	second := saveTestEvent(t, h, "task-a")
+
+	now := time.Now().Add(time.Second)
+	eventModel := claim(t, h, now)
+	if claimedID(eventModel) != first {
+		t.Fatalf("claimed %q, want %q", claimedID(eventModel), first)
+	}
+	h.deliver(eventModel)
+
+	failed, err := h.eventRepo.FindByID(first)
+	mustNoError(t, err)
+	if failed.Status != model.EventStatusFailed || failed.Released || failed.LastError == "" {
+		t.Fatalf("after failed publish: status=%s released=%v error=%q", failed.Status, failed.Released, failed.LastError)
+	}
+	// 未发布的事件阻塞同一任务的后续事件
+	if got := claim(t, h, now); got != nil {
+		t.Fatalf("claimed %q while the first event is unreleased", got.ID)
+	}
+
+	// 创建流后重试成功,后续事件按顺序发布
+	stream := createTestStream(t, js)
+	deliverAll(t, h, time.Now().Add(2*time.Hour))
+	for _, id := range []string{first, second} {
+		delivered, err := h.eventRepo.FindByID(id)
+		mustNoError(t, err)
+		if delivered.Status != model.EventStatusSuccess || !delivered.Released {
+			t.Fatalf("event %q: status=%s released=%v", id, delivered.Status, delivered.Released)
+		}
+	}
+	var ids []string
+	for _, msg := range streamMessages(t, stream) {
+		ids = append(ids, msg.Header.Get("Nats-Msg-Id"))
+	}
+	assertEqualStrings(t, ids, []string{first, second})
+}
@@ -0,0 +1,142 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func busMessage(id string, key string) *BusMessage {
+	return &BusMessage{ID: id, Key: key, Type: "task_approved", Data: []byte(`{}`), Time: time.Now()}
+}
+
+// publishAsync 在后台发布消息,返回发布结果通道
+func publishAsync(ctx context.Context, p EventPublisher, msg *BusMessage) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- p.Publish(ctx, msg) }()
+	return result
+}
+
+// waitResult 等待后台操作结束,超时视为被阻塞
+func waitResult(t *testing.T, result <-chan error, what string) error {
+	t.Helper()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s is blocked", what)
+		return nil
+	}
+}
+
+func TestMemoryPublisherDeliversInOrder(t *testing.T) {
+	p := NewMemoryPublisher()
+	defer p.Close()
+	first, cancelFirst := p.Subscribe(10)
+	defer cancelFirst()
+	second, cancelSecond := p.Subscribe(10)
+	defer cancelSecond()
+
+	for i := 0; i < 5; i++ {
+		mustNoError(t, p.Publish(context.Background(), busMessage(fmt.Sprintf("evt-%d", i), "task-1")))
+	}
+	for _, ch := range []<-chan *BusMessage{first, second} {
+		for i := 0; i < 5; i++ {
+			if msg := <-ch; msg.ID != fmt.Sprintf("evt-%d", i) {
+				t.Fatalf("message %d = %q", i, msg.ID)
+			}
+		}
+	}
+}
+
+func TestMemoryPublisherSlowSubscriberTimesOut(t *testing.T) {
+	p := NewMemoryPublisher()
+	defer p.Close()
+	_, cancel := p.Subscribe(0)
+	defer cancel()
+
+	ctx, stop := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer stop()
+	if err := p.Publish(ctx, busMessage("evt-1", "task-1")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestMemoryPublisherCancelDoesNotWaitForBlockedPublish(t *testing.T) {
+	p := NewMemoryPublisher()
+	defer p.Close()
+	ch, cancel := p.Subscribe(0)
+
+	published := publishAsync(context.Background(), p, busMessage("evt-1", "task-1"))
+	time.Sleep(20 * time.Millisecond)
+
+	// 订阅者一直不消费,取消订阅和之后的发布都不能被阻塞
+	cancelled := make(chan error, 1)
+	go func() { cancel(); cancelled <- nil }()
+	waitResult(t, cancelled, "cancel")
+	mustNoError(t, waitResult(t, published, "publish"))
+	if _, open := <-ch; open {
+		t.Fatal("expected cancelled subscription channel to be closed")
+	}
+
+	other, cancelOther := p.Subscribe(1)
+	defer cancelOther()
+
+	mustNoError(t, waitResult(t, publishAsync(context.Background(), p, busMessage("evt-2", "task-1")), "publish"))
+	if msg := <-other; msg.ID != "evt-2" {
+		t.Fatalf("other subscriber got %q", msg.ID)
+	}
+}
+
+func TestMemoryPublisherCloseDoesNotWaitForBlockedPublish(t *testing.T) {
+	p := NewMemoryPublisher()
+	ch, cancel := p.Subscribe(0)
+	defer cancel()
+
+	published := publishAsync(context.Background(), p, busMessage("evt-1", "task-1"))
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- p.Close() }()
+	mustNoError(t, waitResult(t, closed, "close"))
+	mustNoError(t, waitResult(t, published, "publish"))
+	if _, open := <-ch; open {
+		t.Fatal("expected subscription channel to be closed")
+	}
+
+	if err := p.Publish(context.Background(), busMessage("evt-2", "task-1")); err == nil {
+		t.Fatal("expected publish after close to fail")
+	}
+	if _, open := <-mustSubscribe(p); open {
+		t.Fatal("expected subscription after close to be closed")
+	}
+}
+
+func mustSubscribe(p *MemoryPublisher) <-chan *BusMessage {
+	ch, _ := p.Subscribe(1)
+	return ch
+}
+
+func TestNewEventPublisher(t *testing.T) {
+	publisher, err := NewEventPublisher(PublisherOptions{})
+	mustNoError(t, err)
+	if publisher != nil {
+		t.Fatalf("expected no publisher, got %T", publisher)
+	}
+
+	publisher, err = NewEventPublisher(PublisherOptions{Backend: PublisherMemory})
+	mustNoError(t, err)
+	if _, ok := publisher.(*MemoryPublisher); !ok {
+		t.Fatalf("expected memory publisher, got %T", publisher)
+	}
+	publisher.Close()
+
+	if _, err := NewEventPublisher(PublisherOptions{Backend: PublisherNATS}); err == nil {
+		t.Fatal("expected nats publisher without url to fail")
+	}
+	if _, err := NewEventPublisher(PublisherOptions{Backend: "kafka"}); err == nil {
+		t.Fatal("expected unsupported backend to fail")
+	}
+}
@@ -0,0 +1,243 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/template"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// 审批节点被驳回后的处理方式
+const (
+	// RejectActionTerminate 结束任务(默认)
+	RejectActionTerminate = "terminate"
+	// RejectActionPrevious 驳回到上一个已完成的审批节点,没有时退回发起人
+	RejectActionPrevious = "previous"
+	// RejectActionInitiator 退回发起人修改,按节点的 return_policy 重新提交
+	RejectActionInitiator = "initiator"
+	// RejectActionChoice 由审批人从已完成的审批节点中选择驳回目标
+	RejectActionChoice = "choice"
+)
+
+// RoundKindReject 审批轮次因驳回到之前的节点或发起人结束
+const RoundKindReject = "reject"
+
+// ErrInvalidRejectTarget 驳回目标节点无效
+var ErrInvalidRejectTarget = errors.New("invalid reject target")
+
+// rejectAction 审批节点被驳回后执行的动作
+// 从节点原始 config 的 reject_action 中解析
+type rejectAction struct {
+	Mode    string   `json:"mode,omitempty"`    // 驳回方式: terminate/previous/initiator/choice
+	Targets []string `json:"targets,omitempty"` // choice: 允许选择的目标节点,为空时可以选择任一已完成的上游审批节点
+}
+
+// rejectActionConfig 审批节点中驳回动作相关的配置
+type rejectActionConfig struct {
+	RejectAction *rejectAction `json:"reject_action,omitempty"`
+}
+
+// rejectActionFor 解析节点配置的驳回动作,未配置时驳回即结束任务
+func (f *flowDefinition) rejectActionFor(nodeID string) (*rejectAction, error) {
+	action := &rejectAction{}
+	if node, exists := f.Nodes[nodeID]; exists && node != nil && len(node.Config) > 0 && string(node.Config) != "null" {
+		var cfg rejectActionConfig
+		if err := json.Unmarshal(node.Config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid reject action for node %q: %w", nodeID, err)
+		}
+		if cfg.RejectAction != nil {
+			action = cfg.RejectAction
+		}
+	}
+	if action.Mode == "" {
+		action.Mode = RejectActionTerminate
+	}
+	switch action.Mode {
+	case RejectActionTerminate, RejectActionPrevious, RejectActionInitiator:
+		if len(action.Targets) > 0 {
+			return nil, fmt.Errorf("node %q: reject_action.targets is only allowed in choice mode", nodeID)
+		}
+	case RejectActionChoice:
+		for _, target := range action.Targets {
+			if f.nodeType(target) != string(template.NodeTypeApproval) {
+				return nil, fmt.Errorf("node %q: reject target %q is not an approval node", nodeID, target)
+			}
+			if !f.reaches(target, nodeID) {
+				return nil, fmt.Errorf("node %q: reject target %q is not upstream of the node", nodeID, target)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("node %q: unknown reject_action.mode %q", nodeID, action.Mode)
+	}
+	return action, nil
+}
+
+// target 确定驳回的目标节点
+// 返回空字符串表示退回发起人;requested 为审批人选择的目标,只在 choice 方式下允许
+func (a *rejectAction) target(tsk *task.Task, flow *flowDefinition, nodeID string, requested string) (string, error) {
+	if requested != "" && a.Mode != RejectActionChoice {
+		return "", fmt.Errorf("%w: node %q does not allow choosing a reject target", ErrInvalidRejectTarget, nodeID)
+	}
+
+	switch a.Mode {
+	case RejectActionPrevious:
+		// 最近完成的上游审批节点
+		for i := len(tsk.CompletedNodes) - 1; i >= 0; i-- {
+			candidate := tsk.CompletedNodes[i]
+			if candidate != nodeID && flow.nodeType(candidate) == string(template.NodeTypeApproval) && flow.reaches(candidate, nodeID) {
+				return candidate, nil
+			}
+		}
+		return "", nil
+	case RejectActionChoice:
+		if requested == "" {
+			return "", fmt.Errorf("%w: node %q requires a reject target", ErrInvalidRejectTarget, nodeID)
+		}
+		if len(a.Targets) > 0 && !containsString(a.Targets, requested) {
+			return "", fmt.Errorf("%w: node %q is not an allowed reject target", ErrInvalidRejectTarget, requested)
+		}
+		if requested == nodeID || flow.nodeType(requested) != string(template.NodeTypeApproval) || !flow.reaches(requested, nodeID) {
+			return "", fmt.Errorf("%w: node %q is not an upstream approval node", ErrInvalidRejectTarget, requested)
+		}
+		if !containsString(tsk.CompletedNodes, requested) {
+			return "", fmt.Errorf("%w: node %q has not been completed", ErrInvalidRejectTarget, requested)
+		}
+		return requested, nil
+	}
+	return "", nil
+}
+
+// resolve 确定节点被驳回时的驳回方式和目标节点,previous 方式没有上一个审批节点时退回发起人
+func (a *rejectAction) resolve(tsk *task.Task, flow *flowDefinition, nodeID string, requested string) (string, string, error) {
+	target, err := a.target(tsk, flow, nodeID, requested)
+	if err != nil {
+		return "", "", err
+	}
+	if a.Mode == RejectActionPrevious && target == "" {
+		return RejectActionInitiator, "", nil
+	}
+	return a.Mode, target, nil
+}
+
+// resolveAutomatic 确定没有审批人选择目标时(如超时自动驳回)的驳回方式和目标节点
+// choice 方式驳回到最近完成的可选目标节点;没有可驳回的节点时退回发起人
+func (a *rejectAction) resolveAutomatic(tsk *task.Task, flow *flowDefinition, nodeID string) (string, string) {
+	if a.Mode != RejectActionPrevious && a.Mode != RejectActionChoice {
+		return a.Mode, ""
+	}
+	for i := len(tsk.CompletedNodes) - 1; i >= 0; i-- {
+		candidate := tsk.CompletedNodes[i]
+		if a.Mode == RejectActionChoice && len(a.Targets) > 0 && !containsString(a.Targets, candidate) {
+			continue
+		}
+		if candidate != nodeID && flow.nodeType(candidate) == string(template.NodeTypeApproval) && flow.reaches(candidate, nodeID) {
+			return a.Mode, candidate
+		}
+	}
+	return RejectActionInitiator, ""
+}
+
+// applyRejectAction 节点被驳回后按驳回方式处理
+// initiator 退回发起人;previous、choice 驳回到 target 节点;terminate 结束任务(转换为 rejected),
+// 所有活动节点(包括其他并行分支)一起失效,被驳回节点的输出为 output
+func (m *dbTaskManager) applyRejectAction(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, mode string, target string, operator string, comment string, reason string, output json.RawMessage) (*task.Task, error) {
+	switch mode {
+	case RejectActionInitiator:
+		return m.returnTask(tsk, rt, flow, nodeID, operator, comment, RoundKindReject)
+	case RejectActionPrevious, RejectActionChoice:
+		if err := m.sendBack(tsk, rt, flow, nodeID, target, operator, comment); err != nil {
+			return nil, err
+		}
+		return tsk, nil
+	}
+
+	if !m.stateMachine.CanTransition(tsk.State, types.TaskStateRejected) {
+		return nil, fmt.Errorf("invalid state transition: cannot reject task in state %q", tsk.State)
+	}
+	adapter := &taskAdapter{task: tsk}
+	oldState := tsk.State
+	newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateRejected, reason)
+	if err != nil {
+		return nil, fmt.Errorf("state transition failed: %w", err)
+	}
+	tsk = newTaskAdapter.(*taskAdapter).task
+	if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, reason, operator); err != nil {
+		return nil, fmt.Errorf("failed to save state history: %w", err)
+	}
+
+	for _, activeNodeID := range append([]string{}, rt.ActiveNodes...) {
+		m.detachNode(rt, activeNodeID)
+	}
+	markNodeCompleted(tsk, nodeID)
+	setNodeOutput(tsk, nodeID, output)
+	return tsk, nil
+}
+
+// emitRejectAction 生成驳回动作的事件(退回发起人或驳回到之前的节点)
+func (m *dbTaskManager) emitRejectAction(tsk *task.Task, nodeID string, mode string, target string, operator string, comment string) {
+	switch mode {
+	case RejectActionInitiator:
+		m.emit(EventTaskReturned, tsk, nodeID, operator, "reject", comment)
+	case RejectActionPrevious, RejectActionChoice:
+		m.emit(EventTaskSentBack, tsk, target, operator, "reject", comment)
+	}
+}
+
+// sendBack 将任务驳回到之前完成的审批节点重新审批
+// 本轮数据归档后开始新一轮审批:目标节点及其下游节点的审批结果、节点输出和汇聚记录清空(审批记录保留),
+// 然后重新激活目标节点;与目标节点无关的并行分支不受影响
+func (m *dbTaskManager) sendBack(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, targetNodeID string, operator string, comment string) error {
+	if err := m.archiveRound(tsk, rt, RoundKindReject, nodeID, operator, comment); err != nil {
+		return err
+	}
+	rt.Round = rt.round() + 1
+
+	downstream := func(id string) bool {
+		return id == targetNodeID || flow.reaches(targetNodeID, id)
+	}
+	for _, activeNodeID := range append([]string{}, rt.ActiveNodes...) {
+		if downstream(activeNodeID) {
+			m.detachNode(rt, activeNodeID)
+		}
+	}
+	for joinID, sources := range rt.JoinArrivals {
+		remaining := make([]string, 0, len(sources))
+		for _, source := range sources {
+			if !downstream(source) {
+				remaining = append(remaining, source)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(rt.JoinArrivals, joinID)
+		} else {
+			rt.JoinArrivals[joinID] = remaining
+		}
+	}
+
+	completed := make([]string, 0, len(tsk.CompletedNodes))
+	for _, completedNodeID := range tsk.CompletedNodes {
+		if !downstream(completedNodeID) {
+			completed = append(completed, completedNodeID)
+		}
+	}
+	tsk.CompletedNodes = completed
+	for approvalNodeID := range tsk.Approvals {
+		if downstream(approvalNodeID) {
+			delete(tsk.Approvals, approvalNodeID)
+		}
+	}
+	for outputNodeID := range tsk.NodeOutputs {
+		if downstream(outputNodeID) {
+			delete(tsk.NodeOutputs, outputNodeID)
+		}
+	}
+
+	if err := m.enterNode(tsk, rt, flow, "", targetNodeID, 0); err != nil {
+		return fmt.Errorf("failed to activate node %q: %w", targetNodeID, err)
+	}
+	syncCurrentNode(tsk, rt)
+	return nil
+}
@@ -0,0 +1,140 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// threeStepTemplate 创建 start -> first(ann) -> second(bob) -> third(cat) -> end 的模板,thirdExtra 为 third 节点的额外配置
+func threeStepTemplate(t *testing.T, env *testEnv, id string, thirdExtra string) {
+	t.Helper()
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("first", []string{"ann"}, "")+`,
+		`+approvalNode("second", []string{"bob"}, "")+`,
+		`+approvalNode("third", []string{"cat"}, thirdExtra)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"first"},{"from":"first","to":"second"},{"from":"second","to":"third"},{"from":"third","to":"end"}]`)
+}
+
+// startAtThird 启动任务并通过 first、second 节点
+func (e *testEnv) startAtThird(t *testing.T, templateID string) string {
+	t.Helper()
+	taskID := e.startTask(t, templateID, `{}`).ID
+	mustNoError(t, e.tasks.Approve(taskID, "first", "ann", ""))
+	mustNoError(t, e.tasks.Approve(taskID, "second", "bob", ""))
+	return taskID
+}
+
+func TestRejectToPreviousNode(t *testing.T) {
+	env := newTestEnv(t)
+	threeStepTemplate(t, env, "previous", `"reject_action":{"mode":"previous"}`)
+	taskID := env.startAtThird(t, "previous")
+
+	mustNoError(t, env.tasks.Reject(taskID, "third", "cat", "recheck"))
+	tsk := env.getTask(t, taskID)
+	if tsk.State != types.TaskStateApproving {
+		t.Fatalf("state = %s, want %s", tsk.State, types.TaskStateApproving)
+	}
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"second"})
+	// 目标节点之前的结果保留,目标节点及下游的结果清空
+	if tsk.Approvals["first"]["ann"] == nil || len(tsk.Approvals["second"]) != 0 || len(tsk.Approvals["third"]) != 0 {
+		t.Fatalf("unexpected approvals after sending back: %v", tsk.Approvals)
+	}
+	rounds, err := repository.NewTaskRoundRepository(env.db).FindByTaskID(taskID)
+	mustNoError(t, err)
+	if len(rounds) != 1 || rounds[0].Kind != RoundKindReject || rounds[0].NodeID != "third" {
+		t.Fatalf("unexpected archived rounds: %+v", rounds)
+	}
+	if round, err := env.tasks.CurrentRound(taskID); err != nil || round != 2 {
+		t.Fatalf("current round = %d, %v, want 2", round, err)
+	}
+	last := env.events.events[len(env.events.events)-1]
+	if last.Type != EventTaskSentBack || last.Node == nil || last.Node.ID != "second" {
+		t.Fatalf("last event = %s on %+v, want %s on second", last.Type, last.Node, EventTaskSentBack)
+	}
+
+	mustNoError(t, env.tasks.Approve(taskID, "second", "bob", ""))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"third"})
+}
+
+func TestRejectToChosenNode(t *testing.T) {
+	env := newTestEnv(t)
+	threeStepTemplate(t, env, "choice", `"reject_action":{"mode":"choice","targets":["first"]}`)
+	taskID := env.startAtThird(t, "choice")
+
+	for target, why := range map[string]string{
+		"":       "missing target",
+		"second": "target not allowed",
+		"end":    "not an approval node",
+	} {
+		if err := env.tasks.RejectToNode(taskID, "third", "cat", "", target, nil); !errors.Is(err, ErrInvalidRejectTarget) {
+			t.Errorf("%s: error = %v, want %v", why, err, ErrInvalidRejectTarget)
+		}
+	}
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"third"})
+
+	mustNoError(t, env.tasks.RejectToNode(taskID, "third", "cat", "restart", "first", nil))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"first"})
+	if approvals := env.getTask(t, taskID).Approvals; len(approvals["first"]) != 0 || len(approvals["second"]) != 0 {
+		t.Fatalf("unexpected approvals after sending back: %v", approvals)
+	}
+
+	// 非 choice 方式不能选择驳回目标
+	threeStepTemplate(t, env, "terminate", "")
+	other := env.startAtThird(t, "terminate")
+	if err := env.tasks.RejectToNode(other, "third", "cat", "", "first", nil); !errors.Is(err, ErrInvalidRejectTarget) {
+		t.Fatalf("error = %v, want %v", err, ErrInvalidRejectTarget)
+	}
+}
+
+func TestRejectToInitiator(t *testing.T) {
+	env := newTestEnv(t)
+	threeStepTemplate(t, env, "initiator", `"reject_action":{"mode":"initiator"}`)
+	taskID := env.startAtThird(t, "initiator")
+
+	mustNoError(t, env.tasks.Reject(taskID, "third", "cat", "redo"))
+	if got := env.getTask(t, taskID).State; got != types.TaskStatePending {
+		t.Fatalf("state = %s, want %s", got, types.TaskStatePending)
+	}
+	returned, err := env.tasks.Returned(taskID)
+	mustNoError(t, err)
+	if returned == nil || returned.NodeID != "third" || returned.ReturnedBy != "cat" {
+		t.Fatalf("unexpected return info: %+v", returned)
+	}
+	rounds, err := repository.NewTaskRoundRepository(env.db).FindByTaskID(taskID)
+	mustNoError(t, err)
+	if len(rounds) != 1 || rounds[0].Kind != RoundKindReject {
+		t.Fatalf("unexpected archived rounds: %+v", rounds)
+	}
+	mustNoError(t, env.tasks.Resubmit(taskID, nil, ""))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"first"})
+
+	// previous 方式没有上一个审批节点时退回发起人
+	env.createTemplate(t, "first-only",
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("review", []string{"ann"}, `"reject_action":{"mode":"previous"}`)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+	first := env.startTask(t, "first-only", `{}`).ID
+	mustNoError(t, env.tasks.Reject(first, "review", "ann", ""))
+	if got := env.getTask(t, first).State; got != types.TaskStatePending {
+		t.Fatalf("state = %s, want %s", got, types.TaskStatePending)
+	}
+}
+
+func TestTimeoutRejectAppliesRejectAction(t *testing.T) {
+	env := newTestEnv(t)
+	threeStepTemplate(t, env, "timeout",
+		`"reject_action":{"mode":"choice"},"timeout_policy":{"after":"1h","action":"`+TimeoutActionAutoReject+`"}`)
+	taskID := env.startAtThird(t, "timeout")
+	env.activateAt(t, taskID, "third", time.Now().Add(-2*time.Hour))
+
+	// 超时自动驳回没有审批人选择目标,驳回到最近完成的审批节点
+	mustNoError(t, env.tasks.HandleTimeout(taskID))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"second"})
+}
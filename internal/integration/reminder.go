@@ -0,0 +1,346 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/template"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// maxReminderSnooze 单次推迟提醒的最长时间
+const maxReminderSnooze = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidSnooze 推迟提醒的参数无效(时间不在允许范围内或节点没有提醒策略)
+	ErrInvalidSnooze = errors.New("invalid reminder snooze")
+	// ErrNotPendingApprover 用户不是节点上待审批的审批人
+	ErrNotPendingApprover = errors.New("user is not a pending approver of the node")
+)
+
+// reminderPolicy 审批节点的提醒策略
+// 从节点原始 config 的 reminder_policy 中解析,例如节点激活 4 小时后首次提醒,之后每 24 小时提醒一次,最多 3 次
+type reminderPolicy struct {
+	FirstAfter string `json:"first_after"`         // 节点激活后首次提醒的时间(Go duration 格式)
+	Interval   string `json:"interval,omitempty"`  // 之后每次提醒的间隔,为空时只提醒一次
+	MaxCount   int    `json:"max_count,omitempty"` // 每位审批人最多提醒次数,为 0 时不限制
+
+	firstAfter time.Duration
+	interval   time.Duration
+}
+
+// reminderPolicyConfig 审批节点中提醒策略相关的配置
+type reminderPolicyConfig struct {
+	ReminderPolicy *reminderPolicy `json:"reminder_policy,omitempty"`
+}
+
+// ReminderState 审批人在节点上的提醒状态
+type ReminderState struct {
+	Count        int        `json:"count"`                   // 已提醒次数
+	LastAt       *time.Time `json:"last_at,omitempty"`       // 最近一次提醒时间
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"` // 推迟到该时间之后再提醒
+}
+
+// reminderPolicyFor 解析审批节点配置的提醒策略,未配置时返回 nil
+func (f *flowDefinition) reminderPolicyFor(nodeID string) (*reminderPolicy, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || node.Type != string(template.NodeTypeApproval) || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, nil
+	}
+	var cfg reminderPolicyConfig
+	if err := json.Unmarshal(node.Config, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid reminder policy for node %q: %w", nodeID, err)
+	}
+	if cfg.ReminderPolicy == nil {
+		return nil, nil
+	}
+	if err := cfg.ReminderPolicy.normalize(); err != nil {
+		return nil, fmt.Errorf("node %q: %w", nodeID, err)
+	}
+	return cfg.ReminderPolicy, nil
+}
+
+// normalize 解析时间配置并校验
+func (p *reminderPolicy) normalize() error {
+	firstAfter, err := time.ParseDuration(p.FirstAfter)
+	if err != nil {
+		return fmt.Errorf("invalid reminder_policy.first_after %q: %w", p.FirstAfter, err)
+	}
+	if firstAfter <= 0 {
+		return fmt.Errorf("reminder_policy.first_after must be positive")
+	}
+	p.firstAfter = firstAfter
+
+	if p.Interval != "" {
+		interval, err := time.ParseDuration(p.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid reminder_policy.interval %q: %w", p.Interval, err)
+		}
+		if interval <= 0 {
+			return fmt.Errorf("reminder_policy.interval must be positive")
+		}
+		p.interval = interval
+	}
+
+	if p.MaxCount < 0 {
+		return fmt.Errorf("reminder_policy.max_count must not be negative")
+	}
+	if p.interval == 0 {
+		p.MaxCount = 1
+	}
+	return nil
+}
+
+// nextReminder 计算审批人的下次提醒时间,已达到最多提醒次数时返回 false
+// 首次提醒从节点激活开始计时,之后从上次提醒开始计时,都按工作日历计算;推迟提醒时不早于推迟的时间
+func (p *reminderPolicy) nextReminder(activatedAt time.Time, state *ReminderState, cal *BusinessCalendar) (time.Time, bool) {
+	if state == nil {
+		state = &ReminderState{}
+	}
+	if p.MaxCount > 0 && state.Count >= p.MaxCount {
+		return time.Time{}, false
+	}
+
+	due := cal.Add(activatedAt, p.firstAfter)
+	if state.Count > 0 && state.LastAt != nil {
+		due = cal.Add(*state.LastAt, p.interval)
+	}
+	if state.SnoozedUntil != nil && state.SnoozedUntil.After(due) {
+		due = *state.SnoozedUntil
+	}
+	return due, true
+}
+
+// reminderState 获取审批人在节点上的提醒状态,不存在时创建
+func (rt *taskRuntime) reminderState(nodeID string, approver string) *ReminderState {
+	if rt.Reminders == nil {
+		rt.Reminders = make(map[string]map[string]*ReminderState)
+	}
+	if rt.Reminders[nodeID] == nil {
+		rt.Reminders[nodeID] = make(map[string]*ReminderState)
+	}
+	state, exists := rt.Reminders[nodeID][approver]
+	if !exists {
+		state = &ReminderState{}
+		rt.Reminders[nodeID][approver] = state
+	}
+	return state
+}
+
+// shiftReminders 任务恢复后顺延节点上的提醒时间
+// 上次提醒时间保留暂停前已经过的工作时间,推迟提醒保留暂停时剩余的推迟时长
+func (rt *taskRuntime) shiftReminders(nodeID string, cal *BusinessCalendar, pausedAt time.Time, now time.Time) {
+	for _, state := range rt.Reminders[nodeID] {
+		if state.LastAt != nil {
+			lastAt := cal.Sub(now, cal.Between(*state.LastAt, pausedAt))
+			state.LastAt = &lastAt
+		}
+		if state.SnoozedUntil != nil && state.SnoozedUntil.After(pausedAt) {
+			snoozedUntil := now.Add(state.SnoozedUntil.Sub(pausedAt))
+			state.SnoozedUntil = &snoozedUntil
+		}
+	}
+}
+
+// remindableApprovers 获取节点上需要提醒的审批人
+// 依次审批模式只提醒当前轮到的审批人,其余模式提醒所有尚未审批的审批人
+func remindableApprovers(tsk *task.Task, flow *flowDefinition, nodeID string) ([]string, error) {
+	pending := pendingApprovers(tsk, nodeID)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	policy, err := flow.approvalPolicyFor(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if policy.Mode == ApprovalModeSequential {
+		return pending[:1], nil
+	}
+	return pending, nil
+}
+
+// nextRemindAt 计算任务所有待提醒审批人中最早的提醒时间,没有需要提醒的审批人时返回 nil
+func (m *dbTaskManager) nextRemindAt(tsk *task.Task, rt *taskRuntime, flow *flowDefinition) (*time.Time, error) {
+	var earliest *time.Time
+	for _, nodeID := range rt.ActiveNodes {
+		policy, err := flow.reminderPolicyFor(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			continue
+		}
+		approvers, err := remindableApprovers(tsk, flow, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		cal, err := nodeCalendar(m.db, flow, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		activatedAt := nodeActivatedAt(tsk, rt, nodeID)
+		for _, approver := range approvers {
+			due, ok := policy.nextReminder(activatedAt, rt.Reminders[nodeID][approver], cal)
+			if ok && (earliest == nil || due.Before(*earliest)) {
+				earliest = &due
+			}
+		}
+	}
+	return earliest, nil
+}
+
+// SendReminders 向提醒时间已到的审批人发送提醒
+// 每次提醒产生 task_reminder 事件(经事件管道推送)并记录到任务状态历史
+func (m *dbTaskManager) SendReminders(id string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.sendReminders(id)
+	})
+}
+
+// sendReminders SendReminders 的事务内实现
+func (m *dbTaskManager) sendReminders(id string) error {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return nil
+	}
+
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+
+	now := time.Now()
+	sent := 0
+	for _, nodeID := range rt.ActiveNodes {
+		policy, err := flow.reminderPolicyFor(nodeID)
+		if err != nil {
+			return err
+		}
+		if policy == nil {
+			continue
+		}
+		approvers, err := remindableApprovers(tsk, flow, nodeID)
+		if err != nil {
+			return err
+		}
+		cal, err := nodeCalendar(m.db, flow, nodeID)
+		if err != nil {
+			return err
+		}
+		activatedAt := nodeActivatedAt(tsk, rt, nodeID)
+		for _, approver := range approvers {
+			state := rt.reminderState(nodeID, approver)
+			due, ok := policy.nextReminder(activatedAt, state, cal)
+			if !ok || now.Before(due) {
+				continue
+			}
+
+			state.Count++
+			state.LastAt = &now
+			state.SnoozedUntil = nil
+
+			reason := fmt.Sprintf("提醒 %s 审批节点 %s(第 %d 次)", approver, nodeID, state.Count)
+			if err := m.saveStateHistory(id, tsk.State, tsk.State, reason, systemOperator); err != nil {
+				return fmt.Errorf("failed to save state history: %w", err)
+			}
+			m.emit(EventTaskReminder, tsk, nodeID, approver, "remind", reason)
+			sent++
+		}
+	}
+
+	// 没有到期的提醒时只刷新提醒时间,避免扫描器重复领取
+	if sent == 0 {
+		return m.refreshSchedule(id)
+	}
+	return m.saveRuntime(tsk, rt)
+}
+
+// SnoozeReminder 审批人推迟自己在节点上的提醒,until 之前不再提醒
+func (m *dbTaskManager) SnoozeReminder(id string, nodeID string, approver string, until time.Time) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.snoozeReminder(id, nodeID, approver, until)
+	})
+}
+
+// snoozeReminder SnoozeReminder 的事务内实现
+func (m *dbTaskManager) snoozeReminder(id string, nodeID string, approver string, until time.Time) error {
+	now := time.Now()
+	if !until.After(now) || until.Sub(now) > maxReminderSnooze {
+		return fmt.Errorf("%w: snooze time must be within %s from now", ErrInvalidSnooze, maxReminderSnooze)
+	}
+
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return fmt.Errorf("%w: task state %q has no reminders", ErrInvalidSnooze, tsk.State)
+	}
+
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if !rt.isActive(nodeID) {
+		return fmt.Errorf("%w: node %q is not active", ErrInvalidSnooze, nodeID)
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	policy, err := flow.reminderPolicyFor(nodeID)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return fmt.Errorf("%w: node %q has no reminder policy", ErrInvalidSnooze, nodeID)
+	}
+	if !containsString(pendingApprovers(tsk, nodeID), approver) {
+		return fmt.Errorf("%w: user %q, node %q", ErrNotPendingApprover, approver, nodeID)
+	}
+
+	state := rt.reminderState(nodeID, approver)
+	state.SnoozedUntil = &until
+
+	reason := fmt.Sprintf("%s 推迟节点 %s 的提醒至 %s", approver, nodeID, until.Format(time.RFC3339))
+	if err := m.saveStateHistory(id, tsk.State, tsk.State, reason, approver); err != nil {
+		return fmt.Errorf("failed to save state history: %w", err)
+	}
+	return m.saveRuntime(tsk, rt)
+}
+
+// saveRuntime 只保存任务的运行时状态(任务数据不变),同样按修订号做乐观锁检查
+func (m *dbTaskManager) saveRuntime(tsk *task.Task, rt *taskRuntime) error {
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
+	return m.saveTask(&model.TaskModel{
+		ID:      tsk.ID,
+		Runtime: runtimeData,
+	})
+}
+
+// Reminders 获取任务各节点审批人的提醒状态
+func (m *DBTaskManager) Reminders(id string) (map[string]map[string]*ReminderState, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+	return rt.Reminders, nil
+}
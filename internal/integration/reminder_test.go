@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// reminderTemplate 创建审批节点 review(ann、bob 会签)激活 1 小时后提醒、之后每 2 小时提醒、最多 2 次的模板
+func reminderTemplate(t *testing.T, env *testEnv, id string) {
+	t.Helper()
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("review", []string{"ann", "bob"}, `"approval_mode":"all","reminder_policy":{"first_after":"1h","interval":"2h","max_count":2}`)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+}
+
+// reminded 返回任务的 task_reminder 事件中被提醒的审批人
+func (e *testEnv) reminded(taskID string) []string {
+	var got []string
+	for _, evt := range e.events.events {
+		if evt.Type == EventTaskReminder && evt.Task.ID == taskID {
+			got = append(got, evt.Approval.Approver)
+		}
+	}
+	return got
+}
+
+func TestReminderSchedulerRemindsPendingApprovers(t *testing.T) {
+	env := newTestEnv(t)
+	reminderTemplate(t, env, "remind")
+	taskID := env.startTask(t, "remind", `{}`).ID
+	scheduler := NewReminderScheduler(env.db, env.tasks, SchedulerOptions{})
+
+	if tm := env.taskModel(t, taskID); tm.RemindAt == nil || time.Until(*tm.RemindAt) < 59*time.Minute {
+		t.Fatalf("remind_at = %v, want an hour from now", tm.RemindAt)
+	}
+	scheduler.Scan()
+	assertEqualStrings(t, env.reminded(taskID), nil)
+
+	// 节点激活已超过 1 小时,首次提醒所有待审批人
+	env.activateAt(t, taskID, "review", time.Now().Add(-90*time.Minute))
+	scheduler.Scan()
+	assertEqualStrings(t, env.reminded(taskID), []string{"ann", "bob"})
+	if tm := env.taskModel(t, taskID); tm.RemindAt == nil || time.Until(*tm.RemindAt) < 119*time.Minute {
+		t.Fatalf("remind_at = %v, want two hours from now", tm.RemindAt)
+	}
+
+	// 已审批的审批人不再提醒
+	mustNoError(t, env.tasks.Approve(taskID, "review", "ann", ""))
+	earlier := time.Now().Add(-3 * time.Hour)
+	env.setRuntime(t, taskID, func(rt *taskRuntime) {
+		for _, state := range rt.Reminders["review"] {
+			state.LastAt = &earlier
+		}
+	})
+	scheduler.Scan()
+	assertEqualStrings(t, env.reminded(taskID), []string{"ann", "bob", "bob"})
+
+	// 达到最多提醒次数后不再提醒
+	if tm := env.taskModel(t, taskID); tm.RemindAt != nil {
+		t.Fatalf("remind_at = %v after the last reminder, want nil", tm.RemindAt)
+	}
+	reminders, err := env.tasks.Reminders(taskID)
+	mustNoError(t, err)
+	if state := reminders["review"]["bob"]; state == nil || state.Count != 2 {
+		t.Fatalf("reminder state of bob = %+v, want 2 reminders", state)
+	}
+}
+
+func TestSnoozeReminderIsPerApprover(t *testing.T) {
+	env := newTestEnv(t)
+	reminderTemplate(t, env, "remind")
+	singleApprovalTemplate(t, env, "plain", []string{"ann"})
+	taskID := env.startTask(t, "remind", `{}`).ID
+	until := time.Now().Add(5 * time.Hour)
+
+	for name, tc := range map[string]struct {
+		taskID   string
+		approver string
+		until    time.Time
+		want     error
+	}{
+		"not an approver": {taskID, "cat", until, ErrNotPendingApprover},
+		"in the past":     {taskID, "ann", time.Now().Add(-time.Minute), ErrInvalidSnooze},
+		"too far":         {taskID, "ann", time.Now().Add(8 * 24 * time.Hour), ErrInvalidSnooze},
+		"no policy":       {env.startTask(t, "plain", `{}`).ID, "ann", until, ErrInvalidSnooze},
+	} {
+		if err := env.tasks.SnoozeReminder(tc.taskID, "review", tc.approver, tc.until); !errors.Is(err, tc.want) {
+			t.Errorf("%s: error = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// 推迟只影响审批人自己的提醒
+	mustNoError(t, env.tasks.SnoozeReminder(taskID, "review", "ann", until))
+	env.activateAt(t, taskID, "review", time.Now().Add(-90*time.Minute))
+	NewReminderScheduler(env.db, env.tasks, SchedulerOptions{}).Scan()
+	assertEqualStrings(t, env.reminded(taskID), []string{"bob"})
+	reminders, err := env.tasks.Reminders(taskID)
+	mustNoError(t, err)
+	if state := reminders["review"]["ann"]; state == nil || state.SnoozedUntil == nil || !state.SnoozedUntil.Equal(until) {
+		t.Fatalf("reminder state of ann = %+v, want snoozed until %v", state, until)
+	}
+
+	// 审批后不再是待审批人,不能推迟
+	mustNoError(t, env.tasks.Approve(taskID, "review", "ann", ""))
+	if err := env.tasks.SnoozeReminder(taskID, "review", "ann", until); !errors.Is(err, ErrNotPendingApprover) {
+		t.Fatalf("error = %v, want %v", err, ErrNotPendingApprover)
+	}
+}
@@ -0,0 +1,352 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// 退回修改后重新提交的起点
+const (
+	// ResubmitFromStart 从开始节点重新审批(默认)
+	ResubmitFromStart = "start"
+	// ResubmitFromReturnedNode 直接回到退回任务的节点,之前已通过的节点不再审批
+	ResubmitFromReturnedNode = "returned_node"
+)
+
+// RoundKindReturn 审批轮次因退回修改结束
+const RoundKindReturn = "return"
+
+// ErrNotReturned 任务没有被退回修改,不能重新提交
+var ErrNotReturned = errors.New("task is not returned for revision")
+
+// returnPolicy 审批节点的退回修改策略
+// 从节点原始 config 的 return_policy 中解析,决定发起人重新提交后从哪里继续审批
+type returnPolicy struct {
+	ResubmitFrom string `json:"resubmit_from,omitempty"` // 重新提交的起点: start/returned_node
+}
+
+// returnPolicyConfig 审批节点中退回修改策略相关的配置
+type returnPolicyConfig struct {
+	ReturnPolicy *returnPolicy `json:"return_policy,omitempty"`
+}
+
+// ReturnInfo 任务被退回修改的信息
+type ReturnInfo struct {
+	NodeID       string    `json:"node_id"`           // 退回任务的节点
+	ReturnedBy   string    `json:"returned_by"`       // 退回人
+	Comment      string    `json:"comment,omitempty"` // 退回意见
+	ReturnedAt   time.Time `json:"returned_at"`       // 退回时间
+	ResubmitFrom string    `json:"resubmit_from"`     // 重新提交的起点: start/returned_node
+	ActiveNodes  []string  `json:"active_nodes"`      // 退回时的活动节点,从退回节点继续时重新激活
+}
+
+// returnPolicyFor 解析节点配置的退回修改策略,未配置时从开始节点重新审批
+func (f *flowDefinition) returnPolicyFor(nodeID string) (*returnPolicy, error) {
+	policy := &returnPolicy{}
+	if node, exists := f.Nodes[nodeID]; exists && node != nil && len(node.Config) > 0 && string(node.Config) != "null" {
+		var cfg returnPolicyConfig
+		if err := json.Unmarshal(node.Config, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid return policy for node %q: %w", nodeID, err)
+		}
+		if cfg.ReturnPolicy != nil {
+			policy = cfg.ReturnPolicy
+		}
+	}
+	if policy.ResubmitFrom == "" {
+		policy.ResubmitFrom = ResubmitFromStart
+	}
+	switch policy.ResubmitFrom {
+	case ResubmitFromStart, ResubmitFromReturnedNode:
+	default:
+		return nil, fmt.Errorf("node %q: unknown return_policy.resubmit_from %q", nodeID, policy.ResubmitFrom)
+	}
+	return policy, nil
+}
+
+// ReturnForRevision 审批人将任务退回发起人修改
+// 本轮审批数据归档为历史轮次,任务回到 pending 状态,等待发起人修改参数后重新提交
+func (m *DBTaskManager) ReturnForRevision(id string, nodeID string, approver string, comment string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.returnForRevision(id, nodeID, approver, comment)
+	})
+}
+
+// returnForRevision ReturnForRevision 的事务内实现
+func (m *dbTaskManager) returnForRevision(id string, nodeID string, approver string, comment string) error {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return fmt.Errorf("task state %q cannot be returned", tsk.State)
+	}
+
+	// 只有活动节点上轮到审批的审批人可以退回
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if !rt.isActive(nodeID) {
+		return fmt.Errorf("node %q is not active", nodeID)
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	policy, err := flow.approvalPolicyFor(nodeID)
+	if err != nil {
+		return err
+	}
+	if err := policy.checkTurn(nodeID, tsk.Approvers[nodeID], tsk.Approvals[nodeID], approver); err != nil {
+		return err
+	}
+
+	record := &task.Record{
+		ID:          generateRecordID(),
+		TaskID:      tsk.ID,
+		NodeID:      nodeID,
+		Approver:    approver,
+		Result:      "return",
+		Comment:     comment,
+		CreatedAt:   time.Now(),
+		Attachments: []string{},
+	}
+	tsk.Records = append(tsk.Records, record)
+	if err := m.saveRecord(record, rt); err != nil {
+		return err
+	}
+
+	tsk, err = m.returnTask(tsk, rt, flow, nodeID, approver, comment, RoundKindReturn)
+	if err != nil {
+		return err
+	}
+	tsk.UpdatedAt = time.Now()
+	if err := m.saveTaskData(tsk, rt); err != nil {
+		return err
+	}
+
+	m.emit(EventTaskReturned, tsk, nodeID, approver, "return", comment)
+	return nil
+}
+
+// returnTask 结束当前审批轮次并将任务退回发起人修改
+// 归档本轮数据、清空活动节点,任务回到 pending 状态;kind 为本轮的结束方式(退回或驳回到发起人)
+func (m *dbTaskManager) returnTask(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, operator string, comment string, kind string) (*task.Task, error) {
+	policy, err := flow.returnPolicyFor(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.stateMachine.CanTransition(tsk.State, types.TaskStatePending) {
+		return nil, fmt.Errorf("invalid state transition: cannot return task in state %q", tsk.State)
+	}
+
+	if err := m.archiveRound(tsk, rt, kind, nodeID, operator, comment); err != nil {
+		return nil, err
+	}
+
+	// 退回后没有活动节点,超时和提醒停止计时;并行汇聚记录保留到重新提交时处理
+	rt.Returned = &ReturnInfo{
+		NodeID:       nodeID,
+		ReturnedBy:   operator,
+		Comment:      comment,
+		ReturnedAt:   time.Now(),
+		ResubmitFrom: policy.ResubmitFrom,
+		ActiveNodes:  append([]string{}, rt.ActiveNodes...),
+	}
+	for _, activeNodeID := range rt.Returned.ActiveNodes {
+		m.detachNode(rt, activeNodeID)
+	}
+
+	adapter := &taskAdapter{task: tsk}
+	oldState := tsk.State
+	newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStatePending, "task returned for revision")
+	if err != nil {
+		return nil, fmt.Errorf("state transition failed: %w", err)
+	}
+	tsk = newTaskAdapter.(*taskAdapter).task
+	tsk.CurrentNode = nodeID
+
+	if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, "task returned for revision", operator); err != nil {
+		return nil, fmt.Errorf("failed to save state history: %w", err)
+	}
+	return tsk, nil
+}
+
+// Resubmit 发起人修改参数后重新提交被退回的任务,开始新一轮审批
+// params 为空时沿用原参数;按退回节点的 return_policy 从开始节点或退回节点继续
+func (m *DBTaskManager) Resubmit(id string, params json.RawMessage, comment string) error {
+	if len(params) > 0 && !json.Valid(params) {
+		return fmt.Errorf("params must be valid JSON")
+	}
+	return m.inTxWithPreActionHook(id, HookActionSubmit, "", m.actor(), comment, params, func(txm *dbTaskManager) error {
+		return txm.resubmit(id, params, comment)
+	})
+}
+
+// resubmit Resubmit 的事务内实现
+func (m *dbTaskManager) resubmit(id string, params json.RawMessage, comment string) error {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if tsk.State != types.TaskStatePending || rt.Returned == nil {
+		return fmt.Errorf("%w: task %q is in state %q", ErrNotReturned, id, tsk.State)
+	}
+	if !m.stateMachine.CanTransition(tsk.State, types.TaskStateSubmitted) {
+		return fmt.Errorf("invalid state transition: cannot resubmit task in state %q", tsk.State)
+	}
+
+	if len(params) > 0 {
+		if !json.Valid(params) {
+			return fmt.Errorf("params must be valid JSON")
+		}
+		tsk.Params = params
+	}
+
+	// 应用前置钩子修改的任务参数
+	if err := m.applyPreActionOutcome(tsk); err != nil {
+		return err
+	}
+
+	adapter := &taskAdapter{task: tsk}
+	oldState := tsk.State
+	newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateSubmitted, "task resubmitted")
+	if err != nil {
+		return fmt.Errorf("state transition failed: %w", err)
+	}
+	tsk = newTaskAdapter.(*taskAdapter).task
+	now := time.Now()
+	tsk.SubmittedAt = &now
+
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	startNodeID := findStartNode(tpl)
+
+	// 新一轮审批开始,之后的审批记录属于新轮次
+	returned := rt.Returned
+	rt.Returned = nil
+	rt.Round = rt.round() + 1
+
+	record := &task.Record{
+		ID:          generateRecordID(),
+		TaskID:      tsk.ID,
+		NodeID:      startNodeID,
+		Approver:    m.actor(),
+		Result:      "resubmit",
+		Comment:     comment,
+		CreatedAt:   now,
+		Attachments: []string{},
+	}
+	tsk.Records = append(tsk.Records, record)
+	if err := m.saveRecord(record, rt); err != nil {
+		return err
+	}
+	m.emit(EventTaskResubmitted, tsk, "", m.actor(), "resubmit", comment)
+
+	params = tsk.Params
+	if len(params) == 0 {
+		params = json.RawMessage("{}")
+	}
+
+	if returned.ResubmitFrom == ResubmitFromReturnedNode {
+		// 更新开始节点输出供后续分支条件使用,重新激活退回时的活动节点,退回节点重新审批
+		setNodeOutput(tsk, startNodeID, params)
+		if tsk.Approvals != nil {
+			delete(tsk.Approvals, returned.NodeID)
+		}
+		for _, nodeID := range returned.ActiveNodes {
+			if err := m.enterNode(tsk, rt, flow, "", nodeID, 0); err != nil {
+				return fmt.Errorf("failed to activate node %q: %w", nodeID, err)
+			}
+		}
+		syncCurrentNode(tsk, rt)
+	} else {
+		// 从开始节点重新审批,上一轮的审批结果和节点输出已归档
+		tsk.Approvals = make(map[string]map[string]*task.Approval)
+		tsk.NodeOutputs = make(map[string]json.RawMessage)
+		tsk.CompletedNodes = []string{}
+		tsk.CurrentNode = startNodeID
+		rt.JoinArrivals = nil
+		setNodeOutput(tsk, startNodeID, params)
+		if err := m.completeNode(tsk, rt, flow, startNodeID); err != nil {
+			return fmt.Errorf("failed to select next node: %w", err)
+		}
+	}
+
+	if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, "task resubmitted", m.actor()); err != nil {
+		return fmt.Errorf("failed to save state history: %w", err)
+	}
+	tsk.UpdatedAt = time.Now()
+	return m.saveTaskData(tsk, rt)
+}
+
+// generateRoundID 生成审批轮次 ID
+func generateRoundID() string {
+	return fmt.Sprintf("round-%d", time.Now().UnixNano())
+}
+
+// archiveRound 将当前审批轮次结束时的任务数据归档到 task_rounds
+func (m *dbTaskManager) archiveRound(tsk *task.Task, rt *taskRuntime, kind string, nodeID string, operator string, comment string) error {
+	data, err := json.Marshal(tsk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	round := &model.TaskRoundModel{
+		ID:        generateRoundID(),
+		TaskID:    tsk.ID,
+		Round:     rt.round(),
+		Kind:      kind,
+		NodeID:    nodeID,
+		Operator:  operator,
+		Comment:   comment,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+	if err := repository.NewTaskRoundRepository(m.db).Save(round); err != nil {
+		return fmt.Errorf("failed to save task round: %w", err)
+	}
+	return nil
+}
+
+// CurrentRound 获取任务当前的审批轮次
+func (m *DBTaskManager) CurrentRound(id string) (int, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return 0, err
+	}
+	return rt.round(), nil
+}
+
+// Returned 获取任务被退回修改的信息,任务没有处于退回状态时返回 nil
+func (m *DBTaskManager) Returned(id string) (*ReturnInfo, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+	return rt.Returned, nil
+}
@@ -0,0 +1,116 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// twoStepTemplate 创建 start -> first(ann) -> second(bob) -> end 的模板,secondExtra 为 second 节点的额外配置
+func twoStepTemplate(t *testing.T, env *testEnv, id string, secondExtra string) {
+	t.Helper()
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("first", []string{"ann"}, "")+`,
+		`+approvalNode("second", []string{"bob"}, secondExtra)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"first"},{"from":"first","to":"second"},{"from":"second","to":"end"}]`)
+}
+
+// recordRounds 按时间顺序返回任务审批记录的"结果:轮次"
+func (e *testEnv) recordRounds(t *testing.T, taskID string) []string {
+	t.Helper()
+	var records []model.ApprovalRecordModel
+	mustNoError(t, e.db.Where("task_id = ?", taskID).Order("created_at").Find(&records).Error)
+	var got []string
+	for _, record := range records {
+		got = append(got, fmt.Sprintf("%s:%d", record.Result, record.Round))
+	}
+	return got
+}
+
+func TestReturnForRevisionArchivesRound(t *testing.T) {
+	env := newTestEnv(t)
+	twoStepTemplate(t, env, "return", "")
+	taskID := env.startTask(t, "return", `{"amount":100}`).ID
+	mustNoError(t, env.tasks.Approve(taskID, "first", "ann", ""))
+
+	// 未轮到的审批人不能退回
+	if err := env.tasks.ReturnForRevision(taskID, "second", "ann", "fix"); err == nil {
+		t.Fatal("expected return by a non-approver to fail")
+	}
+	mustNoError(t, env.tasks.ReturnForRevision(taskID, "second", "bob", "amount too high"))
+
+	tsk := env.getTask(t, taskID)
+	if tsk.State != types.TaskStatePending {
+		t.Fatalf("state = %s, want %s", tsk.State, types.TaskStatePending)
+	}
+	assertEqualStrings(t, env.activeNodes(t, taskID), nil)
+	returned, err := env.tasks.Returned(taskID)
+	mustNoError(t, err)
+	if returned == nil || returned.NodeID != "second" || returned.ReturnedBy != "bob" || returned.ResubmitFrom != ResubmitFromStart {
+		t.Fatalf("unexpected return info: %+v", returned)
+	}
+
+	// 本轮数据归档,包含已通过节点的审批结果
+	rounds, err := repository.NewTaskRoundRepository(env.db).FindByTaskID(taskID)
+	mustNoError(t, err)
+	if len(rounds) != 1 || rounds[0].Round != 1 || rounds[0].Kind != RoundKindReturn || rounds[0].Operator != "bob" {
+		t.Fatalf("unexpected archived rounds: %+v", rounds)
+	}
+	var archived task.Task
+	mustNoError(t, json.Unmarshal(rounds[0].Data, &archived))
+	if archived.Approvals["first"]["ann"] == nil || string(archived.Params) != `{"amount":100}` {
+		t.Fatalf("archived round is missing round data: approvals=%v params=%s", archived.Approvals, archived.Params)
+	}
+
+	// 从开始节点重新审批,新一轮的记录属于第 2 轮
+	mustNoError(t, env.tasks.Resubmit(taskID, json.RawMessage(`{"amount":80}`), "lowered"))
+	tsk = env.getTask(t, taskID)
+	if tsk.State != types.TaskStateSubmitted || string(tsk.Params) != `{"amount":80}` || len(tsk.Approvals["first"]) != 0 {
+		t.Fatalf("after resubmit: state=%s params=%s approvals=%v", tsk.State, tsk.Params, tsk.Approvals)
+	}
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"first"})
+	if round, err := env.tasks.CurrentRound(taskID); err != nil || round != 2 {
+		t.Fatalf("current round = %d, %v, want 2", round, err)
+	}
+	mustNoError(t, env.tasks.Approve(taskID, "first", "ann", ""))
+	assertEqualStrings(t, env.recordRounds(t, taskID), []string{"approve:1", "return:1", "resubmit:2", "approve:2"})
+	eventTypes := env.events.types()
+	for _, want := range []event.EventType{EventTaskReturned, EventTaskResubmitted} {
+		if !containsString(eventTypes, string(want)) {
+			t.Errorf("missing %s event in %v", want, eventTypes)
+		}
+	}
+}
+
+func TestResubmitFromReturnedNode(t *testing.T) {
+	env := newTestEnv(t)
+	twoStepTemplate(t, env, "resume", `"return_policy":{"resubmit_from":"returned_node"}`)
+	taskID := env.startTask(t, "resume", `{}`).ID
+	mustNoError(t, env.tasks.Approve(taskID, "first", "ann", ""))
+	mustNoError(t, env.tasks.ReturnForRevision(taskID, "second", "bob", "fix"))
+
+	// 已通过的节点保留结果,直接回到退回节点
+	mustNoError(t, env.tasks.Resubmit(taskID, nil, ""))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"second"})
+	if env.getTask(t, taskID).Approvals["first"]["ann"] == nil {
+		t.Fatal("approval of the completed node was discarded")
+	}
+	mustNoError(t, env.tasks.Approve(taskID, "second", "bob", ""))
+	if got := env.getTask(t, taskID).State; got != types.TaskStateApproved {
+		t.Fatalf("state = %s, want %s", got, types.TaskStateApproved)
+	}
+
+	// 没有被退回的任务不能重新提交
+	if err := env.tasks.Resubmit(taskID, nil, ""); !errors.Is(err, ErrNotReturned) {
+		t.Fatalf("resubmit error = %v, want %v", err, ErrNotReturned)
+	}
+}
@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// taskRuntime 流程运行时状态
+// 保存 approval-kit Task 模型之外的引擎状态,持久化到 tasks.runtime 列
+type taskRuntime struct {
+	ActiveNodes  []string            `json:"active_nodes"`            // 当前活动节点集合(并行分支时包含多个节点)
+	JoinArrivals map[string][]string `json:"join_arrivals,omitempty"` // 汇聚节点已到达的分支(汇聚节点 ID -> 来源节点 ID 列表)
+	// ApproverProvenance 审批人的解析来源(节点 ID -> 审批人 ID -> 来源)
+	ApproverProvenance map[string]map[string]*ApproverProvenance `json:"approver_provenance,omitempty"`
+	// ActivatedAt 活动节点的激活时间(超时计时起点,升级或转派后重新计时)
+	ActivatedAt map[string]time.Time `json:"activated_at,omitempty"`
+	// Escalations 活动节点已超时升级的次数
+	Escalations map[string]int `json:"escalations,omitempty"`
+	// Reminders 活动节点上审批人的提醒状态(节点 ID -> 审批人 ID -> 提醒状态)
+	Reminders map[string]map[string]*ReminderState `json:"reminders,omitempty"`
+	// Round 当前审批轮次,退回修改后重新提交时加 1(旧数据为 0,视为第 1 轮)
+	Round int `json:"round,omitempty"`
+	// Returned 任务被退回修改、等待发起人重新提交时的退回信息
+	Returned *ReturnInfo `json:"returned,omitempty"`
+	// ServiceCalls 活动服务节点的调用状态(节点 ID -> 调用状态)
+	ServiceCalls map[string]*ServiceCallState `json:"service_calls,omitempty"`
+	// Timers 活动定时节点的计时状态(节点 ID -> 计时状态)
+	Timers map[string]*TimerState `json:"timers,omitempty"`
+	// Subprocesses 活动子流程节点等待的子任务(节点 ID -> 子任务 ID)
+	Subprocesses map[string]string `json:"subprocesses,omitempty"`
+}
+
+// loadRuntime 加载任务的运行时状态
+// 旧数据没有运行时状态时,审批中的任务以 CurrentNode 作为唯一活动节点
+func (m *dbTaskManager) loadRuntime(tsk *task.Task) (*taskRuntime, error) {
+	var tm model.TaskModel
+	if err := m.db.Select("id", "runtime").Where("id = ?", tsk.ID).First(&tm).Error; err != nil {
+		return nil, fmt.Errorf("task not found: %w", err)
+	}
+
+	rt := &taskRuntime{}
+	if len(tm.Runtime) > 0 {
+		if err := json.Unmarshal(tm.Runtime, rt); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal task runtime: %w", err)
+		}
+		return rt, nil
+	}
+
+	if tsk.CurrentNode != "" && (tsk.State == types.TaskStateSubmitted || tsk.State == types.TaskStateApproving) {
+		rt.ActiveNodes = []string{tsk.CurrentNode}
+	}
+	return rt, nil
+}
+
+// marshal 序列化运行时状态
+func (rt *taskRuntime) marshal() ([]byte, error) {
+	if rt.ActiveNodes == nil {
+		rt.ActiveNodes = []string{}
+	}
+	data, err := json.Marshal(rt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task runtime: %w", err)
+	}
+	return data, nil
+}
+
+// round 获取当前审批轮次
+func (rt *taskRuntime) round() int {
+	if rt == nil || rt.Round <= 0 {
+		return 1
+	}
+	return rt.Round
+}
+
+// isActive 判断节点是否处于活动状态
+func (rt *taskRuntime) isActive(nodeID string) bool {
+	for _, activeNodeID := range rt.ActiveNodes {
+		if activeNodeID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// activate 将节点加入活动集合并记录激活时间(已存在时忽略)
+func (rt *taskRuntime) activate(nodeID string) {
+	if !rt.isActive(nodeID) {
+		rt.ActiveNodes = append(rt.ActiveNodes, nodeID)
+		rt.restartTimer(nodeID)
+	}
+}
+
+// restartTimer 将节点的超时计时起点重置为当前时间
+func (rt *taskRuntime) restartTimer(nodeID string) {
+	if rt.ActivatedAt == nil {
+		rt.ActivatedAt = make(map[string]time.Time)
+	}
+	rt.ActivatedAt[nodeID] = time.Now()
+}
+
+// deactivate 将节点移出活动集合,并清除节点的超时计时、提醒、服务调用、定时和子流程状态
+func (rt *taskRuntime) deactivate(nodeID string) {
+	delete(rt.ActivatedAt, nodeID)
+	delete(rt.Escalations, nodeID)
+	delete(rt.Reminders, nodeID)
+	delete(rt.ServiceCalls, nodeID)
+	delete(rt.Timers, nodeID)
+	delete(rt.Subprocesses, nodeID)
+	activeNodes := make([]string, 0, len(rt.ActiveNodes))
+	for _, activeNodeID := range rt.ActiveNodes {
+		if activeNodeID != nodeID {
+			activeNodes = append(activeNodes, activeNodeID)
+		}
+	}
+	rt.ActiveNodes = activeNodes
+}
+
+// arrive 记录分支到达汇聚节点(同一来源只记录一次)
+func (rt *taskRuntime) arrive(joinID string, fromNodeID string) {
+	if rt.JoinArrivals == nil {
+		rt.JoinArrivals = make(map[string][]string)
+	}
+	for _, arrived := range rt.JoinArrivals[joinID] {
+		if arrived == fromNodeID {
+			return
+		}
+	}
+	rt.JoinArrivals[joinID] = append(rt.JoinArrivals[joinID], fromNodeID)
+}
+
+// syncCurrentNode 同步 CurrentNode 字段
+// 存在活动节点时,CurrentNode 保持为活动集合中的一个节点,兼容只读取单个当前节点的调用方
+func syncCurrentNode(tsk *task.Task, rt *taskRuntime) {
+	if len(rt.ActiveNodes) == 0 || rt.isActive(tsk.CurrentNode) {
+		return
+	}
+	tsk.CurrentNode = rt.ActiveNodes[0]
+}
+
+// ApproverProvenance 获取任务各节点审批人的解析来源
+func (m *DBTaskManager) ApproverProvenance(id string) (map[string]map[string]*ApproverProvenance, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+	return rt.ApproverProvenance, nil
+}
+
+// ActiveNodes 获取任务当前的活动节点集合
+func (m *DBTaskManager) ActiveNodes(id string) ([]string, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+	if rt.ActiveNodes == nil {
+		return []string{}, nil
+	}
+	return rt.ActiveNodes, nil
+}
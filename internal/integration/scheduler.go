@@ -0,0 +1,271 @@
+package integration
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/types"
+	"gorm.io/gorm"
+)
+
+// 后台调度器的 leader 租约名称
+const (
+	timeoutSchedulerLease  = "timeout_scanner"
+	reminderSchedulerLease = "reminder_scanner"
+	serviceSchedulerLease  = "service_scanner"
+	timerSchedulerLease    = "timer_scanner"
+)
+
+// SchedulerOptions 后台扫描配置
+type SchedulerOptions struct {
+	Interval  time.Duration // 扫描间隔
+	Lease     time.Duration // leader 租约时长,需大于扫描间隔
+	BatchSize int           // 每次扫描处理的最大任务数
+}
+
+// maxScanRetryDelay 处理失败的任务推迟重试的最大间隔
+const maxScanRetryDelay = time.Hour
+
+// scanFailure 任务连续处理失败的记录
+type scanFailure struct {
+	count    int       // 连续失败次数
+	failedAt time.Time // 最近一次失败时间
+}
+
+// withDefaults 补全未设置的扫描配置
+func (o SchedulerOptions) withDefaults() SchedulerOptions {
+	if o.Interval <= 0 {
+		o.Interval = 30 * time.Second
+	}
+	if o.Lease <= o.Interval {
+		o.Lease = 3 * o.Interval
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	return o
+}
+
+// taskScanner 按任务上的时间列扫描到期任务的后台调度器
+// 定时查询时间列已到的审批中任务并逐个处理;通过 leader 租约保证多副本部署时只有一个实例扫描
+type taskScanner struct {
+	db     *gorm.DB
+	leader *LeaderElector
+	opts   SchedulerOptions
+	column string             // 到期时间列,如 timeout_at
+	handle func(string) error // 处理单个到期任务
+	// failures 处理失败的任务(任务 ID -> 失败记录),只由扫描 goroutine 访问
+	failures map[string]*scanFailure
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// newTaskScanner 创建任务扫描器
+func newTaskScanner(db *gorm.DB, leaseName string, column string, handle func(string) error, opts SchedulerOptions) *taskScanner {
+	opts = opts.withDefaults()
+	return &taskScanner{
+		db:       db,
+		leader:   NewLeaderElector(db, leaseName, opts.Lease),
+		opts:     opts,
+		column:   column,
+		handle:   handle,
+		failures: make(map[string]*scanFailure),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start 启动扫描 goroutine
+func (s *taskScanner) Start() {
+	go s.run()
+}
+
+// Stop 停止扫描并释放 leader 租约
+func (s *taskScanner) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		if err := s.leader.Release(); err != nil {
+			log.Printf("failed to release %s lease: %v", s.leader.name, err)
+		}
+	})
+}
+
+// run 扫描循环
+func (s *taskScanner) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+
+		isLeader, err := s.leader.Acquire()
+		if err != nil {
+			log.Printf("failed to acquire %s lease: %v", s.leader.name, err)
+			continue
+		}
+		if !isLeader {
+			continue
+		}
+		s.Scan()
+	}
+}
+
+// Scan 处理一批已到期的任务
+// 版本冲突的任务(同时有人审批)留到下一轮扫描;处理失败的任务按失败次数指数推迟到期时间,
+// 避免反复失败的任务每轮都排在最前面占满批次。扫描期间定期续约 leader 租约,
+// 续约失败或已失去 leader 身份时停止本轮扫描,避免与新 leader 重复处理
+func (s *taskScanner) Scan() {
+	s.pruneFailures()
+
+	var taskModels []model.TaskModel
+	err := s.db.Select("id").
+		Where(s.column+" <= ? AND state IN ?", time.Now(), []string{string(types.TaskStateSubmitted), string(types.TaskStateApproving)}).
+		Order(s.column).
+		Limit(s.opts.BatchSize).
+		Find(&taskModels).Error
+	if err != nil {
+		log.Printf("failed to query tasks due by %s: %v", s.column, err)
+		return
+	}
+	if len(taskModels) == 0 {
+		return
+	}
+
+	lost := make(chan struct{})
+	finished := make(chan struct{})
+	defer close(finished)
+	go s.renewLease(lost, finished)
+
+	for _, tm := range taskModels {
+		select {
+		case <-s.stop:
+			return
+		case <-lost:
+			return
+		default:
+		}
+		err := s.handle(tm.ID)
+		if err == nil {
+			delete(s.failures, tm.ID)
+			continue
+		}
+		if errors.Is(err, ErrTaskConflict) {
+			continue
+		}
+		log.Printf("failed to process task %s due by %s: %v", tm.ID, s.column, err)
+		s.postpone(tm.ID)
+	}
+}
+
+// renewLease 扫描期间每隔三分之一租约时长续约一次,直到 finished 关闭
+// 单个任务的处理(如服务节点的 HTTP 调用)可能超过租约时长;续约失败或失去 leader 身份时关闭 lost
+func (s *taskScanner) renewLease(lost chan<- struct{}, finished <-chan struct{}) {
+	ticker := time.NewTicker(s.opts.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-finished:
+			return
+		}
+		isLeader, err := s.leader.Acquire()
+		if err != nil {
+			log.Printf("failed to renew %s lease: %v", s.leader.name, err)
+		}
+		if err != nil || !isLeader {
+			close(lost)
+			return
+		}
+	}
+}
+
+// postpone 将处理失败的任务的到期时间推迟(扫描间隔按连续失败次数翻倍,最多 maxScanRetryDelay)
+// 任务之后被其他操作保存时到期时间会重新计算
+func (s *taskScanner) postpone(taskID string) {
+	failure := s.failures[taskID]
+	if failure == nil {
+		failure = &scanFailure{}
+		s.failures[taskID] = failure
+	}
+	failure.count++
+	failure.failedAt = time.Now()
+
+	delay := s.opts.Interval
+	for i := 1; i < failure.count && delay < maxScanRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxScanRetryDelay {
+		delay = maxScanRetryDelay
+	}
+	if err := s.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumn(s.column, failure.failedAt.Add(delay)).Error; err != nil {
+		log.Printf("failed to postpone task %s due by %s: %v", taskID, s.column, err)
+	}
+}
+
+// pruneFailures 清理长时间没有再失败的记录(任务已恢复正常、已结束或由其他实例处理)
+func (s *taskScanner) pruneFailures() {
+	for taskID, failure := range s.failures {
+		if time.Since(failure.failedAt) > 2*maxScanRetryDelay {
+			delete(s.failures, taskID)
+		}
+	}
+}
+
+// TimeoutScheduler 审批节点超时扫描器
+// 查询超时时间(tasks.timeout_at)已到的任务,执行节点配置的超时动作
+type TimeoutScheduler struct {
+	*taskScanner
+}
+
+// NewTimeoutScheduler 创建超时扫描器
+func NewTimeoutScheduler(db *gorm.DB, taskMgr *DBTaskManager, opts SchedulerOptions) *TimeoutScheduler {
+	mgr := taskMgr.WithOperator(systemOperator)
+	return &TimeoutScheduler{newTaskScanner(db, timeoutSchedulerLease, "timeout_at", mgr.HandleTimeout, opts)}
+}
+
+// ReminderScheduler 审批提醒扫描器
+// 查询下次提醒时间(tasks.remind_at)已到的任务,向到期的审批人发送提醒
+type ReminderScheduler struct {
+	*taskScanner
+}
+
+// NewReminderScheduler 创建提醒扫描器
+func NewReminderScheduler(db *gorm.DB, taskMgr *DBTaskManager, opts SchedulerOptions) *ReminderScheduler {
+	mgr := taskMgr.WithOperator(systemOperator)
+	return &ReminderScheduler{newTaskScanner(db, reminderSchedulerLease, "remind_at", mgr.SendReminders, opts)}
+}
+
+// ServiceScheduler 服务节点扫描器
+// 查询服务节点调用时间(tasks.service_at)已到的任务,调用服务节点配置的接口
+type ServiceScheduler struct {
+	*taskScanner
+}
+
+// NewServiceScheduler 创建服务节点扫描器
+func NewServiceScheduler(db *gorm.DB, taskMgr *DBTaskManager, opts SchedulerOptions) *ServiceScheduler {
+	mgr := taskMgr.WithOperator(systemOperator)
+	return &ServiceScheduler{newTaskScanner(db, serviceSchedulerLease, "service_at", mgr.RunServiceNodes, opts)}
+}
+
+// TimerScheduler 定时节点扫描器
+// 查询定时节点触发时间(tasks.timer_at)已到的任务,完成到期的定时节点并继续流转
+type TimerScheduler struct {
+	*taskScanner
+}
+
+// NewTimerScheduler 创建定时节点扫描器
+func NewTimerScheduler(db *gorm.DB, taskMgr *DBTaskManager, opts SchedulerOptions) *TimerScheduler {
+	mgr := taskMgr.WithOperator(systemOperator)
+	return &TimerScheduler{newTaskScanner(db, timerSchedulerLease, "timer_at", mgr.FireTimers, opts)}
+}
@@ -0,0 +1,304 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// setRuntime 修改任务的运行时状态,并重新计算扫描器使用的到期时间
+func (e *testEnv) setRuntime(t *testing.T, taskID string, fn func(rt *taskRuntime)) {
+	t.Helper()
+	rt, err := e.tasks.loadRuntime(e.getTask(t, taskID))
+	mustNoError(t, err)
+	fn(rt)
+	data, err := rt.marshal()
+	mustNoError(t, err)
+	mustNoError(t, e.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumn("runtime", data).Error)
+	mustNoError(t, e.tasks.refreshSchedule(taskID))
+}
+
+// activateAt 将节点的计时起点改为 at
+func (e *testEnv) activateAt(t *testing.T, taskID string, nodeID string, at time.Time) {
+	t.Helper()
+	e.setRuntime(t, taskID, func(rt *taskRuntime) { rt.ActivatedAt[nodeID] = at })
+}
+
+// taskModel 读取任务行
+func (e *testEnv) taskModel(t *testing.T, taskID string) *model.TaskModel {
+	t.Helper()
+	var tm model.TaskModel
+	mustNoError(t, e.db.Where("id = ?", taskID).First(&tm).Error)
+	return &tm
+}
+
+// pauseSince 暂停任务,并将暂停时间改为 at
+func (e *testEnv) pauseSince(t *testing.T, taskID string, at time.Time) {
+	t.Helper()
+	mustNoError(t, e.tasks.Pause(taskID, "waiting"))
+	var tsk task.Task
+	mustNoError(t, json.Unmarshal(e.taskModel(t, taskID).Data, &tsk))
+	tsk.PausedAt = &at
+	data, err := json.Marshal(&tsk)
+	mustNoError(t, err)
+	mustNoError(t, e.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumn("data", data).Error)
+}
+
+// timeoutTemplate 创建单个审批节点 review 的模板,节点 1 小时超时并执行 action
+func timeoutTemplate(t *testing.T, env *testEnv, id string, action string, extra string) {
+	t.Helper()
+	policy := `"timeout_policy":{"after":"1h","action":"` + action + `"` + extra + `}`
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("review", []string{"ann"}, policy)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+}
+
+func TestTimeoutSchedulerAppliesEachAction(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	directory.managers["ann"] = "lead"
+	env.tasks.SetApproverDirectory(directory)
+
+	timeoutTemplate(t, env, "mark", TimeoutActionMark, "")
+	timeoutTemplate(t, env, "approve", TimeoutActionAutoApprove, "")
+	timeoutTemplate(t, env, "reject", TimeoutActionAutoReject, "")
+	timeoutTemplate(t, env, "escalate", TimeoutActionEscalate, "")
+	timeoutTemplate(t, env, "reassign", TimeoutActionReassign, `,"backup_approvers":["bob"]`)
+
+	tasks := make(map[string]string)
+	for _, id := range []string{"mark", "approve", "reject", "escalate", "reassign"} {
+		tasks[id] = env.startTask(t, id, `{}`).ID
+		env.activateAt(t, tasks[id], "review", time.Now().Add(-2*time.Hour))
+	}
+	// 未超时的任务不处理
+	pending := env.startTask(t, "mark", `{}`).ID
+
+	NewTimeoutScheduler(env.db, env.tasks, SchedulerOptions{}).Scan()
+
+	states := map[string]types.TaskState{
+		"mark":     types.TaskStateTimeout,
+		"approve":  types.TaskStateApproved,
+		"reject":   types.TaskStateRejected,
+		"escalate": types.TaskStateSubmitted,
+		"reassign": types.TaskStateSubmitted,
+	}
+	for id, want := range states {
+		if got := env.getTask(t, tasks[id]).State; got != want {
+			t.Errorf("%s: state = %s, want %s", id, got, want)
+		}
+	}
+	if got := env.getTask(t, pending).State; got != types.TaskStateSubmitted {
+		t.Errorf("task before its deadline: state = %s", got)
+	}
+
+	// 升级和转派替换审批人并重新计时
+	assertEqualStrings(t, env.getTask(t, tasks["escalate"]).Approvers["review"], []string{"lead"})
+	assertEqualStrings(t, env.getTask(t, tasks["reassign"]).Approvers["review"], []string{"bob"})
+	for _, id := range []string{"escalate", "reassign"} {
+		tm := env.taskModel(t, tasks[id])
+		if tm.TimeoutAt == nil || tm.TimeoutAt.Before(time.Now().Add(59*time.Minute)) {
+			t.Errorf("%s: timeout_at = %v, want about an hour from now", id, tm.TimeoutAt)
+		}
+	}
+
+	var actions []string
+	for _, evt := range env.events.events {
+		if evt.Type == EventNodeTimeout {
+			actions = append(actions, evt.Approval.Result)
+		}
+	}
+	if len(actions) != 5 {
+		t.Fatalf("node_timeout actions = %v, want one per overdue task", actions)
+	}
+}
+
+func TestEscalationFallsBackToMarkAfterMaxEscalations(t *testing.T) {
+	env := newTestEnv(t)
+	directory := newFakeDirectory(env.db)
+	directory.managers["ann"] = "lead"
+	directory.managers["lead"] = "head"
+	env.tasks.SetApproverDirectory(directory)
+	timeoutTemplate(t, env, "escalate", TimeoutActionEscalate, "")
+	taskID := env.startTask(t, "escalate", `{}`).ID
+	scheduler := NewTimeoutScheduler(env.db, env.tasks, SchedulerOptions{})
+
+	env.activateAt(t, taskID, "review", time.Now().Add(-2*time.Hour))
+	scheduler.Scan()
+	assertEqualStrings(t, env.getTask(t, taskID).Approvers["review"], []string{"lead"})
+
+	// 默认最多升级一次,再次超时时标记为超时
+	env.activateAt(t, taskID, "review", time.Now().Add(-2*time.Hour))
+	scheduler.Scan()
+	if got := env.getTask(t, taskID).State; got != types.TaskStateTimeout {
+		t.Fatalf("state = %s, want %s", got, types.TaskStateTimeout)
+	}
+}
+
+func TestLeaderLeaseIsExclusive(t *testing.T) {
+	db := newTestDB(t)
+	first := NewLeaderElector(db, "scanner", time.Minute)
+	second := NewLeaderElector(db, "scanner", time.Minute)
+
+	for i, want := range []bool{true, false, true, false} {
+		elector := first
+		if i%2 == 1 {
+			elector = second
+		}
+		isLeader, err := elector.Acquire()
+		mustNoError(t, err)
+		if isLeader != want {
+			t.Fatalf("acquire #%d = %v, want %v", i+1, isLeader, want)
+		}
+	}
+
+	// 释放后其他实例无需等待租约过期
+	mustNoError(t, first.Release())
+	isLeader, err := second.Acquire()
+	mustNoError(t, err)
+	if !isLeader {
+		t.Fatal("second elector did not take over a released lease")
+	}
+}
+
+func TestScanRenewsLeaseWhileHandlingTasks(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	taskID := env.startTask(t, "single", `{}`).ID
+	mustNoError(t, env.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumn("timeout_at", time.Now().Add(-time.Minute)).Error)
+
+	lease := 300 * time.Millisecond
+	other := NewLeaderElector(env.db, "renew_scanner", lease)
+	var otherAcquired bool
+	scanner := newTaskScanner(env.db, "renew_scanner", "timeout_at", func(string) error {
+		// 处理时间超过租约时长,续约使其他实例拿不到租约
+		time.Sleep(2 * lease)
+		var err error
+		otherAcquired, err = other.Acquire()
+		return err
+	}, SchedulerOptions{Interval: lease / 3, Lease: lease})
+	isLeader, err := scanner.leader.Acquire()
+	mustNoError(t, err)
+	if !isLeader {
+		t.Fatal("scanner did not acquire the lease")
+	}
+
+	scanner.Scan()
+	if otherAcquired {
+		t.Fatal("another elector acquired the lease while the leader was scanning")
+	}
+}
+
+func TestScanStopsAfterLosingLease(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	for i := 0; i < 2; i++ {
+		taskID := env.startTask(t, "single", `{}`).ID
+		mustNoError(t, env.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumn("timeout_at", time.Now().Add(-time.Minute)).Error)
+	}
+
+	lease := 300 * time.Millisecond
+	var handled []string
+	scanner := newTaskScanner(env.db, "lost_scanner", "timeout_at", func(taskID string) error {
+		handled = append(handled, taskID)
+		// 租约被其他实例接管,续约时发现失去 leader 身份
+		if err := env.db.Model(&model.SchedulerLeaseModel{}).Where("name = ?", "lost_scanner").
+			UpdateColumns(map[string]interface{}{"holder": "other", "expires_at": time.Now().Add(time.Hour)}).Error; err != nil {
+			return err
+		}
+		time.Sleep(lease)
+		return nil
+	}, SchedulerOptions{Interval: lease / 3, Lease: lease})
+	isLeader, err := scanner.leader.Acquire()
+	mustNoError(t, err)
+	if !isLeader {
+		t.Fatal("scanner did not acquire the lease")
+	}
+
+	scanner.Scan()
+	if len(handled) != 1 {
+		t.Fatalf("handled %d tasks after losing the lease, want 1", len(handled))
+	}
+}
+
+func TestScanBacksOffFailingTasks(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	taskID := env.startTask(t, "single", `{}`).ID
+	overdue := func() {
+		mustNoError(t, env.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumn("timeout_at", time.Now().Add(-time.Minute)).Error)
+	}
+
+	interval := time.Minute
+	handleErr := errors.New("handler failed")
+	scanner := newTaskScanner(env.db, "backoff_scanner", "timeout_at", func(string) error { return handleErr }, SchedulerOptions{Interval: interval})
+
+	// 连续失败时推迟时间按扫描间隔翻倍
+	for _, delay := range []time.Duration{interval, 2 * interval, 4 * interval} {
+		overdue()
+		before := time.Now()
+		scanner.Scan()
+		tm := env.taskModel(t, taskID)
+		if tm.TimeoutAt == nil || tm.TimeoutAt.Before(before.Add(delay)) || tm.TimeoutAt.After(time.Now().Add(delay)) {
+			t.Fatalf("timeout_at = %v, want %s after the scan", tm.TimeoutAt, delay)
+		}
+	}
+
+	// 版本冲突不算失败,留到下一轮扫描
+	handleErr = ErrTaskConflict
+	overdue()
+	scanner.Scan()
+	if tm := env.taskModel(t, taskID); tm.TimeoutAt == nil || tm.TimeoutAt.After(time.Now()) {
+		t.Fatalf("conflicting task was postponed to %v", tm.TimeoutAt)
+	}
+
+	// 处理成功后清除失败记录,下次失败重新从一个扫描间隔开始
+	handleErr = nil
+	scanner.Scan()
+	if len(scanner.failures) != 0 {
+		t.Fatalf("failures after success = %v", scanner.failures)
+	}
+	handleErr = errors.New("handler failed again")
+	overdue()
+	before := time.Now()
+	scanner.Scan()
+	if tm := env.taskModel(t, taskID); tm.TimeoutAt == nil || tm.TimeoutAt.After(before.Add(interval+time.Second)) {
+		t.Fatalf("timeout_at = %v, want one interval after the scan", tm.TimeoutAt)
+	}
+}
+
+func TestResumeShiftsNodeActivation(t *testing.T) {
+	env := newTestEnv(t)
+	timeoutTemplate(t, env, "mark", TimeoutActionMark, "")
+	taskID := env.startTask(t, "mark", `{}`).ID
+
+	// 节点在暂停前已计时 30 分钟,随后暂停了 2 小时
+	pausedAt := time.Now().Add(-2 * time.Hour)
+	env.activateAt(t, taskID, "review", pausedAt.Add(-30*time.Minute))
+	env.pauseSince(t, taskID, pausedAt)
+
+	mustNoError(t, env.tasks.Resume(taskID, "continue"))
+
+	rt, err := env.tasks.loadRuntime(env.getTask(t, taskID))
+	mustNoError(t, err)
+	elapsed := time.Since(rt.ActivatedAt["review"])
+	if elapsed < 30*time.Minute || elapsed > 31*time.Minute {
+		t.Fatalf("node has been active for %s after resume, want 30m", elapsed)
+	}
+	resumed := env.taskModel(t, taskID)
+	if resumed.TimeoutAt == nil || resumed.TimeoutAt.Before(time.Now().Add(29*time.Minute)) {
+		t.Fatalf("timeout_at = %v, want about 30 minutes from now", resumed.TimeoutAt)
+	}
+
+	// 恢复后不会因暂停期间的时间超时
+	NewTimeoutScheduler(env.db, env.tasks, SchedulerOptions{}).Scan()
+	if got := env.getTask(t, taskID).State; got != types.TaskStateSubmitted {
+		t.Fatalf("state = %s after scan, want %s", got, types.TaskStateSubmitted)
+	}
+}
@@ -0,0 +1,479 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// 服务节点重试用尽且没有失败分支时的处理方式
+const (
+	// ServiceFailurePause 暂停任务,恢复任务后重新调用(默认)
+	ServiceFailurePause = "pause"
+	// ServiceFailureReject 驳回任务
+	ServiceFailureReject = "reject"
+)
+
+// 服务节点调用参数的默认值和上限
+const (
+	defaultServiceTimeout     = 10 * time.Second
+	maxServiceTimeout         = 60 * time.Second
+	defaultServiceMaxAttempts = 3
+	maxServiceMaxAttempts     = 20
+	defaultServiceBackoff     = time.Second
+	defaultServiceMaxBackoff  = 5 * time.Minute
+	maxServiceResponseSize    = 1 << 20 // 响应体最大读取 1MB
+)
+
+// serviceNodeClient 调用服务节点的 HTTP 客户端,超时由每次请求的 context 控制
+var serviceNodeClient = &http.Client{}
+
+// serviceNodeConfig 服务节点配置
+// 从节点原始 config 的 service 中解析
+type serviceNodeConfig struct {
+	URL          string            `json:"url"`                      // 调用地址
+	Method       string            `json:"method,omitempty"`         // 请求方法: POST(默认)/PUT/PATCH/GET
+	Headers      map[string]string `json:"headers,omitempty"`        // 请求头(如认证信息)
+	TimeoutMs    int               `json:"timeout_ms,omitempty"`     // 单次调用超时(毫秒),默认 10000,最大 60000
+	MaxAttempts  int               `json:"max_attempts,omitempty"`   // 最大调用次数(含首次),默认 3
+	BackoffMs    int               `json:"backoff_ms,omitempty"`     // 首次重试间隔(毫秒),默认 1000,之后每次翻倍
+	MaxBackoffMs int               `json:"max_backoff_ms,omitempty"` // 最大重试间隔(毫秒),默认 300000
+	OnFailure    string            `json:"on_failure,omitempty"`     // 重试用尽且没有失败分支时: pause(默认)/reject
+}
+
+// serviceNodeConfigHolder 服务节点中服务调用相关的配置
+type serviceNodeConfigHolder struct {
+	Service *serviceNodeConfig `json:"service,omitempty"`
+}
+
+// ServiceCallState 服务节点的调用状态
+type ServiceCallState struct {
+	Attempts      int       `json:"attempts"`              // 已调用次数
+	NextAttemptAt time.Time `json:"next_attempt_at"`       // 下次调用时间
+	LastError     string    `json:"last_error,omitempty"`  // 最近一次调用的错误
+	LastStatus    int       `json:"last_status,omitempty"` // 最近一次调用的响应状态码
+	Failed        bool      `json:"failed,omitempty"`      // 重试已用尽,任务恢复后重新调用
+}
+
+// ServiceRequest 发送给服务节点地址的请求体
+type ServiceRequest struct {
+	TaskID          string                     `json:"task_id"`                // 任务 ID
+	TemplateID      string                     `json:"template_id"`            // 模板 ID
+	TemplateVersion int                        `json:"template_version"`       // 模板版本
+	BusinessID      string                     `json:"business_id"`            // 业务 ID
+	NodeID          string                     `json:"node_id"`                // 服务节点 ID
+	Attempt         int                        `json:"attempt"`                // 本次调用次数(从 1 开始)
+	Params          json.RawMessage            `json:"params,omitempty"`       // 任务参数
+	NodeOutputs     map[string]json.RawMessage `json:"node_outputs,omitempty"` // 已完成节点的输出
+}
+
+// serviceCallError 服务调用失败
+// retryable 为 false 时(如 4xx 响应)不再重试
+type serviceCallError struct {
+	status    int
+	retryable bool
+	err       error
+}
+
+// Error 实现 error 接口
+func (e *serviceCallError) Error() string {
+	return e.err.Error()
+}
+
+// serviceConfigFor 解析服务节点配置并补全默认值
+func (f *flowDefinition) serviceConfigFor(nodeID string) (*serviceNodeConfig, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, fmt.Errorf("service node %q: service config is required", nodeID)
+	}
+	var holder serviceNodeConfigHolder
+	if err := json.Unmarshal(node.Config, &holder); err != nil {
+		return nil, fmt.Errorf("invalid service config for node %q: %w", nodeID, err)
+	}
+	cfg := holder.Service
+	if cfg == nil {
+		return nil, fmt.Errorf("service node %q: service config is required", nodeID)
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("service node %q: invalid url %q", nodeID, cfg.URL)
+	}
+	cfg.Method = strings.ToUpper(strings.TrimSpace(cfg.Method))
+	switch cfg.Method {
+	case "":
+		cfg.Method = http.MethodPost
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodGet:
+	default:
+		return nil, fmt.Errorf("service node %q: unsupported method %q", nodeID, cfg.Method)
+	}
+	if cfg.TimeoutMs < 0 || time.Duration(cfg.TimeoutMs)*time.Millisecond > maxServiceTimeout {
+		return nil, fmt.Errorf("service node %q: timeout_ms must be between 0 and %d", nodeID, maxServiceTimeout.Milliseconds())
+	}
+	if cfg.MaxAttempts < 0 || cfg.MaxAttempts > maxServiceMaxAttempts {
+		return nil, fmt.Errorf("service node %q: max_attempts must be between 0 and %d", nodeID, maxServiceMaxAttempts)
+	}
+	if cfg.BackoffMs < 0 || cfg.MaxBackoffMs < 0 {
+		return nil, fmt.Errorf("service node %q: backoff must not be negative", nodeID)
+	}
+	switch cfg.OnFailure {
+	case "":
+		cfg.OnFailure = ServiceFailurePause
+	case ServiceFailurePause, ServiceFailureReject:
+	default:
+		return nil, fmt.Errorf("service node %q: unsupported on_failure %q", nodeID, cfg.OnFailure)
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = defaultServiceMaxAttempts
+	}
+	return cfg, nil
+}
+
+// timeout 获取单次调用超时时间
+func (c *serviceNodeConfig) timeout() time.Duration {
+	if c.TimeoutMs <= 0 {
+		return defaultServiceTimeout
+	}
+	return time.Duration(c.TimeoutMs) * time.Millisecond
+}
+
+// backoff 计算第 attempts 次调用失败后的重试间隔(指数退避)
+func (c *serviceNodeConfig) backoff(attempts int) time.Duration {
+	delay := defaultServiceBackoff
+	if c.BackoffMs > 0 {
+		delay = time.Duration(c.BackoffMs) * time.Millisecond
+	}
+	maxDelay := defaultServiceMaxBackoff
+	if c.MaxBackoffMs > 0 {
+		maxDelay = time.Duration(c.MaxBackoffMs) * time.Millisecond
+	}
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// failureEdge 获取服务节点或子流程节点的失败分支,没有时返回 nil
+func (f *flowDefinition) failureEdge(nodeID string) *flowEdge {
+	for _, edge := range f.outgoing(nodeID) {
+		if edge.Failure {
+			return edge
+		}
+	}
+	return nil
+}
+
+// activateService 激活服务节点,由服务节点扫描器立即调用
+func (m *dbTaskManager) activateService(tsk *task.Task, rt *taskRuntime, nodeID string) {
+	rt.activate(nodeID)
+	if rt.ServiceCalls == nil {
+		rt.ServiceCalls = make(map[string]*ServiceCallState)
+	}
+	rt.ServiceCalls[nodeID] = &ServiceCallState{NextAttemptAt: time.Now()}
+	m.emit(EventNodeActivated, tsk, nodeID, "", "", "")
+}
+
+// nextServiceAt 计算任务活动服务节点中最早的调用时间,没有待调用的服务节点时返回 nil
+func nextServiceAt(rt *taskRuntime) *time.Time {
+	var earliest *time.Time
+	for _, nodeID := range rt.ActiveNodes {
+		call := rt.ServiceCalls[nodeID]
+		if call == nil || call.Failed {
+			continue
+		}
+		due := call.NextAttemptAt
+		if earliest == nil || due.Before(*earliest) {
+			earliest = &due
+		}
+	}
+	return earliest
+}
+
+// retryFailedServices 重置重试已用尽的服务节点,恢复任务后立即重新调用
+func (rt *taskRuntime) retryFailedServices() {
+	for _, call := range rt.ServiceCalls {
+		if call != nil && call.Failed {
+			call.Attempts = 0
+			call.Failed = false
+			call.NextAttemptAt = time.Now()
+		}
+	}
+}
+
+// RunServiceNodes 调用任务中已到调用时间的服务节点
+// HTTP 调用在事务外进行,结果在单独的事务中写回;调用期间节点状态被其他操作改变时丢弃本次结果
+func (m *DBTaskManager) RunServiceNodes(id string) error {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return nil
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+
+	now := time.Now()
+	for _, nodeID := range rt.ActiveNodes {
+		call := rt.ServiceCalls[nodeID]
+		if flow.nodeType(nodeID) != NodeTypeService || call == nil || call.Failed || call.NextAttemptAt.After(now) {
+			continue
+		}
+		cfg, err := flow.serviceConfigFor(nodeID)
+		if err != nil {
+			return err
+		}
+
+		output, callErr := callService(cfg, tsk, rt, nodeID, call.Attempts+1)
+		attempts := call.Attempts
+		if err := m.inTx(func(txm *dbTaskManager) error {
+			return txm.applyServiceResult(id, nodeID, attempts, output, callErr)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callService 调用服务节点配置的地址
+// 2xx 响应体(为空时为 {})作为节点输出;5xx、429 和网络错误可以重试,其他状态码不再重试
+func callService(cfg *serviceNodeConfig, tsk *task.Task, rt *taskRuntime, nodeID string, attempt int) (json.RawMessage, error) {
+	body, err := json.Marshal(&ServiceRequest{
+		TaskID:          tsk.ID,
+		TemplateID:      tsk.TemplateID,
+		TemplateVersion: tsk.TemplateVersion,
+		BusinessID:      tsk.BusinessID,
+		NodeID:          nodeID,
+		Attempt:         attempt,
+		Params:          tsk.Params,
+		NodeOutputs:     tsk.NodeOutputs,
+	})
+	if err != nil {
+		return nil, &serviceCallError{err: fmt.Errorf("failed to marshal service request: %w", err)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+	defer cancel()
+
+	var reqBody io.Reader
+	if cfg.Method != http.MethodGet {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, reqBody)
+	if err != nil {
+		return nil, &serviceCallError{err: fmt.Errorf("failed to create service request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 同一次节点激活的所有重试使用相同的幂等键,便于服务端去重
+	req.Header.Set("Idempotency-Key", fmt.Sprintf("%s:%s:%d", tsk.ID, nodeID, nodeActivatedAt(tsk, rt, nodeID).UnixNano()))
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := serviceNodeClient.Do(req)
+	if err != nil {
+		return nil, &serviceCallError{retryable: true, err: fmt.Errorf("failed to call service: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxServiceResponseSize))
+	if err != nil {
+		return nil, &serviceCallError{status: resp.StatusCode, retryable: true, err: fmt.Errorf("failed to read service response: %w", err)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, &serviceCallError{status: resp.StatusCode, retryable: retryable, err: fmt.Errorf("service returned status code: %d", resp.StatusCode)}
+	}
+
+	if len(bytes.TrimSpace(respBody)) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+	if !json.Valid(respBody) {
+		return nil, &serviceCallError{status: resp.StatusCode, err: errors.New("service response is not valid JSON")}
+	}
+	return json.RawMessage(respBody), nil
+}
+
+// applyServiceResult 写回服务节点的调用结果(事务内)
+// 成功时保存节点输出并继续流转;失败时按指数退避安排重试,重试用尽后走失败分支,
+// 没有失败分支时按 on_failure 暂停或驳回任务
+func (m *dbTaskManager) applyServiceResult(id string, nodeID string, attempts int, output json.RawMessage, callErr error) error {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return nil
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	call := rt.ServiceCalls[nodeID]
+	if !rt.isActive(nodeID) || call == nil || call.Failed || call.Attempts != attempts {
+		// 调用期间节点已被处理(如任务被取消、回退或其他实例已写回结果)
+		return nil
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	cfg, err := flow.serviceConfigFor(nodeID)
+	if err != nil {
+		return err
+	}
+
+	if tsk.State == types.TaskStateSubmitted {
+		adapter := &taskAdapter{task: tsk}
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproving, "service node called")
+		if err != nil {
+			return fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+	}
+
+	call.Attempts++
+	if callErr == nil {
+		setNodeOutput(tsk, nodeID, output)
+		if err := m.completeNode(tsk, rt, flow, nodeID); err != nil {
+			return fmt.Errorf("failed to select next node: %w", err)
+		}
+		m.emit(EventServiceCompleted, tsk, nodeID, systemOperator, "service", "")
+		return m.finishAutomaticNode(tsk, rt, nodeID, "all nodes completed")
+	}
+
+	call.LastError = callErr.Error()
+	var serviceErr *serviceCallError
+	retryable := errors.As(callErr, &serviceErr) && serviceErr.retryable
+	if serviceErr != nil {
+		call.LastStatus = serviceErr.status
+	}
+	if retryable && call.Attempts < cfg.MaxAttempts {
+		call.NextAttemptAt = time.Now().Add(cfg.backoff(call.Attempts))
+		tsk.UpdatedAt = time.Now()
+		return m.saveTaskData(tsk, rt)
+	}
+
+	// 重试用尽(或不可重试): 失败信息作为节点输出,供失败分支的条件和后续节点使用
+	failure, _ := json.Marshal(map[string]interface{}{
+		"error":       call.LastError,
+		"status_code": call.LastStatus,
+		"attempts":    call.Attempts,
+	})
+	setNodeOutput(tsk, nodeID, failure)
+	m.emit(EventServiceFailed, tsk, nodeID, systemOperator, "service", call.LastError)
+
+	if edge := flow.failureEdge(nodeID); edge != nil {
+		rt.deactivate(nodeID)
+		markNodeCompleted(tsk, nodeID)
+		if err := m.enterNode(tsk, rt, flow, nodeID, edge.To, 0); err != nil {
+			return fmt.Errorf("failed to enter failure branch: %w", err)
+		}
+		if err := m.settleJoins(tsk, rt, flow); err != nil {
+			return err
+		}
+		syncCurrentNode(tsk, rt)
+		return m.finishAutomaticNode(tsk, rt, nodeID, "all nodes completed")
+	}
+
+	reason := fmt.Sprintf("service node %q failed: %s", nodeID, call.LastError)
+	oldState := tsk.State
+	switch cfg.OnFailure {
+	case ServiceFailureReject:
+		if !m.stateMachine.CanTransition(tsk.State, types.TaskStateRejected) {
+			return fmt.Errorf("invalid state transition: cannot reject task in state %q", tsk.State)
+		}
+		adapter := &taskAdapter{task: tsk}
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateRejected, reason)
+		if err != nil {
+			return fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+		markNodeCompleted(tsk, nodeID)
+		rt.deactivate(nodeID)
+	default:
+		// 节点保持活动,恢复任务后重新调用
+		call.Failed = true
+		if !m.stateMachine.CanTransition(tsk.State, types.TaskStatePaused) {
+			return fmt.Errorf("invalid state transition: cannot pause task in state %q", tsk.State)
+		}
+		adapter := &taskAdapter{task: tsk}
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStatePaused, reason)
+		if err != nil {
+			return fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+		now := time.Now()
+		tsk.PausedAt = &now
+		tsk.PausedState = oldState
+	}
+
+	if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, reason, systemOperator); err != nil {
+		return fmt.Errorf("failed to save state history: %w", err)
+	}
+	tsk.UpdatedAt = time.Now()
+	if err := m.saveTaskData(tsk, rt); err != nil {
+		return err
+	}
+
+	if tsk.State == types.TaskStatePaused {
+		m.emit(EventTaskPaused, tsk, nodeID, systemOperator, "pause", reason)
+	}
+	m.emitCompleted(tsk, nodeID, systemOperator)
+	return nil
+}
+
+// finishAutomaticNode 自动节点(服务节点、定时节点)流转后保存任务,所有分支都到达结束节点时任务审批通过
+func (m *dbTaskManager) finishAutomaticNode(tsk *task.Task, rt *taskRuntime, nodeID string, reason string) error {
+	if len(rt.ActiveNodes) == 0 && m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
+		adapter := &taskAdapter{task: tsk}
+		oldState := tsk.State
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproved, reason)
+		if err != nil {
+			return fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+		if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, reason, systemOperator); err != nil {
+			return fmt.Errorf("failed to save state history: %w", err)
+		}
+	}
+
+	tsk.UpdatedAt = time.Now()
+	if err := m.saveTaskData(tsk, rt); err != nil {
+		return err
+	}
+	m.emitCompleted(tsk, nodeID, systemOperator)
+	return nil
+}
+
+// ServiceCalls 获取任务活动服务节点的调用状态
+func (m *DBTaskManager) ServiceCalls(id string) (map[string]*ServiceCallState, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+	return rt.ServiceCalls, nil
+}
@@ -0,0 +1,201 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// serviceStub 按顺序返回预设状态码的服务节点接口,记录收到的请求
+type serviceStub struct {
+	mu       sync.Mutex
+	statuses []int
+	body     string
+	requests []*ServiceRequest
+	headers  []http.Header
+}
+
+func (s *serviceStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var req ServiceRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	s.requests = append(s.requests, &req)
+	s.headers = append(s.headers, r.Header.Clone())
+
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status = s.statuses[0]
+		s.statuses = s.statuses[1:]
+	}
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		_, _ = w.Write([]byte(s.body))
+	}
+}
+
+// calls 返回已收到的请求数
+func (s *serviceStub) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// serviceTemplate 创建 start -> svc -> end 的模板,svc 调用 url;failure 为 true 时增加到审批节点 manual 的失败分支
+func serviceTemplate(t *testing.T, env *testEnv, id string, url string, extra string, failure bool) {
+	t.Helper()
+	service := `{"url":"` + url + `","headers":{"Authorization":"Bearer token"}` + extra + `}`
+	edges := `[{"from":"start","to":"svc"},{"from":"svc","to":"end"}]`
+	if failure {
+		edges = `[{"from":"start","to":"svc"},{"from":"svc","to":"end"},{"from":"svc","to":"manual","failure":true},{"from":"manual","to":"end"}]`
+	}
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		"svc":{"id":"svc","type":"service","config":{"service":`+service+`}},
+		`+approvalNode("manual", []string{"ann"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		edges)
+}
+
+// serviceCall 获取服务节点 svc 的调用状态
+func (e *testEnv) serviceCall(t *testing.T, taskID string) *ServiceCallState {
+	t.Helper()
+	calls, err := e.tasks.ServiceCalls(taskID)
+	mustNoError(t, err)
+	return calls["svc"]
+}
+
+// makeServiceDue 将服务节点 svc 的下次调用时间改为现在
+func (e *testEnv) makeServiceDue(t *testing.T, taskID string) {
+	t.Helper()
+	e.setRuntime(t, taskID, func(rt *taskRuntime) { rt.ServiceCalls["svc"].NextAttemptAt = time.Now().Add(-time.Second) })
+}
+
+func TestServiceNodeWritesOutputAndContinues(t *testing.T) {
+	stub := &serviceStub{body: `{"approved_amount":100}`}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	env := newTestEnv(t)
+	serviceTemplate(t, env, "svc", server.URL, "", false)
+
+	taskID := env.startTask(t, "svc", `{"amount":100}`).ID
+	NewServiceScheduler(env.db, env.tasks, SchedulerOptions{}).Scan()
+
+	tsk := env.getTask(t, taskID)
+	if tsk.State != types.TaskStateApproved {
+		t.Fatalf("state = %s, want %s", tsk.State, types.TaskStateApproved)
+	}
+	if string(tsk.NodeOutputs["svc"]) != `{"approved_amount":100}` {
+		t.Fatalf("node output = %s", tsk.NodeOutputs["svc"])
+	}
+	if stub.calls() != 1 {
+		t.Fatalf("service called %d times, want 1", stub.calls())
+	}
+	req := stub.requests[0]
+	if req.TaskID != taskID || req.NodeID != "svc" || req.Attempt != 1 || string(req.Params) != `{"amount":100}` {
+		t.Fatalf("unexpected service request: %+v", req)
+	}
+	if stub.headers[0].Get("Authorization") != "Bearer token" || stub.headers[0].Get("Idempotency-Key") == "" {
+		t.Fatalf("unexpected service request headers: %v", stub.headers[0])
+	}
+	eventTypes := env.events.types()
+	assertEqualStrings(t, eventTypes[len(eventTypes)-2:], []string{string(EventServiceCompleted), string(EventTaskCompleted)})
+}
+
+func TestServiceNodeRetriesWithBackoff(t *testing.T) {
+	stub := &serviceStub{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, body: `{}`}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	env := newTestEnv(t)
+	serviceTemplate(t, env, "svc", server.URL, `,"max_attempts":3,"backoff_ms":60000`, false)
+	taskID := env.startTask(t, "svc", `{}`).ID
+
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		mustNoError(t, env.tasks.RunServiceNodes(taskID))
+		call := env.serviceCall(t, taskID)
+		if call.Attempts != attempt+1 || call.LastStatus == 0 || call.LastError == "" {
+			t.Fatalf("after attempt %d: %+v", attempt+1, call)
+		}
+		if call.NextAttemptAt.Before(before.Add(delay)) || call.NextAttemptAt.After(time.Now().Add(delay)) {
+			t.Fatalf("after attempt %d: next attempt at %v, want %s later", attempt+1, call.NextAttemptAt, delay)
+		}
+		// 退避期间不调用
+		mustNoError(t, env.tasks.RunServiceNodes(taskID))
+		if stub.calls() != attempt+1 {
+			t.Fatalf("service called %d times during backoff", stub.calls())
+		}
+		env.makeServiceDue(t, taskID)
+	}
+
+	mustNoError(t, env.tasks.RunServiceNodes(taskID))
+	if got := env.getTask(t, taskID).State; got != types.TaskStateApproved {
+		t.Fatalf("state = %s after the third attempt, want %s", got, types.TaskStateApproved)
+	}
+	if stub.requests[2].Attempt != 3 || stub.headers[2].Get("Idempotency-Key") != stub.headers[0].Get("Idempotency-Key") {
+		t.Fatalf("retries should reuse the idempotency key: attempt=%d", stub.requests[2].Attempt)
+	}
+}
+
+func TestServiceNodeFailureTakesFailureBranch(t *testing.T) {
+	stub := &serviceStub{statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	env := newTestEnv(t)
+	serviceTemplate(t, env, "svc", server.URL, "", true)
+	taskID := env.startTask(t, "svc", `{}`).ID
+
+	// 4xx 响应不重试,直接走失败分支
+	mustNoError(t, env.tasks.RunServiceNodes(taskID))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"manual"})
+	var output struct {
+		Error      string `json:"error"`
+		StatusCode int    `json:"status_code"`
+		Attempts   int    `json:"attempts"`
+	}
+	mustNoError(t, json.Unmarshal(env.getTask(t, taskID).NodeOutputs["svc"], &output))
+	if output.StatusCode != http.StatusBadRequest || output.Attempts != 1 || output.Error == "" {
+		t.Fatalf("failure output = %+v", output)
+	}
+
+	mustNoError(t, env.tasks.Approve(taskID, "manual", "ann", ""))
+	if got := env.getTask(t, taskID).State; got != types.TaskStateApproved {
+		t.Fatalf("state = %s, want %s", got, types.TaskStateApproved)
+	}
+}
+
+func TestServiceNodeFailureWithoutBranch(t *testing.T) {
+	stub := &serviceStub{statuses: []int{http.StatusBadRequest, http.StatusBadRequest}, body: `{"ok":true}`}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	env := newTestEnv(t)
+	serviceTemplate(t, env, "pause", server.URL, "", false)
+	serviceTemplate(t, env, "reject", server.URL, `,"on_failure":"reject"`, false)
+
+	// 默认暂停任务,恢复后重新调用
+	paused := env.startTask(t, "pause", `{}`).ID
+	mustNoError(t, env.tasks.RunServiceNodes(paused))
+	if got := env.getTask(t, paused).State; got != types.TaskStatePaused {
+		t.Fatalf("state = %s, want %s", got, types.TaskStatePaused)
+	}
+	if call := env.serviceCall(t, paused); !call.Failed {
+		t.Fatalf("service call not marked failed: %+v", call)
+	}
+
+	rejected := env.startTask(t, "reject", `{}`).ID
+	mustNoError(t, env.tasks.RunServiceNodes(rejected))
+	if got := env.getTask(t, rejected).State; got != types.TaskStateRejected {
+		t.Fatalf("state = %s, want %s", got, types.TaskStateRejected)
+	}
+
+	mustNoError(t, env.tasks.Resume(paused, "service fixed"))
+	mustNoError(t, env.tasks.RunServiceNodes(paused))
+	if got := env.getTask(t, paused).State; got != types.TaskStateApproved {
+		t.Fatalf("state = %s after resume, want %s", got, types.TaskStateApproved)
+	}
+}
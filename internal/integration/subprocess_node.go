@@ -0,0 +1,420 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// maxSubprocessDepth 子流程最大嵌套层数,防止模板互相引用时无限创建子任务
+const maxSubprocessDepth = 5
+
+// subprocessNodeConfig 子流程节点配置
+// 从节点原始 config 的 subprocess 中解析
+type subprocessNodeConfig struct {
+	TemplateID string            `json:"template_id"`       // 子任务使用的模板(最新版本)
+	Params     map[string]string `json:"params,omitempty"`  // 子任务参数名 -> 父任务变量路径(如 params.vendor_id),为空时传递父任务的全部参数
+	Outputs    map[string]string `json:"outputs,omitempty"` // 节点输出字段名 -> 子任务变量路径(如 outputs.finance.account_id)
+
+	params  map[string]*pathNode
+	outputs map[string]*pathNode
+}
+
+// subprocessNodeConfigHolder 子流程节点中子流程相关的配置
+type subprocessNodeConfigHolder struct {
+	Subprocess *subprocessNodeConfig `json:"subprocess,omitempty"`
+}
+
+// SubprocessChild 子流程节点创建的子任务
+type SubprocessChild struct {
+	TaskID     string          `json:"task_id"`                    // 子任务 ID
+	NodeID     string          `json:"node_id"`                    // 父任务中的子流程节点 ID
+	TemplateID string          `json:"template_id"`                // 子任务模板 ID
+	State      types.TaskState `json:"state" swaggertype:"string"` // 子任务状态
+	CreatedAt  time.Time       `json:"created_at"`                 // 创建时间
+}
+
+// subprocessConfigFor 解析子流程节点配置
+func (f *flowDefinition) subprocessConfigFor(nodeID string) (*subprocessNodeConfig, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, fmt.Errorf("subprocess node %q: subprocess config is required", nodeID)
+	}
+	var holder subprocessNodeConfigHolder
+	if err := json.Unmarshal(node.Config, &holder); err != nil {
+		return nil, fmt.Errorf("invalid subprocess config for node %q: %w", nodeID, err)
+	}
+	cfg := holder.Subprocess
+	if cfg == nil || cfg.TemplateID == "" {
+		return nil, fmt.Errorf("subprocess node %q: template_id is required", nodeID)
+	}
+
+	var err error
+	if cfg.params, err = parseVariablePaths(cfg.Params); err != nil {
+		return nil, fmt.Errorf("subprocess node %q: invalid params: %w", nodeID, err)
+	}
+	if cfg.outputs, err = parseVariablePaths(cfg.Outputs); err != nil {
+		return nil, fmt.Errorf("subprocess node %q: invalid outputs: %w", nodeID, err)
+	}
+	for name := range cfg.outputs {
+		if name == "task_id" || name == "state" {
+			return nil, fmt.Errorf("subprocess node %q: output %q is reserved", nodeID, name)
+		}
+	}
+	return cfg, nil
+}
+
+// parseVariablePaths 解析名称到变量路径的映射,路径必须是 params.x 或 outputs.node.x 形式
+func parseVariablePaths(mapping map[string]string) (map[string]*pathNode, error) {
+	paths := make(map[string]*pathNode, len(mapping))
+	for name, expr := range mapping {
+		if name == "" {
+			return nil, fmt.Errorf("name must not be empty")
+		}
+		ast, err := parseExpression(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		}
+		path, ok := ast.(*pathNode)
+		if !ok {
+			return nil, fmt.Errorf("%q must be a variable path such as params.amount", name)
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
+
+// evalPaths 按变量路径从任务参数和节点输出中取值
+func evalPaths(tsk *task.Task, paths map[string]*pathNode) (map[string]interface{}, error) {
+	ctx, err := newExprContext(tsk.Params, tsk.NodeOutputs)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, len(paths))
+	for name, path := range paths {
+		value, err := path.eval(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %q: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// childParams 构造子任务参数
+func (c *subprocessNodeConfig) childParams(tsk *task.Task) (json.RawMessage, error) {
+	if len(c.params) == 0 {
+		return tsk.Params, nil
+	}
+	values, err := evalPaths(tsk, c.params)
+	if err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal subprocess params: %w", err)
+	}
+	return params, nil
+}
+
+// subprocessDepth 计算任务所在的子流程嵌套层数(顶层任务为 0)
+func (m *dbTaskManager) subprocessDepth(taskID string) (int, error) {
+	depth := 0
+	for id := taskID; ; depth++ {
+		var tm model.TaskModel
+		if err := m.db.Select("id", "parent_task_id").Where("id = ?", id).First(&tm).Error; err != nil {
+			return 0, fmt.Errorf("task not found: %w", err)
+		}
+		if tm.ParentTaskID == "" || depth > maxSubprocessDepth {
+			return depth, nil
+		}
+		id = tm.ParentTaskID
+	}
+}
+
+// enterSubprocess 进入子流程节点
+// 按配置的模板创建并提交子任务,子任务继承父任务的业务 ID 和发起人;节点保持活动直到子任务结束
+func (m *dbTaskManager) enterSubprocess(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string) error {
+	cfg, err := flow.subprocessConfigFor(nodeID)
+	if err != nil {
+		return err
+	}
+	depth, err := m.subprocessDepth(tsk.ID)
+	if err != nil {
+		return err
+	}
+	if depth >= maxSubprocessDepth {
+		return fmt.Errorf("subprocess node %q: subprocesses are nested more than %d levels", nodeID, maxSubprocessDepth)
+	}
+	params, err := cfg.childParams(tsk)
+	if err != nil {
+		return fmt.Errorf("subprocess node %q: %w", nodeID, err)
+	}
+
+	var parent model.TaskModel
+	if err := m.db.Select("id", "created_by").Where("id = ?", tsk.ID).First(&parent).Error; err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+	child, err := m.create(cfg.TemplateID, tsk.BusinessID, params, parent.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to create subprocess task: %w", err)
+	}
+	link := map[string]interface{}{
+		"parent_task_id": tsk.ID,
+		"parent_node_id": nodeID,
+	}
+	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", child.ID).UpdateColumns(link).Error; err != nil {
+		return fmt.Errorf("failed to link subprocess task: %w", err)
+	}
+	// 父任务发起人可以查看子任务
+	if parent.CreatedBy != "" {
+		m.queueViewer(child.ID, parent.CreatedBy)
+	}
+
+	rt.activate(nodeID)
+	if rt.Subprocesses == nil {
+		rt.Subprocesses = make(map[string]string)
+	}
+	rt.Subprocesses[nodeID] = child.ID
+	m.emit(EventNodeActivated, tsk, nodeID, "", "", "")
+	m.emit(EventSubprocessStarted, tsk, nodeID, m.actor(), "subprocess", child.ID)
+
+	// 子任务结束时在其所在的事务内推进本节点(见 settleFinishedTasks)
+	if err := m.submit(child.ID); err != nil {
+		return fmt.Errorf("failed to submit subprocess task: %w", err)
+	}
+	return nil
+}
+
+// settleFinishedTasks 处理事务内结束的任务(事务提交前调用)
+// 子任务结束时父任务的子流程节点按结果流转,父任务结束时取消仍在运行的子任务,
+// 子流程节点未完成即失效时取消其子任务(见 detachNode);处理过程中结束的任务(如因此结束的父任务、被取消的子任务)继续处理
+func (m *dbTaskManager) settleFinishedTasks() error {
+	for len(*m.finishedTasks) > 0 || len(*m.detachedTasks) > 0 {
+		if len(*m.detachedTasks) > 0 {
+			id := (*m.detachedTasks)[0]
+			*m.detachedTasks = (*m.detachedTasks)[1:]
+			if err := m.cancelDetached(id); err != nil {
+				return err
+			}
+			continue
+		}
+
+		id := (*m.finishedTasks)[0]
+		*m.finishedTasks = (*m.finishedTasks)[1:]
+
+		var tm model.TaskModel
+		if err := m.db.Select("id", "state", "parent_task_id", "parent_node_id").Where("id = ?", id).First(&tm).Error; err != nil {
+			return fmt.Errorf("task not found: %w", err)
+		}
+		if containsString(RunningTaskStates, tm.State) {
+			continue
+		}
+		if err := m.cancelChildren(&tm); err != nil {
+			return err
+		}
+		if tm.ParentTaskID != "" {
+			if err := m.settleSubprocess(tm.ParentTaskID, tm.ParentNodeID, tm.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cancelChildren 取消已结束任务仍在运行的子任务
+func (m *dbTaskManager) cancelChildren(parent *model.TaskModel) error {
+	var children []model.TaskModel
+	if err := m.db.Select("id", "state").
+		Where("parent_task_id = ? AND state IN ?", parent.ID, RunningTaskStates).
+		Order("created_at").Find(&children).Error; err != nil {
+		return fmt.Errorf("failed to get subprocess tasks: %w", err)
+	}
+	reason := fmt.Sprintf("parent task %q %s", parent.ID, parent.State)
+	for _, child := range children {
+		if !m.stateMachine.CanTransition(types.TaskState(child.State), types.TaskStateCancelled) {
+			continue
+		}
+		if err := m.cancel(child.ID, reason); err != nil {
+			return fmt.Errorf("failed to cancel subprocess task %q: %w", child.ID, err)
+		}
+	}
+	return nil
+}
+
+// cancelDetached 取消所在子流程节点已失效的子任务(子任务已结束时忽略)
+func (m *dbTaskManager) cancelDetached(childID string) error {
+	var child model.TaskModel
+	if err := m.db.Select("id", "state", "parent_task_id", "parent_node_id").Where("id = ?", childID).First(&child).Error; err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+	if !containsString(RunningTaskStates, child.State) ||
+		!m.stateMachine.CanTransition(types.TaskState(child.State), types.TaskStateCancelled) {
+		return nil
+	}
+	reason := fmt.Sprintf("subprocess node %q of parent task %q is no longer active", child.ParentNodeID, child.ParentTaskID)
+	if err := m.cancel(child.ID, reason); err != nil {
+		return fmt.Errorf("failed to cancel subprocess task %q: %w", child.ID, err)
+	}
+	return nil
+}
+
+// settleSubprocess 子任务结束后推进父任务的子流程节点
+// 子任务审批通过时节点完成并继续流转;否则走失败分支,没有失败分支时驳回父任务。
+// 父任务不在审批中(如暂停,恢复时再处理)或节点已不再等待该子任务时忽略
+func (m *dbTaskManager) settleSubprocess(parentID string, nodeID string, childID string) error {
+	tsk, err := m.Get(parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get parent task: %w", err)
+	}
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return nil
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if !rt.isActive(nodeID) || rt.Subprocesses[nodeID] != childID {
+		return nil
+	}
+	child, err := m.Get(childID)
+	if err != nil {
+		return fmt.Errorf("failed to get subprocess task: %w", err)
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	cfg, err := flow.subprocessConfigFor(nodeID)
+	if err != nil {
+		return err
+	}
+
+	// 子任务结果作为节点输出,供后续分支条件和节点使用
+	values, err := evalPaths(child, cfg.outputs)
+	if err != nil {
+		return fmt.Errorf("subprocess node %q: %w", nodeID, err)
+	}
+	values["task_id"] = child.ID
+	values["state"] = child.State
+	output, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subprocess output: %w", err)
+	}
+
+	if tsk.State == types.TaskStateSubmitted {
+		adapter := &taskAdapter{task: tsk}
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproving, "subprocess completed")
+		if err != nil {
+			return fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+	}
+	setNodeOutput(tsk, nodeID, output)
+	m.emit(EventSubprocessCompleted, tsk, nodeID, systemOperator, string(child.State), child.ID)
+
+	if child.State == types.TaskStateApproved {
+		if err := m.completeNode(tsk, rt, flow, nodeID); err != nil {
+			return fmt.Errorf("failed to select next node: %w", err)
+		}
+		return m.finishAutomaticNode(tsk, rt, nodeID, "all nodes completed")
+	}
+
+	if edge := flow.failureEdge(nodeID); edge != nil {
+		rt.deactivate(nodeID)
+		markNodeCompleted(tsk, nodeID)
+		if err := m.enterNode(tsk, rt, flow, nodeID, edge.To, 0); err != nil {
+			return fmt.Errorf("failed to enter failure branch: %w", err)
+		}
+		if err := m.settleJoins(tsk, rt, flow); err != nil {
+			return err
+		}
+		syncCurrentNode(tsk, rt)
+		return m.finishAutomaticNode(tsk, rt, nodeID, "all nodes completed")
+	}
+
+	reason := fmt.Sprintf("subprocess node %q: task %q %s", nodeID, child.ID, child.State)
+	if !m.stateMachine.CanTransition(tsk.State, types.TaskStateRejected) {
+		return fmt.Errorf("invalid state transition: cannot reject task in state %q", tsk.State)
+	}
+	oldState := tsk.State
+	adapter := &taskAdapter{task: tsk}
+	newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateRejected, reason)
+	if err != nil {
+		return fmt.Errorf("state transition failed: %w", err)
+	}
+	tsk = newTaskAdapter.(*taskAdapter).task
+	markNodeCompleted(tsk, nodeID)
+	// 父任务结束,其他并行分支一起失效
+	for _, activeNodeID := range append([]string{}, rt.ActiveNodes...) {
+		m.detachNode(rt, activeNodeID)
+	}
+	if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, reason, systemOperator); err != nil {
+		return fmt.Errorf("failed to save state history: %w", err)
+	}
+	tsk.UpdatedAt = time.Now()
+	if err := m.saveTaskData(tsk, rt); err != nil {
+		return err
+	}
+	m.emitCompleted(tsk, nodeID, systemOperator)
+	return nil
+}
+
+// queueSubprocesses 将活动子流程节点等待的子任务加入待处理列表
+// 父任务暂停期间结束的子任务在恢复后处理
+func (m *dbTaskManager) queueSubprocesses(rt *taskRuntime) {
+	if m.finishedTasks == nil || len(rt.Subprocesses) == 0 {
+		return
+	}
+	nodeIDs := make([]string, 0, len(rt.Subprocesses))
+	for nodeID := range rt.Subprocesses {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		*m.finishedTasks = append(*m.finishedTasks, rt.Subprocesses[nodeID])
+	}
+}
+
+// detachNode 将未完成即失效的节点移出活动集合(退回、驳回到之前节点、并行汇聚后放弃的分支)
+// 子流程节点等待的子任务在事务提交前取消
+func (m *dbTaskManager) detachNode(rt *taskRuntime, nodeID string) {
+	if childID, exists := rt.Subprocesses[nodeID]; exists && m.detachedTasks != nil {
+		*m.detachedTasks = append(*m.detachedTasks, childID)
+	}
+	rt.deactivate(nodeID)
+}
+
+// ParentTask 获取子任务的父任务 ID 和父任务中的子流程节点 ID,不是子任务时返回空字符串
+func (m *DBTaskManager) ParentTask(id string) (string, string, error) {
+	var tm model.TaskModel
+	if err := m.db.Select("id", "parent_task_id", "parent_node_id").Where("id = ?", id).First(&tm).Error; err != nil {
+		return "", "", fmt.Errorf("task not found: %w", err)
+	}
+	return tm.ParentTaskID, tm.ParentNodeID, nil
+}
+
+// Children 获取任务的子任务(按创建时间排序)
+func (m *DBTaskManager) Children(id string) ([]*SubprocessChild, error) {
+	var models []model.TaskModel
+	if err := m.db.Select("id", "template_id", "state", "parent_node_id", "created_at").
+		Where("parent_task_id = ?", id).Order("created_at").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("failed to get subprocess tasks: %w", err)
+	}
+	children := make([]*SubprocessChild, 0, len(models))
+	for _, tm := range models {
+		children = append(children, &SubprocessChild{
+			TaskID:     tm.ID,
+			NodeID:     tm.ParentNodeID,
+			TemplateID: tm.TemplateID,
+			State:      types.TaskState(tm.State),
+			CreatedAt:  tm.CreatedAt,
+		})
+	}
+	return children, nil
+}
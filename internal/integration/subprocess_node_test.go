@@ -0,0 +1,124 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// subprocessNode 子流程节点 JSON,子任务使用模板 security
+func subprocessNode(id string, extra string) string {
+	return `"` + id + `":{"id":"` + id + `","type":"subprocess","config":{"subprocess":{"template_id":"security"` + extra + `}}}`
+}
+
+// parallelSubprocessTemplate 创建子流程节点 sub 与审批节点 b(审批人 bob)并行的模板
+func parallelSubprocessTemplate(t *testing.T, env *testEnv, id string) {
+	t.Helper()
+	singleApprovalTemplate(t, env, "security", []string{"ann"})
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		"fork":{"id":"fork","type":"parallel_split"},
+		`+subprocessNode("sub", "")+`,
+		`+approvalNode("b", []string{"bob"}, "")+`,
+		"join":{"id":"join","type":"parallel_join"},
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"fork"},{"from":"fork","to":"sub"},{"from":"fork","to":"b"},
+		{"from":"sub","to":"join"},{"from":"b","to":"join"},{"from":"join","to":"end"}]`)
+}
+
+// childOf 获取父任务唯一的子任务
+func (e *testEnv) childOf(t *testing.T, parentID string) *SubprocessChild {
+	t.Helper()
+	children, err := e.tasks.Children(parentID)
+	mustNoError(t, err)
+	if len(children) != 1 {
+		t.Fatalf("task has %d subprocess tasks, want 1", len(children))
+	}
+	return children[0]
+}
+
+func TestSubprocessNodeLinksChildTask(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "security", []string{"ann"})
+	env.createTemplate(t, "onboard",
+		`{"start":{"id":"start","type":"start"},
+		`+subprocessNode("sub", `,"params":{"vendor":"params.vendor_name"},"outputs":{"vendor":"params.vendor"}`)+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"sub"},{"from":"sub","to":"end"}]`)
+
+	parentID := env.startTask(t, "onboard", `{"vendor_name":"acme"}`).ID
+	child := env.childOf(t, parentID)
+	if child.NodeID != "sub" || child.TemplateID != "security" || child.State != types.TaskStateSubmitted {
+		t.Fatalf("unexpected subprocess task: %+v", child)
+	}
+	gotParent, gotNode, err := env.tasks.ParentTask(child.TaskID)
+	mustNoError(t, err)
+	if gotParent != parentID || gotNode != "sub" {
+		t.Fatalf("parent of child = %q/%q, want %q/sub", gotParent, gotNode, parentID)
+	}
+	if gotParent, _, err := env.tasks.ParentTask(parentID); err != nil || gotParent != "" {
+		t.Fatalf("parent of top-level task = %q, %v", gotParent, err)
+	}
+	if params := string(env.getTask(t, child.TaskID).Params); params != `{"vendor":"acme"}` {
+		t.Fatalf("child params = %s", params)
+	}
+	assertEqualStrings(t, env.activeNodes(t, parentID), []string{"sub"})
+
+	// 子任务审批通过后父任务的子流程节点完成
+	mustNoError(t, env.tasks.Approve(child.TaskID, "review", "ann", ""))
+	parent := env.getTask(t, parentID)
+	if parent.State != types.TaskStateApproved {
+		t.Fatalf("parent state = %s, want %s", parent.State, types.TaskStateApproved)
+	}
+	var output map[string]string
+	mustNoError(t, json.Unmarshal(parent.NodeOutputs["sub"], &output))
+	if output["vendor"] != "acme" || output["task_id"] != child.TaskID || output["state"] != string(types.TaskStateApproved) {
+		t.Fatalf("subprocess output = %s", parent.NodeOutputs["sub"])
+	}
+	var subprocessEvents []string
+	for _, evt := range env.events.events {
+		if evt.Task.ID == parentID && (evt.Type == EventSubprocessStarted || evt.Type == EventSubprocessCompleted) {
+			subprocessEvents = append(subprocessEvents, string(evt.Type))
+		}
+	}
+	assertEqualStrings(t, subprocessEvents, []string{string(EventSubprocessStarted), string(EventSubprocessCompleted)})
+}
+
+func TestSubprocessChildCancelledWhenNodeAbandoned(t *testing.T) {
+	env := newTestEnv(t)
+	parallelSubprocessTemplate(t, env, "vendor")
+
+	cases := map[string]func(parentID string) error{
+		"cancel": func(parentID string) error { return env.tasks.Cancel(parentID, "withdrawn") },
+		"reject": func(parentID string) error { return env.tasks.Reject(parentID, "b", "bob", "no") },
+		"return": func(parentID string) error { return env.tasks.ReturnForRevision(parentID, "b", "bob", "fix") },
+	}
+	for name, abandon := range cases {
+		parentID := env.startTask(t, "vendor", `{}`).ID
+		child := env.childOf(t, parentID)
+		mustNoError(t, abandon(parentID))
+		if got := env.getTask(t, child.TaskID).State; got != types.TaskStateCancelled {
+			t.Errorf("%s: subprocess task state = %s, want %s", name, got, types.TaskStateCancelled)
+		}
+	}
+}
+
+func TestRejectedChildRejectsParentAndDeactivatesBranches(t *testing.T) {
+	env := newTestEnv(t)
+	parallelSubprocessTemplate(t, env, "vendor")
+
+	parentID := env.startTask(t, "vendor", `{}`).ID
+	assertEqualStrings(t, env.activeNodes(t, parentID), []string{"sub", "b"})
+	child := env.childOf(t, parentID)
+
+	// 没有失败分支时子任务被驳回,父任务一并驳回,并行分支 b 失效
+	mustNoError(t, env.tasks.Reject(child.TaskID, "review", "ann", "unsafe"))
+	if got := env.getTask(t, parentID).State; got != types.TaskStateRejected {
+		t.Fatalf("parent state = %s, want %s", got, types.TaskStateRejected)
+	}
+	assertEqualStrings(t, env.activeNodes(t, parentID), nil)
+	if err := env.tasks.Approve(parentID, "b", "bob", ""); err == nil {
+		t.Fatal("expected approving the deactivated branch to fail")
+	}
+}
@@ -3,6 +3,7 @@ package integration
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/mautops/approval-gin/internal/model"
@@ -152,16 +153,31 @@ func (a *taskAdapter) Clone() pkgSM.TransitionableTask {
 	return &taskAdapter{task: taskCopy}
 }
 
-// dbTaskManager 基于数据库的任务管理器
-type dbTaskManager struct {
+// DBTaskManager 基于数据库的任务管理器(导出以便服务层调用)
+type DBTaskManager struct {
 	db           *gorm.DB
 	templateMgr  template.TemplateManager
 	stateMachine pkgSM.StateMachine
 	eventHandler event.EventHandler
 	recordRepo   repository.ApprovalRecordRepository
 	historyRepo  repository.StateHistoryRepository
+
+	approverDirectory ApproverDirectory // 审批人目录,用于解析角色、用户组、上级等审批人来源
+	approverRelations ApproverRelations // 审批人权限关系,委托替换审批人时同步
+
+	expectedRevision *int64            // 调用方期望的任务修订号(If-Match),为 nil 时不校验
+	loadedRevisions  map[string]int64  // 事务内读取任务时的修订号,仅事务内的管理器副本使用
+	operator         string            // 操作人,记录在产生的事件中
+	pendingEvents    *[]*event.Event   // 事务内产生、等待提交后分发的事件
+	pendingRelations *[]relationChange // 事务内产生、等待提交后同步的审批人权限关系变更
+	finishedTasks    *[]string         // 事务内进入终态、等待提交前处理父子任务关联的任务
+	preAction        *preActionOutcome // 事务外调用的前置钩子结果,事务内应用
+	detachedTasks    *[]string         // 事务内所在子流程节点已失效、等待提交前取消的子任务
 }
 
+// dbTaskManager 基于数据库的任务管理器(内部别名)
+type dbTaskManager = DBTaskManager
+
 // NewTaskManager 创建任务管理器
 // 返回 pkg/task.TaskManager 接口实现
 func NewTaskManager(db *gorm.DB, templateMgr template.TemplateManager, stateMachine pkgSM.StateMachine, eventHandler event.EventHandler) task.TaskManager {
@@ -182,6 +198,20 @@ func NewTaskManager(db *gorm.DB, templateMgr template.TemplateManager, stateMach
 
 // Create 创建任务
 func (m *dbTaskManager) Create(templateID string, businessID string, params json.RawMessage) (*task.Task, error) {
+	var tsk *task.Task
+	err := m.inTx(func(txm *dbTaskManager) error {
+		var err error
+		tsk, err = txm.create(templateID, businessID, params, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tsk, nil
+}
+
+// create Create 的事务内实现,initiator 为任务发起人(为空时不记录)
+func (m *dbTaskManager) create(templateID string, businessID string, params json.RawMessage, initiator string) (*task.Task, error) {
 	// 1. 获取模板
 	tpl, err := m.templateMgr.Get(templateID, 0)
 	if err != nil {
@@ -231,11 +261,14 @@ func (m *dbTaskManager) Create(templateID string, businessID string, params json
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
+		CreatedBy:       initiator,
 	}
 
+	// 5. 任务和创建事件在同一事务中保存
 	if err := m.db.Create(taskModel).Error; err != nil {
 		return nil, fmt.Errorf("failed to save task: %w", err)
 	}
+	m.emit(EventTaskCreated, tsk, "", m.actor(), "create", "")
 
 	return tsk, nil
 }
@@ -246,6 +279,9 @@ func (m *dbTaskManager) Get(id string) (*task.Task, error) {
 	if err := m.db.Where("id = ?", id).First(&tm).Error; err != nil {
 		return nil, fmt.Errorf("task not found: %w", err)
 	}
+	if err := m.trackRevision(&tm); err != nil {
+		return nil, err
+	}
 
 	// 反序列化
 	var tsk task.Task
@@ -269,6 +305,13 @@ func generateRecordID() string {
 // Submit 提交任务进入审批流程
 // 使用状态机进行状态转换,从 pending 转换为 submitted
 func (m *dbTaskManager) Submit(id string) error {
+	return m.inTxWithPreActionHook(id, HookActionSubmit, "", m.actor(), "", nil, func(txm *dbTaskManager) error {
+		return txm.submit(id)
+	})
+}
+
+// submit Submit 的事务内实现
+func (m *dbTaskManager) submit(id string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -280,6 +323,11 @@ func (m *dbTaskManager) Submit(id string) error {
 		return fmt.Errorf("invalid state transition: cannot submit task in state %q", tsk.State)
 	}
 
+	// 应用前置钩子修改的任务参数
+	if err := m.applyPreActionOutcome(tsk); err != nil {
+		return err
+	}
+
 	// 3. 使用状态机执行状态转换
 	adapter := &taskAdapter{task: tsk}
 	oldState := tsk.State
@@ -291,40 +339,46 @@ func (m *dbTaskManager) Submit(id string) error {
 	// 4. 获取转换后的任务对象
 	newTask := newTaskAdapter.(*taskAdapter).task
 
-	// 保存状态历史到数据库
-	if err := m.saveStateHistory(id, oldState, newTask.State, "task submitted", "system"); err != nil {
-		return fmt.Errorf("failed to save state history: %w", err)
-	}
-
 	// 5. 设置提交时间
 	now := time.Now()
 	newTask.SubmittedAt = &now
+	m.emit(EventTaskSubmitted, newTask, "", m.actor(), "submit", "")
 
-	// 6. 执行开始节点逻辑(如果当前节点是开始节点,找到下一个节点)
+	// 6. 执行开始节点逻辑(如果当前节点是开始节点,按出边条件激活后续节点)
+	rt := &taskRuntime{}
 	if newTask.CurrentNode != "" {
-		tpl, err := m.templateMgr.Get(newTask.TemplateID, newTask.TemplateVersion)
-		if err == nil {
-			if node, exists := tpl.Nodes[newTask.CurrentNode]; exists && node.Type == template.NodeTypeStart {
-				// 查找从 start 节点出发的下一个节点
-				nextNodeID := findNextNode(tpl, newTask.CurrentNode)
-				if nextNodeID != "" {
-					// 更新当前节点为下一个节点
-					newTask.CurrentNode = nextNodeID
-					// 保存开始节点的输出(任务参数)
-					if newTask.NodeOutputs == nil {
-						newTask.NodeOutputs = make(map[string]json.RawMessage)
-					}
-					if newTask.Params != nil && len(newTask.Params) > 0 {
-						newTask.NodeOutputs[node.ID] = newTask.Params
-					} else {
-						newTask.NodeOutputs[node.ID] = json.RawMessage("{}")
-					}
-					// 将开始节点标记为已完成
-					newTask.CompletedNodes = append(newTask.CompletedNodes, node.ID)
-				}
+		flow, err := loadFlow(m.db, newTask.TemplateID, newTask.TemplateVersion)
+		if err != nil {
+			return fmt.Errorf("failed to load template flow: %w", err)
+		}
+		startNodeID := newTask.CurrentNode
+		if flow.nodeType(startNodeID) == string(template.NodeTypeStart) {
+			// 保存开始节点的输出(任务参数),供后续分支条件使用
+			if len(newTask.Params) > 0 {
+				setNodeOutput(newTask, startNodeID, newTask.Params)
+			} else {
+				setNodeOutput(newTask, startNodeID, json.RawMessage("{}"))
 			}
+
+			// 从 start 节点出发激活后续节点(自动穿过条件网关和并行网关)
+			if err := m.completeNode(newTask, rt, flow, startNodeID); err != nil {
+				return fmt.Errorf("failed to select next node: %w", err)
+			}
+		} else {
+			// 撤回后重新提交时,当前节点仍是撤回前的节点
+			rt.activate(startNodeID)
+			m.emit(EventNodeActivated, newTask, startNodeID, "", "", "")
 		}
 	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
+
+	// 保存状态历史到数据库
+	if err := m.saveStateHistory(id, oldState, newTask.State, "task submitted", "system"); err != nil {
+		return fmt.Errorf("failed to save state history: %w", err)
+	}
 
 	// 7. 序列化并保存到数据库
 	data, err := json.Marshal(newTask)
@@ -341,13 +395,14 @@ func (m *dbTaskManager) Submit(id string) error {
 		State:           string(newTask.State),
 		CurrentNode:     newTask.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       newTask.CreatedAt,
 		UpdatedAt:       newTask.UpdatedAt,
 		SubmittedAt:     newTask.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
 	return nil
@@ -355,6 +410,21 @@ func (m *dbTaskManager) Submit(id string) error {
 
 // Approve 审批人进行同意操作
 func (m *dbTaskManager) Approve(id string, nodeID string, approver string, comment string) error {
+	return m.inTxWithPreActionHook(id, HookActionApprove, nodeID, approver, comment, nil, func(txm *dbTaskManager) error {
+		return txm.approve(id, nodeID, approver, comment, []string{}, false)
+	})
+}
+
+// ApproveWithAttachments 审批人进行同意操作(带附件)
+func (m *dbTaskManager) ApproveWithAttachments(id string, nodeID string, approver string, comment string, attachments []string) error {
+	return m.inTxWithPreActionHook(id, HookActionApprove, nodeID, approver, comment, nil, func(txm *dbTaskManager) error {
+		return txm.approve(id, nodeID, approver, comment, attachments, true)
+	})
+}
+
+// approve 同意操作的公共实现
+// checkAttachments 为 true 时校验节点的附件必填配置
+func (m *dbTaskManager) approve(id string, nodeID string, approver string, comment string, attachments []string, checkAttachments bool) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -367,211 +437,30 @@ func (m *dbTaskManager) Approve(id string, nodeID string, approver string, comme
 		return fmt.Errorf("task state %q cannot be approved", currentState)
 	}
 
-	// 3. 获取模板和节点配置,验证审批意见必填
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	// 验证节点处于活动状态(并行分支时可以审批任一活动节点)
+	rt, err := m.loadRuntime(tsk)
 	if err != nil {
-		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
-	}
-
-	node, exists := tpl.Nodes[nodeID]
-	if !exists {
-		return fmt.Errorf("node %q not found in template", nodeID)
-	}
-
-	if node.Type == template.NodeTypeApproval {
-		approvalConfig, ok := node.Config.(template.ApprovalNodeConfigAccessor)
-		if ok && approvalConfig.RequireComment() {
-			if comment == "" {
-				return fmt.Errorf("comment is required for approval node %q", nodeID)
-			}
-		}
-	}
-
-	// 4. 更新任务状态为 approving(如果还是 submitted)
-	if currentState == types.TaskStateSubmitted {
-		adapter := &taskAdapter{task: tsk}
-		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproving, "task approved")
-		if err != nil {
-			return fmt.Errorf("state transition failed: %w", err)
-		}
-		tsk = newTaskAdapter.(*taskAdapter).task
-	}
-
-	// 5. 记录审批结果
-	// 初始化节点审批记录
-	if tsk.Approvals == nil {
-		tsk.Approvals = make(map[string]map[string]*task.Approval)
-	}
-	if tsk.Approvals[nodeID] == nil {
-		tsk.Approvals[nodeID] = make(map[string]*task.Approval)
-	}
-
-	// 记录审批结果
-	tsk.Approvals[nodeID][approver] = &task.Approval{
-		Result:    "approve",
-		Comment:   comment,
-		CreatedAt: time.Now(),
+		return err
 	}
-
-	// 6. 生成审批记录
-	record := &task.Record{
-		ID:          generateRecordID(),
-		TaskID:      id,
-		NodeID:      nodeID,
-		Approver:    approver,
-		Result:      "approve",
-		Comment:     comment,
-		CreatedAt:   time.Now(),
-		Attachments: []string{},
+	if !rt.isActive(nodeID) {
+		return fmt.Errorf("node %q is not active", nodeID)
 	}
 
-	// 添加到记录列表
-	tsk.Records = append(tsk.Records, record)
-
-	// 保存审批记录到数据库
-	attachmentsJSON, _ := json.Marshal(record.Attachments)
-	recordModel := &model.ApprovalRecordModel{
-		ID:          record.ID,
-		TaskID:      record.TaskID,
-		NodeID:      record.NodeID,
-		Approver:    record.Approver,
-		Result:      record.Result,
-		Comment:     record.Comment,
-		Attachments: attachmentsJSON,
-		CreatedAt:   record.CreatedAt,
-	}
-	if err := m.recordRepo.Save(recordModel); err != nil {
-		return fmt.Errorf("failed to save approval record: %w", err)
-	}
-
-	// 7. 检查审批是否完成(简化处理: 对于单人审批模式,审批人同意后立即完成)
-	// 获取审批人列表
-	approvers := tsk.Approvers[nodeID]
-	shouldTransition := false
-
-	// 简化处理: 如果只有一个审批人且就是当前审批人,且已同意,将任务状态转换为已通过
-	if len(approvers) == 1 && approvers[0] == approver {
-		// 单人审批模式,审批人已同意
-		if m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
-			shouldTransition = true
-		}
-	} else if len(approvers) > 1 {
-		// 多人审批模式: 检查是否所有审批人都已同意(简化处理,假设是会签模式)
-		allApproved := true
-		for _, approverID := range approvers {
-			approval, exists := tsk.Approvals[nodeID][approverID]
-			if !exists || approval == nil || approval.Result != "approve" {
-				allApproved = false
-				break
-			}
-		}
-		if allApproved && m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
-			shouldTransition = true
-		}
-	} else if len(approvers) == 0 {
-		// 如果没有审批人列表,假设是单人审批模式(当前审批人就是唯一审批人)
-		if m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
-			shouldTransition = true
-		}
-	}
-
-	// 8. 如果需要转换状态,执行状态转换
-	if shouldTransition {
-		adapter := &taskAdapter{task: tsk}
-		oldState := tsk.State
-		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproved, "all approvers approved")
-		if err != nil {
-			return fmt.Errorf("state transition failed: %w", err)
-		}
-		tsk = newTaskAdapter.(*taskAdapter).task
-
-		// 保存状态历史到数据库
-		if err := m.saveStateHistory(id, oldState, tsk.State, "all approvers approved", approver); err != nil {
-			return fmt.Errorf("failed to save state history: %w", err)
-		}
-
-		// 节点完成,添加到已完成节点列表
-		if tsk.CompletedNodes == nil {
-			tsk.CompletedNodes = []string{}
-		}
-		// 检查节点是否已在列表中
-		found := false
-		for _, completedNodeID := range tsk.CompletedNodes {
-			if completedNodeID == nodeID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			tsk.CompletedNodes = append(tsk.CompletedNodes, nodeID)
-		}
-
-		// 查找下一个节点
-		nextNodeID := findNextNode(tpl, nodeID)
-		if nextNodeID != "" {
-			tsk.CurrentNode = nextNodeID
-			// 保存审批节点的输出
-			if tsk.NodeOutputs == nil {
-				tsk.NodeOutputs = make(map[string]json.RawMessage)
-			}
-			output := json.RawMessage(`{"result":"approve"}`)
-			tsk.NodeOutputs[nodeID] = output
-		} else {
-			// 如果没有下一个节点,任务完成
-			tsk.CurrentNode = ""
-		}
-	}
-
-	// 9. 更新任务更新时间
-	tsk.UpdatedAt = time.Now()
-
-	// 10. 序列化并保存到数据库
-	data, err := json.Marshal(tsk)
+	// 按节点的多人审批策略校验审批人(审批人列表、重复审批、依次审批顺序)
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
+		return fmt.Errorf("failed to load template flow: %w", err)
 	}
-
-	taskModel := &model.TaskModel{
-		ID:              tsk.ID,
-		TemplateID:      tsk.TemplateID,
-		TemplateVersion: tsk.TemplateVersion,
-		BusinessID:      tsk.BusinessID,
-		State:           string(tsk.State),
-		CurrentNode:     tsk.CurrentNode,
-		Data:            data,
-		CreatedAt:       tsk.CreatedAt,
-		UpdatedAt:       tsk.UpdatedAt,
-		SubmittedAt:     tsk.SubmittedAt,
-	}
-
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
-	}
-
-	// 11. 生成审批事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
-
-	return nil
-}
-
-// ApproveWithAttachments 审批人进行同意操作(带附件)
-func (m *dbTaskManager) ApproveWithAttachments(id string, nodeID string, approver string, comment string, attachments []string) error {
-	// 1. 获取任务
-	tsk, err := m.Get(id)
+	policy, err := flow.approvalPolicyFor(nodeID)
 	if err != nil {
-		return fmt.Errorf("failed to get task: %w", err)
+		return err
 	}
-
-	// 2. 验证任务状态(只有 submitted 或 approving 状态才能审批)
-	currentState := tsk.State
-	if currentState != types.TaskStateSubmitted && currentState != types.TaskStateApproving {
-		return fmt.Errorf("task state %q cannot be approved", currentState)
+	if err := policy.checkTurn(nodeID, tsk.Approvers[nodeID], tsk.Approvals[nodeID], approver); err != nil {
+		return err
 	}
 
 	// 3. 获取模板和节点配置,验证审批意见和附件要求
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
 		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
 	}
@@ -587,12 +476,17 @@ func (m *dbTaskManager) ApproveWithAttachments(id string, nodeID string, approve
 			if approvalConfig.RequireComment() && comment == "" {
 				return fmt.Errorf("comment is required for approval node %q", nodeID)
 			}
-			if approvalConfig.RequireAttachments() && len(attachments) == 0 {
+			if checkAttachments && approvalConfig.RequireAttachments() && len(attachments) == 0 {
 				return fmt.Errorf("attachments are required for approval node %q", nodeID)
 			}
 		}
 	}
 
+	// 应用前置钩子修改的任务参数
+	if err := m.applyPreActionOutcome(tsk); err != nil {
+		return err
+	}
+
 	// 4. 更新任务状态为 approving(如果还是 submitted)
 	if currentState == types.TaskStateSubmitted {
 		adapter := &taskAdapter{task: tsk}
@@ -635,88 +529,37 @@ func (m *dbTaskManager) ApproveWithAttachments(id string, nodeID string, approve
 	tsk.Records = append(tsk.Records, record)
 
 	// 保存审批记录到数据库
-	attachmentsJSON, _ := json.Marshal(record.Attachments)
-	recordModel := &model.ApprovalRecordModel{
-		ID:          record.ID,
-		TaskID:      record.TaskID,
-		NodeID:      record.NodeID,
-		Approver:    record.Approver,
-		Result:      record.Result,
-		Comment:     record.Comment,
-		Attachments: attachmentsJSON,
-		CreatedAt:   record.CreatedAt,
-	}
-	if err := m.recordRepo.Save(recordModel); err != nil {
-		return fmt.Errorf("failed to save approval record: %w", err)
+	if err := m.saveRecord(record, rt); err != nil {
+		return err
 	}
 
-	// 7. 检查审批是否完成(与 Approve 方法相同的逻辑)
-	approvers := tsk.Approvers[nodeID]
-	shouldTransition := false
+	// 7. 按多人审批策略检查节点审批是否完成
+	outcome := policy.evaluate(tsk.Approvers[nodeID], tsk.Approvals[nodeID], "approve")
 
-	if len(approvers) == 1 && approvers[0] == approver {
-		if m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
-			shouldTransition = true
-		}
-	} else if len(approvers) > 1 {
-		allApproved := true
-		for _, approverID := range approvers {
-			approval, exists := tsk.Approvals[nodeID][approverID]
-			if !exists || approval == nil || approval.Result != "approve" {
-				allApproved = false
-				break
-			}
+	// 8. 节点完成后推进流程
+	if outcome == nodeOutcomeApproved {
+		// 保存审批节点的输出,节点移出活动集合并激活后续节点(自动穿过网关)
+		setNodeOutput(tsk, nodeID, json.RawMessage(`{"result":"approve"}`))
+		if err := m.completeNode(tsk, rt, flow, nodeID); err != nil {
+			return fmt.Errorf("failed to select next node: %w", err)
 		}
-		if allApproved && m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
-			shouldTransition = true
-		}
-	} else if len(approvers) == 0 {
-		if m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
-			shouldTransition = true
-		}
-	}
-
-	// 8. 如果需要转换状态,执行状态转换
-	if shouldTransition {
-		adapter := &taskAdapter{task: tsk}
-		oldState := tsk.State
-		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproved, "all approvers approved")
-		if err != nil {
-			return fmt.Errorf("state transition failed: %w", err)
-		}
-		tsk = newTaskAdapter.(*taskAdapter).task
 
-		// 保存状态历史到数据库
-		if err := m.saveStateHistory(id, oldState, tsk.State, "all approvers approved", approver); err != nil {
-			return fmt.Errorf("failed to save state history: %w", err)
-		}
-
-		// 节点完成,添加到已完成节点列表
-		if tsk.CompletedNodes == nil {
-			tsk.CompletedNodes = []string{}
-		}
-		found := false
-		for _, completedNodeID := range tsk.CompletedNodes {
-			if completedNodeID == nodeID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			tsk.CompletedNodes = append(tsk.CompletedNodes, nodeID)
-		}
+		// 所有分支都到达结束节点(没有活动节点)时任务审批通过
+		if len(rt.ActiveNodes) == 0 {
+			if m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
+				adapter := &taskAdapter{task: tsk}
+				oldState := tsk.State
+				newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproved, "all approvers approved")
+				if err != nil {
+					return fmt.Errorf("state transition failed: %w", err)
+				}
+				tsk = newTaskAdapter.(*taskAdapter).task
 
-		// 查找下一个节点
-		nextNodeID := findNextNode(tpl, nodeID)
-		if nextNodeID != "" {
-			tsk.CurrentNode = nextNodeID
-			if tsk.NodeOutputs == nil {
-				tsk.NodeOutputs = make(map[string]json.RawMessage)
+				// 保存状态历史到数据库
+				if err := m.saveStateHistory(id, oldState, tsk.State, "all approvers approved", approver); err != nil {
+					return fmt.Errorf("failed to save state history: %w", err)
+				}
 			}
-			output := json.RawMessage(`{"result":"approve"}`)
-			tsk.NodeOutputs[nodeID] = output
-		} else {
-			tsk.CurrentNode = ""
 		}
 	}
 
@@ -728,6 +571,10 @@ func (m *dbTaskManager) ApproveWithAttachments(id string, nodeID string, approve
 	if err != nil {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
 
 	taskModel := &model.TaskModel{
 		ID:              tsk.ID,
@@ -737,25 +584,49 @@ func (m *dbTaskManager) ApproveWithAttachments(id string, nodeID string, approve
 		State:           string(tsk.State),
 		CurrentNode:     tsk.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
 	// 11. 生成审批事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
+	m.emit(EventTaskApproved, tsk, nodeID, approver, "approve", comment)
+	m.emitCompleted(tsk, nodeID, approver)
 
 	return nil
 }
 
 // Reject 审批人进行拒绝操作
 func (m *dbTaskManager) Reject(id string, nodeID string, approver string, comment string) error {
+	return m.inTxWithPreActionHook(id, HookActionReject, nodeID, approver, comment, nil, func(txm *dbTaskManager) error {
+		return txm.reject(id, nodeID, approver, comment, "", []string{}, false)
+	})
+}
+
+// RejectWithAttachments 审批人进行拒绝操作(带附件)
+func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver string, comment string, attachments []string) error {
+	return m.inTxWithPreActionHook(id, HookActionReject, nodeID, approver, comment, nil, func(txm *dbTaskManager) error {
+		return txm.reject(id, nodeID, approver, comment, "", attachments, true)
+	})
+}
+
+// RejectToNode 审批人拒绝并驳回到指定的已完成审批节点(节点驳回动作为 choice 时使用)
+func (m *DBTaskManager) RejectToNode(id string, nodeID string, approver string, comment string, targetNodeID string, attachments []string) error {
+	return m.inTxWithPreActionHook(id, HookActionReject, nodeID, approver, comment, nil, func(txm *dbTaskManager) error {
+		return txm.reject(id, nodeID, approver, comment, targetNodeID, attachments, len(attachments) > 0)
+	})
+}
+
+// reject 拒绝操作的公共实现
+// 按节点的多人审批策略判断拒绝是否驳回节点,节点被驳回后按节点的驳回动作结束任务、
+// 驳回到之前的审批节点或退回发起人;targetNodeID 为审批人选择的驳回目标
+// checkAttachments 为 true 时校验节点的附件必填配置
+func (m *dbTaskManager) reject(id string, nodeID string, approver string, comment string, targetNodeID string, attachments []string, checkAttachments bool) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -768,149 +639,36 @@ func (m *dbTaskManager) Reject(id string, nodeID string, approver string, commen
 		return fmt.Errorf("task state %q cannot be rejected", currentState)
 	}
 
-	// 3. 获取模板和节点配置,验证审批意见必填
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	// 验证节点处于活动状态
+	rt, err := m.loadRuntime(tsk)
 	if err != nil {
-		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
-	}
-
-	node, exists := tpl.Nodes[nodeID]
-	if !exists {
-		return fmt.Errorf("node %q not found in template", nodeID)
+		return err
 	}
-
-	if node.Type == template.NodeTypeApproval {
-		approvalConfig, ok := node.Config.(template.ApprovalNodeConfigAccessor)
-		if ok && approvalConfig.RequireComment() {
-			if comment == "" {
-				return fmt.Errorf("comment is required for approval node %q", nodeID)
-			}
-		}
-	}
-
-	// 4. 更新任务状态为 approving(如果还是 submitted)
-	if currentState == types.TaskStateSubmitted {
-		adapter := &taskAdapter{task: tsk}
-		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproving, "task rejected")
-		if err != nil {
-			return fmt.Errorf("state transition failed: %w", err)
-		}
-		tsk = newTaskAdapter.(*taskAdapter).task
+	if !rt.isActive(nodeID) {
+		return fmt.Errorf("node %q is not active", nodeID)
 	}
 
-	// 5. 记录审批结果
-	// 初始化节点审批记录
-	if tsk.Approvals == nil {
-		tsk.Approvals = make(map[string]map[string]*task.Approval)
-	}
-	if tsk.Approvals[nodeID] == nil {
-		tsk.Approvals[nodeID] = make(map[string]*task.Approval)
-	}
-
-	// 记录审批结果
-	tsk.Approvals[nodeID][approver] = &task.Approval{
-		Result:    "reject",
-		Comment:   comment,
-		CreatedAt: time.Now(),
-	}
-
-	// 6. 生成审批记录
-	record := &task.Record{
-		ID:          generateRecordID(),
-		TaskID:      id,
-		NodeID:      nodeID,
-		Approver:    approver,
-		Result:      "reject",
-		Comment:     comment,
-		CreatedAt:   time.Now(),
-		Attachments: []string{},
-	}
-
-	// 添加到记录列表
-	tsk.Records = append(tsk.Records, record)
-
-	// 7. 拒绝后,将任务状态转换为 rejected
-	if m.stateMachine.CanTransition(tsk.State, types.TaskStateRejected) {
-		adapter := &taskAdapter{task: tsk}
-		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateRejected, "task rejected")
-		if err != nil {
-			return fmt.Errorf("state transition failed: %w", err)
-		}
-		tsk = newTaskAdapter.(*taskAdapter).task
-
-		// 节点完成,添加到已完成节点列表
-		if tsk.CompletedNodes == nil {
-			tsk.CompletedNodes = []string{}
-		}
-		found := false
-		for _, completedNodeID := range tsk.CompletedNodes {
-			if completedNodeID == nodeID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			tsk.CompletedNodes = append(tsk.CompletedNodes, nodeID)
-		}
-
-		// 保存拒绝节点的输出
-		if tsk.NodeOutputs == nil {
-			tsk.NodeOutputs = make(map[string]json.RawMessage)
-		}
-		output := json.RawMessage(`{"result":"reject"}`)
-		tsk.NodeOutputs[nodeID] = output
-	}
-
-	// 8. 更新任务更新时间
-	tsk.UpdatedAt = time.Now()
-
-	// 9. 序列化并保存到数据库
-	data, err := json.Marshal(tsk)
+	// 按节点的多人审批策略校验审批人
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
-	}
-
-	taskModel := &model.TaskModel{
-		ID:              tsk.ID,
-		TemplateID:      tsk.TemplateID,
-		TemplateVersion: tsk.TemplateVersion,
-		BusinessID:      tsk.BusinessID,
-		State:           string(tsk.State),
-		CurrentNode:     tsk.CurrentNode,
-		Data:            data,
-		CreatedAt:       tsk.CreatedAt,
-		UpdatedAt:       tsk.UpdatedAt,
-		SubmittedAt:     tsk.SubmittedAt,
+		return fmt.Errorf("failed to load template flow: %w", err)
 	}
-
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	policy, err := flow.approvalPolicyFor(nodeID)
+	if err != nil {
+		return err
 	}
-
-	// 10. 生成拒绝事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
+	if err := policy.checkTurn(nodeID, tsk.Approvers[nodeID], tsk.Approvals[nodeID], approver); err != nil {
+		return err
 	}
 
-	return nil
-}
-
-// RejectWithAttachments 审批人进行拒绝操作(带附件)
-func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver string, comment string, attachments []string) error {
-	// 1. 获取任务
-	tsk, err := m.Get(id)
+	// 节点的驳回动作,驳回目标在节点确实被驳回时才确定
+	onReject, err := flow.rejectActionFor(nodeID)
 	if err != nil {
-		return fmt.Errorf("failed to get task: %w", err)
-	}
-
-	// 2. 验证任务状态(只有 submitted 或 approving 状态才能拒绝)
-	currentState := tsk.State
-	if currentState != types.TaskStateSubmitted && currentState != types.TaskStateApproving {
-		return fmt.Errorf("task state %q cannot be rejected", currentState)
+		return err
 	}
 
 	// 3. 获取模板和节点配置,验证审批意见和附件要求
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
 		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
 	}
@@ -926,12 +684,17 @@ func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver
 			if approvalConfig.RequireComment() && comment == "" {
 				return fmt.Errorf("comment is required for approval node %q", nodeID)
 			}
-			if approvalConfig.RequireAttachments() && len(attachments) == 0 {
+			if checkAttachments && approvalConfig.RequireAttachments() && len(attachments) == 0 {
 				return fmt.Errorf("attachments are required for approval node %q", nodeID)
 			}
 		}
 	}
 
+	// 应用前置钩子修改的任务参数
+	if err := m.applyPreActionOutcome(tsk); err != nil {
+		return err
+	}
+
 	// 4. 更新任务状态为 approving(如果还是 submitted)
 	if currentState == types.TaskStateSubmitted {
 		adapter := &taskAdapter{task: tsk}
@@ -973,36 +736,25 @@ func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver
 	// 添加到记录列表
 	tsk.Records = append(tsk.Records, record)
 
-	// 7. 拒绝后,将任务状态转换为 rejected
-	if m.stateMachine.CanTransition(tsk.State, types.TaskStateRejected) {
-		adapter := &taskAdapter{task: tsk}
-		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateRejected, "task rejected")
-		if err != nil {
-			return fmt.Errorf("state transition failed: %w", err)
-		}
-		tsk = newTaskAdapter.(*taskAdapter).task
+	// 保存审批记录到数据库
+	if err := m.saveRecord(record, rt); err != nil {
+		return err
+	}
 
-		// 节点完成,添加到已完成节点列表
-		if tsk.CompletedNodes == nil {
-			tsk.CompletedNodes = []string{}
-		}
-		found := false
-		for _, completedNodeID := range tsk.CompletedNodes {
-			if completedNodeID == nodeID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			tsk.CompletedNodes = append(tsk.CompletedNodes, nodeID)
+	// 7. 节点被驳回后按驳回动作处理: 结束任务(转换为 rejected)、驳回到之前的审批节点或退回发起人
+	// 投票类模式下单人拒绝不一定驳回节点,此时任务继续等待其他审批人,也不需要驳回目标
+	outcome := policy.evaluate(tsk.Approvers[nodeID], tsk.Approvals[nodeID], "reject")
+	nodeRejected := outcome == nodeOutcomeRejected
+	var rejectMode, rejectTarget string
+	if nodeRejected {
+		rejectMode, rejectTarget, err = onReject.resolve(tsk, flow, nodeID, targetNodeID)
+		if err != nil {
+			return err
 		}
-
-		// 保存拒绝节点的输出
-		if tsk.NodeOutputs == nil {
-			tsk.NodeOutputs = make(map[string]json.RawMessage)
+		tsk, err = m.applyRejectAction(tsk, rt, flow, nodeID, rejectMode, rejectTarget, approver, comment, "task rejected", json.RawMessage(`{"result":"reject"}`))
+		if err != nil {
+			return err
 		}
-		output := json.RawMessage(`{"result":"reject"}`)
-		tsk.NodeOutputs[nodeID] = output
 	}
 
 	// 8. 更新任务更新时间
@@ -1013,6 +765,10 @@ func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver
 	if err != nil {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
 
 	taskModel := &model.TaskModel{
 		ID:              tsk.ID,
@@ -1022,19 +778,22 @@ func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver
 		State:           string(tsk.State),
 		CurrentNode:     tsk.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
 	// 10. 生成拒绝事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
+	m.emit(EventTaskRejected, tsk, nodeID, approver, "reject", comment)
+	if nodeRejected {
+		m.emitRejectAction(tsk, nodeID, rejectMode, rejectTarget, approver, comment)
 	}
+	m.emitCompleted(tsk, nodeID, approver)
 
 	return nil
 }
@@ -1042,6 +801,13 @@ func (m *dbTaskManager) RejectWithAttachments(id string, nodeID string, approver
 // Cancel 取消任务
 // 使用状态机进行状态转换,从当前状态转换为 cancelled
 func (m *dbTaskManager) Cancel(id string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.cancel(id, reason)
+	})
+}
+
+// cancel Cancel 的事务内实现
+func (m *dbTaskManager) cancel(id string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1083,10 +849,13 @@ func (m *dbTaskManager) Cancel(id string, reason string) error {
 		SubmittedAt:     newTask.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
+	// 生成取消事件
+	m.emit(EventTaskCancelled, newTask, "", m.actor(), "cancel", reason)
+
 	return nil
 }
 
@@ -1094,6 +863,13 @@ func (m *dbTaskManager) Cancel(id string, reason string) error {
 // 撤回会将任务从 submitted 或 approving 状态撤回回 pending 状态
 // 如果任务已有审批记录,不允许撤回
 func (m *dbTaskManager) Withdraw(id string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.withdraw(id, reason)
+	})
+}
+
+// withdraw Withdraw 的事务内实现
+func (m *dbTaskManager) withdraw(id string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1137,6 +913,12 @@ func (m *dbTaskManager) Withdraw(id string, reason string) error {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
 
+	// 撤回后任务回到待提交状态,没有活动节点
+	runtimeData, err := (&taskRuntime{}).marshal()
+	if err != nil {
+		return err
+	}
+
 	taskModel := &model.TaskModel{
 		ID:              newTask.ID,
 		TemplateID:      newTask.TemplateID,
@@ -1145,19 +927,18 @@ func (m *dbTaskManager) Withdraw(id string, reason string) error {
 		State:           string(newTask.State),
 		CurrentNode:     newTask.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       newTask.CreatedAt,
 		UpdatedAt:       newTask.UpdatedAt,
 		SubmittedAt:     newTask.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
 	// 9. 生成撤回事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
+	m.emit(EventTaskWithdrawn, newTask, "", m.actor(), "withdraw", reason)
 
 	return nil
 }
@@ -1166,6 +947,13 @@ func (m *dbTaskManager) Withdraw(id string, reason string) error {
 // 将审批任务从原审批人转交给新审批人
 // 转交需要节点配置允许转交,且原审批人必须是当前审批人
 func (m *dbTaskManager) Transfer(id string, nodeID string, fromApprover string, toApprover string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.transfer(id, nodeID, fromApprover, toApprover, reason)
+	})
+}
+
+// transfer Transfer 的事务内实现
+func (m *dbTaskManager) transfer(id string, nodeID string, fromApprover string, toApprover string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1173,7 +961,7 @@ func (m *dbTaskManager) Transfer(id string, nodeID string, fromApprover string,
 	}
 
 	// 2. 获取模板
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
 		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
 	}
@@ -1201,7 +989,15 @@ func (m *dbTaskManager) Transfer(id string, nodeID string, fromApprover string,
 		}
 	}
 
-	// 7. 检查原审批人是否是任务的审批人
+	// 7. 检查节点处于活动状态,且原审批人是任务的审批人
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if !rt.isActive(nodeID) {
+		return fmt.Errorf("node %q is not active", nodeID)
+	}
+
 	approvers, exists := tsk.Approvers[nodeID]
 	if !exists {
 		return fmt.Errorf("approvers not found for node %q", nodeID)
@@ -1261,14 +1057,26 @@ func (m *dbTaskManager) Transfer(id string, nodeID string, fromApprover string,
 	// 添加到记录列表
 	tsk.Records = append(tsk.Records, record)
 
-	// 11. 更新任务更新时间
+	// 11. 生成转交事件
+	m.emit(EventTaskTransferred, tsk, nodeID, fromApprover, "transfer", reason)
+
+	// 12. 新审批人处于委托期间时转给其代理人
+	if _, err := m.applyDelegations(tsk, rt, nodeID); err != nil {
+		return err
+	}
+
+	// 13. 更新任务更新时间
 	tsk.UpdatedAt = time.Now()
 
-	// 12. 序列化并保存到数据库
+	// 14. 序列化并保存到数据库
 	data, err := json.Marshal(tsk)
 	if err != nil {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
 
 	taskModel := &model.TaskModel{
 		ID:              tsk.ID,
@@ -1278,27 +1086,26 @@ func (m *dbTaskManager) Transfer(id string, nodeID string, fromApprover string,
 		State:           string(tsk.State),
 		CurrentNode:     tsk.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
-	}
-
-	// 13. 生成转交事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
-
-	return nil
+	return m.saveTask(taskModel)
 }
 
 // AddApprover 加签
 // 在审批人列表中添加新的审批人
 // 加签需要节点配置允许加签
 func (m *dbTaskManager) AddApprover(id string, nodeID string, approver string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.addApprover(id, nodeID, approver, reason)
+	})
+}
+
+// addApprover AddApprover 的事务内实现
+func (m *dbTaskManager) addApprover(id string, nodeID string, approver string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1306,7 +1113,7 @@ func (m *dbTaskManager) AddApprover(id string, nodeID string, approver string, r
 	}
 
 	// 2. 获取模板
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
 		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
 	}
@@ -1371,14 +1178,32 @@ func (m *dbTaskManager) AddApprover(id string, nodeID string, approver string, r
 	// 添加到记录列表
 	tsk.Records = append(tsk.Records, record)
 
-	// 9. 更新任务更新时间
+	// 9. 生成加签事件
+	m.emit(EventApproverAdded, tsk, nodeID, m.actor(), "add_approver", reason)
+
+	// 10. 节点处于活动状态且新审批人处于委托期间时转给其代理人(未激活的节点在激活时处理)
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if rt.isActive(nodeID) {
+		if _, err := m.applyDelegations(tsk, rt, nodeID); err != nil {
+			return err
+		}
+	}
+
+	// 11. 更新任务更新时间
 	tsk.UpdatedAt = time.Now()
 
-	// 10. 序列化并保存到数据库
+	// 12. 序列化并保存到数据库
 	data, err := json.Marshal(tsk)
 	if err != nil {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
 
 	taskModel := &model.TaskModel{
 		ID:              tsk.ID,
@@ -1388,27 +1213,26 @@ func (m *dbTaskManager) AddApprover(id string, nodeID string, approver string, r
 		State:           string(tsk.State),
 		CurrentNode:     tsk.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
-	}
-
-	// 11. 生成加签事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
-
-	return nil
+	return m.saveTask(taskModel)
 }
 
 // RemoveApprover 减签
 // 从审批人列表中移除指定的审批人
 // 减签需要节点配置允许减签,且审批人必须在审批人列表中
 func (m *dbTaskManager) RemoveApprover(id string, nodeID string, approver string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.removeApprover(id, nodeID, approver, reason)
+	})
+}
+
+// removeApprover RemoveApprover 的事务内实现
+func (m *dbTaskManager) removeApprover(id string, nodeID string, approver string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1416,7 +1240,7 @@ func (m *dbTaskManager) RemoveApprover(id string, nodeID string, approver string
 	}
 
 	// 2. 获取模板
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
 		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
 	}
@@ -1475,8 +1299,34 @@ func (m *dbTaskManager) RemoveApprover(id string, nodeID string, approver string
 			newApprovers = append(newApprovers, existingApprover)
 		}
 	}
+	// 审批人列表为空表示不限制审批人,不允许减掉最后一个审批人
+	if len(newApprovers) == 0 {
+		return fmt.Errorf("cannot remove the last approver of node %q", nodeID)
+	}
 	tsk.Approvers[nodeID] = newApprovers
 
+	// 按剩余审批人重新计算节点结论: 减掉的是唯一未审批的人时节点直接通过,
+	// 剩余审批人已无法通过时不允许减签(应由审批人拒绝)
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	policy, err := flow.approvalPolicyFor(nodeID)
+	if err != nil {
+		return err
+	}
+	outcome := nodeOutcomePending
+	if rt.isActive(nodeID) {
+		outcome = policy.evaluate(newApprovers, tsk.Approvals[nodeID], "")
+	}
+	if outcome == nodeOutcomeRejected {
+		return fmt.Errorf("cannot remove approver %q: the remaining approvers of node %q can no longer approve it", approver, nodeID)
+	}
+
 	// 10. 生成减签记录
 	record := &task.Record{
 		ID:          generateRecordID(),
@@ -1492,36 +1342,37 @@ func (m *dbTaskManager) RemoveApprover(id string, nodeID string, approver string
 	// 添加到记录列表
 	tsk.Records = append(tsk.Records, record)
 
-	// 11. 更新任务更新时间
-	tsk.UpdatedAt = time.Now()
-
-	// 12. 序列化并保存到数据库
-	data, err := json.Marshal(tsk)
-	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
+	if outcome == nodeOutcomeApproved {
+		setNodeOutput(tsk, nodeID, json.RawMessage(`{"result":"approve"}`))
+		if err := m.completeNode(tsk, rt, flow, nodeID); err != nil {
+			return fmt.Errorf("failed to select next node: %w", err)
+		}
+		// 所有分支都到达结束节点(没有活动节点)时任务审批通过
+		if len(rt.ActiveNodes) == 0 && m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
+			adapter := &taskAdapter{task: tsk}
+			oldState := tsk.State
+			newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproved, "remaining approvers approved")
+			if err != nil {
+				return fmt.Errorf("state transition failed: %w", err)
+			}
+			tsk = newTaskAdapter.(*taskAdapter).task
+			if err := m.saveStateHistory(id, oldState, tsk.State, "remaining approvers approved", m.actor()); err != nil {
+				return fmt.Errorf("failed to save state history: %w", err)
+			}
+		}
 	}
 
-	taskModel := &model.TaskModel{
-		ID:              tsk.ID,
-		TemplateID:      tsk.TemplateID,
-		TemplateVersion: tsk.TemplateVersion,
-		BusinessID:      tsk.BusinessID,
-		State:           string(tsk.State),
-		CurrentNode:     tsk.CurrentNode,
-		Data:            data,
-		CreatedAt:       tsk.CreatedAt,
-		UpdatedAt:       tsk.UpdatedAt,
-		SubmittedAt:     tsk.SubmittedAt,
-	}
+	// 11. 更新任务更新时间
+	tsk.UpdatedAt = time.Now()
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	// 12. 保存任务数据和运行时状态
+	if err := m.saveTaskData(tsk, rt); err != nil {
+		return err
 	}
 
 	// 13. 生成减签事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
+	m.emit(EventApproverRemoved, tsk, nodeID, m.actor(), "remove_approver", reason)
+	m.emitCompleted(tsk, nodeID, m.actor())
 
 	return nil
 }
@@ -1614,8 +1465,6 @@ func findStartNode(tpl *template.Template) string {
 	return ""
 }
 
-// findNextNode 查找指定节点的下一个节点
-// 从 pkg/template 导入边类型,实现节点查找逻辑
 // saveStateHistory 保存状态历史到数据库
 func (m *dbTaskManager) saveStateHistory(taskID string, fromState types.TaskState, toState types.TaskState, reason string, operator string) error {
 	historyModel := &model.StateHistoryModel{
@@ -1630,137 +1479,379 @@ func (m *dbTaskManager) saveStateHistory(taskID string, fromState types.TaskStat
 	return m.historyRepo.Save(historyModel)
 }
 
+// saveRecord 保存审批记录到数据库
+// 记录所属的审批轮次和代理审批时的委托人从运行时状态中获取
+func (m *dbTaskManager) saveRecord(record *task.Record, rt *taskRuntime) error {
+	attachmentsJSON, _ := json.Marshal(record.Attachments)
+	recordModel := &model.ApprovalRecordModel{
+		ID:          record.ID,
+		TaskID:      record.TaskID,
+		NodeID:      record.NodeID,
+		Approver:    record.Approver,
+		Result:      record.Result,
+		Comment:     record.Comment,
+		Attachments: attachmentsJSON,
+		OnBehalfOf:  delegatedFrom(rt, record.NodeID, record.Approver),
+		Round:       rt.round(),
+		CreatedAt:   record.CreatedAt,
+	}
+	if err := m.recordRepo.Save(recordModel); err != nil {
+		return fmt.Errorf("failed to save approval record: %w", err)
+	}
+	return nil
+}
+
+// saveTaskData 保存任务数据和运行时状态
+func (m *dbTaskManager) saveTaskData(tsk *task.Task, rt *taskRuntime) error {
+	data, err := json.Marshal(tsk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
+
+	taskModel := &model.TaskModel{
+		ID:              tsk.ID,
+		TemplateID:      tsk.TemplateID,
+		TemplateVersion: tsk.TemplateVersion,
+		BusinessID:      tsk.BusinessID,
+		State:           string(tsk.State),
+		CurrentNode:     tsk.CurrentNode,
+		Data:            data,
+		Runtime:         runtimeData,
+		CreatedAt:       tsk.CreatedAt,
+		UpdatedAt:       tsk.UpdatedAt,
+		SubmittedAt:     tsk.SubmittedAt,
+	}
+
+	return m.saveTask(taskModel)
+}
+
 // generateHistoryID 生成状态历史 ID
 func generateHistoryID() string {
 	return fmt.Sprintf("hist-%d", time.Now().UnixNano())
 }
 
-func findNextNode(tpl *template.Template, nodeID string) string {
-	for _, edge := range tpl.Edges {
-		if edge.From == nodeID {
-			return edge.To
-		}
+// completeNode 节点完成后推进流程
+// 将节点移出活动集合,沿出边激活后续节点(网关节点自动流转),最后处理可以汇聚的并行分支
+func (m *dbTaskManager) completeNode(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string) error {
+	rt.deactivate(nodeID)
+	markNodeCompleted(tsk, nodeID)
+	if err := m.leaveNode(tsk, rt, flow, nodeID, 0); err != nil {
+		return err
 	}
-	return ""
-}
-
-// HandleTimeout 处理任务超时
-func (m *dbTaskManager) HandleTimeout(id string) error {
-	// 1. 获取任务
-	tsk, err := m.Get(id)
-	if err != nil {
-		return fmt.Errorf("failed to get task: %w", err)
+	if err := m.settleJoins(tsk, rt, flow); err != nil {
+		return err
 	}
+	syncCurrentNode(tsk, rt)
+	return nil
+}
 
-	// 2. 检查任务是否超时
-	// 只有 submitted 或 approving 状态的任务才需要检查超时
-	currentState := tsk.State
-	if currentState != types.TaskStateSubmitted && currentState != types.TaskStateApproving {
-		// 任务不在需要检查超时的状态,直接返回
+// leaveNode 沿节点出边流转到后续节点
+// depth 为本次自动流转经过的节点数,超过节点总数说明网关之间形成环路
+func (m *dbTaskManager) leaveNode(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, depth int) error {
+	if flow.nodeType(nodeID) == NodeTypeParallelSplit {
+		branches, err := flow.selectParallelBranches(nodeID, tsk)
+		if err != nil {
+			return err
+		}
+		output, _ := json.Marshal(map[string][]string{"branches": branches})
+		setNodeOutput(tsk, nodeID, output)
+		for _, branch := range branches {
+			if err := m.enterNode(tsk, rt, flow, nodeID, branch, depth+1); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 
-	// 3. 获取模板和当前节点
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	nextNodeID, err := flow.selectNextNode(nodeID, tsk)
 	if err != nil {
-		return fmt.Errorf("failed to get template: %w", err)
+		return err
 	}
-
-	currentNodeID := tsk.CurrentNode
-	if currentNodeID == "" {
-		// 没有当前节点,无法检查超时
+	if nextNodeID == "" {
 		return nil
 	}
+	return m.enterNode(tsk, rt, flow, nodeID, nextNodeID, depth+1)
+}
 
-	node, exists := tpl.Nodes[currentNodeID]
-	if !exists {
-		// 节点不存在,无法检查超时
-		return nil
+// enterNode 进入节点
+// 条件网关、并行分支网关和通知节点自动流转,汇聚网关记录分支到达,结束节点终止当前分支,
+// 服务节点和定时节点等待后台扫描器处理,子流程节点等待子任务结束,其余节点加入活动集合等待处理
+func (m *dbTaskManager) enterNode(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, fromNodeID string, nodeID string, depth int) error {
+	if depth > len(flow.Nodes) {
+		return fmt.Errorf("gateways form a cycle at node %q", nodeID)
 	}
 
-	// 4. 检查节点类型和配置
-	if node.Type != template.NodeTypeApproval {
-		// 只有审批节点才需要检查超时
+	switch flow.nodeType(nodeID) {
+	case NodeTypeCondition:
+		// 记录网关的选择结果
+		nextNodeID, err := flow.selectNextNode(nodeID, tsk)
+		if err != nil {
+			return err
+		}
+		markNodeCompleted(tsk, nodeID)
+		output, _ := json.Marshal(map[string]string{"selected": nextNodeID})
+		setNodeOutput(tsk, nodeID, output)
+		if nextNodeID == "" {
+			return nil
+		}
+		return m.enterNode(tsk, rt, flow, nodeID, nextNodeID, depth+1)
+	case NodeTypeParallelSplit:
+		markNodeCompleted(tsk, nodeID)
+		return m.leaveNode(tsk, rt, flow, nodeID, depth)
+	case NodeTypeParallelJoin:
+		// 汇聚条件在本次流转结束后统一判断,避免其他分支尚未激活时提前汇聚
+		rt.arrive(nodeID, fromNodeID)
+		return nil
+	case string(template.NodeTypeEnd):
+		tsk.CurrentNode = nodeID
+		return nil
+	case string(template.NodeTypeApproval):
+		// 审批节点激活时按配置的来源解析审批人
+		if err := m.resolveApprovers(tsk, rt, flow, nodeID); err != nil {
+			return err
+		}
+		rt.activate(nodeID)
+		m.emit(EventNodeActivated, tsk, nodeID, "", "", "")
+		// 处于委托期间的审批人转给其代理人
+		_, err := m.applyDelegations(tsk, rt, nodeID)
+		return err
+	case NodeTypeService:
+		m.activateService(tsk, rt, nodeID)
+		return nil
+	case NodeTypeTimer:
+		return m.activateTimer(tsk, rt, flow, nodeID)
+	case NodeTypeNotify:
+		return m.enterNotify(tsk, rt, flow, nodeID, depth)
+	case NodeTypeSubprocess:
+		return m.enterSubprocess(tsk, rt, flow, nodeID)
+	default:
+		rt.activate(nodeID)
+		m.emit(EventNodeActivated, tsk, nodeID, "", "", "")
 		return nil
 	}
+}
 
-	// 5. 获取审批节点配置
-	config, ok := node.Config.(template.ApprovalNodeConfigAccessor)
-	if !ok {
-		// 节点配置不是审批节点配置,无法检查超时
-		return nil
+// settleJoins 检查已有分支到达的汇聚节点,满足汇聚条件时继续流转
+// 汇聚条件: 配置需要的分支已全部到达,或者没有任何活动节点还能到达该汇聚节点(未被激活的条件分支不再等待)
+func (m *dbTaskManager) settleJoins(tsk *task.Task, rt *taskRuntime, flow *flowDefinition) error {
+	for rounds := 0; ; rounds++ {
+		if rounds > len(flow.Nodes) {
+			return fmt.Errorf("parallel joins form a cycle")
+		}
+
+		joinID, err := m.readyJoin(rt, flow)
+		if err != nil {
+			return err
+		}
+		if joinID == "" {
+			return nil
+		}
+
+		arrived := rt.JoinArrivals[joinID]
+		delete(rt.JoinArrivals, joinID)
+
+		// 汇聚后,仍在进行中的同一并行块分支不再需要处理
+		var cancelled []string
+		for _, activeNodeID := range append([]string{}, rt.ActiveNodes...) {
+			if flow.reaches(activeNodeID, joinID) {
+				m.detachNode(rt, activeNodeID)
+				cancelled = append(cancelled, activeNodeID)
+			}
+		}
+
+		markNodeCompleted(tsk, joinID)
+		output, _ := json.Marshal(map[string][]string{"arrived": arrived, "cancelled": cancelled})
+		setNodeOutput(tsk, joinID, output)
+		if err := m.leaveNode(tsk, rt, flow, joinID, 0); err != nil {
+			return err
+		}
 	}
+}
 
-	// 6. 检查是否配置了超时
-	timeout := config.GetTimeout()
-	if timeout == nil {
-		// 未配置超时,直接返回
-		return nil
+// readyJoin 返回一个满足汇聚条件的汇聚节点 ID,没有时返回空字符串
+func (m *dbTaskManager) readyJoin(rt *taskRuntime, flow *flowDefinition) (string, error) {
+	joinIDs := make([]string, 0, len(rt.JoinArrivals))
+	for joinID := range rt.JoinArrivals {
+		joinIDs = append(joinIDs, joinID)
 	}
+	sort.Strings(joinIDs)
+
+	for _, joinID := range joinIDs {
+		cfg, err := flow.joinConfig(joinID)
+		if err != nil {
+			return "", err
+		}
+
+		candidates := cfg.Branches
+		if len(candidates) == 0 {
+			candidates = flow.incoming(joinID)
+		}
+		required := cfg.Required
+		if required == 0 {
+			required = len(candidates)
+		}
+
+		arrivedCount := 0
+		for _, arrived := range rt.JoinArrivals[joinID] {
+			if containsString(candidates, arrived) {
+				arrivedCount++
+			}
+		}
+		if arrivedCount >= required {
+			return joinID, nil
+		}
 
-	// 7. 计算开始时间(使用提交时间或创建时间)
-	startTime := tsk.CreatedAt
-	if tsk.SubmittedAt != nil {
-		startTime = *tsk.SubmittedAt
+		pending := false
+		for _, activeNodeID := range rt.ActiveNodes {
+			if flow.reaches(activeNodeID, joinID) {
+				pending = true
+				break
+			}
+		}
+		if !pending {
+			return joinID, nil
+		}
 	}
+	return "", nil
+}
 
-	// 8. 检查是否超时
-	if time.Since(startTime) <= *timeout {
-		// 未超时,直接返回
-		return nil
+// setNodeOutput 保存节点输出
+func setNodeOutput(tsk *task.Task, nodeID string, output json.RawMessage) {
+	if tsk.NodeOutputs == nil {
+		tsk.NodeOutputs = make(map[string]json.RawMessage)
 	}
+	tsk.NodeOutputs[nodeID] = output
+}
 
-	// 9. 任务已超时,验证当前状态允许转换为超时状态
-	if !m.stateMachine.CanTransition(currentState, types.TaskStateTimeout) {
-		return fmt.Errorf("invalid state transition: cannot timeout task in state %q", currentState)
+// markNodeCompleted 将节点添加到已完成节点列表(已存在时忽略)
+func markNodeCompleted(tsk *task.Task, nodeID string) {
+	if tsk.CompletedNodes == nil {
+		tsk.CompletedNodes = []string{}
 	}
+	for _, completedNodeID := range tsk.CompletedNodes {
+		if completedNodeID == nodeID {
+			return
+		}
+	}
+	tsk.CompletedNodes = append(tsk.CompletedNodes, nodeID)
+}
 
-	// 10. 使用状态机执行状态转换
-	adapter := &taskAdapter{task: tsk}
-	newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateTimeout, "task timeout")
+// HandleTimeout 处理任务超时
+// 检查所有活动审批节点,对已超时的节点执行节点配置的超时动作(见 timeout_policy)
+func (m *dbTaskManager) HandleTimeout(id string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.handleTimeout(id)
+	})
+}
+
+// handleTimeout HandleTimeout 的事务内实现
+func (m *dbTaskManager) handleTimeout(id string) error {
+	// 1. 获取任务
+	tsk, err := m.Get(id)
 	if err != nil {
-		return fmt.Errorf("state transition failed: %w", err)
+		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// 11. 获取转换后的任务对象
-	newTask := newTaskAdapter.(*taskAdapter).task
-	newTask.UpdatedAt = time.Now()
+	// 2. 只有 submitted 或 approving 状态的任务才需要检查超时
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return nil
+	}
 
-	// 12. 序列化并保存到数据库
-	data, err := json.Marshal(newTask)
+	// 3. 获取运行时状态、流程定义和模板
+	rt, err := m.loadRuntime(tsk)
 	if err != nil {
-		return fmt.Errorf("failed to marshal task: %w", err)
+		return err
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get template: %w", err)
 	}
 
-	taskModel := &model.TaskModel{
-		ID:              newTask.ID,
-		TemplateID:      newTask.TemplateID,
-		TemplateVersion: newTask.TemplateVersion,
-		BusinessID:      newTask.BusinessID,
-		State:           string(newTask.State),
-		CurrentNode:     newTask.CurrentNode,
-		Data:            data,
-		CreatedAt:       newTask.CreatedAt,
-		UpdatedAt:       newTask.UpdatedAt,
-		SubmittedAt:     newTask.SubmittedAt,
+	// 4. 逐个检查活动节点,对已超时的节点执行超时动作
+	// 动作可能改变活动集合(自动通过后激活后续节点)或结束任务,因此遍历开始时的快照
+	now := time.Now()
+	handled := false
+	activeNodes := append([]string(nil), rt.ActiveNodes...)
+	for _, nodeID := range activeNodes {
+		if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+			break
+		}
+		if !rt.isActive(nodeID) {
+			continue
+		}
+		policy, err := nodeTimeoutPolicy(flow, tpl, nodeID)
+		if err != nil {
+			return err
+		}
+		if policy == nil {
+			continue
+		}
+		deadline, err := m.nodeDeadline(tsk, rt, flow, nodeID, policy)
+		if err != nil {
+			return err
+		}
+		if now.Before(deadline) {
+			continue
+		}
+		tsk, err = m.applyTimeoutAction(tsk, rt, flow, nodeID, policy)
+		if err != nil {
+			return err
+		}
+		handled = true
+	}
+
+	// 没有超时的节点时只刷新到期时间,避免扫描器重复领取
+	if !handled {
+		return m.refreshSchedule(id)
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	// 5. 序列化并保存到数据库
+	tsk.UpdatedAt = time.Now()
+	data, err := json.Marshal(tsk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
 	}
 
-	// 13. 生成超时事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
+	taskModel := &model.TaskModel{
+		ID:              tsk.ID,
+		TemplateID:      tsk.TemplateID,
+		TemplateVersion: tsk.TemplateVersion,
+		BusinessID:      tsk.BusinessID,
+		State:           string(tsk.State),
+		CurrentNode:     tsk.CurrentNode,
+		Data:            data,
+		Runtime:         runtimeData,
+		CreatedAt:       tsk.CreatedAt,
+		UpdatedAt:       tsk.UpdatedAt,
+		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	return nil
+	return m.saveTask(taskModel)
 }
 
 // Pause 暂停任务
 // 只有 pending、submitted、approving 状态可以暂停
 // 暂停时会记录暂停前的状态,用于恢复时恢复到正确状态
 func (m *dbTaskManager) Pause(id string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.pause(id, reason)
+	})
+}
+
+// pause Pause 的事务内实现
+func (m *dbTaskManager) pause(id string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1811,14 +1902,12 @@ func (m *dbTaskManager) Pause(id string, reason string) error {
 		SubmittedAt:     newTask.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
 	// 8. 生成暂停事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
+	m.emit(EventTaskPaused, newTask, "", m.actor(), "pause", reason)
 
 	return nil
 }
@@ -1827,6 +1916,13 @@ func (m *dbTaskManager) Pause(id string, reason string) error {
 // 只有 paused 状态可以恢复
 // 恢复时会恢复到暂停前的状态(pending、submitted 或 approving)
 func (m *dbTaskManager) Resume(id string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.resume(id, reason)
+	})
+}
+
+// resume Resume 的事务内实现
+func (m *dbTaskManager) resume(id string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -1851,6 +1947,11 @@ func (m *dbTaskManager) Resume(id string, reason string) error {
 		return fmt.Errorf("cannot resume task to state %q from paused state", targetState)
 	}
 
+	pausedAt := tsk.UpdatedAt
+	if tsk.PausedAt != nil {
+		pausedAt = *tsk.PausedAt
+	}
+
 	// 5. 使用状态机执行状态转换
 	adapter := &taskAdapter{task: tsk}
 	newTaskAdapter, err := m.stateMachine.Transition(adapter, targetState, reason)
@@ -1866,6 +1967,23 @@ func (m *dbTaskManager) Resume(id string, reason string) error {
 	newTask.PausedState = types.TaskState("") // 清除暂停前状态
 	newTask.UpdatedAt = time.Now()
 
+	// 重试已用尽而暂停任务的服务节点,恢复后重新调用;节点计时起点和定时节点按暂停时长顺延
+	rt, err := m.loadRuntime(newTask)
+	if err != nil {
+		return err
+	}
+	rt.retryFailedServices()
+	if err := m.shiftActivatedAt(newTask, rt, pausedAt); err != nil {
+		return err
+	}
+	if err := m.rescheduleTimers(newTask, rt, pausedAt); err != nil {
+		return err
+	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
+
 	// 8. 序列化并保存到数据库
 	data, err := json.Marshal(newTask)
 	if err != nil {
@@ -1880,19 +1998,20 @@ func (m *dbTaskManager) Resume(id string, reason string) error {
 		State:           string(newTask.State),
 		CurrentNode:     newTask.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       newTask.CreatedAt,
 		UpdatedAt:       newTask.UpdatedAt,
 		SubmittedAt:     newTask.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
+	// 暂停期间结束的子任务在恢复后推进子流程节点
+	m.queueSubprocesses(rt)
 
 	// 9. 生成恢复事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
+	m.emit(EventTaskResumed, newTask, "", m.actor(), "resume", reason)
 
 	return nil
 }
@@ -1901,6 +2020,13 @@ func (m *dbTaskManager) Resume(id string, reason string) error {
 // 只能回退到已完成的节点
 // 回退时会清理回退节点之后的审批记录和状态
 func (m *dbTaskManager) RollbackToNode(id string, nodeID string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.rollbackToNode(id, nodeID, reason)
+	})
+}
+
+// rollbackToNode RollbackToNode 的事务内实现
+func (m *dbTaskManager) rollbackToNode(id string, nodeID string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -2045,6 +2171,20 @@ func (m *dbTaskManager) RollbackToNode(id string, nodeID string, reason string)
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
 
+	// 回退后目标节点成为唯一活动节点,其余并行分支和汇聚记录一并清除,审批轮次保持不变
+	oldRuntime, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	rt := &taskRuntime{Round: oldRuntime.Round}
+	if targetState != types.TaskStatePending {
+		rt.activate(nodeID)
+	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
+
 	taskModel := &model.TaskModel{
 		ID:              tsk.ID,
 		TemplateID:      tsk.TemplateID,
@@ -2053,18 +2193,20 @@ func (m *dbTaskManager) RollbackToNode(id string, nodeID string, reason string)
 		State:           string(tsk.State),
 		CurrentNode:     tsk.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
+	if err := m.saveTask(taskModel); err != nil {
+		return err
 	}
 
 	// 14. 生成回退事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
+	m.emit(EventTaskRolledBack, tsk, nodeID, m.actor(), "rollback", reason)
+	if targetState != types.TaskStatePending {
+		m.emit(EventNodeActivated, tsk, nodeID, "", "", "")
 	}
 
 	return nil
@@ -2074,6 +2216,13 @@ func (m *dbTaskManager) RollbackToNode(id string, nodeID string, reason string)
 // 只能替换尚未审批的审批人
 // 替换后会保留原审批人的审批记录(如果有),新审批人可以继续审批
 func (m *dbTaskManager) ReplaceApprover(id string, nodeID string, oldApprover string, newApprover string, reason string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.replaceApprover(id, nodeID, oldApprover, newApprover, reason)
+	})
+}
+
+// replaceApprover ReplaceApprover 的事务内实现
+func (m *dbTaskManager) replaceApprover(id string, nodeID string, oldApprover string, newApprover string, reason string) error {
 	// 1. 获取任务
 	tsk, err := m.Get(id)
 	if err != nil {
@@ -2081,7 +2230,7 @@ func (m *dbTaskManager) ReplaceApprover(id string, nodeID string, oldApprover st
 	}
 
 	// 2. 获取模板
-	tpl, err := m.templateMgr.Get(tsk.TemplateID, 0)
+	tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
 	if err != nil {
 		return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
 	}
@@ -2173,14 +2322,32 @@ func (m *dbTaskManager) ReplaceApprover(id string, nodeID string, oldApprover st
 	// 添加到记录列表
 	tsk.Records = append(tsk.Records, record)
 
-	// 10. 更新任务更新时间
+	// 10. 生成替换审批人事件
+	m.emit(EventApproverReplaced, tsk, nodeID, m.actor(), "replace_approver", reason)
+
+	// 11. 节点处于活动状态且新审批人处于委托期间时转给其代理人
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+	if rt.isActive(nodeID) {
+		if _, err := m.applyDelegations(tsk, rt, nodeID); err != nil {
+			return err
+		}
+	}
+
+	// 12. 更新任务更新时间
 	tsk.UpdatedAt = time.Now()
 
-	// 11. 序列化并保存到数据库
+	// 13. 序列化并保存到数据库
 	data, err := json.Marshal(tsk)
 	if err != nil {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
+	runtimeData, err := rt.marshal()
+	if err != nil {
+		return err
+	}
 
 	taskModel := &model.TaskModel{
 		ID:              tsk.ID,
@@ -2190,19 +2357,11 @@ func (m *dbTaskManager) ReplaceApprover(id string, nodeID string, oldApprover st
 		State:           string(tsk.State),
 		CurrentNode:     tsk.CurrentNode,
 		Data:            data,
+		Runtime:         runtimeData,
 		CreatedAt:       tsk.CreatedAt,
 		UpdatedAt:       tsk.UpdatedAt,
 		SubmittedAt:     tsk.SubmittedAt,
 	}
 
-	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", id).Updates(taskModel).Error; err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
-	}
-
-	// 12. 生成替换审批人事件
-	if m.eventHandler != nil {
-		// 事件生成逻辑将在后续实现
-	}
-
-	return nil
+	return m.saveTask(taskModel)
 }
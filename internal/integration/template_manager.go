@@ -12,7 +12,8 @@ import (
 
 // DBTemplateManager 基于数据库的模板管理器(导出以便服务层调用)
 type DBTemplateManager struct {
-	db *gorm.DB
+	db     *gorm.DB
+	signer *WebhookSigner // Webhook 签名器,保存模板时加密存储 HMAC 签名密钥
 }
 
 // dbTemplateManager 基于数据库的模板管理器(内部别名)
@@ -24,58 +25,27 @@ func NewTemplateManager(db *gorm.DB) template.TemplateManager {
 	return &DBTemplateManager{db: db}
 }
 
-// Create 创建模板
-func (m *DBTemplateManager) Create(tpl *template.Template) error {
-	// 1. 序列化模板数据
-	data, err := json.Marshal(tpl)
-	if err != nil {
-		return fmt.Errorf("failed to marshal template: %w", err)
-	}
-
-	// 2. 保存到数据库
-	model := &model.TemplateModel{
-		ID:          tpl.ID,
-		Name:        tpl.Name,
-		Description: tpl.Description,
-		Version:     tpl.Version,
-		Data:        data,
-		CreatedAt:   tpl.CreatedAt,
-		UpdatedAt:   tpl.UpdatedAt,
-	}
-
-	return m.db.Create(model).Error
+// SetWebhookSigner 设置 Webhook 签名器
+// 未设置时模板不能配置新的 HMAC 签名密钥
+func (m *DBTemplateManager) SetWebhookSigner(signer *WebhookSigner) {
+	m.signer = signer
 }
 
-func (m *DBTemplateManager) CreateWithNodePositions(tpl *template.Template, rawNodesJSON json.RawMessage) error {
-	// 如果提供了原始节点 JSON，直接使用它来构建模板数据（保留 position 信息）
-	if len(rawNodesJSON) > 0 {
-		// 解析原始节点 JSON
-		var rawNodesMap map[string]interface{}
-		if err := json.Unmarshal(rawNodesJSON, &rawNodesMap); err != nil {
-			return fmt.Errorf("failed to unmarshal raw nodes: %w", err)
+// Create 创建模板
+func (m *DBTemplateManager) Create(tpl *template.Template) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		// 1. 保存 Webhook 签名密钥(模板数据中不保留明文密钥)
+		if err := m.signer.storeSecrets(tx, tpl); err != nil {
+			return err
 		}
 
-		// 将模板对象序列化为 map，以便合并节点数据
-		templateData, err := json.Marshal(tpl)
+		// 2. 序列化模板数据
+		data, err := json.Marshal(tpl)
 		if err != nil {
 			return fmt.Errorf("failed to marshal template: %w", err)
 		}
 
-		var templateMap map[string]interface{}
-		if err := json.Unmarshal(templateData, &templateMap); err != nil {
-			return fmt.Errorf("failed to unmarshal template: %w", err)
-		}
-
-		// 直接使用原始节点 JSON 替换模板中的 nodes（保留 position 信息）
-		// 这样可以确保 position 字段被正确保存
-		templateMap["nodes"] = rawNodesMap
-
-		// 重新序列化模板数据
-		data, err := json.Marshal(templateMap)
-		if err != nil {
-			return fmt.Errorf("failed to remarshal template: %w", err)
-		}
-
+		// 3. 保存到数据库
 		model := &model.TemplateModel{
 			ID:          tpl.ID,
 			Name:        tpl.Name,
@@ -86,14 +56,96 @@ func (m *DBTemplateManager) CreateWithNodePositions(tpl *template.Template, rawN
 			UpdatedAt:   tpl.UpdatedAt,
 		}
 
-		return m.db.Create(model).Error
+		return tx.Create(model).Error
+	})
+}
+
+func (m *DBTemplateManager) CreateWithNodePositions(tpl *template.Template, rawNodesJSON json.RawMessage) error {
+	return m.CreateWithRawGraph(tpl, rawNodesJSON, nil, nil)
+}
+
+// CreateWithRawGraph 使用原始节点、连线和配置 JSON 创建模板
+// 原始 JSON 中 approval-kit 模型之外的字段(节点 position、扩展节点配置、连线条件、Webhook 请求模板、前置钩子等)会被完整保留
+func (m *DBTemplateManager) CreateWithRawGraph(tpl *template.Template, rawNodesJSON json.RawMessage, rawEdgesJSON json.RawMessage, rawConfigJSON json.RawMessage) error {
+	// 如果提供了原始节点、连线或配置 JSON，直接使用它们来构建模板数据
+	if len(rawNodesJSON) > 0 || len(rawEdgesJSON) > 0 || len(rawConfigJSON) > 0 {
+		return m.db.Transaction(func(tx *gorm.DB) error {
+			// 保存 Webhook 签名密钥(模板数据中不保留明文密钥)
+			if err := m.signer.storeSecrets(tx, tpl); err != nil {
+				return err
+			}
+
+			// 将模板对象序列化为 map，以便合并原始数据
+			templateData, err := json.Marshal(tpl)
+			if err != nil {
+				return fmt.Errorf("failed to marshal template: %w", err)
+			}
+
+			var templateMap map[string]interface{}
+			if err := json.Unmarshal(templateData, &templateMap); err != nil {
+				return fmt.Errorf("failed to unmarshal template: %w", err)
+			}
+
+			if len(rawNodesJSON) > 0 {
+				// 解析原始节点 JSON
+				var rawNodesMap map[string]interface{}
+				if err := json.Unmarshal(rawNodesJSON, &rawNodesMap); err != nil {
+					return fmt.Errorf("failed to unmarshal raw nodes: %w", err)
+				}
+				// 直接使用原始节点 JSON 替换模板中的 nodes（保留 position 信息）
+				templateMap["nodes"] = rawNodesMap
+			}
+
+			if len(rawEdgesJSON) > 0 {
+				// 解析原始连线 JSON（保留条件表达式、默认分支等信息）
+				var rawEdges []interface{}
+				if err := json.Unmarshal(rawEdgesJSON, &rawEdges); err != nil {
+					return fmt.Errorf("failed to unmarshal raw edges: %w", err)
+				}
+				templateMap["edges"] = rawEdges
+			}
+
+			// 合并 Webhook 请求模板、前置钩子和工作日历(签名密钥等认证信息不从原始配置合并)
+			if err := mergeWebhookTemplates(templateMap, rawConfigJSON); err != nil {
+				return err
+			}
+			if err := mergePreActionHook(templateMap, rawConfigJSON); err != nil {
+				return err
+			}
+			if err := mergeCalendarID(templateMap, rawConfigJSON); err != nil {
+				return err
+			}
+
+			// 重新序列化模板数据
+			data, err := json.Marshal(templateMap)
+			if err != nil {
+				return fmt.Errorf("failed to remarshal template: %w", err)
+			}
+
+			model := &model.TemplateModel{
+				ID:          tpl.ID,
+				Name:        tpl.Name,
+				Description: tpl.Description,
+				Version:     tpl.Version,
+				Data:        data,
+				CreatedAt:   tpl.CreatedAt,
+				UpdatedAt:   tpl.UpdatedAt,
+			}
+
+			return tx.Create(model).Error
+		})
 	}
 
-	// 如果没有提供原始节点 JSON，使用标准创建方法
+	// 如果没有提供原始 JSON，使用标准创建方法
 	return m.Create(tpl)
 }
 
 func (m *DBTemplateManager) UpdateWithNodePositions(id string, tpl *template.Template, rawNodesJSON json.RawMessage) error {
+	return m.UpdateWithRawGraph(id, tpl, rawNodesJSON, nil, nil)
+}
+
+// UpdateWithRawGraph 使用原始节点、连线和配置 JSON 更新模板(创建新版本)
+func (m *DBTemplateManager) UpdateWithRawGraph(id string, tpl *template.Template, rawNodesJSON json.RawMessage, rawEdgesJSON json.RawMessage, rawConfigJSON json.RawMessage) error {
 	current, err := m.Get(id, 0)
 	if err != nil {
 		return fmt.Errorf("failed to get current template: %w", err)
@@ -102,7 +154,38 @@ func (m *DBTemplateManager) UpdateWithNodePositions(id string, tpl *template.Tem
 	tpl.Version = current.Version + 1
 	tpl.UpdatedAt = time.Now()
 
-	return m.CreateWithNodePositions(tpl, rawNodesJSON)
+	return m.CreateWithRawGraph(tpl, rawNodesJSON, rawEdgesJSON, rawConfigJSON)
+}
+
+// GetRawGraph 获取模板指定版本的原始节点和连线 JSON(包含 position、分支条件等扩展字段)
+func (m *DBTemplateManager) GetRawGraph(id string, version int) (json.RawMessage, json.RawMessage, error) {
+	nodes, edges, _, err := m.GetRawData(id, version)
+	return nodes, edges, err
+}
+
+// GetRawData 获取模板指定版本的原始节点、连线和配置 JSON
+// 配置中包含 Webhook 请求模板等扩展字段,不包含签名密钥
+func (m *DBTemplateManager) GetRawData(id string, version int) (json.RawMessage, json.RawMessage, json.RawMessage, error) {
+	var tm model.TemplateModel
+	query := m.db.Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	} else {
+		query = query.Order("version DESC").Limit(1)
+	}
+	if err := query.First(&tm).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("template not found: %w", err)
+	}
+
+	var raw struct {
+		Nodes  json.RawMessage `json:"nodes"`
+		Edges  json.RawMessage `json:"edges"`
+		Config json.RawMessage `json:"config"`
+	}
+	if err := json.Unmarshal(tm.Data, &raw); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to unmarshal template: %w", err)
+	}
+	return raw.Nodes, raw.Edges, raw.Config, nil
 }
 
 // Get 获取模板
@@ -147,7 +230,13 @@ func (m *dbTemplateManager) Update(id string, tpl *template.Template) error {
 
 // Delete 删除模板
 func (m *dbTemplateManager) Delete(id string) error {
-	return m.db.Where("id = ?", id).Delete(&model.TemplateModel{}).Error
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).Delete(&model.TemplateModel{}).Error; err != nil {
+			return err
+		}
+		// 删除模板时一并删除其 Webhook 签名密钥
+		return tx.Where("template_id = ?", id).Delete(&model.WebhookSecretModel{}).Error
+	})
 }
 
 // ListVersions 列出模板版本
@@ -0,0 +1,535 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/template"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// 节点超时处理动作
+const (
+	// TimeoutActionMark 只将任务标记为超时(默认)
+	TimeoutActionMark = "mark"
+	// TimeoutActionAutoApprove 以 system 身份自动通过节点
+	TimeoutActionAutoApprove = "auto_approve"
+	// TimeoutActionAutoReject 以 system 身份自动驳回任务
+	TimeoutActionAutoReject = "auto_reject"
+	// TimeoutActionEscalate 将未审批的审批人替换为其直属上级,重新计时
+	TimeoutActionEscalate = "escalate"
+	// TimeoutActionReassign 将未审批的审批人替换为备用审批人,重新计时
+	TimeoutActionReassign = "reassign"
+)
+
+// 超时替换审批人时记录的审批人来源
+const (
+	// ApproverSourceEscalation 超时升级为原审批人的上级
+	ApproverSourceEscalation = "escalation"
+	// ApproverSourceReassign 超时转派给备用审批人
+	ApproverSourceReassign = "reassign"
+)
+
+// timeoutPolicy 审批节点的超时策略
+// 从节点原始 config 的 timeout_policy 中解析;未配置时使用 approval-kit 节点配置的 timeout,动作为 mark
+type timeoutPolicy struct {
+	After           string   `json:"after"`                      // 超时时长(Go duration 格式,如 48h、90m),从节点激活开始计时
+	Action          string   `json:"action,omitempty"`           // 超时动作: mark/auto_approve/auto_reject/escalate/reassign
+	BackupApprovers []string `json:"backup_approvers,omitempty"` // reassign: 备用审批人
+	MaxEscalations  int      `json:"max_escalations,omitempty"`  // escalate: 最多升级次数,默认 1
+	Comment         string   `json:"comment,omitempty"`          // auto_approve/auto_reject: 审批意见
+
+	timeout time.Duration
+}
+
+// timeoutPolicyConfig 审批节点中超时策略相关的配置
+type timeoutPolicyConfig struct {
+	TimeoutPolicy *timeoutPolicy `json:"timeout_policy,omitempty"`
+}
+
+// timeoutPolicyFor 解析节点配置的超时策略,未配置时返回 nil
+func (f *flowDefinition) timeoutPolicyFor(nodeID string) (*timeoutPolicy, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, nil
+	}
+	var cfg timeoutPolicyConfig
+	if err := json.Unmarshal(node.Config, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid timeout policy for node %q: %w", nodeID, err)
+	}
+	if cfg.TimeoutPolicy == nil {
+		return nil, nil
+	}
+	if err := cfg.TimeoutPolicy.normalize(); err != nil {
+		return nil, fmt.Errorf("node %q: %w", nodeID, err)
+	}
+	return cfg.TimeoutPolicy, nil
+}
+
+// normalize 补全默认值并校验配置
+func (p *timeoutPolicy) normalize() error {
+	timeout, err := time.ParseDuration(p.After)
+	if err != nil {
+		return fmt.Errorf("invalid timeout_policy.after %q: %w", p.After, err)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("timeout_policy.after must be positive")
+	}
+	p.timeout = timeout
+
+	if p.Action == "" {
+		p.Action = TimeoutActionMark
+	}
+	switch p.Action {
+	case TimeoutActionMark, TimeoutActionAutoApprove, TimeoutActionAutoReject:
+	case TimeoutActionEscalate:
+		if p.MaxEscalations < 0 {
+			return fmt.Errorf("timeout_policy.max_escalations must not be negative")
+		}
+		if p.MaxEscalations == 0 {
+			p.MaxEscalations = 1
+		}
+	case TimeoutActionReassign:
+		if len(p.BackupApprovers) == 0 {
+			return fmt.Errorf("timeout action %q requires backup_approvers", p.Action)
+		}
+	default:
+		return fmt.Errorf("unknown timeout action %q", p.Action)
+	}
+	return nil
+}
+
+// nodeTimeoutPolicy 获取活动审批节点的超时策略,没有配置超时时返回 nil
+func nodeTimeoutPolicy(flow *flowDefinition, tpl *template.Template, nodeID string) (*timeoutPolicy, error) {
+	if flow.nodeType(nodeID) != string(template.NodeTypeApproval) {
+		return nil, nil
+	}
+	policy, err := flow.timeoutPolicyFor(nodeID)
+	if err != nil || policy != nil {
+		return policy, err
+	}
+
+	// 兼容 approval-kit 节点配置中的 timeout
+	node, exists := tpl.Nodes[nodeID]
+	if !exists {
+		return nil, nil
+	}
+	config, ok := node.Config.(template.ApprovalNodeConfigAccessor)
+	if !ok {
+		return nil, nil
+	}
+	timeout := config.GetTimeout()
+	if timeout == nil || *timeout <= 0 {
+		return nil, nil
+	}
+	return &timeoutPolicy{Action: TimeoutActionMark, timeout: *timeout}, nil
+}
+
+// nodeActivatedAt 获取节点的计时起点
+// 旧数据没有记录节点激活时间时,从任务提交时间(或创建时间)开始计时
+func nodeActivatedAt(tsk *task.Task, rt *taskRuntime, nodeID string) time.Time {
+	if activatedAt, ok := rt.ActivatedAt[nodeID]; ok {
+		return activatedAt
+	}
+	if tsk.SubmittedAt != nil {
+		return *tsk.SubmittedAt
+	}
+	return tsk.CreatedAt
+}
+
+// shiftActivatedAt 任务恢复后顺延活动节点的计时起点和提醒时间
+// 保留暂停前已经过的(工作)时间,暂停期间不计入节点超时和提醒
+func (m *dbTaskManager) shiftActivatedAt(tsk *task.Task, rt *taskRuntime, pausedAt time.Time) error {
+	if len(rt.ActiveNodes) == 0 {
+		return nil
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+
+	now := time.Now()
+	if rt.ActivatedAt == nil {
+		rt.ActivatedAt = make(map[string]time.Time)
+	}
+	for _, nodeID := range rt.ActiveNodes {
+		cal, err := nodeCalendar(m.db, flow, nodeID)
+		if err != nil {
+			return err
+		}
+		elapsed := cal.Between(nodeActivatedAt(tsk, rt, nodeID), pausedAt)
+		rt.ActivatedAt[nodeID] = cal.Sub(now, elapsed)
+		rt.shiftReminders(nodeID, cal, pausedAt, now)
+	}
+	return nil
+}
+
+// nodeDeadline 计算节点的超时时间
+// 节点或模板配置了工作日历时,超时时长只计算日历内的工作时间
+func (m *dbTaskManager) nodeDeadline(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, policy *timeoutPolicy) (time.Time, error) {
+	cal, err := nodeCalendar(m.db, flow, nodeID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cal.Add(nodeActivatedAt(tsk, rt, nodeID), policy.timeout), nil
+}
+
+// nextTimeoutAt 计算任务活动节点中最早的超时时间,没有需要计时的节点时返回 nil
+func (m *dbTaskManager) nextTimeoutAt(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, tpl *template.Template) (*time.Time, error) {
+	var earliest *time.Time
+	for _, nodeID := range rt.ActiveNodes {
+		policy, err := nodeTimeoutPolicy(flow, tpl, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			continue
+		}
+		deadline, err := m.nodeDeadline(tsk, rt, flow, nodeID, policy)
+		if err != nil {
+			return nil, err
+		}
+		if earliest == nil || deadline.Before(*earliest) {
+			earliest = &deadline
+		}
+	}
+	return earliest, nil
+}
+
+// refreshSchedule 重新计算并保存任务的超时时间(tasks.timeout_at)、下次提醒时间(tasks.remind_at)、
+// 服务节点调用时间(tasks.service_at)和定时节点触发时间(tasks.timer_at),供后台扫描器查询到期任务。直接更新列,不增加修订号
+func (m *dbTaskManager) refreshSchedule(taskID string) error {
+	var tm model.TaskModel
+	if err := m.db.Where("id = ?", taskID).First(&tm).Error; err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+	var tsk task.Task
+	if err := json.Unmarshal(tm.Data, &tsk); err != nil {
+		return fmt.Errorf("failed to unmarshal task: %w", err)
+	}
+	tsk.State = types.TaskState(tm.State)
+
+	updates := map[string]interface{}{"timeout_at": nil, "remind_at": nil, "service_at": nil, "timer_at": nil}
+	if tsk.State == types.TaskStateSubmitted || tsk.State == types.TaskStateApproving {
+		rt, err := m.loadRuntime(&tsk)
+		if err != nil {
+			return err
+		}
+		if len(rt.ActiveNodes) > 0 {
+			flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+			if err != nil {
+				return fmt.Errorf("failed to load template flow: %w", err)
+			}
+			tpl, err := m.templateMgr.Get(tsk.TemplateID, tsk.TemplateVersion)
+			if err != nil {
+				return fmt.Errorf("failed to get template %q: %w", tsk.TemplateID, err)
+			}
+			timeoutAt, err := m.nextTimeoutAt(&tsk, rt, flow, tpl)
+			if err != nil {
+				return err
+			}
+			remindAt, err := m.nextRemindAt(&tsk, rt, flow)
+			if err != nil {
+				return err
+			}
+			updates["timeout_at"] = timeoutAt
+			updates["remind_at"] = remindAt
+			updates["service_at"] = nextServiceAt(rt)
+			updates["timer_at"] = nextTimerAt(rt)
+		}
+	}
+
+	if err := m.db.Model(&model.TaskModel{}).Where("id = ?", taskID).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to update task schedule: %w", err)
+	}
+	return nil
+}
+
+// applyTimeoutAction 对已超时的活动节点执行超时动作
+// escalate 超过最大升级次数、reassign 已转派过一次、或找不到可升级的上级时,退回 mark 动作
+func (m *dbTaskManager) applyTimeoutAction(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, policy *timeoutPolicy) (*task.Task, error) {
+	switch policy.Action {
+	case TimeoutActionAutoApprove:
+		return m.timeoutApprove(tsk, rt, flow, nodeID, policy)
+	case TimeoutActionAutoReject:
+		return m.timeoutReject(tsk, rt, flow, nodeID, policy)
+	case TimeoutActionEscalate:
+		if rt.Escalations[nodeID] < policy.MaxEscalations {
+			escalated, err := m.timeoutEscalate(tsk, rt, nodeID)
+			if err != nil || escalated {
+				return tsk, err
+			}
+		}
+	case TimeoutActionReassign:
+		if rt.Escalations[nodeID] == 0 {
+			return tsk, m.timeoutReassign(tsk, rt, nodeID, policy)
+		}
+	}
+	return m.timeoutMark(tsk, nodeID)
+}
+
+// timeoutMark 将任务标记为超时
+func (m *dbTaskManager) timeoutMark(tsk *task.Task, nodeID string) (*task.Task, error) {
+	if !m.stateMachine.CanTransition(tsk.State, types.TaskStateTimeout) {
+		return nil, fmt.Errorf("invalid state transition: cannot timeout task in state %q", tsk.State)
+	}
+	adapter := &taskAdapter{task: tsk}
+	newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateTimeout, "task timeout")
+	if err != nil {
+		return nil, fmt.Errorf("state transition failed: %w", err)
+	}
+	tsk = newTaskAdapter.(*taskAdapter).task
+
+	m.emit(EventNodeTimeout, tsk, nodeID, m.actor(), TimeoutActionMark, "")
+	m.emit(EventTaskTimeout, tsk, nodeID, m.actor(), "timeout", "")
+	return tsk, nil
+}
+
+// timeoutApprove 以 system 身份自动通过节点(不论多人审批策略),并推进流程
+func (m *dbTaskManager) timeoutApprove(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, policy *timeoutPolicy) (*task.Task, error) {
+	comment := policy.Comment
+	if comment == "" {
+		comment = "审批超时,自动通过"
+	}
+
+	if tsk.State == types.TaskStateSubmitted {
+		adapter := &taskAdapter{task: tsk}
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproving, "task approved")
+		if err != nil {
+			return nil, fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+	}
+
+	if err := m.recordTimeoutDecision(tsk, rt, nodeID, "approve", comment); err != nil {
+		return nil, err
+	}
+
+	setNodeOutput(tsk, nodeID, json.RawMessage(`{"result":"approve","timeout":true}`))
+	if err := m.completeNode(tsk, rt, flow, nodeID); err != nil {
+		return nil, fmt.Errorf("failed to select next node: %w", err)
+	}
+
+	if len(rt.ActiveNodes) == 0 && m.stateMachine.CanTransition(tsk.State, types.TaskStateApproved) {
+		adapter := &taskAdapter{task: tsk}
+		oldState := tsk.State
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproved, "node timeout auto approved")
+		if err != nil {
+			return nil, fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+
+		if err := m.saveStateHistory(tsk.ID, oldState, tsk.State, "node timeout auto approved", systemOperator); err != nil {
+			return nil, fmt.Errorf("failed to save state history: %w", err)
+		}
+	}
+
+	m.emit(EventNodeTimeout, tsk, nodeID, systemOperator, TimeoutActionAutoApprove, comment)
+	m.emit(EventTaskApproved, tsk, nodeID, systemOperator, "approve", comment)
+	m.emitCompleted(tsk, nodeID, systemOperator)
+	return tsk, nil
+}
+
+// timeoutReject 以 system 身份自动驳回节点(不论多人审批策略),并按节点的驳回动作处理
+// choice 方式没有审批人选择目标,驳回到最近完成的可选目标节点
+func (m *dbTaskManager) timeoutReject(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string, policy *timeoutPolicy) (*task.Task, error) {
+	comment := policy.Comment
+	if comment == "" {
+		comment = "审批超时,自动驳回"
+	}
+	onReject, err := flow.rejectActionFor(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	mode, target := onReject.resolveAutomatic(tsk, flow, nodeID)
+
+	if err := m.recordTimeoutDecision(tsk, rt, nodeID, "reject", comment); err != nil {
+		return nil, err
+	}
+	tsk, err = m.applyRejectAction(tsk, rt, flow, nodeID, mode, target, systemOperator, comment, "node timeout auto rejected", json.RawMessage(`{"result":"reject","timeout":true}`))
+	if err != nil {
+		return nil, err
+	}
+
+	m.emit(EventNodeTimeout, tsk, nodeID, systemOperator, TimeoutActionAutoReject, comment)
+	m.emit(EventTaskRejected, tsk, nodeID, systemOperator, "reject", comment)
+	m.emitRejectAction(tsk, nodeID, mode, target, systemOperator, comment)
+	m.emitCompleted(tsk, nodeID, systemOperator)
+	return tsk, nil
+}
+
+// recordTimeoutDecision 记录 system 在节点上的自动审批结果
+func (m *dbTaskManager) recordTimeoutDecision(tsk *task.Task, rt *taskRuntime, nodeID string, result string, comment string) error {
+	if tsk.Approvals == nil {
+		tsk.Approvals = make(map[string]map[string]*task.Approval)
+	}
+	if tsk.Approvals[nodeID] == nil {
+		tsk.Approvals[nodeID] = make(map[string]*task.Approval)
+	}
+	tsk.Approvals[nodeID][systemOperator] = &task.Approval{
+		Result:    result,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	}
+
+	record := &task.Record{
+		ID:          generateRecordID(),
+		TaskID:      tsk.ID,
+		NodeID:      nodeID,
+		Approver:    systemOperator,
+		Result:      result,
+		Comment:     comment,
+		CreatedAt:   time.Now(),
+		Attachments: []string{},
+	}
+	tsk.Records = append(tsk.Records, record)
+	return m.saveRecord(record, rt)
+}
+
+// pendingApprovers 获取节点上尚未审批的审批人
+func pendingApprovers(tsk *task.Task, nodeID string) []string {
+	var pending []string
+	for _, approver := range tsk.Approvers[nodeID] {
+		if approval, exists := tsk.Approvals[nodeID][approver]; exists && approval != nil {
+			continue
+		}
+		pending = append(pending, approver)
+	}
+	return pending
+}
+
+// timeoutEscalate 将节点上未审批的审批人原位替换为其直属上级(保持依次审批的顺序),并重新计时
+// 没有任何审批人找到上级时返回 false
+func (m *dbTaskManager) timeoutEscalate(tsk *task.Task, rt *taskRuntime, nodeID string) (bool, error) {
+	pending := pendingApprovers(tsk, nodeID)
+	if len(pending) == 0 || m.approverDirectory == nil {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), approverResolveTimeout)
+	defer cancel()
+
+	managers := make(map[string]string)
+	for _, approver := range pending {
+		manager, err := m.approverDirectory.ManagerOf(ctx, approver)
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve manager of %q: %w", approver, err)
+		}
+		if manager != "" && manager != approver {
+			managers[approver] = manager
+		}
+	}
+	if len(managers) == 0 {
+		return false, nil
+	}
+
+	approvers := make([]string, 0, len(tsk.Approvers[nodeID]))
+	replaced := make(map[string]string)
+	var descriptions []string
+	for _, approver := range tsk.Approvers[nodeID] {
+		next := approver
+		if manager, exists := managers[approver]; exists {
+			next = manager
+			replaced[approver] = fmt.Sprintf("超时升级给 %s", manager)
+			descriptions = append(descriptions, fmt.Sprintf("%s -> %s", approver, manager))
+			m.setProvenance(rt, nodeID, approver, manager, &ApproverProvenance{Source: ApproverSourceEscalation, Detail: approver})
+		}
+		if !containsString(approvers, next) {
+			approvers = append(approvers, next)
+		}
+	}
+	tsk.Approvers[nodeID] = approvers
+
+	m.finishReplacement(tsk, rt, nodeID, replaced, TimeoutActionEscalate)
+	m.emit(EventNodeTimeout, tsk, nodeID, systemOperator, TimeoutActionEscalate, "超时升级: "+strings.Join(descriptions, ", "))
+	// 上级处于委托期间时转给其代理人
+	if _, err := m.applyDelegations(tsk, rt, nodeID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// timeoutReassign 将节点上未审批的审批人替换为备用审批人,并重新计时
+// 已审批的审批人保留,备用审批人追加到列表末尾
+func (m *dbTaskManager) timeoutReassign(tsk *task.Task, rt *taskRuntime, nodeID string, policy *timeoutPolicy) error {
+	comment := fmt.Sprintf("超时转派给 %s", strings.Join(policy.BackupApprovers, ", "))
+	pending := pendingApprovers(tsk, nodeID)
+	replaced := make(map[string]string)
+	for _, approver := range pending {
+		replaced[approver] = comment
+		m.setProvenance(rt, nodeID, approver, "", nil)
+	}
+
+	approvers := make([]string, 0, len(tsk.Approvers[nodeID])+len(policy.BackupApprovers))
+	for _, approver := range tsk.Approvers[nodeID] {
+		if _, exists := replaced[approver]; !exists {
+			approvers = append(approvers, approver)
+		}
+	}
+	for _, backup := range policy.BackupApprovers {
+		if !containsString(approvers, backup) {
+			approvers = append(approvers, backup)
+			m.setProvenance(rt, nodeID, "", backup, &ApproverProvenance{Source: ApproverSourceReassign})
+		}
+	}
+	if tsk.Approvers == nil {
+		tsk.Approvers = make(map[string][]string)
+	}
+	tsk.Approvers[nodeID] = approvers
+
+	m.finishReplacement(tsk, rt, nodeID, replaced, TimeoutActionReassign)
+	m.emit(EventNodeTimeout, tsk, nodeID, systemOperator, TimeoutActionReassign, comment)
+	// 备用审批人处于委托期间时转给其代理人
+	_, err := m.applyDelegations(tsk, rt, nodeID)
+	return err
+}
+
+// setProvenance 更新审批人来源: 移除原审批人 from 的来源,记录新审批人 to 的来源
+func (m *dbTaskManager) setProvenance(rt *taskRuntime, nodeID string, from string, to string, provenance *ApproverProvenance) {
+	if rt.ApproverProvenance == nil {
+		rt.ApproverProvenance = make(map[string]map[string]*ApproverProvenance)
+	}
+	if rt.ApproverProvenance[nodeID] == nil {
+		rt.ApproverProvenance[nodeID] = make(map[string]*ApproverProvenance)
+	}
+	if from != "" {
+		delete(rt.ApproverProvenance[nodeID], from)
+	}
+	if to != "" && provenance != nil {
+		rt.ApproverProvenance[nodeID][to] = provenance
+	}
+}
+
+// finishReplacement 为被替换的审批人生成记录,累计升级次数并重新计时
+// replaced 为原审批人 -> 记录说明
+func (m *dbTaskManager) finishReplacement(tsk *task.Task, rt *taskRuntime, nodeID string, replaced map[string]string, action string) {
+	approvers := make([]string, 0, len(replaced))
+	for approver := range replaced {
+		approvers = append(approvers, approver)
+	}
+	sort.Strings(approvers)
+	for _, approver := range approvers {
+		tsk.Records = append(tsk.Records, &task.Record{
+			ID:          generateRecordID(),
+			TaskID:      tsk.ID,
+			NodeID:      nodeID,
+			Approver:    approver,
+			Result:      action,
+			Comment:     replaced[approver],
+			CreatedAt:   time.Now(),
+			Attachments: []string{},
+		})
+	}
+
+	if rt.Escalations == nil {
+		rt.Escalations = make(map[string]int)
+	}
+	rt.Escalations[nodeID]++
+	rt.restartTimer(nodeID)
+}
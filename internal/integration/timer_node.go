@@ -0,0 +1,291 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/task"
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// timerDateLayouts until 取值支持的日期格式,不带时区的格式按服务器本地时区解析
+var timerDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// timerNodeConfig 定时节点配置
+// 从节点原始 config 的 timer 中解析;duration 和 until 二选一
+type timerNodeConfig struct {
+	Duration      string `json:"duration,omitempty"`       // 等待时长(Go duration 格式,如 48h),从节点激活开始计时
+	Until         string `json:"until,omitempty"`          // 等待到的时间,为变量路径,如 params.contract_start
+	BusinessHours bool   `json:"business_hours,omitempty"` // duration 只计算节点或模板工作日历内的工作时间
+
+	duration time.Duration
+	until    *pathNode
+}
+
+// timerNodeConfigHolder 定时节点中定时相关的配置
+type timerNodeConfigHolder struct {
+	Timer *timerNodeConfig `json:"timer,omitempty"`
+}
+
+// TimerState 定时节点的计时状态
+type TimerState struct {
+	FireAt time.Time `json:"fire_at"` // 触发时间
+}
+
+// timerConfigFor 解析定时节点配置
+func (f *flowDefinition) timerConfigFor(nodeID string) (*timerNodeConfig, error) {
+	node, exists := f.Nodes[nodeID]
+	if !exists || node == nil || len(node.Config) == 0 || string(node.Config) == "null" {
+		return nil, fmt.Errorf("timer node %q: timer config is required", nodeID)
+	}
+	var holder timerNodeConfigHolder
+	if err := json.Unmarshal(node.Config, &holder); err != nil {
+		return nil, fmt.Errorf("invalid timer config for node %q: %w", nodeID, err)
+	}
+	cfg := holder.Timer
+	if cfg == nil {
+		return nil, fmt.Errorf("timer node %q: timer config is required", nodeID)
+	}
+
+	if (cfg.Duration == "") == (cfg.Until == "") {
+		return nil, fmt.Errorf("timer node %q: exactly one of duration and until is required", nodeID)
+	}
+	if cfg.Duration != "" {
+		duration, err := time.ParseDuration(cfg.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("timer node %q: invalid duration %q: %w", nodeID, cfg.Duration, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("timer node %q: duration must be positive", nodeID)
+		}
+		cfg.duration = duration
+		return cfg, nil
+	}
+
+	ast, err := parseExpression(cfg.Until)
+	if err != nil {
+		return nil, fmt.Errorf("timer node %q: invalid until: %w", nodeID, err)
+	}
+	path, ok := ast.(*pathNode)
+	if !ok {
+		return nil, fmt.Errorf("timer node %q: until must be a variable path such as params.start_date", nodeID)
+	}
+	if cfg.BusinessHours {
+		return nil, fmt.Errorf("timer node %q: business_hours only applies to duration", nodeID)
+	}
+	cfg.until = path
+	return cfg, nil
+}
+
+// untilTime 求 until 变量路径的值并解析为时间
+// 支持日期字符串和 Unix 时间戳(秒)
+func (c *timerNodeConfig) untilTime(tsk *task.Task) (time.Time, error) {
+	ctx, err := newExprContext(tsk.Params, tsk.NodeOutputs)
+	if err != nil {
+		return time.Time{}, err
+	}
+	value, err := c.until.eval(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to evaluate %q: %w", c.Until, err)
+	}
+	switch v := value.(type) {
+	case string:
+		for _, layout := range timerDateLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(seconds, 0), nil
+		}
+		return time.Time{}, fmt.Errorf("%q is not a valid time: %q", c.Until, v)
+	case float64:
+		return time.Unix(int64(v), 0), nil
+	case nil:
+		return time.Time{}, fmt.Errorf("%q is not set", c.Until)
+	default:
+		return time.Time{}, fmt.Errorf("%q is not a valid time: %v", c.Until, v)
+	}
+}
+
+// timerFireAt 计算定时节点从 start 开始计时的触发时间
+// until 已经过去时立即触发
+func (m *dbTaskManager) timerFireAt(tsk *task.Task, flow *flowDefinition, nodeID string, start time.Time, remaining time.Duration) (time.Time, error) {
+	cfg, err := flow.timerConfigFor(nodeID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cfg.until != nil {
+		fireAt, err := cfg.untilTime(tsk)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("timer node %q: %w", nodeID, err)
+		}
+		if fireAt.Before(start) {
+			return start, nil
+		}
+		return fireAt, nil
+	}
+
+	if remaining < 0 {
+		remaining = cfg.duration
+	}
+	if !cfg.BusinessHours {
+		return start.Add(remaining), nil
+	}
+	cal, err := nodeCalendar(m.db, flow, nodeID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cal.Add(start, remaining), nil
+}
+
+// activateTimer 激活定时节点并计算触发时间,由定时节点扫描器在到期后继续流转
+func (m *dbTaskManager) activateTimer(tsk *task.Task, rt *taskRuntime, flow *flowDefinition, nodeID string) error {
+	fireAt, err := m.timerFireAt(tsk, flow, nodeID, time.Now(), -1)
+	if err != nil {
+		return err
+	}
+	rt.activate(nodeID)
+	if rt.Timers == nil {
+		rt.Timers = make(map[string]*TimerState)
+	}
+	rt.Timers[nodeID] = &TimerState{FireAt: fireAt}
+	m.emit(EventNodeActivated, tsk, nodeID, "", "", "")
+	return nil
+}
+
+// nextTimerAt 计算任务活动定时节点中最早的触发时间,没有定时节点时返回 nil
+func nextTimerAt(rt *taskRuntime) *time.Time {
+	var earliest *time.Time
+	for _, nodeID := range rt.ActiveNodes {
+		timer := rt.Timers[nodeID]
+		if timer == nil {
+			continue
+		}
+		fireAt := timer.FireAt
+		if earliest == nil || fireAt.Before(*earliest) {
+			earliest = &fireAt
+		}
+	}
+	return earliest
+}
+
+// rescheduleTimers 任务恢复后重新计算定时节点的触发时间
+// duration 定时器顺延暂停的时长(只计算暂停时剩余的等待时间),until 定时器按当前任务参数重新求值
+func (m *dbTaskManager) rescheduleTimers(tsk *task.Task, rt *taskRuntime, pausedAt time.Time) error {
+	if len(rt.Timers) == 0 {
+		return nil
+	}
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+
+	now := time.Now()
+	for nodeID, timer := range rt.Timers {
+		cfg, err := flow.timerConfigFor(nodeID)
+		if err != nil {
+			return err
+		}
+		remaining := time.Duration(-1)
+		if cfg.until == nil {
+			var cal *BusinessCalendar
+			if cfg.BusinessHours {
+				if cal, err = nodeCalendar(m.db, flow, nodeID); err != nil {
+					return err
+				}
+			}
+			remaining = cal.Between(pausedAt, timer.FireAt)
+		}
+		fireAt, err := m.timerFireAt(tsk, flow, nodeID, now, remaining)
+		if err != nil {
+			return err
+		}
+		timer.FireAt = fireAt
+	}
+	return nil
+}
+
+// FireTimers 触发任务中已到期的定时节点,节点完成后继续流转
+func (m *dbTaskManager) FireTimers(id string) error {
+	return m.inTx(func(txm *dbTaskManager) error {
+		return txm.fireTimers(id)
+	})
+}
+
+// fireTimers FireTimers 的事务内实现
+func (m *dbTaskManager) fireTimers(id string) error {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	// 暂停期间不触发定时节点,恢复时重新计算触发时间
+	if tsk.State != types.TaskStateSubmitted && tsk.State != types.TaskStateApproving {
+		return nil
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var due []string
+	for _, nodeID := range rt.ActiveNodes {
+		if timer := rt.Timers[nodeID]; timer != nil && !timer.FireAt.After(now) {
+			due = append(due, nodeID)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	flow, err := loadFlow(m.db, tsk.TemplateID, tsk.TemplateVersion)
+	if err != nil {
+		return fmt.Errorf("failed to load template flow: %w", err)
+	}
+	if tsk.State == types.TaskStateSubmitted {
+		adapter := &taskAdapter{task: tsk}
+		newTaskAdapter, err := m.stateMachine.Transition(adapter, types.TaskStateApproving, "timer fired")
+		if err != nil {
+			return fmt.Errorf("state transition failed: %w", err)
+		}
+		tsk = newTaskAdapter.(*taskAdapter).task
+	}
+
+	for _, nodeID := range due {
+		// 前一个定时节点流转时可能已经汇聚取消了该节点
+		if !rt.isActive(nodeID) {
+			continue
+		}
+		output, _ := json.Marshal(map[string]time.Time{
+			"fire_at":  rt.Timers[nodeID].FireAt,
+			"fired_at": now,
+		})
+		setNodeOutput(tsk, nodeID, output)
+		if err := m.completeNode(tsk, rt, flow, nodeID); err != nil {
+			return fmt.Errorf("failed to select next node: %w", err)
+		}
+		m.emit(EventTimerFired, tsk, nodeID, systemOperator, "timer", "")
+	}
+	return m.finishAutomaticNode(tsk, rt, due[len(due)-1], "all nodes completed")
+}
+
+// Timers 获取任务活动定时节点的计时状态
+func (m *DBTaskManager) Timers(id string) (map[string]*TimerState, error) {
+	tsk, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := m.loadRuntime(tsk)
+	if err != nil {
+		return nil, err
+	}
+	return rt.Timers, nil
+}
@@ -0,0 +1,114 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-kit/pkg/types"
+)
+
+// timerTemplate 创建 start -> wait -> review -> end 的模板,timer 为定时节点的 timer 配置 JSON
+func timerTemplate(t *testing.T, env *testEnv, id string, timer string) {
+	t.Helper()
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		"wait":{"id":"wait","type":"timer","config":{"timer":`+timer+`}},
+		`+approvalNode("review", []string{"ann"}, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"wait"},{"from":"wait","to":"review"},{"from":"review","to":"end"}]`)
+}
+
+// timerFireAt 获取定时节点 wait 的触发时间
+func (e *testEnv) timerFireAt(t *testing.T, taskID string) time.Time {
+	t.Helper()
+	timers, err := e.tasks.Timers(taskID)
+	mustNoError(t, err)
+	if timers["wait"] == nil {
+		t.Fatal("timer node wait is not active")
+	}
+	return timers["wait"].FireAt
+}
+
+func TestTimerNodeFiresThroughScheduler(t *testing.T) {
+	env := newTestEnv(t)
+	timerTemplate(t, env, "wait", `{"duration":"1h"}`)
+	taskID := env.startTask(t, "wait", `{}`).ID
+	scheduler := NewTimerScheduler(env.db, env.tasks, SchedulerOptions{})
+
+	fireAt := env.timerFireAt(t, taskID)
+	if fireAt.Before(time.Now().Add(59*time.Minute)) || fireAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("fire at %v, want an hour from now", fireAt)
+	}
+	if tm := env.taskModel(t, taskID); tm.TimerAt == nil || !tm.TimerAt.Equal(fireAt) {
+		t.Fatalf("timer_at = %v, want %v", tm.TimerAt, fireAt)
+	}
+
+	// 未到期时扫描不处理
+	scheduler.Scan()
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"wait"})
+
+	env.setRuntime(t, taskID, func(rt *taskRuntime) { rt.Timers["wait"].FireAt = time.Now().Add(-time.Second) })
+	scheduler.Scan()
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"review"})
+	tsk := env.getTask(t, taskID)
+	if tsk.State != types.TaskStateApproving {
+		t.Fatalf("state = %s, want %s", tsk.State, types.TaskStateApproving)
+	}
+	var output map[string]time.Time
+	mustNoError(t, json.Unmarshal(tsk.NodeOutputs["wait"], &output))
+	if output["fire_at"].IsZero() || output["fired_at"].IsZero() {
+		t.Fatalf("timer output = %s", tsk.NodeOutputs["wait"])
+	}
+	if tm := env.taskModel(t, taskID); tm.TimerAt != nil {
+		t.Fatalf("timer_at = %v after firing, want nil", tm.TimerAt)
+	}
+}
+
+func TestTimerNodeWaitsUntilParam(t *testing.T) {
+	env := newTestEnv(t)
+	timerTemplate(t, env, "until", `{"until":"params.start_date"}`)
+
+	future := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	taskID := env.startTask(t, "until", `{"start_date":"`+future.Format(time.RFC3339)+`"}`).ID
+	if got := env.timerFireAt(t, taskID); !got.Equal(future) {
+		t.Fatalf("fire at %v, want %v", got, future)
+	}
+
+	// 已经过去的时间立即触发
+	past := env.startTask(t, "until", `{"start_date":"2020-01-01"}`).ID
+	mustNoError(t, env.tasks.FireTimers(past))
+	assertEqualStrings(t, env.activeNodes(t, past), []string{"review"})
+
+	// 参数缺失时无法计算触发时间,提交失败
+	tsk, err := env.tasks.Create("until", "biz", json.RawMessage(`{}`))
+	mustNoError(t, err)
+	if err := env.tasks.Submit(tsk.ID); err == nil {
+		t.Fatal("expected submission without start_date to fail")
+	}
+}
+
+func TestPausedTimerShiftsOnResume(t *testing.T) {
+	env := newTestEnv(t)
+	timerTemplate(t, env, "wait", `{"duration":"1h"}`)
+	taskID := env.startTask(t, "wait", `{}`).ID
+
+	// 暂停时还剩 30 分钟,暂停期间原触发时间已过
+	pausedAt := time.Now().Add(-2 * time.Hour)
+	env.setRuntime(t, taskID, func(rt *taskRuntime) { rt.Timers["wait"].FireAt = pausedAt.Add(30 * time.Minute) })
+	env.pauseSince(t, taskID, pausedAt)
+
+	// 暂停期间不触发
+	mustNoError(t, env.tasks.FireTimers(taskID))
+	if got := env.getTask(t, taskID).State; got != types.TaskStatePaused {
+		t.Fatalf("state = %s, want %s", got, types.TaskStatePaused)
+	}
+
+	mustNoError(t, env.tasks.Resume(taskID, "continue"))
+	fireAt := env.timerFireAt(t, taskID)
+	if remaining := time.Until(fireAt); remaining < 29*time.Minute || remaining > 30*time.Minute {
+		t.Fatalf("timer fires in %s after resume, want 30m", remaining)
+	}
+	mustNoError(t, env.tasks.FireTimers(taskID))
+	assertEqualStrings(t, env.activeNodes(t, taskID), []string{"wait"})
+}
@@ -0,0 +1,175 @@
+package integration
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/task"
+	"gorm.io/gorm"
+)
+
+// ErrTaskConflict 任务在本次操作读取之后已被其他操作修改(并发冲突)
+var ErrTaskConflict = errors.New("task has been modified concurrently")
+
+// ErrRevisionMismatch 任务当前修订号与调用方期望的修订号(If-Match)不一致
+var ErrRevisionMismatch = errors.New("task revision does not match")
+
+// WithExpectedRevision 返回校验任务修订号的任务管理器副本
+// 副本执行变更操作时,任务的当前修订号必须等于 revision,否则返回 ErrRevisionMismatch
+func (m *DBTaskManager) WithExpectedRevision(revision int64) *DBTaskManager {
+	mgr := *m
+	mgr.expectedRevision = &revision
+	return &mgr
+}
+
+// GetWithRevision 获取任务及其当前修订号
+func (m *DBTaskManager) GetWithRevision(id string) (*task.Task, int64, error) {
+	var revision int64
+	var tsk *task.Task
+	err := m.inTx(func(txm *dbTaskManager) error {
+		var err error
+		if tsk, err = txm.Get(id); err != nil {
+			return err
+		}
+		revision = txm.loadedRevisions[id]
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return tsk, revision, nil
+}
+
+// Snapshot 在只读事务中执行 fn,fn 收到绑定到事务的管理器副本
+// 事务使用可重复读隔离级别,fn 内的多次查询读取同一个数据库快照,用于返回与修订号(ETag)一致的任务详情
+func (m *DBTaskManager) Snapshot(fn func(snap *DBTaskManager) error) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		snap := *m
+		snap.db = tx
+		snap.recordRepo = repository.NewApprovalRecordRepository(tx)
+		snap.historyRepo = repository.NewStateHistoryRepository(tx)
+		return fn(&snap)
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+// inTx 在一个数据库事务中执行任务变更
+// fn 收到绑定到事务的管理器副本,任务、审批记录和状态历史的写入一起提交或回滚;
+// 事件处理器支持 outbox 时事件在同一事务内写入 events 表,否则在提交后分发;
+// 审批人权限关系变更在提交后同步;事务内结束的任务在提交前推进父任务的子流程节点并取消其子任务,
+// 未完成即失效的子流程节点(退回、驳回、并行汇聚)的子任务在提交前取消。
+// 事务内需要查询审批人目录(角色、用户组、上级、关系)时先回滚,在事务外查询后重新执行 fn,
+// 因此 fn 除数据库写入和事件、关系变更的收集外不应有其他副作用
+func (m *dbTaskManager) inTx(fn func(txm *dbTaskManager) error) error {
+	lookups := newDirectoryLookups(m.approverDirectory)
+	for round := 0; ; round++ {
+		events, relations, err := m.runTx(fn, lookups)
+		if lookups.pending() {
+			if round+1 >= maxDirectoryLookupRounds {
+				return fmt.Errorf("approver directory lookups did not finish after %d rounds", maxDirectoryLookupRounds)
+			}
+			lookups.fetch()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		m.afterCommit(events, relations)
+		return nil
+	}
+}
+
+// runTx 执行一次事务,返回提交的事件和待同步的权限关系变更
+// 事务内有未执行的目录查询时回滚
+func (m *dbTaskManager) runTx(fn func(txm *dbTaskManager) error, lookups *directoryLookups) ([]*event.Event, []relationChange, error) {
+	var events []*event.Event
+	var relations []relationChange
+	var finished []string
+	var detached []string
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		txm := *m
+		txm.db = tx
+		txm.recordRepo = repository.NewApprovalRecordRepository(tx)
+		txm.historyRepo = repository.NewStateHistoryRepository(tx)
+		txm.loadedRevisions = make(map[string]int64)
+		txm.pendingEvents = &events
+		txm.pendingRelations = &relations
+		txm.finishedTasks = &finished
+		txm.detachedTasks = &detached
+		if lookups != nil {
+			txm.approverDirectory = lookups
+		}
+		if err := fn(&txm); err != nil {
+			return err
+		}
+		if err := txm.settleFinishedTasks(); err != nil {
+			return err
+		}
+		// 解析失败被调用方忽略时也要回滚,查询后重新执行
+		if lookups.pending() {
+			return errDirectoryLookupRequired
+		}
+		return txm.persistEvents(events)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return events, relations, nil
+}
+
+// afterCommit 事务提交后同步权限关系并分发事件
+func (m *dbTaskManager) afterCommit(events []*event.Event, relations []relationChange) {
+	m.syncRelations(relations)
+	if outbox, ok := m.eventHandler.(outboxEventHandler); ok {
+		if len(events) > 0 {
+			outbox.Notify()
+		}
+		return
+	}
+	m.dispatch(events)
+}
+
+// trackRevision 记录事务内首次读取任务时的修订号,并校验调用方期望的修订号
+func (m *dbTaskManager) trackRevision(tm *model.TaskModel) error {
+	if m.loadedRevisions == nil {
+		return nil
+	}
+	if _, loaded := m.loadedRevisions[tm.ID]; loaded {
+		return nil
+	}
+	if m.expectedRevision != nil && *m.expectedRevision != tm.Revision {
+		return fmt.Errorf("%w: task %q is at revision %d, expected %d", ErrRevisionMismatch, tm.ID, tm.Revision, *m.expectedRevision)
+	}
+	m.loadedRevisions[tm.ID] = tm.Revision
+	return nil
+}
+
+// saveTask 保存任务(乐观锁)
+// 只有数据库中的修订号仍是本次操作读取时的修订号才会写入,并将修订号加 1;否则返回 ErrTaskConflict
+func (m *dbTaskManager) saveTask(taskModel *model.TaskModel) error {
+	revision, loaded := m.loadedRevisions[taskModel.ID]
+	if !loaded {
+		return fmt.Errorf("task %q must be loaded in the transaction before saving", taskModel.ID)
+	}
+	taskModel.Revision = revision + 1
+
+	result := m.db.Model(&model.TaskModel{}).
+		Where("id = ? AND revision = ?", taskModel.ID, revision).
+		Updates(taskModel)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update task: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: task %q", ErrTaskConflict, taskModel.ID)
+	}
+	m.loadedRevisions[taskModel.ID] = taskModel.Revision
+	// 任务结束后在事务提交前处理父子任务关联;只保存运行时数据(State 为空)时状态没有变化
+	if m.finishedTasks != nil && taskModel.State != "" && !containsString(RunningTaskStates, taskModel.State) {
+		*m.finishedTasks = append(*m.finishedTasks, taskModel.ID)
+	}
+	// 任务状态或活动节点变化后重新计算超时和提醒时间
+	return m.refreshSchedule(taskModel.ID)
+}
@@ -0,0 +1,154 @@
+package integration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// singleApprovalTemplate 创建只有一个审批节点的模板
+func singleApprovalTemplate(t *testing.T, env *testEnv, id string, users []string) {
+	t.Helper()
+	env.createTemplate(t, id,
+		`{"start":{"id":"start","type":"start"},
+		`+approvalNode("review", users, "")+`,
+		"end":{"id":"end","type":"end"}}`,
+		`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+}
+
+func TestTaskRevisionIncrementsOnEveryMutation(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann", "bob"})
+
+	tsk := env.startTask(t, "single", `{}`)
+	_, revision, err := env.tasks.GetWithRevision(tsk.ID)
+	mustNoError(t, err)
+
+	mustNoError(t, env.tasks.Approve(tsk.ID, "review", "ann", ""))
+	_, next, err := env.tasks.GetWithRevision(tsk.ID)
+	mustNoError(t, err)
+	if next <= revision {
+		t.Fatalf("revision did not increase: %d -> %d", revision, next)
+	}
+}
+
+func TestExpectedRevisionMismatch(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann", "bob"})
+
+	tsk := env.startTask(t, "single", `{}`)
+	_, revision, err := env.tasks.GetWithRevision(tsk.ID)
+	mustNoError(t, err)
+
+	stale := env.tasks.WithExpectedRevision(revision - 1)
+	if err := stale.Approve(tsk.ID, "review", "ann", ""); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch, got %v", err)
+	}
+	if approvals := env.getTask(t, tsk.ID).Approvals["review"]; len(approvals) != 0 {
+		t.Fatalf("approval was saved despite the revision mismatch: %v", approvals)
+	}
+
+	mustNoError(t, env.tasks.WithExpectedRevision(revision).Approve(tsk.ID, "review", "ann", ""))
+
+	// 同一个期望修订号不能再次使用
+	if err := env.tasks.WithExpectedRevision(revision).Approve(tsk.ID, "review", "bob", ""); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch for a reused revision, got %v", err)
+	}
+}
+
+func TestConcurrentModificationConflicts(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	tsk := env.startTask(t, "single", `{}`)
+
+	err := env.tasks.inTx(func(txm *dbTaskManager) error {
+		loaded, err := txm.Get(tsk.ID)
+		if err != nil {
+			return err
+		}
+		rt, err := txm.loadRuntime(loaded)
+		if err != nil {
+			return err
+		}
+		// 模拟读取之后其他操作已修改任务
+		if err := txm.db.Model(&model.TaskModel{}).Where("id = ?", tsk.ID).
+			Update("revision", gorm.Expr("revision + 1")).Error; err != nil {
+			return err
+		}
+		return txm.saveRuntime(loaded, rt)
+	})
+	if !errors.Is(err, ErrTaskConflict) {
+		t.Fatalf("expected ErrTaskConflict, got %v", err)
+	}
+}
+
+func TestSaveTaskRequiresLoadInTransaction(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	tsk := env.startTask(t, "single", `{}`)
+
+	err := env.tasks.inTx(func(txm *dbTaskManager) error {
+		return txm.saveTask(&model.TaskModel{ID: tsk.ID})
+	})
+	if err == nil {
+		t.Fatal("expected saving an unloaded task to fail")
+	}
+}
+
+func TestRuntimeOnlySaveIsNotTreatedAsFinished(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann"})
+	tsk := env.startTask(t, "single", `{}`)
+
+	var finished []string
+	err := env.tasks.inTx(func(txm *dbTaskManager) error {
+		loaded, err := txm.Get(tsk.ID)
+		if err != nil {
+			return err
+		}
+		rt, err := txm.loadRuntime(loaded)
+		if err != nil {
+			return err
+		}
+		if err := txm.saveRuntime(loaded, rt); err != nil {
+			return err
+		}
+		finished = append(finished, *txm.finishedTasks...)
+		return nil
+	})
+	mustNoError(t, err)
+	if len(finished) != 0 {
+		t.Fatalf("runtime-only save marked tasks as finished: %v", finished)
+	}
+}
+
+func TestSnapshotReadsConsistentRevision(t *testing.T) {
+	env := newTestEnv(t)
+	singleApprovalTemplate(t, env, "single", []string{"ann", "bob"})
+	tsk := env.startTask(t, "single", `{}`)
+	mustNoError(t, env.tasks.Approve(tsk.ID, "review", "ann", ""))
+
+	_, want, err := env.tasks.GetWithRevision(tsk.ID)
+	mustNoError(t, err)
+	err = env.tasks.Snapshot(func(snap *DBTaskManager) error {
+		got, revision, err := snap.GetWithRevision(tsk.ID)
+		if err != nil {
+			return err
+		}
+		if revision != want {
+			t.Errorf("revision = %d, want %d", revision, want)
+		}
+		if len(got.Approvals["review"]) != 1 {
+			t.Errorf("approvals = %v, want one approval", got.Approvals["review"])
+		}
+		nodes, err := snap.ActiveNodes(tsk.ID)
+		if err != nil {
+			return err
+		}
+		assertEqualStrings(t, nodes, []string{"review"})
+		return nil
+	})
+	mustNoError(t, err)
+}
@@ -0,0 +1,169 @@
+package integration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-gin/internal/utils"
+	"github.com/mautops/approval-kit/pkg/template"
+	"gorm.io/gorm"
+)
+
+// Webhook 请求头
+const (
+	// HeaderDeliveryID 投递 ID(即事件 ID,重试时不变),接收方据此做幂等处理
+	HeaderDeliveryID = "X-Delivery-ID"
+	// HeaderSignatureTimestamp 签名时间戳(Unix 秒),接收方据此拒绝过期请求
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	// HeaderSignature 签名,格式为 sha256=<hex>,密钥轮换宽限期内包含多个以逗号分隔的签名
+	HeaderSignature = "X-Signature"
+)
+
+// webhookAuthHMAC Webhook 认证类型: HMAC-SHA256 签名
+// auth.token 为签名密钥,保存模板时加密写入 webhook_secrets 表,模板数据中不保留
+const webhookAuthHMAC = "hmac"
+
+// ErrSigningKeyNotConfigured 未配置加密签名密钥所需的主密钥
+var ErrSigningKeyNotConfigured = errors.New("webhook signing key is not configured")
+
+// WebhookSigner Webhook 签名器
+// 负责签名密钥的加密存储、轮换和请求签名
+type WebhookSigner struct {
+	key   string        // 加密签名密钥的主密钥
+	grace time.Duration // 轮换后旧密钥的有效期
+}
+
+// NewWebhookSigner 创建 Webhook 签名器
+// key 为加密签名密钥的主密钥(至少 32 字节),grace 为密钥轮换后旧密钥的有效期
+func NewWebhookSigner(key string, grace time.Duration) *WebhookSigner {
+	return &WebhookSigner{
+		key:   key,
+		grace: grace,
+	}
+}
+
+// storeSecrets 保存模板中使用 HMAC 签名的 Webhook 的密钥
+// 明文密钥从模板中移除并加密写入 webhook_secrets 表;密钥为空时沿用已保存的密钥,
+// 与已保存的密钥不同时轮换,旧密钥在宽限期内仍用于签名
+func (s *WebhookSigner) storeSecrets(tx *gorm.DB, tpl *template.Template) error {
+	if tpl.Config == nil {
+		return nil
+	}
+
+	secretRepo := repository.NewWebhookSecretRepository(tx)
+	for _, webhook := range tpl.Config.Webhooks {
+		if webhook == nil || webhook.Auth == nil || webhook.Auth.Type != webhookAuthHMAC {
+			continue
+		}
+		secret := webhook.Auth.Token
+		webhook.Auth.Token = ""
+		if err := s.saveSecret(secretRepo, tpl.ID, webhook.URL, secret); err != nil {
+			return fmt.Errorf("webhook %s: %w", webhook.URL, err)
+		}
+	}
+	return nil
+}
+
+// SaveSecret 保存或轮换全局订阅等模板之外的 Webhook 的签名密钥
+// owner 为密钥所属的订阅 ID,secret 为空时沿用已保存的密钥
+func (s *WebhookSigner) SaveSecret(db *gorm.DB, owner string, endpoint string, secret string) error {
+	return s.saveSecret(repository.NewWebhookSecretRepository(db), owner, endpoint, secret)
+}
+
+// saveSecret 保存或轮换端点的签名密钥
+// owner 为密钥所属的模板 ID 或订阅 ID
+func (s *WebhookSigner) saveSecret(secretRepo repository.WebhookSecretRepository, owner string, endpoint string, secret string) error {
+	existing, err := secretRepo.Find(owner, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+	if secret == "" {
+		if existing == nil {
+			return errors.New("hmac signing secret is required")
+		}
+		return nil
+	}
+	if s == nil || s.key == "" {
+		return ErrSigningKeyNotConfigured
+	}
+
+	now := time.Now()
+	if existing != nil {
+		if current, err := utils.Decrypt(existing.Secret, s.key); err == nil && current == secret {
+			return nil
+		}
+		// 轮换: 当前密钥在宽限期内继续有效
+		expiresAt := now.Add(s.grace)
+		existing.PreviousSecret = existing.Secret
+		existing.PreviousExpiresAt = &expiresAt
+	} else {
+		existing = &model.WebhookSecretModel{
+			TemplateID: owner,
+			Endpoint:   endpoint,
+			CreatedAt:  now,
+		}
+	}
+
+	encrypted, err := utils.Encrypt(secret, s.key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	existing.Secret = encrypted
+	existing.UpdatedAt = now
+	if err := existing.Validate(); err != nil {
+		return err
+	}
+	if err := secretRepo.Save(existing); err != nil {
+		return fmt.Errorf("failed to save webhook secret: %w", err)
+	}
+	return nil
+}
+
+// validSecrets 获取端点当前有效的签名密钥(当前密钥在前,宽限期内的旧密钥在后)
+func (s *WebhookSigner) validSecrets(db *gorm.DB, owner string, endpoint string) ([]string, error) {
+	if s == nil || s.key == "" {
+		return nil, ErrSigningKeyNotConfigured
+	}
+
+	stored, err := repository.NewWebhookSecretRepository(db).Find(owner, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook secret: %w", err)
+	}
+	if stored == nil {
+		return nil, errors.New("webhook signing secret not found")
+	}
+
+	current, err := utils.Decrypt(stored.Secret, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt webhook secret: %w", err)
+	}
+	secrets := []string{current}
+	if stored.PreviousSecret != "" && stored.PreviousExpiresAt != nil && time.Now().Before(*stored.PreviousExpiresAt) {
+		// 旧密钥解密失败(如主密钥已更换)时只使用当前密钥
+		if previous, err := utils.Decrypt(stored.PreviousSecret, s.key); err == nil {
+			secrets = append(secrets, previous)
+		}
+	}
+	return secrets, nil
+}
+
+// signPayload 计算请求签名
+// 对 "时间戳.请求体" 计算 HMAC-SHA256,每个有效密钥一个签名,以逗号分隔
+func signPayload(secrets []string, timestamp string, body []byte) string {
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp))
+		mac.Write([]byte("."))
+		mac.Write(body)
+		signatures = append(signatures, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return strings.Join(signatures, ",")
+}
@@ -0,0 +1,155 @@
+package integration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/template"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+// expectedSignature 按接收方的方式计算签名
+func expectedSignature(secret string, timestamp string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSignPayload(t *testing.T) {
+	body := `{"type":"task_created"}`
+	got := signPayload([]string{"new-secret", "old-secret"}, "1700000000", []byte(body))
+	want := expectedSignature("new-secret", "1700000000", body) + "," + expectedSignature("old-secret", "1700000000", body)
+	if got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+
+	// 时间戳参与签名,重放时修改时间戳会导致签名不一致
+	if signPayload([]string{"new-secret"}, "1700000001", []byte(body)) == strings.Split(want, ",")[0] {
+		t.Fatal("signature does not depend on the timestamp")
+	}
+}
+
+func TestWebhookSecretRotation(t *testing.T) {
+	db := newTestDB(t)
+	signer := NewWebhookSigner(testSigningKey, time.Hour)
+	const endpoint = "https://example.com/hook"
+
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, "first"))
+	secrets, err := signer.validSecrets(db, "sub-1", endpoint)
+	mustNoError(t, err)
+	assertEqualStrings(t, secrets, []string{"first"})
+
+	// 密钥只加密保存
+	var stored model.WebhookSecretModel
+	mustNoError(t, db.Where("template_id = ? AND endpoint = ?", "sub-1", endpoint).First(&stored).Error)
+	if strings.Contains(stored.Secret, "first") {
+		t.Fatal("secret is stored in plain text")
+	}
+
+	// 相同密钥和空密钥都不轮换
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, "first"))
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, ""))
+	secrets, err = signer.validSecrets(db, "sub-1", endpoint)
+	mustNoError(t, err)
+	assertEqualStrings(t, secrets, []string{"first"})
+
+	// 轮换后宽限期内新旧密钥同时签名
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, "second"))
+	secrets, err = signer.validSecrets(db, "sub-1", endpoint)
+	mustNoError(t, err)
+	assertEqualStrings(t, secrets, []string{"second", "first"})
+
+	// 宽限期结束后只使用新密钥
+	mustNoError(t, db.Model(&model.WebhookSecretModel{}).
+		Where("template_id = ? AND endpoint = ?", "sub-1", endpoint).
+		Update("previous_expires_at", time.Now().Add(-time.Minute)).Error)
+	secrets, err = signer.validSecrets(db, "sub-1", endpoint)
+	mustNoError(t, err)
+	assertEqualStrings(t, secrets, []string{"second"})
+}
+
+func TestWebhookSecretErrors(t *testing.T) {
+	db := newTestDB(t)
+	const endpoint = "https://example.com/hook"
+
+	signer := NewWebhookSigner(testSigningKey, time.Hour)
+	if err := signer.SaveSecret(db, "sub-1", endpoint, ""); err == nil {
+		t.Fatal("expected a missing secret to fail")
+	}
+	if _, err := signer.validSecrets(db, "sub-1", endpoint); err == nil {
+		t.Fatal("expected signing without a stored secret to fail")
+	}
+
+	unconfigured := NewWebhookSigner("", time.Hour)
+	if err := unconfigured.SaveSecret(db, "sub-1", endpoint, "secret"); !errors.Is(err, ErrSigningKeyNotConfigured) {
+		t.Fatalf("expected ErrSigningKeyNotConfigured, got %v", err)
+	}
+	var nilSigner *WebhookSigner
+	if _, err := nilSigner.validSecrets(db, "sub-1", endpoint); !errors.Is(err, ErrSigningKeyNotConfigured) {
+		t.Fatalf("expected ErrSigningKeyNotConfigured, got %v", err)
+	}
+
+	// 更换主密钥后无法解密已保存的密钥
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, "secret"))
+	rotatedKey := NewWebhookSigner(strings.Repeat("z", 32), time.Hour)
+	if _, err := rotatedKey.validSecrets(db, "sub-1", endpoint); err == nil {
+		t.Fatal("expected decrypting with another master key to fail")
+	}
+}
+
+func TestStoreSecretsRemovesTokenFromTemplate(t *testing.T) {
+	db := newTestDB(t)
+	signer := NewWebhookSigner(testSigningKey, time.Hour)
+	tpl := &template.Template{
+		ID: "tpl-1",
+		Config: &template.TemplateConfig{Webhooks: []*template.WebhookConfig{
+			{URL: "https://example.com/signed", Auth: &template.AuthConfig{Type: webhookAuthHMAC, Token: "s3cret"}},
+			{URL: "https://example.com/bearer", Auth: &template.AuthConfig{Type: "bearer", Token: "bearer-token"}},
+		}},
+	}
+
+	mustNoError(t, signer.storeSecrets(db, tpl))
+	if tpl.Config.Webhooks[0].Auth.Token != "" {
+		t.Fatal("hmac secret was kept in the template")
+	}
+	if tpl.Config.Webhooks[1].Auth.Token != "bearer-token" {
+		t.Fatal("non-hmac token was removed from the template")
+	}
+	secrets, err := signer.validSecrets(db, "tpl-1", "https://example.com/signed")
+	mustNoError(t, err)
+	assertEqualStrings(t, secrets, []string{"s3cret"})
+}
+
+func TestSignWebhookRequest(t *testing.T) {
+	db := newTestDB(t)
+	signer := NewWebhookSigner(testSigningKey, time.Hour)
+	const endpoint = "https://example.com/hook"
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, "first"))
+	mustNoError(t, signer.SaveSecret(db, "sub-1", endpoint, "second"))
+
+	h := newTestEventHandler(db, EventDeliveryOptions{Signer: signer})
+	body := `{"id":"evt-1"}`
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(body))
+	mustNoError(t, err)
+	mustNoError(t, h.sign(req, "sub-1", endpoint, []byte(body)))
+
+	timestamp := req.Header.Get(HeaderSignatureTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	mustNoError(t, err)
+	if age := time.Since(time.Unix(unix, 0)); age < 0 || age > time.Minute {
+		t.Fatalf("signature timestamp %s is not current", timestamp)
+	}
+	want := expectedSignature("second", timestamp, body) + "," + expectedSignature("first", timestamp, body)
+	if got := req.Header.Get(HeaderSignature); got != want {
+		t.Fatalf("signature = %s, want %s", got, want)
+	}
+}
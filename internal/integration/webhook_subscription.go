@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/event"
+	"github.com/mautops/approval-kit/pkg/template"
+)
+
+// EventWebhookTest 测试事件,只由"发送测试事件"接口直接发送,不写入 outbox
+const EventWebhookTest event.EventType = "webhook_test"
+
+// WebhookTestResult 测试事件的投递结果
+type WebhookTestResult struct {
+	Success      bool   `json:"success"`                 // 是否成功
+	StatusCode   int    `json:"status_code,omitempty"`   // HTTP 响应码
+	LatencyMs    int64  `json:"latency_ms"`              // 请求耗时(毫秒)
+	ResponseBody string `json:"response_body,omitempty"` // 响应体(截断)
+	Error        string `json:"error,omitempty"`         // 失败原因
+}
+
+// SendTestEvent 向订阅地址同步发送一个测试事件
+// 测试事件使用订阅的认证和签名配置,不写入 outbox,也不会重试
+func (h *dbEventHandler) SendTestEvent(subscription *model.WebhookSubscriptionModel) *WebhookTestResult {
+	evt := &event.Event{
+		Type: EventWebhookTest,
+		Time: time.Now(),
+		Task: &event.TaskInfo{
+			ID:         "test",
+			TemplateID: subscription.TemplateID,
+			BusinessID: subscription.BusinessID,
+		},
+	}
+
+	start := time.Now()
+	resp, err := h.sendWebhookRequest(subscriptionTarget(subscription), evt, "test-"+uuid.New().String())
+
+	result := &WebhookTestResult{
+		Success:   err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if resp != nil {
+		result.StatusCode = resp.statusCode
+		result.ResponseBody = resp.body
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// subscriptionMatches 判断事件是否匹配订阅的事件类型、模板和业务 ID 过滤条件
+func subscriptionMatches(subscription *model.WebhookSubscriptionModel, evt *event.Event) bool {
+	if subscription.TemplateID != "" && subscription.TemplateID != evt.Task.TemplateID {
+		return false
+	}
+	if subscription.BusinessID != "" && subscription.BusinessID != evt.Task.BusinessID {
+		return false
+	}
+
+	var eventTypes []string
+	if len(subscription.EventTypes) > 0 {
+		if err := json.Unmarshal(subscription.EventTypes, &eventTypes); err != nil {
+			log.Printf("invalid event types of webhook subscription %q: %v", subscription.ID, err)
+			return false
+		}
+	}
+	if len(eventTypes) == 0 {
+		return true
+	}
+	for _, eventType := range eventTypes {
+		if eventType == string(evt.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionTarget 将订阅转换为投递目标
+func subscriptionTarget(subscription *model.WebhookSubscriptionModel) *webhookTarget {
+	webhook := &template.WebhookConfig{
+		URL:    subscription.URL,
+		Method: subscription.Method,
+	}
+	if len(subscription.Headers) > 0 {
+		if err := json.Unmarshal(subscription.Headers, &webhook.Headers); err != nil {
+			log.Printf("invalid headers of webhook subscription %q: %v", subscription.ID, err)
+		}
+	}
+	if subscription.AuthType != "" {
+		webhook.Auth = &template.AuthConfig{
+			Type:  subscription.AuthType,
+			Key:   subscription.AuthKey,
+			Token: subscription.AuthToken,
+		}
+	}
+	return &webhookTarget{key: "subscription:" + subscription.ID, webhook: webhook, secretOwner: subscription.ID, format: subscription.PayloadFormat}
+}
@@ -0,0 +1,458 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-kit/pkg/event"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidWebhookTemplate Webhook 请求模板无效
+var ErrInvalidWebhookTemplate = errors.New("invalid webhook template")
+
+// Webhook 请求模板限制
+const (
+	maxWebhookTemplateSize   = 16 * 1024  // 单个模板的最大长度
+	maxWebhookBodySize       = 256 * 1024 // 渲染后请求体的最大长度
+	maxWebhookHeaderSize     = 4 * 1024   // 渲染后单个请求头的最大长度
+	webhookTemplateTruncated = "..."      // truncate 函数截断后追加的后缀
+)
+
+// errWebhookOutputTooLarge 模板渲染结果超过长度限制
+var errWebhookOutputTooLarge = errors.New("rendered output is too large")
+
+// WebhookPayloadTemplate Webhook 请求模板
+// 保存在模板配置的 webhooks 中(approval-kit 的 WebhookConfig 之外的扩展字段),
+// 使用 Go text/template 语法,按事件、任务、模板和节点渲染请求体和请求头
+type WebhookPayloadTemplate struct {
+	BodyTemplate    string            `json:"body_template,omitempty"`    // 请求体模板,为空时发送事件 JSON
+	HeaderTemplates map[string]string `json:"header_templates,omitempty"` // 请求头模板,在固定请求头之后设置
+}
+
+// RenderedWebhook 渲染后的 Webhook 请求
+type RenderedWebhook struct {
+	Body    string            `json:"body"`    // 请求体
+	Headers map[string]string `json:"headers"` // 模板渲染的请求头
+}
+
+// webhookTemplateData 请求模板的渲染数据
+// 只包含普通字段,不向模板暴露可调用的方法
+type webhookTemplateData struct {
+	Event    webhookTemplateEvent
+	Task     webhookTemplateTask
+	Template webhookTemplateInfo
+	Node     webhookTemplateNode
+	Approval webhookTemplateApproval
+}
+
+// webhookTemplateEvent 事件信息
+type webhookTemplateEvent struct {
+	ID   string    // 事件 ID(投递 ID)
+	Type string    // 事件类型
+	Time time.Time // 事件产生时间
+}
+
+// webhookTemplateTask 任务信息
+type webhookTemplateTask struct {
+	ID         string
+	TemplateID string
+	BusinessID string
+	State      string
+	Params     map[string]interface{} // 任务参数
+}
+
+// webhookTemplateInfo 模板信息
+type webhookTemplateInfo struct {
+	ID      string
+	Name    string
+	Version int
+}
+
+// webhookTemplateNode 节点信息
+type webhookTemplateNode struct {
+	ID   string
+	Name string
+	Type string
+}
+
+// webhookTemplateApproval 审批信息(审批类事件)
+type webhookTemplateApproval struct {
+	Approver string
+	Result   string
+	Comment  string
+}
+
+// webhookTemplateFuncs 模板可用的函数(固定集合,不提供访问文件、网络或环境变量的函数)
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if n < 0 || len(runes) <= n {
+			return s
+		}
+		return string(runes[:n]) + webhookTemplateTruncated
+	},
+	"default": func(def interface{}, value interface{}) interface{} {
+		if value == nil {
+			return def
+		}
+		if s, ok := value.(string); ok && s == "" {
+			return def
+		}
+		return value
+	},
+	"formatTime": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+}
+
+// Validate 校验请求模板(模板保存和预览时调用)
+// 除语法检查外,还会使用示例数据试渲染,提前发现引用不存在字段等错误
+func (t *WebhookPayloadTemplate) Validate() error {
+	if t.BodyTemplate != "" {
+		if _, err := parseWebhookTemplate("body", t.BodyTemplate); err != nil {
+			return err
+		}
+	}
+	for name, text := range t.HeaderTemplates {
+		if !validHeaderName(name) {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidWebhookTemplate, name)
+		}
+		if _, err := parseWebhookTemplate(name, text); err != nil {
+			return err
+		}
+	}
+	if _, err := t.render(sampleWebhookTemplateData()); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookTemplate, err)
+	}
+	return nil
+}
+
+// isEmpty 是否未配置任何模板
+func (t *WebhookPayloadTemplate) isEmpty() bool {
+	return t == nil || (t.BodyTemplate == "" && len(t.HeaderTemplates) == 0)
+}
+
+// render 渲染请求模板,请求体模板为空时 Body 为空字符串
+func (t *WebhookPayloadTemplate) render(data *webhookTemplateData) (*RenderedWebhook, error) {
+	rendered := &RenderedWebhook{Headers: make(map[string]string)}
+	if t.BodyTemplate != "" {
+		body, err := executeWebhookTemplate("body", t.BodyTemplate, data, maxWebhookBodySize)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Body = body
+	}
+	for name, text := range t.HeaderTemplates {
+		value, err := executeWebhookTemplate(name, text, data, maxWebhookHeaderSize)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %q: rendered value must not contain line breaks", name)
+		}
+		rendered.Headers[name] = value
+	}
+	return rendered, nil
+}
+
+// parseWebhookTemplate 解析模板
+// 不允许 define/template 定义和引用子模板,也不允许对数字字面量 range,避免无限递归和长时间循环
+func parseWebhookTemplate(name string, text string) (*template.Template, error) {
+	if len(text) > maxWebhookTemplateSize {
+		return nil, fmt.Errorf("%w: %s template exceeds %d bytes", ErrInvalidWebhookTemplate, name, maxWebhookTemplateSize)
+	}
+	tmpl, err := template.New(name).Funcs(webhookTemplateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookTemplate, err)
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, fmt.Errorf("%w: %s template must not define sub-templates", ErrInvalidWebhookTemplate, name)
+	}
+	if tmpl.Tree != nil {
+		if err := checkTemplateNode(tmpl.Tree.Root); err != nil {
+			return nil, fmt.Errorf("%w: %s template: %v", ErrInvalidWebhookTemplate, name, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// checkTemplateNode 检查模板语法树中不允许的结构
+func checkTemplateNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return fmt.Errorf("template action %q is not allowed", n.Name)
+	case *parse.IfNode:
+		return checkBranchNode(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranchNode(&n.BranchNode)
+	case *parse.RangeNode:
+		if isNumberPipe(n.Pipe) {
+			return errors.New("range over a number is not allowed")
+		}
+		return checkBranchNode(&n.BranchNode)
+	}
+	return nil
+}
+
+// isNumberPipe 判断管道的结果是否为数字字面量(包括括号包裹的数字)
+func isNumberPipe(pipe *parse.PipeNode) bool {
+	if pipe == nil || len(pipe.Cmds) == 0 {
+		return false
+	}
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	if len(last.Args) != 1 {
+		return false
+	}
+	switch arg := last.Args[0].(type) {
+	case *parse.NumberNode:
+		return true
+	case *parse.PipeNode:
+		return isNumberPipe(arg)
+	}
+	return false
+}
+
+// checkBranchNode 检查 if/with/range 的分支
+func checkBranchNode(n *parse.BranchNode) error {
+	if err := checkTemplateNode(n.List); err != nil {
+		return err
+	}
+	if n.ElseList != nil {
+		return checkTemplateNode(n.ElseList)
+	}
+	return nil
+}
+
+// executeWebhookTemplate 解析并渲染模板,输出超过 limit 时中止
+func executeWebhookTemplate(name string, text string, data *webhookTemplateData, limit int) (string, error) {
+	tmpl, err := parseWebhookTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	out := &limitedBuffer{limit: limit}
+	if err := tmpl.Execute(out, data); err != nil {
+		if errors.Is(err, errWebhookOutputTooLarge) {
+			return "", fmt.Errorf("%s template: %w (limit %d bytes)", name, errWebhookOutputTooLarge, limit)
+		}
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// limitedBuffer 有长度上限的输出缓冲区
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+// Write 写入数据,超过上限时返回错误
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errWebhookOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// validHeaderName 判断是否为合法的 HTTP 请求头名称(RFC 7230 token)
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// webhookTemplateConfig 模板配置中的 Webhook 请求模板(与 config.webhooks 一一对应)
+type webhookTemplateConfig struct {
+	Webhooks []*WebhookPayloadTemplate `json:"webhooks"`
+}
+
+// ValidateWebhookTemplates 校验模板原始配置中所有 Webhook 的请求模板(模板保存时调用)
+func ValidateWebhookTemplates(rawConfig json.RawMessage) error {
+	if len(rawConfig) == 0 {
+		return nil
+	}
+	var cfg webhookTemplateConfig
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	for i, payload := range cfg.Webhooks {
+		if payload.isEmpty() {
+			continue
+		}
+		if err := payload.Validate(); err != nil {
+			return fmt.Errorf("webhook %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// mergeWebhookTemplates 将原始配置中的 Webhook 请求模板合并到待保存的模板数据中
+// 只合并请求模板字段,认证令牌等其他字段以 approval-kit 模型为准(签名密钥已在此之前移除)
+func mergeWebhookTemplates(templateMap map[string]interface{}, rawConfig json.RawMessage) error {
+	if len(rawConfig) == 0 {
+		return nil
+	}
+	var cfg webhookTemplateConfig
+	if err := json.Unmarshal(rawConfig, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	configMap, _ := templateMap["config"].(map[string]interface{})
+	if configMap == nil {
+		return nil
+	}
+	webhooks, _ := configMap["webhooks"].([]interface{})
+	for i, payload := range cfg.Webhooks {
+		if i >= len(webhooks) || payload.isEmpty() {
+			continue
+		}
+		webhook, ok := webhooks[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if payload.BodyTemplate != "" {
+			webhook["body_template"] = payload.BodyTemplate
+		}
+		if len(payload.HeaderTemplates) > 0 {
+			webhook["header_templates"] = payload.HeaderTemplates
+		}
+	}
+	return nil
+}
+
+// loadWebhookTemplates 加载模板版本中各 Webhook 的请求模板(按 config.webhooks 顺序)
+func loadWebhookTemplates(db *gorm.DB, templateID string, version int) ([]*WebhookPayloadTemplate, error) {
+	var tm model.TemplateModel
+	if err := db.Select("data").Where("id = ? AND version = ?", templateID, version).First(&tm).Error; err != nil {
+		return nil, fmt.Errorf("template not found: %w", err)
+	}
+
+	var raw struct {
+		Config webhookTemplateConfig `json:"config"`
+	}
+	if err := json.Unmarshal(tm.Data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal template: %w", err)
+	}
+	return raw.Config.Webhooks, nil
+}
+
+// newWebhookTemplateData 根据事件构建渲染数据
+// 任务参数从任务数据中读取,任务不存在时为空
+func newWebhookTemplateData(db *gorm.DB, evt *event.Event, eventID string, templateName string, templateVersion int) *webhookTemplateData {
+	data := &webhookTemplateData{
+		Event: webhookTemplateEvent{ID: eventID, Type: string(evt.Type), Time: evt.Time},
+		Template: webhookTemplateInfo{
+			Name:    templateName,
+			Version: templateVersion,
+		},
+	}
+	if evt.Task != nil {
+		data.Task = webhookTemplateTask{
+			ID:         evt.Task.ID,
+			TemplateID: evt.Task.TemplateID,
+			BusinessID: evt.Task.BusinessID,
+			State:      evt.Task.State,
+		}
+		data.Template.ID = evt.Task.TemplateID
+
+		var taskModel model.TaskModel
+		if err := db.Select("id", "data").Where("id = ?", evt.Task.ID).First(&taskModel).Error; err == nil {
+			var taskData struct {
+				Params json.RawMessage
+			}
+			if json.Unmarshal(taskModel.Data, &taskData) == nil && len(taskData.Params) > 0 {
+				_ = json.Unmarshal(taskData.Params, &data.Task.Params)
+			}
+		}
+	}
+	if evt.Node != nil {
+		data.Node = webhookTemplateNode{ID: evt.Node.ID, Name: evt.Node.Name, Type: evt.Node.Type}
+	}
+	if evt.Approval != nil {
+		data.Approval = webhookTemplateApproval{
+			Approver: evt.Approval.Approver,
+			Result:   evt.Approval.Result,
+			Comment:  evt.Approval.Comment,
+		}
+	}
+	return data
+}
+
+// sampleWebhookTemplateData 示例渲染数据(校验和预览时使用)
+func sampleWebhookTemplateData() *webhookTemplateData {
+	return &webhookTemplateData{
+		Event: webhookTemplateEvent{
+			ID:   "00000000-0000-0000-0000-000000000000",
+			Type: string(EventTaskApproved),
+			Time: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+		},
+		Task: webhookTemplateTask{
+			ID:         "task-001",
+			TemplateID: "tpl-001",
+			BusinessID: "biz-001",
+			State:      "approved",
+			Params:     map[string]interface{}{"amount": 1000, "reason": "示例"},
+		},
+		Template: webhookTemplateInfo{ID: "tpl-001", Name: "示例模板", Version: 1},
+		Node:     webhookTemplateNode{ID: "approval-1", Name: "审批", Type: "approval"},
+		Approval: webhookTemplateApproval{Approver: "user-001", Result: "approve", Comment: "同意"},
+	}
+}
+
+// PreviewWebhookTemplate 预览请求模板的渲染结果
+// eventModel 不为空时使用该事件(及其任务)渲染,否则使用模板信息和示例数据渲染
+func PreviewWebhookTemplate(db *gorm.DB, payload *WebhookPayloadTemplate, templateID string, templateName string, templateVersion int, eventModel *model.EventModel) (*RenderedWebhook, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
+
+	data := sampleWebhookTemplateData()
+	data.Template = webhookTemplateInfo{ID: templateID, Name: templateName, Version: templateVersion}
+	data.Task.TemplateID = templateID
+	if eventModel != nil {
+		var evt event.Event
+		if err := json.Unmarshal(eventModel.Data, &evt); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		}
+		data = newWebhookTemplateData(db, &evt, eventModel.ID, templateName, templateVersion)
+	}
+
+	rendered, err := payload.render(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookTemplate, err)
+	}
+	return rendered, nil
+}
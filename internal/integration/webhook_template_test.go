@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWebhookTemplateRender(t *testing.T) {
+	payload := &WebhookPayloadTemplate{
+		BodyTemplate: `{"text":{{json (printf "%s %s" .Task.ID .Approval.Result)}},"amount":{{.Task.Params.amount}},` +
+			`"reason":{{json (truncate 1 .Task.Params.reason)}},"missing":{{json (default "n/a" .Task.Params.missing)}},` +
+			`"when":"{{formatTime "2006-01-02" .Event.Time}}","node":"{{upper .Node.Type}}"}`,
+		HeaderTemplates: map[string]string{
+			"X-Task":     "{{.Task.ID}}",
+			"X-Template": `{{lower .Template.ID}}-v{{.Template.Version}}`,
+		},
+	}
+	mustNoError(t, payload.Validate())
+
+	rendered, err := payload.render(sampleWebhookTemplateData())
+	mustNoError(t, err)
+	want := `{"text":"task-001 approve","amount":1000,"reason":"示...","missing":"n/a","when":"2024-01-01","node":"APPROVAL"}`
+	if rendered.Body != want {
+		t.Fatalf("body = %s, want %s", rendered.Body, want)
+	}
+	if rendered.Headers["X-Task"] != "task-001" || rendered.Headers["X-Template"] != "tpl-001-v1" {
+		t.Fatalf("headers = %v", rendered.Headers)
+	}
+}
+
+func TestWebhookTemplateSandbox(t *testing.T) {
+	tests := map[string]string{
+		"sub-template definition":  `{{define "x"}}loop{{end}}{{template "x"}}`,
+		"sub-template call":        `{{template "body"}}`,
+		"range over number":        `{{range 1000000000}}x{{end}}`,
+		"nested range over number": `{{if true}}{{range (1000000000)}}x{{end}}{{end}}`,
+		"unknown function":         `{{env "HOME"}}`,
+		"syntax error":             `{{.Task.ID`,
+		"unknown field":            `{{.Task.Secret}}`,
+	}
+	for name, text := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := (&WebhookPayloadTemplate{BodyTemplate: text}).Validate()
+			if !errors.Is(err, ErrInvalidWebhookTemplate) {
+				t.Fatalf("expected ErrInvalidWebhookTemplate, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookTemplateLimits(t *testing.T) {
+	tooLong := &WebhookPayloadTemplate{BodyTemplate: strings.Repeat("x", maxWebhookTemplateSize+1)}
+	if err := tooLong.Validate(); !errors.Is(err, ErrInvalidWebhookTemplate) {
+		t.Fatalf("expected oversized template to be rejected, got %v", err)
+	}
+
+	// 渲染结果超过上限时中止
+	huge := &WebhookPayloadTemplate{BodyTemplate: `{{range .Task.Params.items}}` + strings.Repeat("x", 1024) + `{{end}}`}
+	data := sampleWebhookTemplateData()
+	items := make([]interface{}, maxWebhookBodySize/1024+1)
+	data.Task.Params = map[string]interface{}{"items": items}
+	if _, err := huge.render(data); !errors.Is(err, errWebhookOutputTooLarge) {
+		t.Fatalf("expected errWebhookOutputTooLarge, got %v", err)
+	}
+}
+
+func TestWebhookTemplateHeaders(t *testing.T) {
+	invalidName := &WebhookPayloadTemplate{HeaderTemplates: map[string]string{"X Bad": "v"}}
+	if err := invalidName.Validate(); !errors.Is(err, ErrInvalidWebhookTemplate) {
+		t.Fatalf("expected invalid header name to be rejected, got %v", err)
+	}
+
+	// 请求头值不能包含换行,防止注入额外的请求头
+	injected := &WebhookPayloadTemplate{HeaderTemplates: map[string]string{"X-Reason": "{{.Task.Params.reason}}"}}
+	data := sampleWebhookTemplateData()
+	data.Task.Params = map[string]interface{}{"reason": "ok\r\nX-Admin: true"}
+	if _, err := injected.render(data); err == nil {
+		t.Fatal("expected a header value with line breaks to be rejected")
+	}
+}
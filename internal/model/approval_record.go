@@ -14,6 +14,8 @@ type ApprovalRecordModel struct {
 	Result      string    `gorm:"type:varchar(32);not null"` // approve/reject/transfer
 	Comment     string    `gorm:"type:text"`
 	Attachments []byte    `gorm:"type:jsonb"` // 附件列表
+	OnBehalfOf  string    `gorm:"type:varchar(64)"` // 代理审批时的委托人(Approver 代 OnBehalfOf 审批)
+	Round       int       `gorm:"not null;default:1"` // 审批轮次,退回修改后重新提交时加 1
 	CreatedAt   time.Time `gorm:"not null;index"`
 }
 
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// CalendarModel 工作日历数据模型
+// 模板或审批节点引用日历后,超时、提醒和统计只计算日历内的工作时间
+type CalendarModel struct {
+	ID            string    `gorm:"primaryKey;type:varchar(64)"`
+	Name          string    `gorm:"type:varchar(255);not null"`
+	Description   string    `gorm:"type:text"`
+	Timezone      string    `gorm:"type:varchar(64);not null"` // IANA 时区,如 Asia/Shanghai
+	Workdays      []byte    `gorm:"type:jsonb"`                // 每周工作日(JSON 数组),如 ["mon","tue","wed","thu","fri"]
+	WorkingHours  []byte    `gorm:"type:jsonb"`                // 每天的工作时段(JSON 数组),如 [{"start":"09:00","end":"18:00"}]
+	Holidays      []byte    `gorm:"type:jsonb"`                // 节假日(JSON 数组),如 ["2025-10-01"]
+	ExtraWorkdays []byte    `gorm:"type:jsonb"`                // 调休上班日(JSON 数组),即使不是每周工作日也按工作日计算
+	CreatedBy     string    `gorm:"type:varchar(64)"`
+	CreatedAt     time.Time `gorm:"not null"`
+	UpdatedAt     time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (CalendarModel) TableName() string {
+	return "calendars"
+}
+
+// Validate 验证工作日历模型
+func (cm *CalendarModel) Validate() error {
+	if cm.ID == "" {
+		return errors.New("calendar ID is required")
+	}
+	if cm.Name == "" {
+		return errors.New("calendar name is required")
+	}
+	if cm.Timezone == "" {
+		return errors.New("calendar timezone is required")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// DelegationModel 审批委托规则数据模型
+// 委托期间到达委托人的审批自动转给代理人,审批记录保留"代理人代委托人审批"
+type DelegationModel struct {
+	ID         string    `gorm:"primaryKey;type:varchar(64)"`
+	Delegator  string    `gorm:"type:varchar(64);not null;index"` // 委托人(休假的审批人)
+	Delegate   string    `gorm:"type:varchar(64);not null;index"` // 代理人
+	TemplateID string    `gorm:"type:varchar(64)"`                // 只委托该模板的审批,为空时不限
+	StartAt    time.Time `gorm:"not null"`                        // 委托开始时间
+	EndAt      time.Time `gorm:"not null"`                        // 委托结束时间(不含)
+	Reason     string    `gorm:"type:text"`
+	CreatedBy  string    `gorm:"type:varchar(64)"`
+	CreatedAt  time.Time `gorm:"not null"`
+	UpdatedAt  time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (DelegationModel) TableName() string {
+	return "delegations"
+}
+
+// Validate 验证委托规则模型
+func (dm *DelegationModel) Validate() error {
+	if dm.ID == "" {
+		return errors.New("delegation ID is required")
+	}
+	if dm.Delegator == "" {
+		return errors.New("delegator is required")
+	}
+	if dm.Delegate == "" {
+		return errors.New("delegate is required")
+	}
+	if dm.Delegator == dm.Delegate {
+		return errors.New("delegate must be different from delegator")
+	}
+	if !dm.EndAt.After(dm.StartAt) {
+		return errors.New("end_at must be after start_at")
+	}
+	return nil
+}
+
+// ActiveAt 判断规则在指定时间是否生效
+func (dm *DelegationModel) ActiveAt(t time.Time) bool {
+	return !t.Before(dm.StartAt) && t.Before(dm.EndAt)
+}
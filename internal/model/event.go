@@ -5,16 +5,29 @@ import (
 	"time"
 )
 
-// EventModel 事件数据模型
+// 事件投递状态
+const (
+	EventStatusPending = "pending" // 待投递
+	EventStatusSuccess = "success" // 投递成功
+	EventStatusFailed  = "failed"  // 投递失败,等待重试
+	EventStatusDead    = "dead"    // 超过最大投递次数,进入死信
+)
+
+// EventModel 事件数据模型(事务性 outbox)
 type EventModel struct {
-	ID         string    `gorm:"primaryKey;type:varchar(64)"`
-	TaskID     string    `gorm:"type:varchar(64);not null;index"`
-	Type       string    `gorm:"type:varchar(32);not null;index"`
-	Data       []byte    `gorm:"type:jsonb;not null"` // 序列化后的事件数据
-	Status     string    `gorm:"type:varchar(32);not null;default:'pending'"` // pending/success/failed
-	RetryCount int       `gorm:"type:int;default:0"`
-	CreatedAt  time.Time `gorm:"not null;index"`
-	UpdatedAt  time.Time `gorm:"not null"`
+	ID             string     `gorm:"primaryKey;type:varchar(64)"`
+	TaskID         string     `gorm:"type:varchar(64);not null;index"`
+	Type           string     `gorm:"type:varchar(32);not null;index"`
+	Data           []byte     `gorm:"type:jsonb;not null"` // 序列化后的事件数据
+	Status         string     `gorm:"type:varchar(32);not null;default:'pending'"` // pending/success/failed/dead
+	RetryCount     int        `gorm:"type:int;default:0"` // 已投递次数
+	NextAttemptAt  *time.Time `gorm:"index"` // 下次投递时间(失败后按指数退避计算)
+	LeaseOwner     string     `gorm:"type:varchar(64)"` // 持有投递租约的实例
+	LeaseExpiresAt *time.Time // 投递租约到期时间,到期后其他实例可以重新领取
+	LastError      string     `gorm:"type:text"` // 最近一次投递失败的原因
+	Released       bool       `gorm:"not null;default:false"` // 已发布到消息总线(未配置时为已尝试投递),同一任务的后续事件可以投递
+	CreatedAt      time.Time  `gorm:"not null;index"`
+	UpdatedAt      time.Time  `gorm:"not null"`
 }
 
 // TableName 指定表名
@@ -37,7 +50,7 @@ func (em *EventModel) Validate() error {
 		return errors.New("event data is required")
 	}
 	if em.Status == "" {
-		em.Status = "pending"
+		em.Status = EventStatusPending
 	}
 	return nil
 }
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// EventDeliveryModel 事件投递记录数据模型
+// 每次向一个投递目标(消息总线或 Webhook 端点)投递事件记录一条,重试时跳过已投递成功的目标
+type EventDeliveryModel struct {
+	ID           string    `gorm:"primaryKey;type:varchar(64)"`
+	EventID      string    `gorm:"type:varchar(64);not null;index"`
+	Endpoint     string    `gorm:"type:varchar(512);not null"` // Webhook URL
+	Target       string    `gorm:"type:varchar(128)"`          // 投递目标: bus:<名称>/template:<模板 ID>:<序号>/subscription:<订阅 ID>
+	Attempt      int       `gorm:"type:int;not null"`          // 第几次投递
+	StatusCode   int       `gorm:"type:int"`                   // HTTP 响应码(请求未完成时为 0)
+	Success      bool      `gorm:"not null"`
+	LatencyMs    int64     `gorm:"type:bigint"` // 请求耗时(毫秒)
+	ResponseBody string    `gorm:"type:text"`   // 响应体(截断)
+	Error        string    `gorm:"type:text"`   // 失败原因
+	CreatedAt    time.Time `gorm:"not null;index"`
+}
+
+// TableName 指定表名
+func (EventDeliveryModel) TableName() string {
+	return "event_deliveries"
+}
+
+// Validate 验证事件投递记录模型
+func (edm *EventDeliveryModel) Validate() error {
+	if edm.ID == "" {
+		return errors.New("delivery ID is required")
+	}
+	if edm.EventID == "" {
+		return errors.New("event ID is required")
+	}
+	if edm.Endpoint == "" {
+		return errors.New("endpoint is required")
+	}
+	return nil
+}
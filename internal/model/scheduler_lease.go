@@ -0,0 +1,31 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// SchedulerLeaseModel 后台调度器的 leader 租约
+// 多副本部署时只有持有未过期租约的实例执行调度,持有者定期续约,实例退出后租约过期由其他实例接管
+type SchedulerLeaseModel struct {
+	Name      string    `gorm:"primaryKey;type:varchar(64)"` // 调度器名称
+	Holder    string    `gorm:"type:varchar(64);not null"`   // 持有者(实例标识)
+	ExpiresAt time.Time `gorm:"not null"`                    // 租约过期时间
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (SchedulerLeaseModel) TableName() string {
+	return "scheduler_leases"
+}
+
+// Validate 验证调度器租约模型
+func (slm *SchedulerLeaseModel) Validate() error {
+	if slm.Name == "" {
+		return errors.New("lease name is required")
+	}
+	if slm.Holder == "" {
+		return errors.New("lease holder is required")
+	}
+	return nil
+}
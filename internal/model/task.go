@@ -12,12 +12,20 @@ type TaskModel struct {
 	TemplateVersion int       `gorm:"type:int;not null"`
 	BusinessID     string     `gorm:"type:varchar(64);index"` // 业务 ID
 	State          string     `gorm:"type:varchar(32);not null;index"` // 任务状态
-	CurrentNode    string     `gorm:"type:varchar(64)"` // 当前节点 ID
+	CurrentNode    string     `gorm:"type:varchar(64)"` // 当前节点 ID(并行分支时为活动节点之一)
 	Data           []byte     `gorm:"type:jsonb;not null"` // 序列化后的 Task 对象
+	Runtime        []byte     `gorm:"type:jsonb"` // 流程运行时状态(活动节点集合、并行汇聚记录等)
+	Revision       int64      `gorm:"not null;default:0"` // 修订号(乐观锁),每次变更加 1
 	CreatedAt      time.Time  `gorm:"not null;index"`
 	UpdatedAt      time.Time  `gorm:"not null;index"`
 	SubmittedAt    *time.Time `gorm:"index"` // 提交时间
+	TimeoutAt      *time.Time `gorm:"index"` // 活动节点中最早的超时时间,由超时扫描器使用
+	RemindAt       *time.Time `gorm:"index"` // 待审批人中最早的下次提醒时间,由提醒扫描器使用
+	ServiceAt      *time.Time `gorm:"index"` // 活动服务节点中最早的调用时间,由服务节点扫描器使用
+	TimerAt        *time.Time `gorm:"index"` // 活动定时节点中最早的触发时间,由定时节点扫描器使用
 	CreatedBy      string     `gorm:"type:varchar(64);index"` // 创建人 ID
+	ParentTaskID   string     `gorm:"type:varchar(64);index"` // 父任务 ID(子流程节点创建的子任务)
+	ParentNodeID   string     `gorm:"type:varchar(64)"` // 父任务中创建该子任务的子流程节点 ID
 }
 
 // TableName 指定表名
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// TaskCCModel 任务抄送数据模型
+// 发起人创建任务时指定的抄送人和通知节点的通知对象,抄送人可以查看任务但不参与审批
+type TaskCCModel struct {
+	TaskID    string    `gorm:"primaryKey;type:varchar(64)"`
+	UserID    string    `gorm:"primaryKey;type:varchar(64);index"` // 抄送人 ID
+	NodeID    string    `gorm:"type:varchar(64)"`                  // 通知节点 ID,创建任务时指定的抄送人为空
+	AddedBy   string    `gorm:"type:varchar(64)"`                  // 添加抄送的操作人
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+// TableName 指定表名
+func (TaskCCModel) TableName() string {
+	return "task_ccs"
+}
+
+// Validate 验证任务抄送模型
+func (tcm *TaskCCModel) Validate() error {
+	if tcm.TaskID == "" {
+		return errors.New("task ID is required")
+	}
+	if tcm.UserID == "" {
+		return errors.New("user ID is required")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// TaskRoundModel 任务审批轮次快照
+// 任务被退回修改时,将本轮结束时的任务数据(参数、审批人、审批结果、节点输出和审批记录)归档,
+// 重新提交后开始新一轮审批,历史轮次不会被覆盖
+type TaskRoundModel struct {
+	ID        string    `gorm:"primaryKey;type:varchar(64)"`
+	TaskID    string    `gorm:"type:varchar(64);not null;index"`
+	Round     int       `gorm:"not null"`                  // 轮次,从 1 开始
+	Kind      string    `gorm:"type:varchar(32);not null"` // 本轮结束方式: return(退回修改)/reject(驳回到之前的节点或发起人)
+	NodeID    string    `gorm:"type:varchar(64)"`          // 结束本轮的节点
+	Operator  string    `gorm:"type:varchar(64)"`          // 结束本轮的操作人
+	Comment   string    `gorm:"type:text"`
+	Data      []byte    `gorm:"type:jsonb;not null"` // 本轮结束时序列化的 Task 对象
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (TaskRoundModel) TableName() string {
+	return "task_rounds"
+}
+
+// Validate 验证轮次快照模型
+func (trm *TaskRoundModel) Validate() error {
+	if trm.ID == "" {
+		return errors.New("round ID is required")
+	}
+	if trm.TaskID == "" {
+		return errors.New("task ID is required")
+	}
+	if trm.Round <= 0 {
+		return errors.New("round must be positive")
+	}
+	if len(trm.Data) == 0 {
+		return errors.New("round data is required")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// WebhookSecretModel Webhook 签名密钥数据模型
+// 按模板和端点保存(模板的所有版本共用),密钥使用 AES-256-GCM 加密存储;
+// 轮换后旧密钥在宽限期内仍然有效
+type WebhookSecretModel struct {
+	TemplateID        string     `gorm:"primaryKey;type:varchar(64)"`  // 模板 ID(全局订阅为订阅 ID)
+	Endpoint          string     `gorm:"primaryKey;type:varchar(512)"` // Webhook URL
+	Secret            string     `gorm:"type:text;not null"`           // 当前密钥(加密)
+	PreviousSecret    string     `gorm:"type:text"`                    // 轮换前的密钥(加密)
+	PreviousExpiresAt *time.Time // 旧密钥失效时间
+	CreatedAt         time.Time  `gorm:"not null"`
+	UpdatedAt         time.Time  `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (WebhookSecretModel) TableName() string {
+	return "webhook_secrets"
+}
+
+// Validate 验证 Webhook 签名密钥模型
+func (wsm *WebhookSecretModel) Validate() error {
+	if wsm.TemplateID == "" {
+		return errors.New("template ID is required")
+	}
+	if wsm.Endpoint == "" {
+		return errors.New("endpoint is required")
+	}
+	if wsm.Secret == "" {
+		return errors.New("secret is required")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// WebhookSubscriptionModel 全局 Webhook 订阅数据模型
+// 订阅不依赖模板配置,匹配的事件在模板 Webhook 之外额外推送到订阅地址
+type WebhookSubscriptionModel struct {
+	ID            string    `gorm:"primaryKey;type:varchar(64)"`
+	Name          string    `gorm:"type:varchar(255);not null"`
+	URL           string    `gorm:"type:varchar(512);not null"`
+	Method        string    `gorm:"type:varchar(16)"`
+	Headers       []byte    `gorm:"type:jsonb"`             // 自定义请求头(JSON 对象)
+	EventTypes    []byte    `gorm:"type:jsonb"`             // 订阅的事件类型(JSON 数组),为空时订阅所有事件
+	TemplateID    string    `gorm:"type:varchar(64);index"` // 只订阅该模板的事件,为空时不限
+	BusinessID    string    `gorm:"type:varchar(255)"`      // 只订阅该业务 ID 的事件,为空时不限
+	AuthType      string    `gorm:"type:varchar(32)"`       // 认证类型: bearer/basic/header/hmac
+	AuthKey       string    `gorm:"type:varchar(255)"`      // basic 用户名或 header 请求头名称
+	AuthToken     string    `gorm:"type:text"`              // 认证令牌(hmac 密钥加密保存在 webhook_secrets 表,此处为空)
+	PayloadFormat string    `gorm:"type:varchar(32)"`       // 请求体格式: raw/cloudevents_structured/cloudevents_binary,为空时为 raw
+	Enabled       bool      `gorm:"not null;default:true;index"`
+	CreatedBy     string    `gorm:"type:varchar(64)"`
+	CreatedAt     time.Time `gorm:"not null"`
+	UpdatedAt     time.Time `gorm:"not null"`
+}
+
+// TableName 指定表名
+func (WebhookSubscriptionModel) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Validate 验证 Webhook 订阅模型
+func (wsm *WebhookSubscriptionModel) Validate() error {
+	if wsm.ID == "" {
+		return errors.New("subscription ID is required")
+	}
+	if wsm.Name == "" {
+		return errors.New("subscription name is required")
+	}
+	if wsm.URL == "" {
+		return errors.New("subscription URL is required")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// CalendarRepository 工作日历仓储接口
+type CalendarRepository interface {
+	Save(calendar *model.CalendarModel) error
+	FindByID(id string) (*model.CalendarModel, error)
+	List() ([]*model.CalendarModel, error)
+	Delete(id string) error
+}
+
+// calendarRepository 工作日历仓储实现
+type calendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository 创建工作日历仓储
+func NewCalendarRepository(db *gorm.DB) CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+// Save 保存日历
+func (r *calendarRepository) Save(calendar *model.CalendarModel) error {
+	return r.db.Save(calendar).Error
+}
+
+// FindByID 根据 ID 查找日历
+func (r *calendarRepository) FindByID(id string) (*model.CalendarModel, error) {
+	var calendar model.CalendarModel
+	if err := r.db.Where("id = ?", id).First(&calendar).Error; err != nil {
+		return nil, err
+	}
+	return &calendar, nil
+}
+
+// List 列出所有日历
+func (r *calendarRepository) List() ([]*model.CalendarModel, error) {
+	var calendars []*model.CalendarModel
+	err := r.db.Order("created_at ASC").Find(&calendars).Error
+	return calendars, err
+}
+
+// Delete 删除日历
+func (r *calendarRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.CalendarModel{}).Error
+}
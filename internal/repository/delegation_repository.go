@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// DelegationFilter 委托规则查询条件
+type DelegationFilter struct {
+	Delegator string // 委托人
+	Delegate  string // 代理人
+	User      string // 委托人或代理人
+}
+
+// DelegationRepository 委托规则仓储接口
+type DelegationRepository interface {
+	Save(delegation *model.DelegationModel) error
+	FindByID(id string) (*model.DelegationModel, error)
+	List(filter *DelegationFilter) ([]*model.DelegationModel, error)
+	FindActive(delegator string, templateID string, at time.Time) (*model.DelegationModel, error)
+	FindOverlapping(delegator string, templateID string, startAt time.Time, endAt time.Time, excludeID string) ([]*model.DelegationModel, error)
+	Delete(id string) error
+}
+
+// delegationRepository 委托规则仓储实现
+type delegationRepository struct {
+	db *gorm.DB
+}
+
+// NewDelegationRepository 创建委托规则仓储
+func NewDelegationRepository(db *gorm.DB) DelegationRepository {
+	return &delegationRepository{db: db}
+}
+
+// Save 保存委托规则
+func (r *delegationRepository) Save(delegation *model.DelegationModel) error {
+	return r.db.Save(delegation).Error
+}
+
+// FindByID 根据 ID 查找委托规则
+func (r *delegationRepository) FindByID(id string) (*model.DelegationModel, error) {
+	var delegation model.DelegationModel
+	if err := r.db.Where("id = ?", id).First(&delegation).Error; err != nil {
+		return nil, err
+	}
+	return &delegation, nil
+}
+
+// List 按条件列出委托规则,按开始时间倒序
+func (r *delegationRepository) List(filter *DelegationFilter) ([]*model.DelegationModel, error) {
+	query := r.db.Model(&model.DelegationModel{})
+	if filter != nil {
+		if filter.Delegator != "" {
+			query = query.Where("delegator = ?", filter.Delegator)
+		}
+		if filter.Delegate != "" {
+			query = query.Where("delegate = ?", filter.Delegate)
+		}
+		if filter.User != "" {
+			query = query.Where("delegator = ? OR delegate = ?", filter.User, filter.User)
+		}
+	}
+	var delegations []*model.DelegationModel
+	err := query.Order("start_at DESC").Find(&delegations).Error
+	return delegations, err
+}
+
+// FindActive 查找委托人在指定时间生效的规则,限定模板的规则优先于不限模板的规则,没有时返回 nil
+func (r *delegationRepository) FindActive(delegator string, templateID string, at time.Time) (*model.DelegationModel, error) {
+	var delegations []*model.DelegationModel
+	err := r.db.Where("delegator = ? AND start_at <= ? AND end_at > ?", delegator, at, at).
+		Where("template_id = '' OR template_id IS NULL OR template_id = ?", templateID).
+		Order("template_id DESC").Order("created_at DESC").
+		Limit(1).
+		Find(&delegations).Error
+	if err != nil || len(delegations) == 0 {
+		return nil, err
+	}
+	return delegations[0], nil
+}
+
+// FindOverlapping 查找委托人在同一模板范围内时间重叠的规则
+func (r *delegationRepository) FindOverlapping(delegator string, templateID string, startAt time.Time, endAt time.Time, excludeID string) ([]*model.DelegationModel, error) {
+	query := r.db.Where("delegator = ? AND start_at < ? AND end_at > ?", delegator, endAt, startAt)
+	if templateID == "" {
+		query = query.Where("template_id = '' OR template_id IS NULL")
+	} else {
+		query = query.Where("template_id = ?", templateID)
+	}
+	if excludeID != "" {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var delegations []*model.DelegationModel
+	err := query.Find(&delegations).Error
+	return delegations, err
+}
+
+// Delete 删除委托规则
+func (r *delegationRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.DelegationModel{}).Error
+}
@@ -1,15 +1,65 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/mautops/approval-gin/internal/model"
 	"gorm.io/gorm"
 )
 
+// claimCandidates 每次领取事件时查询的候选事件数
+const claimCandidates = 10
+
 // EventRepository 事件仓储接口
 type EventRepository interface {
 	Save(event *model.EventModel) error
 	FindByTaskID(taskID string) ([]*model.EventModel, error)
 	FindPending() ([]*model.EventModel, error)
+	// outbox 投递
+	ClaimNext(owner string, now time.Time, leaseUntil time.Time) (*model.EventModel, error)
+	Finish(id string, owner string, updates map[string]interface{}) (bool, error)
+	// 投递记录、查询和重放
+	FindByID(id string) (*model.EventModel, error)
+	List(filter *EventFilter) ([]*model.EventModel, int64, error)
+	SaveDelivery(delivery *model.EventDeliveryModel) error
+	SucceededTargets(eventID string) (map[string]bool, error)
+	FindDeliveries(eventIDs []string) ([]*model.EventDeliveryModel, error)
+	Replay(filter *EventFilter) (int64, error)
+}
+
+// EventFilter 事件查询条件
+type EventFilter struct {
+	IDs       []string   // 事件 ID 列表
+	TaskID    string     // 任务 ID
+	Type      string     // 事件类型
+	Statuses  []string   // 投递状态
+	StartTime *time.Time // 创建时间起始
+	EndTime   *time.Time // 创建时间结束
+	Page      int        // 页码(List 使用)
+	PageSize  int        // 每页数量(List 使用)
+}
+
+// apply 应用查询条件(不含分页)
+func (f *EventFilter) apply(query *gorm.DB) *gorm.DB {
+	if len(f.IDs) > 0 {
+		query = query.Where("id IN ?", f.IDs)
+	}
+	if f.TaskID != "" {
+		query = query.Where("task_id = ?", f.TaskID)
+	}
+	if f.Type != "" {
+		query = query.Where("type = ?", f.Type)
+	}
+	if len(f.Statuses) > 0 {
+		query = query.Where("status IN ?", f.Statuses)
+	}
+	if f.StartTime != nil {
+		query = query.Where("created_at >= ?", *f.StartTime)
+	}
+	if f.EndTime != nil {
+		query = query.Where("created_at <= ?", *f.EndTime)
+	}
+	return query
 }
 
 // eventRepository 事件仓储实现
@@ -42,3 +92,158 @@ func (r *eventRepository) FindPending() ([]*model.EventModel, error) {
 }
 
 
+
+// ClaimNext 领取一个可投递的事件
+// 可投递: 待投递或失败后已到重试时间,且没有其他实例持有未过期的租约;没有可投递事件时返回 nil
+// 同一任务的事件按创建顺序投递: 更早的事件尚未发布到消息总线(且未投递成功或进入死信)时,后续事件不会被领取;
+// 已发布的事件只有个别 Webhook 目标失败时不阻塞后续事件,失败的目标单独重试
+// 领取通过带条件的 UPDATE 完成,多个实例同时领取同一事件时只有一个成功
+func (r *eventRepository) ClaimNext(owner string, now time.Time, leaseUntil time.Time) (*model.EventModel, error) {
+	deliverable := []string{model.EventStatusPending, model.EventStatusFailed}
+
+	var ids []string
+	err := r.db.Model(&model.EventModel{}).
+		Where("status IN ?", deliverable).
+		Where("(next_attempt_at IS NULL OR next_attempt_at <= ?)", now).
+		Where("(lease_expires_at IS NULL OR lease_expires_at < ?)", now).
+		Where("NOT EXISTS (SELECT 1 FROM events prior WHERE prior.task_id = events.task_id AND prior.status IN ? AND prior.released = ? AND prior.created_at < events.created_at)", deliverable, false).
+		Order("created_at ASC").
+		Limit(claimCandidates).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		result := r.db.Model(&model.EventModel{}).
+			Where("id = ? AND status IN ?", id, deliverable).
+			Where("(lease_expires_at IS NULL OR lease_expires_at < ?)", now).
+			Updates(map[string]interface{}{
+				"lease_owner":      owner,
+				"lease_expires_at": leaseUntil,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 已被其他实例领取
+			continue
+		}
+
+		var event model.EventModel
+		if err := r.db.Where("id = ?", id).First(&event).Error; err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+	return nil, nil
+}
+
+// Finish 写入投递结果并释放租约
+// 只有租约持有者可以写入;租约已过期并被其他实例领取时返回 false
+func (r *eventRepository) Finish(id string, owner string, updates map[string]interface{}) (bool, error) {
+	updates["lease_owner"] = ""
+	updates["lease_expires_at"] = nil
+	result := r.db.Model(&model.EventModel{}).
+		Where("id = ? AND lease_owner = ?", id, owner).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindByID 根据 ID 查找事件
+func (r *eventRepository) FindByID(id string) (*model.EventModel, error) {
+	var event model.EventModel
+	if err := r.db.Where("id = ?", id).First(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// List 分页查询事件(按创建时间倒序)
+func (r *eventRepository) List(filter *EventFilter) ([]*model.EventModel, int64, error) {
+	query := filter.apply(r.db.Model(&model.EventModel{}))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := filter.Page
+	if page <= 0 {
+		page = 1
+	}
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+
+	var events []*model.EventModel
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&events).Error
+	return events, total, err
+}
+
+// SaveDelivery 保存投递记录
+func (r *eventRepository) SaveDelivery(delivery *model.EventDeliveryModel) error {
+	return r.db.Create(delivery).Error
+}
+
+// SucceededTargets 查找事件已投递成功的目标
+func (r *eventRepository) SucceededTargets(eventID string) (map[string]bool, error) {
+	var targets []string
+	err := r.db.Model(&model.EventDeliveryModel{}).
+		Where("event_id = ? AND success = ? AND target <> ''", eventID, true).
+		Distinct().Pluck("target", &targets).Error
+	if err != nil {
+		return nil, err
+	}
+	succeeded := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		succeeded[target] = true
+	}
+	return succeeded, nil
+}
+
+// FindDeliveries 查找事件的投递记录(按投递时间排序)
+func (r *eventRepository) FindDeliveries(eventIDs []string) ([]*model.EventDeliveryModel, error) {
+	var deliveries []*model.EventDeliveryModel
+	if len(eventIDs) == 0 {
+		return deliveries, nil
+	}
+	err := r.db.Where("event_id IN ?", eventIDs).Order("created_at ASC").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// Replay 重放符合条件的失败或死信事件
+// 事件重置为待投递并立即可领取,投递次数清零(历史投递记录保留);返回重放的事件数
+func (r *eventRepository) Replay(filter *EventFilter) (int64, error) {
+	statuses := []string{model.EventStatusFailed, model.EventStatusDead}
+	if len(filter.Statuses) > 0 {
+		statuses = nil
+		for _, status := range filter.Statuses {
+			if status == model.EventStatusFailed || status == model.EventStatusDead {
+				statuses = append(statuses, status)
+			}
+		}
+		if len(statuses) == 0 {
+			return 0, nil
+		}
+	}
+
+	scoped := *filter
+	scoped.Statuses = statuses
+	result := scoped.apply(r.db.Model(&model.EventModel{})).
+		Updates(map[string]interface{}{
+			"status":           model.EventStatusPending,
+			"retry_count":      0,
+			"next_attempt_at":  time.Now(),
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SchedulerLeaseRepository 调度器租约仓储接口
+type SchedulerLeaseRepository interface {
+	Acquire(name string, holder string, now time.Time, expiresAt time.Time) (bool, error)
+	Release(name string, holder string) error
+}
+
+// schedulerLeaseRepository 调度器租约仓储实现
+type schedulerLeaseRepository struct {
+	db *gorm.DB
+}
+
+// NewSchedulerLeaseRepository 创建调度器租约仓储
+func NewSchedulerLeaseRepository(db *gorm.DB) SchedulerLeaseRepository {
+	return &schedulerLeaseRepository{db: db}
+}
+
+// Acquire 获取或续约租约
+// 租约由 holder 持有或已过期时更新为 holder 持有;租约不存在时创建。返回 holder 是否持有租约
+func (r *schedulerLeaseRepository) Acquire(name string, holder string, now time.Time, expiresAt time.Time) (bool, error) {
+	result := r.db.Model(&model.SchedulerLeaseModel{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, now).
+		Updates(map[string]interface{}{
+			"holder":     holder,
+			"expires_at": expiresAt,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 租约不存在时创建,并发创建时只有一个实例成功
+	lease := &model.SchedulerLeaseModel{
+		Name:      name,
+		Holder:    holder,
+		ExpiresAt: expiresAt,
+		UpdatedAt: now,
+	}
+	result = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(lease)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Release 释放 holder 持有的租约,其他实例可以立即接管
+func (r *schedulerLeaseRepository) Release(name string, holder string) error {
+	return r.db.Where("name = ? AND holder = ?", name, holder).Delete(&model.SchedulerLeaseModel{}).Error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaskCCRepository 任务抄送仓储接口
+type TaskCCRepository interface {
+	Save(cc *model.TaskCCModel) (bool, error)
+	FindByTaskID(taskID string) ([]*model.TaskCCModel, error)
+}
+
+// taskCCRepository 任务抄送仓储实现
+type taskCCRepository struct {
+	db *gorm.DB
+}
+
+// NewTaskCCRepository 创建任务抄送仓储
+func NewTaskCCRepository(db *gorm.DB) TaskCCRepository {
+	return &taskCCRepository{db: db}
+}
+
+// Save 保存抄送,用户已被抄送时忽略并返回 false
+func (r *taskCCRepository) Save(cc *model.TaskCCModel) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(cc)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindByTaskID 按抄送时间顺序查找任务的抄送人
+func (r *taskCCRepository) FindByTaskID(taskID string) ([]*model.TaskCCModel, error) {
+	var ccs []*model.TaskCCModel
+	err := r.db.Where("task_id = ?", taskID).Order("created_at ASC").Find(&ccs).Error
+	return ccs, err
+}
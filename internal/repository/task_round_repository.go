@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// TaskRoundRepository 任务审批轮次仓储接口
+type TaskRoundRepository interface {
+	Save(round *model.TaskRoundModel) error
+	FindByTaskID(taskID string) ([]*model.TaskRoundModel, error)
+}
+
+// taskRoundRepository 任务审批轮次仓储实现
+type taskRoundRepository struct {
+	db *gorm.DB
+}
+
+// NewTaskRoundRepository 创建任务审批轮次仓储
+func NewTaskRoundRepository(db *gorm.DB) TaskRoundRepository {
+	return &taskRoundRepository{db: db}
+}
+
+// Save 保存轮次快照
+func (r *taskRoundRepository) Save(round *model.TaskRoundModel) error {
+	return r.db.Save(round).Error
+}
+
+// FindByTaskID 按轮次顺序查找任务的历史轮次
+func (r *taskRoundRepository) FindByTaskID(taskID string) ([]*model.TaskRoundModel, error) {
+	var rounds []*model.TaskRoundModel
+	err := r.db.Where("task_id = ?", taskID).Order("round ASC").Order("created_at ASC").Find(&rounds).Error
+	return rounds, err
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// WebhookSecretRepository Webhook 签名密钥仓储接口
+type WebhookSecretRepository interface {
+	Save(secret *model.WebhookSecretModel) error
+	Find(templateID string, endpoint string) (*model.WebhookSecretModel, error)
+}
+
+// webhookSecretRepository Webhook 签名密钥仓储实现
+type webhookSecretRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookSecretRepository 创建 Webhook 签名密钥仓储
+func NewWebhookSecretRepository(db *gorm.DB) WebhookSecretRepository {
+	return &webhookSecretRepository{db: db}
+}
+
+// Save 保存签名密钥(存在则更新)
+func (r *webhookSecretRepository) Save(secret *model.WebhookSecretModel) error {
+	return r.db.Save(secret).Error
+}
+
+// Find 查找模板端点的签名密钥,不存在时返回 nil
+func (r *webhookSecretRepository) Find(templateID string, endpoint string) (*model.WebhookSecretModel, error) {
+	var secret model.WebhookSecretModel
+	err := r.db.Where("template_id = ? AND endpoint = ?", templateID, endpoint).First(&secret).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/mautops/approval-gin/internal/model"
+	"gorm.io/gorm"
+)
+
+// WebhookSubscriptionRepository Webhook 订阅仓储接口
+type WebhookSubscriptionRepository interface {
+	Save(subscription *model.WebhookSubscriptionModel) error
+	FindByID(id string) (*model.WebhookSubscriptionModel, error)
+	List() ([]*model.WebhookSubscriptionModel, error)
+	FindEnabled() ([]*model.WebhookSubscriptionModel, error)
+	Delete(id string) error
+}
+
+// webhookSubscriptionRepository Webhook 订阅仓储实现
+type webhookSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookSubscriptionRepository 创建 Webhook 订阅仓储
+func NewWebhookSubscriptionRepository(db *gorm.DB) WebhookSubscriptionRepository {
+	return &webhookSubscriptionRepository{db: db}
+}
+
+// Save 保存订阅
+func (r *webhookSubscriptionRepository) Save(subscription *model.WebhookSubscriptionModel) error {
+	return r.db.Save(subscription).Error
+}
+
+// FindByID 根据 ID 查找订阅
+func (r *webhookSubscriptionRepository) FindByID(id string) (*model.WebhookSubscriptionModel, error) {
+	var subscription model.WebhookSubscriptionModel
+	if err := r.db.Where("id = ?", id).First(&subscription).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// List 列出所有订阅
+func (r *webhookSubscriptionRepository) List() ([]*model.WebhookSubscriptionModel, error) {
+	var subscriptions []*model.WebhookSubscriptionModel
+	err := r.db.Order("created_at ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// FindEnabled 查找启用的订阅
+func (r *webhookSubscriptionRepository) FindEnabled() ([]*model.WebhookSubscriptionModel, error) {
+	var subscriptions []*model.WebhookSubscriptionModel
+	err := r.db.Where("enabled = ?", true).Order("created_at ASC").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Delete 删除订阅
+func (r *webhookSubscriptionRepository) Delete(id string) error {
+	return r.db.Where("id = ?", id).Delete(&model.WebhookSubscriptionModel{}).Error
+}
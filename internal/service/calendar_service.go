@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"gorm.io/gorm"
+)
+
+// CalendarService 工作日历服务接口
+// 模板配置或审批节点配置的 calendar_id 引用日历后,超时、提醒和统计只计算工作时间
+type CalendarService interface {
+	Create(ctx context.Context, req *CalendarRequest) (*Calendar, error)
+	Get(id string) (*Calendar, error)
+	List() ([]*Calendar, error)
+	Update(ctx context.Context, id string, req *CalendarRequest) (*Calendar, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// CalendarRequest 创建或更新工作日历请求(全量更新)
+type CalendarRequest struct {
+	Name          string                      `json:"name" example:"中国大陆工作日" binding:"required"`
+	Description   string                      `json:"description"`
+	Timezone      string                      `json:"timezone" example:"Asia/Shanghai"`         // IANA 时区,默认 UTC
+	Workdays      []string                    `json:"workdays" example:"mon,tue,wed,thu,fri"`   // 每周工作日,默认周一至周五
+	WorkingHours  []integration.WorkingPeriod `json:"working_hours"`                            // 工作时段,默认 09:00-18:00
+	Holidays      []string                    `json:"holidays" example:"2025-10-01,2025-10-02"` // 节假日(YYYY-MM-DD)
+	ExtraWorkdays []string                    `json:"extra_workdays" example:"2025-09-28"`      // 调休上班日(YYYY-MM-DD)
+}
+
+// Calendar 工作日历
+// @Description 工作日历详情
+type Calendar struct {
+	ID            string                      `json:"id"`
+	Name          string                      `json:"name"`
+	Description   string                      `json:"description,omitempty"`
+	Timezone      string                      `json:"timezone"`
+	Workdays      []string                    `json:"workdays"`
+	WorkingHours  []integration.WorkingPeriod `json:"working_hours"`
+	Holidays      []string                    `json:"holidays"`
+	ExtraWorkdays []string                    `json:"extra_workdays"`
+	CreatedBy     string                      `json:"created_by,omitempty"`
+	CreatedAt     time.Time                   `json:"created_at"`
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+// ErrInvalidCalendar 工作日历配置无效
+var ErrInvalidCalendar = errors.New("invalid calendar")
+
+// ErrCalendarNotFound 工作日历不存在
+var ErrCalendarNotFound = errors.New("calendar not found")
+
+// calendarService 工作日历服务实现
+type calendarService struct {
+	db          *gorm.DB
+	auditLogSvc AuditLogService
+}
+
+// NewCalendarService 创建工作日历服务
+func NewCalendarService(db *gorm.DB, auditLogSvc AuditLogService) CalendarService {
+	return &calendarService{
+		db:          db,
+		auditLogSvc: auditLogSvc,
+	}
+}
+
+// generateCalendarID 生成工作日历 ID
+func generateCalendarID() string {
+	return fmt.Sprintf("cal-%d", time.Now().UnixNano())
+}
+
+// Create 创建工作日历
+func (s *calendarService) Create(ctx context.Context, req *CalendarRequest) (*Calendar, error) {
+	now := time.Now()
+	calendar := &model.CalendarModel{
+		ID:        generateCalendarID(),
+		CreatedBy: getUserIDFromContext(ctx),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.apply(calendar, req); err != nil {
+		return nil, err
+	}
+
+	if err := repository.NewCalendarRepository(s.db).Save(calendar); err != nil {
+		return nil, fmt.Errorf("failed to save calendar: %w", err)
+	}
+
+	s.recordAction(ctx, "create", calendar)
+	return toCalendar(calendar), nil
+}
+
+// Get 获取工作日历
+func (s *calendarService) Get(id string) (*Calendar, error) {
+	calendar, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return toCalendar(calendar), nil
+}
+
+// List 列出所有工作日历
+func (s *calendarService) List() ([]*Calendar, error) {
+	calendars, err := repository.NewCalendarRepository(s.db).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+
+	result := make([]*Calendar, 0, len(calendars))
+	for _, calendar := range calendars {
+		result = append(result, toCalendar(calendar))
+	}
+	return result, nil
+}
+
+// Update 更新工作日历
+// 运行中任务已计算的超时和提醒时间在下次到期检查时按新日历重新计算
+func (s *calendarService) Update(ctx context.Context, id string, req *CalendarRequest) (*Calendar, error) {
+	calendar, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(calendar, req); err != nil {
+		return nil, err
+	}
+	calendar.UpdatedAt = time.Now()
+
+	if err := repository.NewCalendarRepository(s.db).Save(calendar); err != nil {
+		return nil, fmt.Errorf("failed to save calendar: %w", err)
+	}
+
+	s.recordAction(ctx, "update", calendar)
+	return toCalendar(calendar), nil
+}
+
+// Delete 删除工作日历
+// 仍引用该日历的模板按自然时间计算超时和提醒
+func (s *calendarService) Delete(ctx context.Context, id string) error {
+	calendar, err := s.find(id)
+	if err != nil {
+		return err
+	}
+
+	if err := repository.NewCalendarRepository(s.db).Delete(id); err != nil {
+		return fmt.Errorf("failed to delete calendar: %w", err)
+	}
+
+	s.recordAction(ctx, "delete", calendar)
+	return nil
+}
+
+// find 查找工作日历
+func (s *calendarService) find(id string) (*model.CalendarModel, error) {
+	calendar, err := repository.NewCalendarRepository(s.db).FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar: %w", err)
+	}
+	return calendar, nil
+}
+
+// apply 校验请求并写入日历(补全默认的时区、工作日和工作时段)
+func (s *calendarService) apply(calendar *model.CalendarModel, req *CalendarRequest) error {
+	cal := &integration.BusinessCalendar{
+		Timezone:      strings.TrimSpace(req.Timezone),
+		Workdays:      req.Workdays,
+		WorkingHours:  req.WorkingHours,
+		Holidays:      req.Holidays,
+		ExtraWorkdays: req.ExtraWorkdays,
+	}
+	if err := cal.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+
+	fields := []struct {
+		target *[]byte
+		value  interface{}
+	}{
+		{&calendar.Workdays, cal.Workdays},
+		{&calendar.WorkingHours, cal.WorkingHours},
+		{&calendar.Holidays, cal.Holidays},
+		{&calendar.ExtraWorkdays, cal.ExtraWorkdays},
+	}
+	for _, field := range fields {
+		data, err := json.Marshal(field.value)
+		if err != nil {
+			return fmt.Errorf("failed to marshal calendar: %w", err)
+		}
+		*field.target = data
+	}
+
+	calendar.Name = strings.TrimSpace(req.Name)
+	calendar.Description = req.Description
+	calendar.Timezone = cal.Timezone
+	if err := calendar.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+	return nil
+}
+
+// recordAction 记录日历操作审计日志
+func (s *calendarService) recordAction(ctx context.Context, action string, calendar *model.CalendarModel) {
+	if s.auditLogSvc == nil {
+		return
+	}
+	if userID := getUserIDFromContext(ctx); userID != "" {
+		details := map[string]interface{}{
+			"calendar_id": calendar.ID,
+			"name":        calendar.Name,
+			"timezone":    calendar.Timezone,
+		}
+		_ = s.auditLogSvc.RecordAction(ctx, userID, action, "calendar", calendar.ID, details)
+	}
+}
+
+// toCalendar 转换日历模型
+func toCalendar(calendar *model.CalendarModel) *Calendar {
+	result := &Calendar{
+		ID:            calendar.ID,
+		Name:          calendar.Name,
+		Description:   calendar.Description,
+		Timezone:      calendar.Timezone,
+		Workdays:      []string{},
+		WorkingHours:  []integration.WorkingPeriod{},
+		Holidays:      []string{},
+		ExtraWorkdays: []string{},
+		CreatedBy:     calendar.CreatedBy,
+		CreatedAt:     calendar.CreatedAt,
+		UpdatedAt:     calendar.UpdatedAt,
+	}
+	fields := []struct {
+		data   []byte
+		target interface{}
+	}{
+		{calendar.Workdays, &result.Workdays},
+		{calendar.WorkingHours, &result.WorkingHours},
+		{calendar.Holidays, &result.Holidays},
+		{calendar.ExtraWorkdays, &result.ExtraWorkdays},
+	}
+	for _, field := range fields {
+		if len(field.data) > 0 && string(field.data) != "null" {
+			_ = json.Unmarshal(field.data, field.target)
+		}
+	}
+	return result
+}
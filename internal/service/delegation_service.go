@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/task"
+	"gorm.io/gorm"
+)
+
+// DelegationService 审批委托服务接口
+// 用户(或管理员代用户)设置休假期间的代理人,委托期间到达委托人的审批自动转给代理人
+type DelegationService interface {
+	Create(ctx context.Context, req *DelegationRequest) (*Delegation, error)
+	Get(ctx context.Context, id string) (*Delegation, error)
+	List(ctx context.Context, filter *DelegationListFilter) ([]*Delegation, error)
+	Update(ctx context.Context, id string, req *DelegationRequest) (*Delegation, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// DelegationRequest 创建或更新委托规则请求(全量更新)
+type DelegationRequest struct {
+	Delegator  string    `json:"delegator" example:"alice"`                                       // 委托人,默认当前用户;为其他用户设置需要管理员角色
+	Delegate   string    `json:"delegate" example:"bob" binding:"required"`                       // 代理人
+	TemplateID string    `json:"template_id"`                                                     // 只委托该模板的审批,为空时不限
+	StartAt    time.Time `json:"start_at" example:"2025-10-01T00:00:00+08:00" binding:"required"` // 委托开始时间
+	EndAt      time.Time `json:"end_at" example:"2025-10-08T00:00:00+08:00" binding:"required"`   // 委托结束时间(不含)
+	Reason     string    `json:"reason" example:"国庆休假"`
+}
+
+// DelegationListFilter 委托规则查询条件
+type DelegationListFilter struct {
+	Delegator string `form:"delegator"` // 委托人
+	Delegate  string `form:"delegate"`  // 代理人
+}
+
+// Delegation 委托规则
+// @Description 委托规则详情
+type Delegation struct {
+	ID              string    `json:"id"`
+	Delegator       string    `json:"delegator"`
+	Delegate        string    `json:"delegate"`
+	TemplateID      string    `json:"template_id,omitempty"`
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at"`
+	Reason          string    `json:"reason,omitempty"`
+	Active          bool      `json:"active"`                     // 当前是否处于委托期间
+	RedirectedTasks int       `json:"redirected_tasks,omitempty"` // 创建或更新后立即转给代理人的运行中任务数
+	CreatedBy       string    `json:"created_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ErrInvalidDelegation 委托规则无效
+var ErrInvalidDelegation = errors.New("invalid delegation")
+
+// ErrDelegationNotFound 委托规则不存在
+var ErrDelegationNotFound = errors.New("delegation not found")
+
+// ErrDelegationForbidden 无权管理其他用户的委托规则
+var ErrDelegationForbidden = errors.New("not allowed to manage delegations of other users")
+
+// ErrDelegationConflict 委托人在同一模板范围内已有时间重叠的委托规则
+var ErrDelegationConflict = errors.New("delegation overlaps an existing delegation")
+
+// delegationService 审批委托服务实现
+type delegationService struct {
+	db          *gorm.DB
+	taskMgr     task.TaskManager
+	auditLogSvc AuditLogService
+	adminRole   string
+}
+
+// NewDelegationService 创建审批委托服务
+// adminRole 为可以管理所有用户委托规则的角色
+func NewDelegationService(db *gorm.DB, taskMgr task.TaskManager, auditLogSvc AuditLogService, adminRole string) DelegationService {
+	return &delegationService{
+		db:          db,
+		taskMgr:     taskMgr,
+		auditLogSvc: auditLogSvc,
+		adminRole:   adminRole,
+	}
+}
+
+// generateDelegationID 生成委托规则 ID
+func generateDelegationID() string {
+	return fmt.Sprintf("dlg-%d", time.Now().UnixNano())
+}
+
+// Create 创建委托规则
+// 规则当前已生效时,运行中任务里委托人尚未审批的节点立即转给代理人
+func (s *delegationService) Create(ctx context.Context, req *DelegationRequest) (*Delegation, error) {
+	now := time.Now()
+	userID := getUserIDFromContext(ctx)
+	delegation := &model.DelegationModel{
+		ID:        generateDelegationID(),
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.apply(ctx, delegation, req); err != nil {
+		return nil, err
+	}
+
+	if err := repository.NewDelegationRepository(s.db).Save(delegation); err != nil {
+		return nil, fmt.Errorf("failed to save delegation: %w", err)
+	}
+
+	s.recordAction(ctx, "create", delegation)
+	result := toDelegation(delegation)
+	result.RedirectedTasks = s.redirect(delegation)
+	return result, nil
+}
+
+// Get 获取委托规则,委托人、代理人和管理员可见
+func (s *delegationService) Get(ctx context.Context, id string) (*Delegation, error) {
+	delegation, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.canManage(ctx, delegation.Delegator) && getUserIDFromContext(ctx) != delegation.Delegate {
+		return nil, fmt.Errorf("%w: %s", ErrDelegationNotFound, id)
+	}
+	return toDelegation(delegation), nil
+}
+
+// List 列出委托规则
+// 非管理员只能看到自己作为委托人或代理人的规则
+func (s *delegationService) List(ctx context.Context, filter *DelegationListFilter) ([]*Delegation, error) {
+	repoFilter := &repository.DelegationFilter{}
+	if filter != nil {
+		repoFilter.Delegator = filter.Delegator
+		repoFilter.Delegate = filter.Delegate
+	}
+	if userID := getUserIDFromContext(ctx); userID != "" && !s.isAdmin(ctx) {
+		repoFilter.User = userID
+	}
+
+	delegations, err := repository.NewDelegationRepository(s.db).List(repoFilter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delegations: %w", err)
+	}
+
+	result := make([]*Delegation, 0, len(delegations))
+	for _, delegation := range delegations {
+		result = append(result, toDelegation(delegation))
+	}
+	return result, nil
+}
+
+// Update 更新委托规则
+// 已转给代理人的任务不会转回,缩短或删除规则只影响之后到达的审批
+func (s *delegationService) Update(ctx context.Context, id string, req *DelegationRequest) (*Delegation, error) {
+	delegation, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.canManage(ctx, delegation.Delegator) {
+		return nil, fmt.Errorf("%w: %s", ErrDelegationForbidden, delegation.Delegator)
+	}
+	if err := s.apply(ctx, delegation, req); err != nil {
+		return nil, err
+	}
+	delegation.UpdatedAt = time.Now()
+
+	if err := repository.NewDelegationRepository(s.db).Save(delegation); err != nil {
+		return nil, fmt.Errorf("failed to save delegation: %w", err)
+	}
+
+	s.recordAction(ctx, "update", delegation)
+	result := toDelegation(delegation)
+	result.RedirectedTasks = s.redirect(delegation)
+	return result, nil
+}
+
+// Delete 删除委托规则
+func (s *delegationService) Delete(ctx context.Context, id string) error {
+	delegation, err := s.find(id)
+	if err != nil {
+		return err
+	}
+	if !s.canManage(ctx, delegation.Delegator) {
+		return fmt.Errorf("%w: %s", ErrDelegationForbidden, delegation.Delegator)
+	}
+
+	if err := repository.NewDelegationRepository(s.db).Delete(id); err != nil {
+		return fmt.Errorf("failed to delete delegation: %w", err)
+	}
+
+	s.recordAction(ctx, "delete", delegation)
+	return nil
+}
+
+// find 查找委托规则
+func (s *delegationService) find(id string) (*model.DelegationModel, error) {
+	delegation, err := repository.NewDelegationRepository(s.db).FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrDelegationNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delegation: %w", err)
+	}
+	return delegation, nil
+}
+
+// apply 校验请求并写入委托规则
+func (s *delegationService) apply(ctx context.Context, delegation *model.DelegationModel, req *DelegationRequest) error {
+	delegator := strings.TrimSpace(req.Delegator)
+	if delegator == "" {
+		delegator = getUserIDFromContext(ctx)
+	}
+	if delegator == "" {
+		return fmt.Errorf("%w: delegator is required", ErrInvalidDelegation)
+	}
+	if !s.canManage(ctx, delegator) {
+		return fmt.Errorf("%w: %s", ErrDelegationForbidden, delegator)
+	}
+
+	delegation.Delegator = delegator
+	delegation.Delegate = strings.TrimSpace(req.Delegate)
+	delegation.TemplateID = strings.TrimSpace(req.TemplateID)
+	delegation.StartAt = req.StartAt
+	delegation.EndAt = req.EndAt
+	delegation.Reason = req.Reason
+	if err := delegation.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDelegation, err)
+	}
+
+	if delegation.TemplateID != "" {
+		var count int64
+		if err := s.db.Model(&model.TemplateModel{}).Where("id = ?", delegation.TemplateID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check template: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: template %q not found", ErrInvalidDelegation, delegation.TemplateID)
+		}
+	}
+
+	// 同一模板范围内时间重叠的规则无法确定代理人
+	overlapping, err := repository.NewDelegationRepository(s.db).FindOverlapping(delegation.Delegator, delegation.TemplateID, delegation.StartAt, delegation.EndAt, delegation.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check overlapping delegations: %w", err)
+	}
+	if len(overlapping) > 0 {
+		return fmt.Errorf("%w: %s", ErrDelegationConflict, overlapping[0].ID)
+	}
+	return nil
+}
+
+// redirect 规则当前生效时将运行中任务转给代理人,返回转交的任务数;失败时只记录日志
+func (s *delegationService) redirect(delegation *model.DelegationModel) int {
+	if !delegation.ActiveAt(time.Now()) {
+		return 0
+	}
+	dbTaskMgr, ok := s.taskMgr.(*integration.DBTaskManager)
+	if !ok {
+		return 0
+	}
+	count, err := dbTaskMgr.ApplyDelegation(delegation.Delegator, delegation.TemplateID)
+	if err != nil {
+		log.Printf("failed to apply delegation %q to running tasks: %v", delegation.ID, err)
+	}
+	return count
+}
+
+// canManage 判断当前用户能否管理委托人的规则: 本人或管理员;没有认证信息时不校验
+func (s *delegationService) canManage(ctx context.Context, delegator string) bool {
+	userID := getUserIDFromContext(ctx)
+	return userID == "" || userID == delegator || s.isAdmin(ctx)
+}
+
+// isAdmin 判断当前用户是否拥有管理员角色
+func (s *delegationService) isAdmin(ctx context.Context) bool {
+	if s.adminRole == "" {
+		return false
+	}
+	for _, role := range getRolesFromContext(ctx) {
+		if role == s.adminRole {
+			return true
+		}
+	}
+	return false
+}
+
+// recordAction 记录委托规则操作审计日志
+func (s *delegationService) recordAction(ctx context.Context, action string, delegation *model.DelegationModel) {
+	if s.auditLogSvc == nil {
+		return
+	}
+	if userID := getUserIDFromContext(ctx); userID != "" {
+		details := map[string]interface{}{
+			"delegation_id": delegation.ID,
+			"delegator":     delegation.Delegator,
+			"delegate":      delegation.Delegate,
+			"template_id":   delegation.TemplateID,
+			"start_at":      delegation.StartAt,
+			"end_at":        delegation.EndAt,
+		}
+		_ = s.auditLogSvc.RecordAction(ctx, userID, action, "delegation", delegation.ID, details)
+	}
+}
+
+// toDelegation 转换委托规则模型
+func toDelegation(delegation *model.DelegationModel) *Delegation {
+	return &Delegation{
+		ID:         delegation.ID,
+		Delegator:  delegation.Delegator,
+		Delegate:   delegation.Delegate,
+		TemplateID: delegation.TemplateID,
+		StartAt:    delegation.StartAt,
+		EndAt:      delegation.EndAt,
+		Reason:     delegation.Reason,
+		Active:     delegation.ActiveAt(time.Now()),
+		CreatedBy:  delegation.CreatedBy,
+		CreatedAt:  delegation.CreatedAt,
+		UpdatedAt:  delegation.UpdatedAt,
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/event"
+	"gorm.io/gorm"
+)
+
+// EventService 事件服务接口
+// 查询事件及其 Webhook 投递记录,重放失败或死信事件
+type EventService interface {
+	List(filter *ListEventsFilter) ([]*EventDetail, int64, error)
+	Get(id string) (*EventDetail, error)
+	GetCloudEvent(id string) (*integration.CloudEvent, error)
+	Replay(ctx context.Context, id string) error
+	BatchReplay(ctx context.Context, req *ReplayEventsRequest) (*ReplayEventsResponse, error)
+}
+
+// ListEventsFilter 事件列表查询过滤器
+type ListEventsFilter struct {
+	TaskID    string     `form:"task_id"`
+	Type      string     `form:"type"`
+	Status    string     `form:"status"`
+	StartTime *time.Time `form:"created_at_start" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   *time.Time `form:"created_at_end" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page      int        `form:"page"`
+	PageSize  int        `form:"page_size"`
+}
+
+// EventDetail 事件详情
+// @Description 事件及其在各个 Webhook 端点的投递记录
+type EventDetail struct {
+	ID            string           `json:"id"`                        // 事件 ID
+	TaskID        string           `json:"task_id"`                   // 任务 ID
+	Type          string           `json:"type"`                      // 事件类型
+	Status        string           `json:"status"`                    // 投递状态: pending/success/failed/dead
+	Attempts      int              `json:"attempts"`                  // 已投递次数
+	NextAttemptAt *time.Time       `json:"next_attempt_at,omitempty"` // 下次投递时间
+	LastError     string           `json:"last_error,omitempty"`      // 最近一次投递失败的原因
+	Data          json.RawMessage  `json:"data" swaggertype:"object"` // 事件内容
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+	Deliveries    []*EventDelivery `json:"deliveries"` // 投递记录
+}
+
+// EventDelivery 事件投递记录
+// @Description 向一个 Webhook 端点投递一次事件的结果
+type EventDelivery struct {
+	Endpoint     string    `json:"endpoint"`                // Webhook URL
+	Target       string    `json:"target,omitempty"`        // 投递目标标识(消息总线、模板 Webhook 或全局订阅)
+	Attempt      int       `json:"attempt"`                 // 第几次投递
+	Success      bool      `json:"success"`                 // 是否成功
+	StatusCode   int       `json:"status_code,omitempty"`   // HTTP 响应码
+	LatencyMs    int64     `json:"latency_ms"`              // 请求耗时(毫秒)
+	ResponseBody string    `json:"response_body,omitempty"` // 响应体(截断)
+	Error        string    `json:"error,omitempty"`         // 失败原因
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ReplayEventsRequest 批量重放事件请求
+// @Description 批量重放失败或死信事件,指定 event_ids 时只重放这些事件,否则按条件重放
+type ReplayEventsRequest struct {
+	EventIDs  []string   `json:"event_ids"`                                 // 事件 ID 列表
+	TaskID    string     `json:"task_id"`                                   // 任务 ID
+	Type      string     `json:"type"`                                      // 事件类型
+	Status    string     `json:"status" example:"dead" enums:"failed,dead"` // 投递状态,默认 failed 和 dead
+	StartTime *time.Time `json:"created_at_start"`                          // 创建时间起始
+	EndTime   *time.Time `json:"created_at_end"`                            // 创建时间结束
+}
+
+// ReplayEventsResponse 批量重放事件结果
+// @Description 批量重放事件的结果
+type ReplayEventsResponse struct {
+	Replayed int64 `json:"replayed"` // 重放的事件数
+}
+
+// cloudEventEncoder 支持 CloudEvents 编码的事件处理器
+type cloudEventEncoder interface {
+	CloudEvent(eventModel *model.EventModel) (*integration.CloudEvent, error)
+}
+
+// ErrEventNotReplayable 事件不是失败或死信状态,不能重放
+var ErrEventNotReplayable = errors.New("only failed or dead events can be replayed")
+
+// eventService 事件服务实现
+type eventService struct {
+	eventRepo   repository.EventRepository
+	notifier    interface{ Notify() }
+	encoder     cloudEventEncoder
+	auditLogSvc AuditLogService
+}
+
+// NewEventService 创建事件服务
+// eventHandler 支持通知时,重放后立即唤醒投递 worker
+func NewEventService(db *gorm.DB, eventHandler event.EventHandler, auditLogSvc AuditLogService) EventService {
+	notifier, _ := eventHandler.(interface{ Notify() })
+	encoder, _ := eventHandler.(cloudEventEncoder)
+	return &eventService{
+		eventRepo:   repository.NewEventRepository(db),
+		notifier:    notifier,
+		encoder:     encoder,
+		auditLogSvc: auditLogSvc,
+	}
+}
+
+// List 列出事件
+func (s *eventService) List(filter *ListEventsFilter) ([]*EventDetail, int64, error) {
+	repoFilter := &repository.EventFilter{
+		TaskID:    filter.TaskID,
+		Type:      filter.Type,
+		StartTime: filter.StartTime,
+		EndTime:   filter.EndTime,
+		Page:      filter.Page,
+		PageSize:  filter.PageSize,
+	}
+	if filter.Status != "" {
+		repoFilter.Statuses = []string{filter.Status}
+	}
+
+	events, total, err := s.eventRepo.List(repoFilter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list events: %w", err)
+	}
+
+	details, err := s.withDeliveries(events)
+	if err != nil {
+		return nil, 0, err
+	}
+	return details, total, nil
+}
+
+// Get 获取事件详情
+func (s *eventService) Get(id string) (*EventDetail, error) {
+	eventModel, err := s.eventRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("event not found: %w", err)
+	}
+
+	details, err := s.withDeliveries([]*model.EventModel{eventModel})
+	if err != nil {
+		return nil, err
+	}
+	return details[0], nil
+}
+
+// GetCloudEvent 获取事件的 CloudEvents 表示
+func (s *eventService) GetCloudEvent(id string) (*integration.CloudEvent, error) {
+	eventModel, err := s.eventRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("event not found: %w", err)
+	}
+	if s.encoder == nil {
+		return nil, errors.New("event handler does not support cloud events")
+	}
+	return s.encoder.CloudEvent(eventModel)
+}
+
+// Replay 重放单个失败或死信事件
+func (s *eventService) Replay(ctx context.Context, id string) error {
+	eventModel, err := s.eventRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("event not found: %w", err)
+	}
+	if eventModel.Status != model.EventStatusFailed && eventModel.Status != model.EventStatusDead {
+		return fmt.Errorf("%w: event %q is %s", ErrEventNotReplayable, id, eventModel.Status)
+	}
+
+	replayed, err := s.eventRepo.Replay(&repository.EventFilter{IDs: []string{id}})
+	if err != nil {
+		return fmt.Errorf("failed to replay event: %w", err)
+	}
+	if replayed == 0 {
+		return fmt.Errorf("%w: event %q changed state", ErrEventNotReplayable, id)
+	}
+	s.afterReplay(ctx, id, fmt.Sprintf(`{"event_id":"%s"}`, id))
+	return nil
+}
+
+// BatchReplay 批量重放失败或死信事件
+func (s *eventService) BatchReplay(ctx context.Context, req *ReplayEventsRequest) (*ReplayEventsResponse, error) {
+	filter := &repository.EventFilter{
+		IDs:       req.EventIDs,
+		TaskID:    req.TaskID,
+		Type:      req.Type,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+	if req.Status != "" {
+		if req.Status != model.EventStatusFailed && req.Status != model.EventStatusDead {
+			return nil, fmt.Errorf("%w: status %q", ErrEventNotReplayable, req.Status)
+		}
+		filter.Statuses = []string{req.Status}
+	}
+
+	replayed, err := s.eventRepo.Replay(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay events: %w", err)
+	}
+
+	if replayed > 0 {
+		reqJSON, _ := json.Marshal(req)
+		s.afterReplay(ctx, "batch", fmt.Sprintf(`{"request":%s,"replayed":%d}`, reqJSON, replayed))
+	}
+	return &ReplayEventsResponse{Replayed: replayed}, nil
+}
+
+// afterReplay 重放后唤醒投递 worker 并记录审计日志
+func (s *eventService) afterReplay(ctx context.Context, resourceID string, details string) {
+	if s.notifier != nil {
+		s.notifier.Notify()
+	}
+	if s.auditLogSvc != nil {
+		if userID := getUserIDFromContext(ctx); userID != "" {
+			_ = s.auditLogSvc.RecordAction(ctx, userID, "replay", "event", resourceID, details)
+		}
+	}
+}
+
+// withDeliveries 转换事件并附加投递记录
+func (s *eventService) withDeliveries(events []*model.EventModel) ([]*EventDetail, error) {
+	ids := make([]string, 0, len(events))
+	for _, eventModel := range events {
+		ids = append(ids, eventModel.ID)
+	}
+	deliveries, err := s.eventRepo.FindDeliveries(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event deliveries: %w", err)
+	}
+
+	byEvent := make(map[string][]*EventDelivery, len(events))
+	for _, d := range deliveries {
+		byEvent[d.EventID] = append(byEvent[d.EventID], &EventDelivery{
+			Endpoint:     d.Endpoint,
+			Target:       d.Target,
+			Attempt:      d.Attempt,
+			Success:      d.Success,
+			StatusCode:   d.StatusCode,
+			LatencyMs:    d.LatencyMs,
+			ResponseBody: d.ResponseBody,
+			Error:        d.Error,
+			CreatedAt:    d.CreatedAt,
+		})
+	}
+
+	details := make([]*EventDetail, 0, len(events))
+	for _, eventModel := range events {
+		detail := &EventDetail{
+			ID:            eventModel.ID,
+			TaskID:        eventModel.TaskID,
+			Type:          eventModel.Type,
+			Status:        eventModel.Status,
+			Attempts:      eventModel.RetryCount,
+			NextAttemptAt: eventModel.NextAttemptAt,
+			LastError:     eventModel.LastError,
+			Data:          json.RawMessage(eventModel.Data),
+			CreatedAt:     eventModel.CreatedAt,
+			UpdatedAt:     eventModel.UpdatedAt,
+			Deliveries:    byEvent[eventModel.ID],
+		}
+		if detail.Deliveries == nil {
+			detail.Deliveries = []*EventDelivery{}
+		}
+		details = append(details, detail)
+	}
+	return details, nil
+}
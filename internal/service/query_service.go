@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -16,10 +18,15 @@ import (
 // QueryService 查询服务接口
 type QueryService interface {
 	ListTasks(filter *ListTasksFilter) ([]*task.Task, int64, error)
+	ListCCTasks(ctx context.Context, filter *ListTasksFilter) ([]*task.Task, int64, error)
 	GetRecords(taskID string) ([]*ApprovalRecord, error)
 	GetHistory(taskID string) ([]*StateHistory, error)
+	GetRounds(taskID string) ([]*TaskRound, error)
 }
 
+// ErrUnauthenticated 查询当前用户相关的数据时没有登录用户
+var ErrUnauthenticated = errors.New("user is not authenticated")
+
 // ListTasksFilter 任务列表查询过滤器
 type ListTasksFilter struct {
 	State      *types.TaskState
@@ -28,6 +35,8 @@ type ListTasksFilter struct {
 	Approver   *string
 	StartTime  *string
 	EndTime    *string
+	CCUser     *string `form:"-"` // 只查询抄送给该用户的任务
+	ParentID   *string `form:"-"` // 只查询该任务的子任务(子流程节点创建的任务)
 	Page       int
 	PageSize   int
 	SortBy     string
@@ -43,6 +52,8 @@ type ApprovalRecord struct {
 	Result      string
 	Comment     string
 	Attachments []string
+	OnBehalfOf  string // 代理审批时的委托人
+	Round       int    // 审批轮次
 	CreatedAt   string
 }
 
@@ -57,6 +68,19 @@ type StateHistory struct {
 	CreatedAt string
 }
 
+// TaskRound 已结束的审批轮次
+type TaskRound struct {
+	ID        string
+	TaskID    string
+	Round     int
+	Kind      string // 本轮结束方式
+	NodeID    string
+	Operator  string
+	Comment   string
+	Task      json.RawMessage `swaggertype:"object"` // 本轮结束时的任务数据
+	CreatedAt string
+}
+
 // queryService 查询服务实现
 type queryService struct {
 	db         *gorm.DB
@@ -96,6 +120,12 @@ func (s *queryService) ListTasks(filter *ListTasksFilter) ([]*task.Task, int64,
 	if filter.EndTime != nil {
 		query = query.Where("created_at <= ?", *filter.EndTime)
 	}
+	if filter.CCUser != nil {
+		query = query.Where("id IN (?)", s.db.Model(&model.TaskCCModel{}).Select("task_id").Where("user_id = ?", *filter.CCUser))
+	}
+	if filter.ParentID != nil {
+		query = query.Where("parent_task_id = ?", *filter.ParentID)
+	}
 
 	// 获取总数
 	var total int64
@@ -153,6 +183,16 @@ func (s *queryService) ListTasks(filter *ListTasksFilter) ([]*task.Task, int64,
 	return tasks, total, nil
 }
 
+// ListCCTasks 列出抄送给当前用户的任务,支持与 ListTasks 相同的过滤、排序和分页
+func (s *queryService) ListCCTasks(ctx context.Context, filter *ListTasksFilter) ([]*task.Task, int64, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return nil, 0, ErrUnauthenticated
+	}
+	filter.CCUser = &userID
+	return s.ListTasks(filter)
+}
+
 // GetRecords 获取审批记录
 func (s *queryService) GetRecords(taskID string) ([]*ApprovalRecord, error) {
 	models, err := s.recordRepo.FindByTaskID(taskID)
@@ -174,6 +214,8 @@ func (s *queryService) GetRecords(taskID string) ([]*ApprovalRecord, error) {
 			Result:      m.Result,
 			Comment:     m.Comment,
 			Attachments: attachments,
+			OnBehalfOf:  m.OnBehalfOf,
+			Round:       m.Round,
 			CreatedAt:   m.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		})
 	}
@@ -204,3 +246,27 @@ func (s *queryService) GetHistory(taskID string) ([]*StateHistory, error) {
 	return histories, nil
 }
 
+// GetRounds 获取任务已结束的审批轮次
+func (s *queryService) GetRounds(taskID string) ([]*TaskRound, error) {
+	models, err := repository.NewTaskRoundRepository(s.db).FindByTaskID(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rounds: %w", err)
+	}
+
+	rounds := make([]*TaskRound, 0, len(models))
+	for _, m := range models {
+		rounds = append(rounds, &TaskRound{
+			ID:        m.ID,
+			TaskID:    m.TaskID,
+			Round:     m.Round,
+			Kind:      m.Kind,
+			NodeID:    m.NodeID,
+			Operator:  m.Operator,
+			Comment:   m.Comment,
+			Task:      json.RawMessage(m.Data),
+			CreatedAt: m.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	return rounds, nil
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/mautops/approval-gin/internal/integration"
 	"github.com/mautops/approval-gin/internal/model"
 	"gorm.io/gorm"
 )
@@ -40,7 +42,7 @@ type ApprovalStatistics struct {
 	ApprovedCount     int64
 	RejectedCount     int64
 	ApprovalRate      float64
-	AverageApprovalTime float64 // 单位：秒
+	AverageApprovalTime float64 // 单位：秒(配置了工作日历的节点只计算工作时间)
 }
 
 // statisticsService 统计服务实现
@@ -179,8 +181,10 @@ func (s *statisticsService) GetApprovalStatistics() (*ApprovalStatistics, error)
 		approvalRate = float64(approvedCount) / float64(totalCount) * 100
 	}
 
-	// TODO: 计算平均审批时间（需要从审批记录中计算时间差）
-	averageApprovalTime := 0.0
+	averageApprovalTime, err := s.averageApprovalTime()
+	if err != nil {
+		return nil, err
+	}
 
 	return &ApprovalStatistics{
 		TotalApprovals:      totalCount,
@@ -192,3 +196,63 @@ func (s *statisticsService) GetApprovalStatistics() (*ApprovalStatistics, error)
 }
 
 
+
+// approvalDuration 审批记录及其所属任务的计时信息
+type approvalDuration struct {
+	TaskID          string
+	NodeID          string
+	CreatedAt       time.Time
+	TemplateID      string
+	TemplateVersion int
+	SubmittedAt     *time.Time
+}
+
+// averageApprovalTime 计算同意和拒绝记录的平均审批用时(秒)
+// 节点的计时起点为任务提交时间或之前其他节点的最近一次审批时间(并行分支按此近似计算),
+// 节点或模板配置了工作日历时只计算工作时间
+func (s *statisticsService) averageApprovalTime() (float64, error) {
+	var records []*approvalDuration
+	err := s.db.Table("approval_records").
+		Select("approval_records.task_id, approval_records.node_id, approval_records.created_at, tasks.template_id, tasks.template_version, tasks.submitted_at").
+		Joins("JOIN tasks ON tasks.id = approval_records.task_id").
+		Where("approval_records.result IN ?", []string{"approve", "reject"}).
+		Where("tasks.submitted_at IS NOT NULL").
+		Order("approval_records.task_id, approval_records.created_at").
+		Scan(&records).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to query approval durations: %w", err)
+	}
+
+	calendars := make(map[string]*integration.BusinessCalendar)
+	var total time.Duration
+	var count int
+	for i, record := range records {
+		start := *record.SubmittedAt
+		for j := i - 1; j >= 0 && records[j].TaskID == record.TaskID; j-- {
+			if records[j].NodeID != record.NodeID {
+				if records[j].CreatedAt.After(start) {
+					start = records[j].CreatedAt
+				}
+				break
+			}
+		}
+
+		key := fmt.Sprintf("%s:%d:%s", record.TemplateID, record.TemplateVersion, record.NodeID)
+		cal, exists := calendars[key]
+		if !exists {
+			cal, err = integration.LoadNodeCalendar(s.db, record.TemplateID, record.TemplateVersion, record.NodeID)
+			if err != nil {
+				cal = nil // 模板已删除等情况按自然时间计算
+			}
+			calendars[key] = cal
+		}
+
+		total += cal.Between(start, record.CreatedAt)
+		count++
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+	return total.Seconds() / float64(count), nil
+}
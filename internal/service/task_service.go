@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/mautops/approval-gin/internal/auth"
+	"github.com/mautops/approval-gin/internal/integration"
 	"github.com/mautops/approval-gin/internal/metrics"
 	"github.com/mautops/approval-gin/internal/model"
 	"github.com/mautops/approval-kit/pkg/task"
@@ -16,7 +18,7 @@ import (
 // TaskService 任务服务接口
 type TaskService interface {
 	Create(ctx context.Context, req *CreateTaskRequest) (*task.Task, error)
-	Get(id string) (*task.Task, error)
+	Get(id string) (*TaskDetail, error)
 	Submit(ctx context.Context, id string) error
 	Approve(ctx context.Context, id string, req *ApproveRequest) error
 	Reject(ctx context.Context, id string, req *RejectRequest) error
@@ -31,10 +33,44 @@ type TaskService interface {
 	RollbackToNode(ctx context.Context, id string, req *RollbackRequest) error
 	ReplaceApprover(ctx context.Context, id string, req *ReplaceApproverRequest) error
 	HandleTimeout(ctx context.Context, id string) error
+	SnoozeReminder(ctx context.Context, id string, req *SnoozeReminderRequest) (*SnoozeReminderResponse, error)
+	// 退回修改
+	ReturnForRevision(ctx context.Context, id string, req *ReturnRequest) error
+	Resubmit(ctx context.Context, id string, req *ResubmitRequest) error
 	Delete(ctx context.Context, id string) error
 	// 批量操作方法
 	BatchApprove(ctx context.Context, req *BatchApproveRequest) ([]BatchOperationResult, error)
 	BatchTransfer(ctx context.Context, req *BatchTransferRequest) ([]BatchOperationResult, error)
+	// 版本迁移
+	MigrateTasks(ctx context.Context, req *MigrateTasksRequest) (*MigrateTasksResponse, error)
+}
+
+// TaskDetail 任务详情
+// @Description 任务详情,在任务数据的基础上附加流程引擎的活动节点集合
+type TaskDetail struct {
+	*task.Task  `swaggerignore:"true"` // 任务数据(id、template_id、state、approvers、records 等字段平铺在详情中)
+	Revision    int64                  `json:"revision"`     // 修订号(乐观锁),与响应头 ETag 一致
+	ActiveNodes []string               `json:"active_nodes"` // 当前活动节点集合(并行分支时包含多个节点)
+	// ApproverSources 审批人的解析来源(节点 ID -> 审批人 ID -> 来源)
+	ApproverSources map[string]map[string]*integration.ApproverProvenance `json:"approver_sources,omitempty"`
+	// Reminders 审批人的提醒状态(节点 ID -> 审批人 ID -> 提醒状态)
+	Reminders map[string]map[string]*integration.ReminderState `json:"reminders,omitempty"`
+	// Round 当前审批轮次,每次退回修改后重新提交加 1
+	Round int `json:"round"`
+	// Returned 任务被退回修改的信息,只在等待发起人重新提交时存在
+	Returned *integration.ReturnInfo `json:"returned,omitempty"`
+	// ServiceCalls 活动服务节点的调用状态(节点 ID -> 调用状态)
+	ServiceCalls map[string]*integration.ServiceCallState `json:"service_calls,omitempty"`
+	// Timers 活动定时节点的触发时间(节点 ID -> 计时状态)
+	Timers map[string]*integration.TimerState `json:"timers,omitempty"`
+	// CC 任务的抄送人(创建时指定的抄送人和通知节点的通知对象)
+	CC []string `json:"cc"`
+	// ParentTaskID 父任务 ID,只有子流程节点创建的子任务存在
+	ParentTaskID string `json:"parent_task_id,omitempty"`
+	// ParentNodeID 父任务中创建该任务的子流程节点 ID
+	ParentNodeID string `json:"parent_node_id,omitempty"`
+	// Children 子流程节点创建的子任务
+	Children []*integration.SubprocessChild `json:"children,omitempty"`
 }
 
 // CreateTaskRequest 创建任务请求
@@ -43,6 +79,7 @@ type CreateTaskRequest struct {
 	TemplateID string `json:"template_id" example:"tpl-001" binding:"required"` // 模板 ID
 	BusinessID string `json:"business_id" example:"biz-001" binding:"required"` // 业务 ID
 	Params     json.RawMessage `json:"params" swaggertype:"object" example:"{\"amount\":1000}"` // 任务参数(JSON 格式)
+	CC         []string `json:"cc,omitempty" example:"user-005"` // 抄送人 ID 列表,抄送人可以查看任务但不参与审批
 }
 
 // ApproveRequest 审批同意请求
@@ -59,6 +96,7 @@ type RejectRequest struct {
 	NodeID      string `json:"node_id" example:"node-001" binding:"required"` // 节点 ID
 	Comment     string `json:"comment" example:"拒绝"` // 审批意见
 	Attachments []string `json:"attachments" example:"[\"file1.pdf\",\"file2.pdf\"]"` // 附件列表
+	TargetNodeID string `json:"target_node_id" example:"node-000"` // 驳回目标节点(节点驳回动作为 choice 时必填,从已完成的审批节点中选择)
 }
 
 // TransferRequest 转交请求
@@ -101,6 +139,41 @@ type ReplaceApproverRequest struct {
 	Reason      string `json:"reason" example:"替换原因"` // 替换原因
 }
 
+// SnoozeReminderRequest 推迟提醒请求
+// @Description 推迟当前用户在节点上的审批提醒,duration 和 until 二选一
+type SnoozeReminderRequest struct {
+	NodeID   string     `json:"node_id" example:"node-001" binding:"required"` // 节点 ID
+	Duration string     `json:"duration" example:"4h"`                         // 推迟时长(Go duration 格式)
+	Until    *time.Time `json:"until" example:"2025-01-02T09:00:00Z"`          // 推迟到指定时间
+}
+
+// SnoozeReminderResponse 推迟提醒结果
+// @Description 推迟提醒的结果
+type SnoozeReminderResponse struct {
+	NodeID       string    `json:"node_id"`       // 节点 ID
+	SnoozedUntil time.Time `json:"snoozed_until"` // 该时间之前不再提醒
+}
+
+// ReturnRequest 退回修改请求
+// @Description 将任务退回发起人修改的请求参数
+type ReturnRequest struct {
+	NodeID  string `json:"node_id" example:"node-001" binding:"required"` // 节点 ID
+	Comment string `json:"comment" example:"请补充预算明细"`                       // 退回意见
+}
+
+// ResubmitRequest 重新提交请求
+// @Description 发起人修改后重新提交被退回任务的请求参数
+type ResubmitRequest struct {
+	Params  json.RawMessage `json:"params" swaggertype:"object" example:"{\"amount\":800}"` // 修改后的任务参数(JSON 格式),为空时沿用原参数
+	Comment string          `json:"comment" example:"已补充预算明细"`                               // 修改说明
+}
+
+// ErrNotTaskInitiator 当前用户不是任务发起人
+var ErrNotTaskInitiator = errors.New("only the task initiator can perform this operation")
+
+// ErrMigrationForbidden 无权迁移模板的任务
+var ErrMigrationForbidden = errors.New("not allowed to migrate tasks of the template")
+
 // BatchApproveRequest 批量审批请求
 // @Description 批量审批的请求参数
 type BatchApproveRequest struct {
@@ -127,15 +200,39 @@ type BatchOperationResult struct {
 	Error   string `json:"error,omitempty"` // 错误信息(如果失败)
 }
 
+// MigrateTasksRequest 任务版本迁移请求
+// @Description 将运行中的任务迁移到模板指定版本的请求参数
+type MigrateTasksRequest struct {
+	TemplateID    string            `json:"template_id" example:"tpl-001" binding:"required"` // 模板 ID
+	TargetVersion int               `json:"target_version" example:"3" binding:"required"`    // 目标版本
+	FromVersion   int               `json:"from_version" example:"2"`                         // 只迁移该版本上的任务(可选)
+	TaskIDs       []string          `json:"task_ids"`                                         // 只迁移指定任务(可选)
+	NodeMapping   map[string]string `json:"node_mapping"`                                     // 节点映射(原节点 ID -> 新节点 ID),未映射的节点按同名节点处理
+	DryRun        bool              `json:"dry_run"`                                          // 只生成报告,不修改任务
+}
+
+// MigrateTasksResponse 任务版本迁移报告
+// @Description 任务版本迁移的报告
+type MigrateTasksResponse struct {
+	DryRun        bool                               `json:"dry_run"`        // 是否为 dry-run
+	TargetVersion int                                `json:"target_version"` // 目标版本
+	Total         int                                `json:"total"`          // 检查的任务数
+	Migratable    int                                `json:"migratable"`     // 可以安全迁移的任务数
+	Migrated      int                                `json:"migrated"`       // 已迁移的任务数
+	Tasks         []*integration.TaskMigrationResult `json:"tasks"`          // 每个任务的迁移结果
+}
+
 type taskService struct {
 	taskMgr    task.TaskManager
 	db         *gorm.DB
 	fgaClient  *auth.OpenFGAClient
 	auditLogSvc AuditLogService
+	adminRole   string
 }
 
 // NewTaskService 创建任务服务
-func NewTaskService(taskMgr task.TaskManager, db *gorm.DB, auditLogSvc AuditLogService, fgaClient ...*auth.OpenFGAClient) TaskService {
+// adminRole 为可以迁移所有模板任务的角色
+func NewTaskService(taskMgr task.TaskManager, db *gorm.DB, auditLogSvc AuditLogService, adminRole string, fgaClient ...*auth.OpenFGAClient) TaskService {
 	var fga *auth.OpenFGAClient
 	if len(fgaClient) > 0 && fgaClient[0] != nil {
 		fga = fgaClient[0]
@@ -145,13 +242,22 @@ func NewTaskService(taskMgr task.TaskManager, db *gorm.DB, auditLogSvc AuditLogS
 		db:          db,
 		fgaClient:   fga,
 		auditLogSvc: auditLogSvc,
+		adminRole:   adminRole,
 	}
 }
 
 // Create 创建任务
 func (s *taskService) Create(ctx context.Context, req *CreateTaskRequest) (*task.Task, error) {
-	// 调用 TaskManager 创建任务
-	task, err := s.taskMgr.Create(req.TemplateID, req.BusinessID, req.Params)
+	// 发起人和抄送列表与任务在同一事务中保存
+	var tsk *task.Task
+	var err error
+	if mgr, ok := s.taskManager(ctx).(*integration.DBTaskManager); ok {
+		tsk, err = mgr.CreateWithCC(req.TemplateID, req.BusinessID, req.Params, getUserIDFromContext(ctx), req.CC)
+	} else if len(req.CC) > 0 {
+		return nil, fmt.Errorf("cc is not supported by the task manager")
+	} else {
+		tsk, err = s.taskMgr.Create(req.TemplateID, req.BusinessID, req.Params)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
@@ -163,22 +269,98 @@ func (s *taskService) Create(ctx context.Context, req *CreateTaskRequest) (*task
 	if s.auditLogSvc != nil {
 		userID := getUserIDFromContext(ctx)
 		if userID != "" {
-			details := fmt.Sprintf(`{"task_id":"%s","template_id":"%s","business_id":"%s"}`, task.ID, task.TemplateID, task.BusinessID)
-			_ = s.auditLogSvc.RecordAction(ctx, userID, "create", "task", task.ID, details)
+			details := fmt.Sprintf(`{"task_id":"%s","template_id":"%s","business_id":"%s"}`, tsk.ID, tsk.TemplateID, tsk.BusinessID)
+			_ = s.auditLogSvc.RecordAction(ctx, userID, "create", "task", tsk.ID, details)
 		}
 	}
 
-	return task, nil
+	return tsk, nil
 }
 
 // Get 获取任务详情
-func (s *taskService) Get(id string) (*task.Task, error) {
-	return s.taskMgr.Get(id)
+func (s *taskService) Get(id string) (*TaskDetail, error) {
+	dbMgr, ok := s.taskMgr.(*integration.DBTaskManager)
+	if !ok {
+		tsk, err := s.taskMgr.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		detail := &TaskDetail{Task: tsk, ActiveNodes: []string{}}
+		if tsk.CurrentNode != "" {
+			detail.ActiveNodes = []string{tsk.CurrentNode}
+		}
+		return detail, nil
+	}
+
+	// 任务详情的各部分在同一个只读快照中读取,保证与返回的修订号(ETag)一致
+	detail := &TaskDetail{}
+	err := dbMgr.Snapshot(func(snap *integration.DBTaskManager) error {
+		var err error
+		if detail.Task, detail.Revision, err = snap.GetWithRevision(id); err != nil {
+			return err
+		}
+		if detail.ActiveNodes, err = snap.ActiveNodes(id); err != nil {
+			return fmt.Errorf("failed to get active nodes: %w", err)
+		}
+		if detail.ApproverSources, err = snap.ApproverProvenance(id); err != nil {
+			return fmt.Errorf("failed to get approver sources: %w", err)
+		}
+		if detail.Reminders, err = snap.Reminders(id); err != nil {
+			return fmt.Errorf("failed to get reminders: %w", err)
+		}
+		if detail.Round, err = snap.CurrentRound(id); err != nil {
+			return fmt.Errorf("failed to get approval round: %w", err)
+		}
+		if detail.Returned, err = snap.Returned(id); err != nil {
+			return fmt.Errorf("failed to get return info: %w", err)
+		}
+		if detail.ServiceCalls, err = snap.ServiceCalls(id); err != nil {
+			return fmt.Errorf("failed to get service calls: %w", err)
+		}
+		if detail.Timers, err = snap.Timers(id); err != nil {
+			return fmt.Errorf("failed to get timers: %w", err)
+		}
+		if detail.CC, err = snap.CCUsers(id); err != nil {
+			return err
+		}
+		if detail.ParentTaskID, detail.ParentNodeID, err = snap.ParentTask(id); err != nil {
+			return fmt.Errorf("failed to get parent task: %w", err)
+		}
+		detail.Children, err = snap.Children(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// expectedRevisionKey 期望修订号在 context 中的键
+type expectedRevisionKey struct{}
+
+// WithExpectedRevision 在 context 中设置任务的期望修订号(来自 If-Match 请求头)
+// 任务变更操作只在任务当前修订号与之相同时执行
+func WithExpectedRevision(ctx context.Context, revision int64) context.Context {
+	return context.WithValue(ctx, expectedRevisionKey{}, revision)
+}
+
+// taskManager 获取执行任务变更的任务管理器
+// 返回的管理器副本以当前用户作为事件的操作人;context 中带有期望修订号时还会校验修订号
+func (s *taskService) taskManager(ctx context.Context) task.TaskManager {
+	dbMgr, ok := s.taskMgr.(*integration.DBTaskManager)
+	if !ok {
+		return s.taskMgr
+	}
+	mgr := dbMgr.WithOperator(getUserIDFromContext(ctx))
+	if revision, ok := ctx.Value(expectedRevisionKey{}).(int64); ok {
+		mgr = mgr.WithExpectedRevision(revision)
+	}
+	return mgr
 }
 
 // Submit 提交任务
 func (s *taskService) Submit(ctx context.Context, id string) error {
-	if err := s.taskMgr.Submit(id); err != nil {
+	if err := s.taskManager(ctx).Submit(id); err != nil {
 		return err
 	}
 
@@ -198,11 +380,11 @@ func (s *taskService) Submit(ctx context.Context, id string) error {
 func (s *taskService) Approve(ctx context.Context, id string, req *ApproveRequest) error {
 	// 根据是否有附件选择不同的方法
 	if len(req.Attachments) > 0 {
-		if err := s.taskMgr.ApproveWithAttachments(id, req.NodeID, getUserIDFromContext(ctx), req.Comment, req.Attachments); err != nil {
+		if err := s.taskManager(ctx).ApproveWithAttachments(id, req.NodeID, getUserIDFromContext(ctx), req.Comment, req.Attachments); err != nil {
 			return err
 		}
 	} else {
-		if err := s.taskMgr.Approve(id, req.NodeID, getUserIDFromContext(ctx), req.Comment); err != nil {
+		if err := s.taskManager(ctx).Approve(id, req.NodeID, getUserIDFromContext(ctx), req.Comment); err != nil {
 			return err
 		}
 	}
@@ -221,13 +403,21 @@ func (s *taskService) Approve(ctx context.Context, id string, req *ApproveReques
 
 // Reject 审批拒绝
 func (s *taskService) Reject(ctx context.Context, id string, req *RejectRequest) error {
-	// 根据是否有附件选择不同的方法
-	if len(req.Attachments) > 0 {
-		if err := s.taskMgr.RejectWithAttachments(id, req.NodeID, getUserIDFromContext(ctx), req.Comment, req.Attachments); err != nil {
+	// 选择了驳回目标时驳回到该节点,否则根据是否有附件选择不同的方法
+	if req.TargetNodeID != "" {
+		mgr, ok := s.taskManager(ctx).(*integration.DBTaskManager)
+		if !ok {
+			return fmt.Errorf("reject target is not supported by the task manager")
+		}
+		if err := mgr.RejectToNode(id, req.NodeID, getUserIDFromContext(ctx), req.Comment, req.TargetNodeID, req.Attachments); err != nil {
+			return err
+		}
+	} else if len(req.Attachments) > 0 {
+		if err := s.taskManager(ctx).RejectWithAttachments(id, req.NodeID, getUserIDFromContext(ctx), req.Comment, req.Attachments); err != nil {
 			return err
 		}
 	} else {
-		if err := s.taskMgr.Reject(id, req.NodeID, getUserIDFromContext(ctx), req.Comment); err != nil {
+		if err := s.taskManager(ctx).Reject(id, req.NodeID, getUserIDFromContext(ctx), req.Comment); err != nil {
 			return err
 		}
 	}
@@ -236,7 +426,7 @@ func (s *taskService) Reject(ctx context.Context, id string, req *RejectRequest)
 	if s.auditLogSvc != nil {
 		userID := getUserIDFromContext(ctx)
 		if userID != "" {
-			details := fmt.Sprintf(`{"task_id":"%s","node_id":"%s","comment":"%s"}`, id, req.NodeID, req.Comment)
+			details := fmt.Sprintf(`{"task_id":"%s","node_id":"%s","target_node_id":"%s","comment":"%s"}`, id, req.NodeID, req.TargetNodeID, req.Comment)
 			_ = s.auditLogSvc.RecordAction(ctx, userID, "reject", "task", id, details)
 		}
 	}
@@ -246,7 +436,7 @@ func (s *taskService) Reject(ctx context.Context, id string, req *RejectRequest)
 
 // Cancel 取消任务
 func (s *taskService) Cancel(ctx context.Context, id string, reason string) error {
-	if err := s.taskMgr.Cancel(id, reason); err != nil {
+	if err := s.taskManager(ctx).Cancel(id, reason); err != nil {
 		return err
 	}
 
@@ -264,7 +454,7 @@ func (s *taskService) Cancel(ctx context.Context, id string, reason string) erro
 
 // Withdraw 撤回任务
 func (s *taskService) Withdraw(ctx context.Context, id string, reason string) error {
-	if err := s.taskMgr.Withdraw(id, reason); err != nil {
+	if err := s.taskManager(ctx).Withdraw(id, reason); err != nil {
 		return err
 	}
 
@@ -283,7 +473,7 @@ func (s *taskService) Withdraw(ctx context.Context, id string, reason string) er
 // Transfer 转交审批
 func (s *taskService) Transfer(ctx context.Context, id string, req *TransferRequest) error {
 	userID := getUserIDFromContext(ctx)
-	if err := s.taskMgr.Transfer(id, req.NodeID, userID, req.ToApprover, req.Reason); err != nil {
+	if err := s.taskManager(ctx).Transfer(id, req.NodeID, userID, req.ToApprover, req.Reason); err != nil {
 		return err
 	}
 
@@ -300,7 +490,7 @@ func (s *taskService) Transfer(ctx context.Context, id string, req *TransferRequ
 
 // AddApprover 加签
 func (s *taskService) AddApprover(ctx context.Context, id string, req *AddApproverRequest) error {
-	if err := s.taskMgr.AddApprover(id, req.NodeID, req.Approver, req.Reason); err != nil {
+	if err := s.taskManager(ctx).AddApprover(id, req.NodeID, req.Approver, req.Reason); err != nil {
 		return err
 	}
 
@@ -318,7 +508,7 @@ func (s *taskService) AddApprover(ctx context.Context, id string, req *AddApprov
 
 // RemoveApprover 减签
 func (s *taskService) RemoveApprover(ctx context.Context, id string, req *RemoveApproverRequest) error {
-	if err := s.taskMgr.RemoveApprover(id, req.NodeID, req.Approver, req.Reason); err != nil {
+	if err := s.taskManager(ctx).RemoveApprover(id, req.NodeID, req.Approver, req.Reason); err != nil {
 		return err
 	}
 
@@ -336,7 +526,7 @@ func (s *taskService) RemoveApprover(ctx context.Context, id string, req *Remove
 
 // Pause 暂停任务
 func (s *taskService) Pause(ctx context.Context, id string, reason string) error {
-	if err := s.taskMgr.Pause(id, reason); err != nil {
+	if err := s.taskManager(ctx).Pause(id, reason); err != nil {
 		return err
 	}
 
@@ -354,7 +544,7 @@ func (s *taskService) Pause(ctx context.Context, id string, reason string) error
 
 // Resume 恢复任务
 func (s *taskService) Resume(ctx context.Context, id string, reason string) error {
-	if err := s.taskMgr.Resume(id, reason); err != nil {
+	if err := s.taskManager(ctx).Resume(id, reason); err != nil {
 		return err
 	}
 
@@ -372,7 +562,7 @@ func (s *taskService) Resume(ctx context.Context, id string, reason string) erro
 
 // RollbackToNode 回退到指定节点
 func (s *taskService) RollbackToNode(ctx context.Context, id string, req *RollbackRequest) error {
-	if err := s.taskMgr.RollbackToNode(id, req.NodeID, req.Reason); err != nil {
+	if err := s.taskManager(ctx).RollbackToNode(id, req.NodeID, req.Reason); err != nil {
 		return err
 	}
 
@@ -390,7 +580,7 @@ func (s *taskService) RollbackToNode(ctx context.Context, id string, req *Rollba
 
 // ReplaceApprover 替换审批人
 func (s *taskService) ReplaceApprover(ctx context.Context, id string, req *ReplaceApproverRequest) error {
-	if err := s.taskMgr.ReplaceApprover(id, req.NodeID, req.OldApprover, req.NewApprover, req.Reason); err != nil {
+	if err := s.taskManager(ctx).ReplaceApprover(id, req.NodeID, req.OldApprover, req.NewApprover, req.Reason); err != nil {
 		return err
 	}
 
@@ -408,7 +598,7 @@ func (s *taskService) ReplaceApprover(ctx context.Context, id string, req *Repla
 
 // HandleTimeout 处理任务超时
 func (s *taskService) HandleTimeout(ctx context.Context, id string) error {
-	if err := s.taskMgr.HandleTimeout(id); err != nil {
+	if err := s.taskManager(ctx).HandleTimeout(id); err != nil {
 		return err
 	}
 	if s.auditLogSvc != nil {
@@ -421,6 +611,103 @@ func (s *taskService) HandleTimeout(ctx context.Context, id string) error {
 	return nil
 }
 
+// SnoozeReminder 推迟当前用户在节点上的审批提醒
+// 只有节点上尚未审批的审批人可以推迟自己的提醒
+func (s *taskService) SnoozeReminder(ctx context.Context, id string, req *SnoozeReminderRequest) (*SnoozeReminderResponse, error) {
+	mgr, ok := s.taskManager(ctx).(*integration.DBTaskManager)
+	if !ok {
+		return nil, fmt.Errorf("reminders are not supported by the task manager")
+	}
+
+	var until time.Time
+	switch {
+	case req.Until != nil && req.Duration != "":
+		return nil, fmt.Errorf("%w: only one of duration and until may be set", integration.ErrInvalidSnooze)
+	case req.Until != nil:
+		until = *req.Until
+	case req.Duration != "":
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid duration %q", integration.ErrInvalidSnooze, req.Duration)
+		}
+		until = time.Now().Add(duration)
+	default:
+		return nil, fmt.Errorf("%w: duration or until is required", integration.ErrInvalidSnooze)
+	}
+
+	userID := getUserIDFromContext(ctx)
+	if err := mgr.SnoozeReminder(id, req.NodeID, userID, until); err != nil {
+		return nil, err
+	}
+
+	// 记录审计日志
+	if s.auditLogSvc != nil && userID != "" {
+		details := fmt.Sprintf(`{"task_id":"%s","node_id":"%s","snoozed_until":"%s"}`, id, req.NodeID, until.Format(time.RFC3339))
+		_ = s.auditLogSvc.RecordAction(ctx, userID, "snooze_reminder", "task", id, details)
+	}
+
+	return &SnoozeReminderResponse{NodeID: req.NodeID, SnoozedUntil: until}, nil
+}
+
+// ReturnForRevision 将任务退回发起人修改
+// 只有活动节点上轮到审批的审批人可以退回,本轮审批数据归档为历史轮次
+func (s *taskService) ReturnForRevision(ctx context.Context, id string, req *ReturnRequest) error {
+	mgr, ok := s.taskManager(ctx).(*integration.DBTaskManager)
+	if !ok {
+		return fmt.Errorf("return for revision is not supported by the task manager")
+	}
+
+	userID := getUserIDFromContext(ctx)
+	if err := mgr.ReturnForRevision(id, req.NodeID, userID, req.Comment); err != nil {
+		return err
+	}
+
+	// 记录审计日志
+	if s.auditLogSvc != nil && userID != "" {
+		details := fmt.Sprintf(`{"task_id":"%s","node_id":"%s","comment":"%s"}`, id, req.NodeID, req.Comment)
+		_ = s.auditLogSvc.RecordAction(ctx, userID, "return", "task", id, details)
+	}
+
+	return nil
+}
+
+// Resubmit 发起人修改参数后重新提交被退回的任务
+// 只有任务记录的发起人可以重新提交
+func (s *taskService) Resubmit(ctx context.Context, id string, req *ResubmitRequest) error {
+	mgr, ok := s.taskManager(ctx).(*integration.DBTaskManager)
+	if !ok {
+		return fmt.Errorf("resubmit is not supported by the task manager")
+	}
+
+	var taskModel model.TaskModel
+	if err := s.db.Select("id", "created_by").Where("id = ?", id).First(&taskModel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("task not found")
+		}
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	// 没有记录发起人的任务无法确认发起人,不允许重新提交
+	userID := getUserIDFromContext(ctx)
+	if taskModel.CreatedBy == "" {
+		return fmt.Errorf("%w: task %q has no recorded initiator", ErrNotTaskInitiator, id)
+	}
+	if taskModel.CreatedBy != userID {
+		return fmt.Errorf("%w: task %q was created by %q", ErrNotTaskInitiator, id, taskModel.CreatedBy)
+	}
+
+	if err := mgr.Resubmit(id, req.Params, req.Comment); err != nil {
+		return err
+	}
+
+	// 记录审计日志
+	if s.auditLogSvc != nil && userID != "" {
+		details := fmt.Sprintf(`{"task_id":"%s","comment":"%s"}`, id, req.Comment)
+		_ = s.auditLogSvc.RecordAction(ctx, userID, "resubmit", "task", id, details)
+	}
+
+	return nil
+}
+
 // Delete 删除任务
 // 只允许删除特定状态的任务(pending、cancelled),且不能有审批记录
 func (s *taskService) Delete(ctx context.Context, id string) error {
@@ -543,7 +830,7 @@ func (s *taskService) BatchTransfer(ctx context.Context, req *BatchTransferReque
 			fromApprover = req.OldApprover
 		}
 
-		err := s.taskMgr.Transfer(taskID, req.NodeID, fromApprover, req.NewApprover, req.Comment)
+		err := s.taskManager(ctx).Transfer(taskID, req.NodeID, fromApprover, req.NewApprover, req.Comment)
 		result := BatchOperationResult{
 			TaskID:  taskID,
 			Success: err == nil,
@@ -556,3 +843,98 @@ func (s *taskService) BatchTransfer(ctx context.Context, req *BatchTransferReque
 
 	return results, nil
 }
+
+// MigrateTasks 将运行中的任务迁移到模板的指定版本
+// 每个迁移成功的任务记录审计日志;dry-run 时只返回报告
+func (s *taskService) MigrateTasks(ctx context.Context, req *MigrateTasksRequest) (*MigrateTasksResponse, error) {
+	// 迁移事件记录当前用户;批量迁移多个任务,不使用 If-Match 修订号
+	dbMgr, ok := s.taskMgr.(*integration.DBTaskManager)
+	if !ok {
+		return nil, fmt.Errorf("task migration is not supported by the task manager")
+	}
+	dbMgr = dbMgr.WithOperator(getUserIDFromContext(ctx))
+
+	// 1. 校验迁移权限、目标版本和节点映射
+	allowed, err := s.canMigrate(ctx, req.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s", ErrMigrationForbidden, req.TemplateID)
+	}
+	if err := dbMgr.CheckMigrationTarget(req.TemplateID, req.TargetVersion, req.NodeMapping); err != nil {
+		return nil, err
+	}
+
+	// 2. 查询需要检查的运行中任务
+	query := s.db.Model(&model.TaskModel{}).
+		Where("template_id = ? AND template_version <> ?", req.TemplateID, req.TargetVersion).
+		Where("state IN ?", integration.RunningTaskStates)
+	if req.FromVersion > 0 {
+		query = query.Where("template_version = ?", req.FromVersion)
+	}
+	if len(req.TaskIDs) > 0 {
+		query = query.Where("id IN ?", req.TaskIDs)
+	}
+	var taskIDs []string
+	if err := query.Order("created_at ASC").Pluck("id", &taskIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+
+	// 3. 逐个检查并迁移
+	resp := &MigrateTasksResponse{
+		DryRun:        req.DryRun,
+		TargetVersion: req.TargetVersion,
+		Tasks:         make([]*integration.TaskMigrationResult, 0, len(taskIDs)),
+	}
+	userID := getUserIDFromContext(ctx)
+	for _, taskID := range taskIDs {
+		result, err := dbMgr.MigrateToVersion(taskID, req.TargetVersion, req.NodeMapping, req.DryRun)
+		if err != nil {
+			result = &integration.TaskMigrationResult{
+				TaskID:    taskID,
+				ToVersion: req.TargetVersion,
+				Reasons:   []string{err.Error()},
+			}
+		}
+		resp.Tasks = append(resp.Tasks, result)
+		if result.CanMigrate {
+			resp.Migratable++
+		}
+		if result.Migrated {
+			resp.Migrated++
+			if s.auditLogSvc != nil && userID != "" {
+				mappingJSON, _ := json.Marshal(result.NodeMapping)
+				details := fmt.Sprintf(`{"task_id":"%s","template_id":"%s","from_version":%d,"to_version":%d,"node_mapping":%s}`,
+					taskID, req.TemplateID, result.FromVersion, result.ToVersion, mappingJSON)
+				_ = s.auditLogSvc.RecordAction(ctx, userID, "migrate", "task", taskID, details)
+			}
+		}
+	}
+	resp.Total = len(resp.Tasks)
+
+	return resp, nil
+}
+
+// canMigrate 判断当前用户能否迁移模板的任务: 管理员或模板的 editor;没有认证信息时不校验
+func (s *taskService) canMigrate(ctx context.Context, templateID string) (bool, error) {
+	userID := getUserIDFromContext(ctx)
+	if userID == "" {
+		return true, nil
+	}
+	if s.adminRole != "" {
+		for _, role := range getRolesFromContext(ctx) {
+			if role == s.adminRole {
+				return true, nil
+			}
+		}
+	}
+	if s.fgaClient == nil {
+		return false, nil
+	}
+	allowed, err := s.fgaClient.CheckPermission(ctx, userID, "editor", "template", templateID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+	return allowed, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/database"
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-kit/pkg/template"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testAdminRole = "approval-admin"
+
+// 单节点审批模板 leave 的节点和连线,审批人为 ann
+var (
+	testNodes = json.RawMessage(`{"start":{"id":"start","type":"start"},
+		"review":{"id":"review","type":"approval","config":{"approver_sources":[{"type":"users","users":["ann"]}]}},
+		"end":{"id":"end","type":"end"}}`)
+	testEdges = json.RawMessage(`[{"from":"start","to":"review"},{"from":"review","to":"end"}]`)
+)
+
+// newTestTaskManager 创建使用内存数据库的模板管理器和任务管理器,并创建单节点审批模板 leave
+func newTestTaskManager(t *testing.T) (*gorm.DB, *integration.DBTemplateManager, *integration.DBTaskManager) {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database handle: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	templates := integration.NewTemplateManager(db).(*integration.DBTemplateManager)
+	tasks := integration.NewTaskManager(db, templates, nil, nil).(*integration.DBTaskManager)
+	if err := templates.CreateWithRawGraph(&template.Template{ID: "leave", Name: "leave", Version: 1}, testNodes, testEdges, nil); err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	return db, templates, tasks
+}
+
+// newMigrationTestService 创建带单节点审批模板(v1、v2)和一个 v1 任务的任务服务
+func newMigrationTestService(t *testing.T) (TaskService, string) {
+	t.Helper()
+	db, templates, tasks := newTestTaskManager(t)
+	tsk, err := tasks.Create("leave", "biz-1", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := tasks.Submit(tsk.ID); err != nil {
+		t.Fatalf("failed to submit task: %v", err)
+	}
+	if err := templates.UpdateWithRawGraph("leave", &template.Template{ID: "leave", Name: "leave"}, testNodes, testEdges, nil); err != nil {
+		t.Fatalf("failed to add template version: %v", err)
+	}
+	return NewTaskService(tasks, db, nil, testAdminRole), tsk.ID
+}
+
+func userContext(userID string, roles ...string) context.Context {
+	ctx := context.WithValue(context.Background(), "user_id", userID)
+	return context.WithValue(ctx, "roles", roles)
+}
+
+func TestMigrateTasksRequiresAdminRole(t *testing.T) {
+	svc, _ := newMigrationTestService(t)
+	req := &MigrateTasksRequest{TemplateID: "leave", TargetVersion: 2, DryRun: true}
+
+	if _, err := svc.MigrateTasks(userContext("bob", "staff"), req); !errors.Is(err, ErrMigrationForbidden) {
+		t.Fatalf("expected ErrMigrationForbidden, got %v", err)
+	}
+
+	resp, err := svc.MigrateTasks(userContext("root", testAdminRole), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Total != 1 || resp.Migratable != 1 || resp.Migrated != 0 {
+		t.Fatalf("unexpected dry-run report: %+v", resp)
+	}
+}
+
+func TestMigrateTasksValidatesTarget(t *testing.T) {
+	svc, taskID := newMigrationTestService(t)
+	ctx := userContext("root", testAdminRole)
+
+	_, err := svc.MigrateTasks(ctx, &MigrateTasksRequest{TemplateID: "leave", TargetVersion: 7})
+	if !errors.Is(err, integration.ErrTemplateVersionNotFound) {
+		t.Fatalf("expected ErrTemplateVersionNotFound, got %v", err)
+	}
+	_, err = svc.MigrateTasks(ctx, &MigrateTasksRequest{TemplateID: "leave", TargetVersion: 2, NodeMapping: map[string]string{"review": "nope"}})
+	if !errors.Is(err, integration.ErrInvalidNodeMapping) {
+		t.Fatalf("expected ErrInvalidNodeMapping, got %v", err)
+	}
+
+	// 批量迁移不使用 If-Match 修订号
+	resp, err := svc.MigrateTasks(WithExpectedRevision(ctx, 999), &MigrateTasksRequest{TemplateID: "leave", TargetVersion: 2, TaskIDs: []string{taskID}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Migrated != 1 || !resp.Tasks[0].Migrated {
+		t.Fatalf("expected the task to be migrated: %+v", resp.Tasks[0])
+	}
+}
+
+func TestResubmitRequiresRecordedInitiator(t *testing.T) {
+	db, _, tasks := newTestTaskManager(t)
+	svc := NewTaskService(tasks, db, nil, testAdminRole)
+	// returnedTask 以 initiator 为发起人(为空时不记录)创建任务,提交后由 ann 退回
+	returnedTask := func(initiator string) string {
+		tsk, err := tasks.CreateWithCC("leave", "biz-1", json.RawMessage(`{}`), initiator, nil)
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if err := tasks.Submit(tsk.ID); err != nil {
+			t.Fatalf("failed to submit task: %v", err)
+		}
+		if err := tasks.ReturnForRevision(tsk.ID, "review", "ann", "fix"); err != nil {
+			t.Fatalf("failed to return task: %v", err)
+		}
+		return tsk.ID
+	}
+
+	// 没有记录发起人的任务任何人都不能重新提交
+	unknown := returnedTask("")
+	for _, ctx := range []context.Context{userContext("bob"), context.Background()} {
+		if err := svc.Resubmit(ctx, unknown, &ResubmitRequest{}); !errors.Is(err, ErrNotTaskInitiator) {
+			t.Fatalf("expected ErrNotTaskInitiator, got %v", err)
+		}
+	}
+
+	owned := returnedTask("ini")
+	if err := svc.Resubmit(userContext("bob"), owned, &ResubmitRequest{}); !errors.Is(err, ErrNotTaskInitiator) {
+		t.Fatalf("expected ErrNotTaskInitiator, got %v", err)
+	}
+	if err := svc.Resubmit(userContext("ini"), owned, &ResubmitRequest{Comment: "fixed"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSnoozeReminderAppliesToCurrentUser(t *testing.T) {
+	db, templates, tasks := newTestTaskManager(t)
+	svc := NewTaskService(tasks, db, nil, testAdminRole)
+	nodes := json.RawMessage(`{"start":{"id":"start","type":"start"},
+		"review":{"id":"review","type":"approval","config":{"approver_sources":[{"type":"users","users":["ann"]}],"reminder_policy":{"first_after":"1h"}}},
+		"end":{"id":"end","type":"end"}}`)
+	if err := templates.CreateWithRawGraph(&template.Template{ID: "remind", Name: "remind", Version: 1}, nodes, testEdges, nil); err != nil {
+		t.Fatalf("failed to create template: %v", err)
+	}
+	tsk, err := tasks.Create("remind", "biz-1", json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+	if err := tasks.Submit(tsk.ID); err != nil {
+		t.Fatalf("failed to submit task: %v", err)
+	}
+
+	// 只能推迟自己的提醒
+	if _, err := svc.SnoozeReminder(userContext("bob"), tsk.ID, &SnoozeReminderRequest{NodeID: "review", Duration: "2h"}); !errors.Is(err, integration.ErrNotPendingApprover) {
+		t.Fatalf("expected ErrNotPendingApprover, got %v", err)
+	}
+	until := time.Now().Add(time.Hour)
+	if _, err := svc.SnoozeReminder(userContext("ann"), tsk.ID, &SnoozeReminderRequest{NodeID: "review", Duration: "2h", Until: &until}); !errors.Is(err, integration.ErrInvalidSnooze) {
+		t.Fatalf("expected ErrInvalidSnooze, got %v", err)
+	}
+
+	resp, err := svc.SnoozeReminder(userContext("ann"), tsk.ID, &SnoozeReminderRequest{NodeID: "review", Duration: "2h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reminders, err := tasks.Reminders(tsk.ID)
+	if err != nil {
+		t.Fatalf("failed to get reminders: %v", err)
+	}
+	if state := reminders["review"]["ann"]; state == nil || state.SnoozedUntil == nil || !state.SnoozedUntil.Equal(resp.SnoozedUntil) {
+		t.Fatalf("reminder state of ann = %+v, want snoozed until %v", state, resp.SnoozedUntil)
+	}
+}
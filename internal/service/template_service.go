@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/mautops/approval-gin/internal/auth"
 	"github.com/mautops/approval-gin/internal/integration"
 	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
 	"github.com/mautops/approval-gin/internal/utils"
 	"github.com/mautops/approval-kit/pkg/template"
 	"gorm.io/gorm"
@@ -25,22 +27,30 @@ type TemplateService interface {
 	List(filter *TemplateListFilter) (*TemplateListResponse, error)
 	ListVersions(id string) ([]int, error)
 	DeleteVersion(ctx context.Context, id string, version int) error
+	PreviewWebhook(id string, req *PreviewWebhookRequest) (*integration.RenderedWebhook, error)
 }
 
 type CreateTemplateRequest struct {
 	Name        string                   `json:"name" example:"请假审批" binding:"required"`
 	Description string                   `json:"description" example:"员工请假审批流程"`
 	Nodes       json.RawMessage          `json:"nodes" binding:"required"`
-	Edges       []*template.Edge         `json:"edges" binding:"required"`
-	Config      *template.TemplateConfig `json:"config"`
+	Edges       json.RawMessage          `json:"edges" binding:"required" swaggertype:"array,object"` // 连线列表,支持 condition/default/priority 条件分支字段
+	Config      json.RawMessage          `json:"config" swaggertype:"object"` // 模板配置,webhooks 支持 body_template/header_templates 请求模板,pre_action_hook 为前置钩子
 }
 
 type UpdateTemplateRequest struct {
 	Name        string                   `json:"name" example:"请假审批"`
 	Description string                   `json:"description" example:"员工请假审批流程"`
 	Nodes       json.RawMessage          `json:"nodes"`
-	Edges       []*template.Edge         `json:"edges"`
-	Config      *template.TemplateConfig `json:"config"`
+	Edges       json.RawMessage          `json:"edges" swaggertype:"array,object"` // 连线列表,支持 condition/default/priority 条件分支字段
+	Config      json.RawMessage          `json:"config" swaggertype:"object"` // 模板配置,webhooks 支持 body_template/header_templates 请求模板,pre_action_hook 为前置钩子
+}
+
+// PreviewWebhookRequest Webhook 请求模板预览请求
+// @Description 使用指定事件或示例数据渲染 Webhook 请求模板
+type PreviewWebhookRequest struct {
+	integration.WebhookPayloadTemplate
+	EventID string `json:"event_id"` // 用于渲染的事件 ID(须属于该模板的任务),为空时使用示例数据
 }
 
 // TemplateListFilter 模板列表查询过滤器
@@ -105,7 +115,7 @@ func generateTemplateID() string {
 
 // Create 创建模板
 func (s *templateService) Create(ctx context.Context, req *CreateTemplateRequest) (*template.Template, error) {
-	// 1. 解析节点数据,保留原始 JSON 以提取 position 信息
+	// 1. 解析节点和连线数据,保留原始 JSON 以提取 position 和分支条件信息
 	var nodes map[string]*template.Node
 	rawNodesJSON := req.Nodes
 	if len(req.Nodes) > 0 {
@@ -114,6 +124,27 @@ func (s *templateService) Create(ctx context.Context, req *CreateTemplateRequest
 			return nil, fmt.Errorf("failed to parse nodes: %w", err)
 		}
 	}
+	var edges []*template.Edge
+	rawEdgesJSON := req.Edges
+	if len(req.Edges) > 0 {
+		if err := json.Unmarshal(req.Edges, &edges); err != nil {
+			return nil, fmt.Errorf("failed to parse edges: %w", err)
+		}
+	}
+
+	// 校验分支条件表达式等流程配置
+	if err := integration.ValidateFlow(rawNodesJSON, rawEdgesJSON); err != nil {
+		return nil, fmt.Errorf("invalid template flow: %w", err)
+	}
+
+	// 解析模板配置并校验 Webhook 请求模板
+	config, err := parseTemplateConfig(req.Config)
+	if err != nil {
+		return nil, err
+	}
+	if err := integration.ValidateCalendarReferences(s.db, rawNodesJSON, req.Config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplateConfig, err)
+	}
 
 	// 2. 构建模板对象
 	tpl := &template.Template{
@@ -124,15 +155,15 @@ func (s *templateService) Create(ctx context.Context, req *CreateTemplateRequest
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 		Nodes:       nodes,
-		Edges:       req.Edges,
-		Config:      req.Config,
+		Edges:       edges,
+		Config:      config,
 	}
 
-	// 3. 调用 TemplateManager 创建,如果有原始节点 JSON 则使用支持 position 的方法
-	if len(rawNodesJSON) > 0 {
+	// 3. 调用 TemplateManager 创建,如果有原始 JSON 则使用保留原始数据的方法
+	if len(rawNodesJSON) > 0 || len(rawEdgesJSON) > 0 || len(req.Config) > 0 {
 		// 尝试类型断言为 DBTemplateManager
 		if dbMgr, ok := s.templateMgr.(*integration.DBTemplateManager); ok {
-			if err := dbMgr.CreateWithNodePositions(tpl, rawNodesJSON); err != nil {
+			if err := dbMgr.CreateWithRawGraph(tpl, rawNodesJSON, rawEdgesJSON, req.Config); err != nil {
 				return nil, fmt.Errorf("failed to create template: %w", err)
 			}
 		} else {
@@ -203,6 +234,15 @@ func (s *templateService) Update(ctx context.Context, id string, req *UpdateTemp
 	}
 
 	// 2. 解析节点数据,保留原始 JSON 以提取 position 信息
+	// 未提供时沿用当前版本的原始数据,避免丢失扩展字段
+	var currentRawNodes, currentRawEdges, currentRawConfig json.RawMessage
+	if dbMgr, ok := s.templateMgr.(*integration.DBTemplateManager); ok {
+		currentRawNodes, currentRawEdges, currentRawConfig, err = dbMgr.GetRawData(id, current.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current template graph: %w", err)
+		}
+	}
+
 	var nodes map[string]*template.Node
 	var rawNodesJSON json.RawMessage
 	if len(req.Nodes) > 0 {
@@ -213,12 +253,38 @@ func (s *templateService) Update(ctx context.Context, id string, req *UpdateTemp
 		}
 	} else {
 		nodes = current.Nodes
+		rawNodesJSON = currentRawNodes
 	}
 
 	// 3. 处理边数据
-	edges := req.Edges
-	if edges == nil {
+	var edges []*template.Edge
+	var rawEdgesJSON json.RawMessage
+	if len(req.Edges) > 0 {
+		rawEdgesJSON = req.Edges
+		if err := json.Unmarshal(req.Edges, &edges); err != nil {
+			return nil, fmt.Errorf("failed to parse edges: %w", err)
+		}
+	} else {
 		edges = current.Edges
+		rawEdgesJSON = currentRawEdges
+	}
+
+	// 校验分支条件表达式等流程配置
+	if err := integration.ValidateFlow(rawNodesJSON, rawEdgesJSON); err != nil {
+		return nil, fmt.Errorf("invalid template flow: %w", err)
+	}
+
+	// 未提供配置时沿用当前版本的配置(包括 Webhook 请求模板)
+	config := current.Config
+	rawConfigJSON := currentRawConfig
+	if len(req.Config) > 0 {
+		if config, err = parseTemplateConfig(req.Config); err != nil {
+			return nil, err
+		}
+		rawConfigJSON = req.Config
+	}
+	if err := integration.ValidateCalendarReferences(s.db, rawNodesJSON, rawConfigJSON); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplateConfig, err)
 	}
 
 	// 4. 构建更新后的模板对象
@@ -231,19 +297,14 @@ func (s *templateService) Update(ctx context.Context, id string, req *UpdateTemp
 		UpdatedAt:   time.Now(),
 		Nodes:       nodes,
 		Edges:       edges,
-		Config:      req.Config,
-	}
-
-	// 如果没有提供配置,使用当前模板的配置
-	if updated.Config == nil {
-		updated.Config = current.Config
+		Config:      config,
 	}
 
-	// 5. 调用 TemplateManager 更新,传递原始节点 JSON 以保留 position 信息
-	if len(rawNodesJSON) > 0 {
+	// 5. 调用 TemplateManager 更新,传递原始节点、连线和配置 JSON 以保留 position、分支条件和请求模板信息
+	if len(rawNodesJSON) > 0 || len(rawEdgesJSON) > 0 || len(rawConfigJSON) > 0 {
 		// 尝试类型断言为 DBTemplateManager
 		if dbMgr, ok := s.templateMgr.(*integration.DBTemplateManager); ok {
-			if err := dbMgr.UpdateWithNodePositions(id, updated, rawNodesJSON); err != nil {
+			if err := dbMgr.UpdateWithRawGraph(id, updated, rawNodesJSON, rawEdgesJSON, rawConfigJSON); err != nil {
 				return nil, fmt.Errorf("failed to update template: %w", err)
 			}
 		} else {
@@ -458,6 +519,18 @@ func (s *templateService) DeleteVersion(ctx context.Context, id string, version
 		return fmt.Errorf("template version not found")
 	}
 
+	// 运行中的任务固定在创建时的版本上执行,该版本不能删除
+	var runningCount int64
+	if err := s.db.Model(&model.TaskModel{}).
+		Where("template_id = ? AND template_version = ?", id, version).
+		Where("state IN ?", integration.RunningTaskStates).
+		Count(&runningCount).Error; err != nil {
+		return fmt.Errorf("failed to check running tasks: %w", err)
+	}
+	if runningCount > 0 {
+		return fmt.Errorf("无法删除模板版本: 该版本下还有 %d 个运行中的任务", runningCount)
+	}
+
 	// 检查是否还有其他版本
 	var totalCount int64
 	if err := s.db.Model(&model.TemplateModel{}).
@@ -489,6 +562,59 @@ func (s *templateService) DeleteVersion(ctx context.Context, id string, version
 	return nil
 }
 
+// ErrPreviewEventMismatch 预览使用的事件不属于该模板的任务
+var ErrPreviewEventMismatch = errors.New("event does not belong to a task of this template")
+
+// PreviewWebhook 预览 Webhook 请求模板的渲染结果
+// 指定事件时使用该事件、其任务和任务所用的模板版本渲染,否则使用模板最新版本和示例数据渲染
+func (s *templateService) PreviewWebhook(id string, req *PreviewWebhookRequest) (*integration.RenderedWebhook, error) {
+	tpl, err := s.templateMgr.Get(id, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventModel *model.EventModel
+	if req.EventID != "" {
+		eventModel, err = repository.NewEventRepository(s.db).FindByID(req.EventID)
+		if err != nil {
+			return nil, fmt.Errorf("event not found: %w", err)
+		}
+		taskModel, err := repository.NewTaskRepository(s.db).FindByID(eventModel.TaskID)
+		if err != nil {
+			return nil, fmt.Errorf("task not found: %w", err)
+		}
+		if taskModel.TemplateID != id {
+			return nil, ErrPreviewEventMismatch
+		}
+		if tpl, err = s.templateMgr.Get(id, taskModel.TemplateVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	return integration.PreviewWebhookTemplate(s.db, &req.WebhookPayloadTemplate, tpl.ID, tpl.Name, tpl.Version, eventModel)
+}
+
+// ErrInvalidTemplateConfig 模板配置无效
+var ErrInvalidTemplateConfig = errors.New("invalid template config")
+
+// parseTemplateConfig 解析模板原始配置并校验 Webhook 请求模板和前置钩子
+func parseTemplateConfig(rawConfig json.RawMessage) (*template.TemplateConfig, error) {
+	if len(rawConfig) == 0 {
+		return nil, nil
+	}
+	var config *template.TemplateConfig
+	if err := json.Unmarshal(rawConfig, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := integration.ValidateWebhookTemplates(rawConfig); err != nil {
+		return nil, fmt.Errorf("invalid template config: %w", err)
+	}
+	if err := integration.ValidatePreActionHook(rawConfig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplateConfig, err)
+	}
+	return config, nil
+}
+
 // getUserIDFromContext 从 context 中获取用户ID
 func getUserIDFromContext(ctx context.Context) string {
 	if ctx == nil {
@@ -501,6 +627,18 @@ func getUserIDFromContext(ctx context.Context) string {
 	return ""
 }
 
+// getRolesFromContext 从 context 中获取当前用户的角色
+func getRolesFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	// 由认证中间件设置
+	if roles, ok := ctx.Value("roles").([]string); ok {
+		return roles
+	}
+	return nil
+}
+
 // clearTemplateCache 清除模板缓存
 func (s *templateService) clearTemplateCache(id string, version int) {
 	if version > 0 {
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mautops/approval-gin/internal/integration"
+	"github.com/mautops/approval-gin/internal/model"
+	"github.com/mautops/approval-gin/internal/repository"
+	"github.com/mautops/approval-kit/pkg/event"
+	"gorm.io/gorm"
+)
+
+// WebhookService Webhook 订阅服务接口
+// 全局订阅独立于模板配置,匹配的事件在模板 Webhook 之外额外推送到订阅地址
+type WebhookService interface {
+	Create(ctx context.Context, req *CreateWebhookRequest) (*WebhookSubscription, error)
+	Get(id string) (*WebhookSubscription, error)
+	List() ([]*WebhookSubscription, error)
+	Update(ctx context.Context, id string, req *UpdateWebhookRequest) (*WebhookSubscription, error)
+	Delete(ctx context.Context, id string) error
+	Test(ctx context.Context, id string) (*integration.WebhookTestResult, error)
+}
+
+// WebhookAuth Webhook 认证配置
+// @Description Webhook 认证配置,hmac 类型对请求签名
+type WebhookAuth struct {
+	Type  string `json:"type" example:"hmac" enums:"bearer,basic,header,hmac"` // 认证类型
+	Key   string `json:"key,omitempty"`                                        // basic 用户名或 header 请求头名称
+	Token string `json:"token,omitempty"`                                      // 令牌、密码或 HMAC 签名密钥(响应中不返回)
+}
+
+// CreateWebhookRequest 创建 Webhook 订阅请求
+type CreateWebhookRequest struct {
+	Name          string            `json:"name" example:"数据平台" binding:"required"`
+	URL           string            `json:"url" example:"https://example.com/approval-events" binding:"required"`
+	Method        string            `json:"method" example:"POST"`                                                                                 // 请求方法,默认 POST
+	Headers       map[string]string `json:"headers"`                                                                                               // 自定义请求头
+	EventTypes    []string          `json:"event_types" example:"task_created,task_completed"`                                                     // 订阅的事件类型,为空时订阅所有事件
+	TemplateID    string            `json:"template_id"`                                                                                           // 只订阅该模板的事件
+	BusinessID    string            `json:"business_id"`                                                                                           // 只订阅该业务 ID 的事件
+	Auth          *WebhookAuth      `json:"auth"`                                                                                                  // 认证配置
+	PayloadFormat string            `json:"payload_format" example:"cloudevents_structured" enums:"raw,cloudevents_structured,cloudevents_binary"` // 请求体格式,默认 raw(事件 JSON)
+	Enabled       *bool             `json:"enabled"`                                                                                               // 是否启用,默认启用
+}
+
+// UpdateWebhookRequest 更新 Webhook 订阅请求(全量更新)
+// 认证类型不变时 token 留空表示沿用已保存的令牌或签名密钥
+type UpdateWebhookRequest struct {
+	Name          string            `json:"name" example:"数据平台" binding:"required"`
+	URL           string            `json:"url" example:"https://example.com/approval-events" binding:"required"`
+	Method        string            `json:"method" example:"POST"`
+	Headers       map[string]string `json:"headers"`
+	EventTypes    []string          `json:"event_types" example:"task_created,task_completed"`
+	TemplateID    string            `json:"template_id"`
+	BusinessID    string            `json:"business_id"`
+	Auth          *WebhookAuth      `json:"auth"`
+	PayloadFormat string            `json:"payload_format" example:"cloudevents_structured" enums:"raw,cloudevents_structured,cloudevents_binary"`
+	Enabled       *bool             `json:"enabled"`
+}
+
+// WebhookSubscription Webhook 订阅
+// @Description Webhook 订阅详情(不包含认证令牌和签名密钥)
+type WebhookSubscription struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	URL           string            `json:"url"`
+	Method        string            `json:"method"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	EventTypes    []string          `json:"event_types"`
+	TemplateID    string            `json:"template_id,omitempty"`
+	BusinessID    string            `json:"business_id,omitempty"`
+	Auth          *WebhookAuth      `json:"auth,omitempty"`
+	PayloadFormat string            `json:"payload_format"`
+	Enabled       bool              `json:"enabled"`
+	CreatedBy     string            `json:"created_by,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ErrInvalidWebhook Webhook 订阅配置无效
+var ErrInvalidWebhook = errors.New("invalid webhook subscription")
+
+// ErrWebhookNotFound Webhook 订阅不存在
+var ErrWebhookNotFound = errors.New("webhook subscription not found")
+
+// webhookTester 支持发送测试事件的事件处理器
+type webhookTester interface {
+	SendTestEvent(subscription *model.WebhookSubscriptionModel) *integration.WebhookTestResult
+}
+
+// webhookService Webhook 订阅服务实现
+type webhookService struct {
+	db           *gorm.DB
+	eventHandler event.EventHandler
+	signer       *integration.WebhookSigner
+	auditLogSvc  AuditLogService
+}
+
+// NewWebhookService 创建 Webhook 订阅服务
+// signer 用于加密保存 HMAC 签名密钥,eventHandler 用于发送测试事件
+func NewWebhookService(db *gorm.DB, eventHandler event.EventHandler, signer *integration.WebhookSigner, auditLogSvc AuditLogService) WebhookService {
+	return &webhookService{
+		db:           db,
+		eventHandler: eventHandler,
+		signer:       signer,
+		auditLogSvc:  auditLogSvc,
+	}
+}
+
+// generateWebhookID 生成 Webhook 订阅 ID
+func generateWebhookID() string {
+	return fmt.Sprintf("whk-%d", time.Now().UnixNano())
+}
+
+// Create 创建 Webhook 订阅
+func (s *webhookService) Create(ctx context.Context, req *CreateWebhookRequest) (*WebhookSubscription, error) {
+	now := time.Now()
+	subscription := &model.WebhookSubscriptionModel{
+		ID:        generateWebhookID(),
+		Enabled:   true,
+		CreatedBy: getUserIDFromContext(ctx),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.Enabled != nil {
+		subscription.Enabled = *req.Enabled
+	}
+
+	err := s.apply(subscription, &UpdateWebhookRequest{
+		Name:          req.Name,
+		URL:           req.URL,
+		Method:        req.Method,
+		Headers:       req.Headers,
+		EventTypes:    req.EventTypes,
+		TemplateID:    req.TemplateID,
+		BusinessID:    req.BusinessID,
+		Auth:          req.Auth,
+		PayloadFormat: req.PayloadFormat,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.save(subscription, req.Auth); err != nil {
+		return nil, err
+	}
+
+	s.recordAction(ctx, "create", subscription)
+	return toWebhookSubscription(subscription), nil
+}
+
+// Get 获取 Webhook 订阅
+func (s *webhookService) Get(id string) (*WebhookSubscription, error) {
+	subscription, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhookSubscription(subscription), nil
+}
+
+// List 列出所有 Webhook 订阅
+func (s *webhookService) List() ([]*WebhookSubscription, error) {
+	subscriptions, err := repository.NewWebhookSubscriptionRepository(s.db).List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %w", err)
+	}
+
+	result := make([]*WebhookSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		result = append(result, toWebhookSubscription(subscription))
+	}
+	return result, nil
+}
+
+// Update 更新 Webhook 订阅
+func (s *webhookService) Update(ctx context.Context, id string, req *UpdateWebhookRequest) (*WebhookSubscription, error) {
+	subscription, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 认证类型不变时未提供的令牌沿用原值(hmac 密钥保存在 webhook_secrets 表)
+	if req.Auth != nil && req.Auth.Type == subscription.AuthType && req.Auth.Token == "" {
+		req.Auth.Token = subscription.AuthToken
+	}
+	if err := s.apply(subscription, req); err != nil {
+		return nil, err
+	}
+	if req.Enabled != nil {
+		subscription.Enabled = *req.Enabled
+	}
+	subscription.UpdatedAt = time.Now()
+
+	if err := s.save(subscription, req.Auth); err != nil {
+		return nil, err
+	}
+
+	s.recordAction(ctx, "update", subscription)
+	return toWebhookSubscription(subscription), nil
+}
+
+// Delete 删除 Webhook 订阅及其签名密钥
+func (s *webhookService) Delete(ctx context.Context, id string) error {
+	subscription, err := s.find(id)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := repository.NewWebhookSubscriptionRepository(tx).Delete(id); err != nil {
+			return err
+		}
+		return tx.Where("template_id = ?", id).Delete(&model.WebhookSecretModel{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+
+	s.recordAction(ctx, "delete", subscription)
+	return nil
+}
+
+// Test 向订阅地址发送测试事件
+// 停用的订阅也可以测试,便于启用前确认接收方配置
+func (s *webhookService) Test(ctx context.Context, id string) (*integration.WebhookTestResult, error) {
+	subscription, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tester, ok := s.eventHandler.(webhookTester)
+	if !ok {
+		return nil, errors.New("event handler does not support test events")
+	}
+	result := tester.SendTestEvent(subscription)
+
+	s.recordAction(ctx, "test", subscription)
+	return result, nil
+}
+
+// find 查找 Webhook 订阅
+func (s *webhookService) find(id string) (*model.WebhookSubscriptionModel, error) {
+	subscription, err := repository.NewWebhookSubscriptionRepository(s.db).FindByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrWebhookNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// apply 校验请求并写入订阅(不含启用状态)
+func (s *webhookService) apply(subscription *model.WebhookSubscriptionModel, req *UpdateWebhookRequest) error {
+	// 1. 校验地址和请求方法
+	endpoint, err := url.Parse(req.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = "POST"
+	}
+	if method != "POST" && method != "PUT" && method != "PATCH" {
+		return fmt.Errorf("%w: unsupported method %q", ErrInvalidWebhook, req.Method)
+	}
+
+	// 2. 校验事件类型和请求体格式
+	for _, eventType := range req.EventTypes {
+		if !integration.IsTaskEventType(eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+	}
+	payloadFormat := req.PayloadFormat
+	if payloadFormat == "" {
+		payloadFormat = integration.PayloadFormatRaw
+	}
+	if !integration.IsPayloadFormat(payloadFormat) {
+		return fmt.Errorf("%w: unsupported payload format %q", ErrInvalidWebhook, req.PayloadFormat)
+	}
+
+	// 3. 校验认证配置
+	subscription.AuthType, subscription.AuthKey, subscription.AuthToken = "", "", ""
+	if req.Auth != nil && req.Auth.Type != "" {
+		switch req.Auth.Type {
+		case "bearer":
+		case "basic", "header":
+			if req.Auth.Key == "" {
+				return fmt.Errorf("%w: auth key is required for %s auth", ErrInvalidWebhook, req.Auth.Type)
+			}
+		case "hmac":
+		default:
+			return fmt.Errorf("%w: unsupported auth type %q", ErrInvalidWebhook, req.Auth.Type)
+		}
+		if req.Auth.Type != "hmac" && req.Auth.Token == "" {
+			return fmt.Errorf("%w: auth token is required for %s auth", ErrInvalidWebhook, req.Auth.Type)
+		}
+		subscription.AuthType = req.Auth.Type
+		subscription.AuthKey = req.Auth.Key
+		if req.Auth.Type != "hmac" {
+			subscription.AuthToken = req.Auth.Token
+		}
+	}
+
+	headers, err := json.Marshal(req.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal headers: %w", err)
+	}
+	eventTypes, err := json.Marshal(req.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event types: %w", err)
+	}
+
+	subscription.Name = strings.TrimSpace(req.Name)
+	subscription.URL = req.URL
+	subscription.Method = method
+	subscription.Headers = headers
+	subscription.EventTypes = eventTypes
+	subscription.TemplateID = req.TemplateID
+	subscription.BusinessID = req.BusinessID
+	subscription.PayloadFormat = payloadFormat
+	if err := subscription.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	return nil
+}
+
+// save 保存订阅,hmac 认证时在同一事务内保存或轮换签名密钥
+func (s *webhookService) save(subscription *model.WebhookSubscriptionModel, auth *WebhookAuth) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if subscription.AuthType == "hmac" {
+			if err := s.signer.SaveSecret(tx, subscription.ID, subscription.URL, auth.Token); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+			}
+		}
+		// 删除不再使用的签名密钥(地址变更或不再使用 hmac 认证)
+		stale := tx.Where("template_id = ?", subscription.ID)
+		if subscription.AuthType == "hmac" {
+			stale = stale.Where("endpoint <> ?", subscription.URL)
+		}
+		if err := stale.Delete(&model.WebhookSecretModel{}).Error; err != nil {
+			return fmt.Errorf("failed to delete webhook secret: %w", err)
+		}
+
+		if err := repository.NewWebhookSubscriptionRepository(tx).Save(subscription); err != nil {
+			return fmt.Errorf("failed to save webhook subscription: %w", err)
+		}
+		return nil
+	})
+}
+
+// recordAction 记录订阅操作审计日志
+func (s *webhookService) recordAction(ctx context.Context, action string, subscription *model.WebhookSubscriptionModel) {
+	if s.auditLogSvc == nil {
+		return
+	}
+	if userID := getUserIDFromContext(ctx); userID != "" {
+		details := map[string]interface{}{
+			"subscription_id": subscription.ID,
+			"name":            subscription.Name,
+			"url":             subscription.URL,
+		}
+		_ = s.auditLogSvc.RecordAction(ctx, userID, action, "webhook", subscription.ID, details)
+	}
+}
+
+// toWebhookSubscription 转换订阅模型(不包含认证令牌和签名密钥)
+func toWebhookSubscription(subscription *model.WebhookSubscriptionModel) *WebhookSubscription {
+	result := &WebhookSubscription{
+		ID:            subscription.ID,
+		Name:          subscription.Name,
+		URL:           subscription.URL,
+		Method:        subscription.Method,
+		EventTypes:    []string{},
+		TemplateID:    subscription.TemplateID,
+		BusinessID:    subscription.BusinessID,
+		PayloadFormat: subscription.PayloadFormat,
+		Enabled:       subscription.Enabled,
+		CreatedBy:     subscription.CreatedBy,
+		CreatedAt:     subscription.CreatedAt,
+		UpdatedAt:     subscription.UpdatedAt,
+	}
+	if len(subscription.Headers) > 0 {
+		_ = json.Unmarshal(subscription.Headers, &result.Headers)
+	}
+	if len(subscription.EventTypes) > 0 {
+		_ = json.Unmarshal(subscription.EventTypes, &result.EventTypes)
+		if result.EventTypes == nil {
+			result.EventTypes = []string{}
+		}
+	}
+	if subscription.AuthType != "" {
+		result.Auth = &WebhookAuth{Type: subscription.AuthType, Key: subscription.AuthKey}
+	}
+	return result
+}